@@ -58,14 +58,14 @@ const jsondata2 = `
 ]`
 
 func TestReader(t *testing.T) {
-	Uint256, _ := NewType("uint256", nil)
+	Uint256, _ := NewType("uint256", "", nil)
 	exp := ABI{
 		Methods: map[string]Method{
 			"balance": {
-				"balance", true, nil, nil,
+				"balance", "balance", true, nil, nil,
 			},
 			"send": {
-				"send", false, []Argument{
+				"send", "send", false, []Argument{
 					{"amount", Uint256, false},
 				}, nil,
 			},
@@ -179,8 +179,8 @@ func TestTestSlice(t *testing.T) {
 }
 
 func TestMethodSignature(t *testing.T) {
-	String, _ := NewType("string", nil)
-	m := Method{"foo", false, []Argument{{"bar", String, false}, {"baz", String, false}}, nil}
+	String, _ := NewType("string", "", nil)
+	m := Method{"foo", "foo", false, []Argument{{"bar", String, false}, {"baz", String, false}}, nil}
 	exp := "foo(string,string)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
@@ -191,15 +191,15 @@ func TestMethodSignature(t *testing.T) {
 		t.Errorf("expected ids to match %x != %x", m.Id(), idexp)
 	}
 
-	uintt, _ := NewType("uint256", nil)
-	m = Method{"foo", false, []Argument{{"bar", uintt, false}}, nil}
+	uintt, _ := NewType("uint256", "", nil)
+	m = Method{"foo", "foo", false, []Argument{{"bar", uintt, false}}, nil}
 	exp = "foo(uint256)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
 	}
 
 	// Method with tuple arguments
-	s, _ := NewType("tuple", []ArgumentMarshaling{
+	s, _ := NewType("tuple", "", []ArgumentMarshaling{
 		{Name: "a", Type: "int256"},
 		{Name: "b", Type: "int256[]"},
 		{Name: "c", Type: "tuple[]", Components: []ArgumentMarshaling{
@@ -211,13 +211,61 @@ func TestMethodSignature(t *testing.T) {
 			{Name: "y", Type: "int256"},
 		}},
 	})
-	m = Method{"foo", false, []Argument{{"s", s, false}, {"bar", String, false}}, nil}
+	m = Method{"foo", "foo", false, []Argument{{"s", s, false}, {"bar", String, false}}, nil}
 	exp = "foo((int256,int256[],(int256,int256)[],(int256,int256)[2]),string)"
 	if m.Sig() != exp {
 		t.Error("signature mismatch", exp, "!=", m.Sig())
 	}
 }
 
+func TestOverloadedMethodSignature(t *testing.T) {
+	json := `[
+		{"type":"function","name":"foo","constant":true,"inputs":[{"name":"i","type":"uint256"},{"name":"j","type":"uint256"}],"outputs":[]},
+		{"type":"function","name":"foo","constant":true,"inputs":[{"name":"i","type":"uint256"}],"outputs":[]},
+		{"type":"event","name":"bar","anonymous":false,"inputs":[{"indexed":false,"name":"i","type":"uint256"}]},
+		{"type":"event","name":"bar","anonymous":false,"inputs":[{"indexed":false,"name":"i","type":"uint256"},{"indexed":false,"name":"j","type":"uint256"}]}
+	]`
+	abi, err := JSON(strings.NewReader(json))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Overloads are suffixed in declaration order, keeping the raw name for the signature
+	methods := []struct{ name, sig string }{
+		{"foo", "foo(uint256,uint256)"},
+		{"foo0", "foo(uint256)"},
+	}
+	for _, m := range methods {
+		method, ok := abi.Methods[m.name]
+		if !ok {
+			t.Fatalf("method %s missing", m.name)
+		}
+		if method.RawName != "foo" || method.Sig() != m.sig {
+			t.Errorf("method %s: have raw name %s, signature %s; want foo, %s", m.name, method.RawName, method.Sig(), m.sig)
+		}
+	}
+	events := []struct{ name, sig string }{
+		{"bar", "bar(uint256)"},
+		{"bar0", "bar(uint256,uint256)"},
+	}
+	for _, e := range events {
+		event, ok := abi.Events[e.name]
+		if !ok {
+			t.Fatalf("event %s missing", e.name)
+		}
+		if event.RawName != "bar" || event.Id() != crypto.Keccak256Hash([]byte(e.sig)) {
+			t.Errorf("event %s: have raw name %s, id %x; want bar, id of %s", e.name, event.RawName, event.Id(), e.sig)
+		}
+	}
+	// Packing must use the selector of the overload requested
+	packed, err := abi.Pack("foo0", big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed[:4], crypto.Keccak256([]byte("foo(uint256)"))[:4]) {
+		t.Errorf("packed with wrong selector: %x", packed[:4])
+	}
+}
+
 func TestMultiPack(t *testing.T) {
 	abi, err := JSON(strings.NewReader(jsondata2))
 	if err != nil {
@@ -589,9 +637,9 @@ func TestBareEvents(t *testing.T) {
 	{ "type" : "event", "name" : "tuple", "inputs" : [{ "indexed":false, "name":"t", "type":"tuple", "components":[{"name":"a", "type":"uint256"}] }, { "indexed":true, "name":"arg1", "type":"address" }] }
 	]`
 
-	arg0, _ := NewType("uint256", nil)
-	arg1, _ := NewType("address", nil)
-	tuple, _ := NewType("tuple", []ArgumentMarshaling{{Name: "a", Type: "uint256"}})
+	arg0, _ := NewType("uint256", "", nil)
+	arg1, _ := NewType("address", "", nil)
+	tuple, _ := NewType("tuple", "", []ArgumentMarshaling{{Name: "a", Type: "uint256"}})
 
 	expectedEvents := map[string]struct {
 		Anonymous bool
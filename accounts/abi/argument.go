@@ -34,10 +34,11 @@ type Argument struct {
 type Arguments []Argument
 
 type ArgumentMarshaling struct {
-	Name       string
-	Type       string
-	Components []ArgumentMarshaling
-	Indexed    bool
+	Name         string
+	Type         string
+	InternalType string
+	Components   []ArgumentMarshaling
+	Indexed      bool
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface
@@ -48,7 +49,7 @@ func (argument *Argument) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("argument json err: %v", err)
 	}
 
-	argument.Type, err = NewType(arg.Type, arg.Components)
+	argument.Type, err = NewType(arg.Type, arg.InternalType, arg.Components)
 	if err != nil {
 		return err
 	}
@@ -110,7 +111,9 @@ func unpack(t *Type, dst interface{}, src interface{}) error {
 		srcVal = reflect.ValueOf(src)
 	)
 
-	if t.T != TupleTy && !((t.T == SliceTy || t.T == ArrayTy) && t.Elem.T == TupleTy) {
+	// Only tuples (at any nesting depth) need to be mapped field by field, the
+	// rest can be assigned directly
+	if !containsTuple(*t) {
 		return set(dstVal, srcVal)
 	}
 
@@ -160,6 +163,15 @@ func unpack(t *Type, dst interface{}, src interface{}) error {
 	return nil
 }
 
+// containsTuple reports whether the type is a tuple, or a (nested) array or slice
+// of tuples.
+func containsTuple(t Type) bool {
+	for t.T == SliceTy || t.T == ArrayTy {
+		t = *t.Elem
+	}
+	return t.T == TupleTy
+}
+
 // unpackAtomic unpacks ( hexdata -> go ) a single value
 func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interface{}) error {
 	if arguments.LengthNonIndexed() == 0 {
@@ -168,7 +180,9 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 	argument := arguments.NonIndexed()[0]
 	elem := reflect.ValueOf(v).Elem()
 
-	if elem.Kind() == reflect.Struct {
+	// A tuple may be unpacked directly into a struct of its own shape, anything
+	// else goes into the field of the struct named after the argument
+	if elem.Kind() == reflect.Struct && (argument.Type.T != TupleTy || hasArgumentField(elem, argument.Name)) {
 		fieldmap, err := mapArgNamesToStructFields([]string{argument.Name}, elem)
 		if err != nil {
 			return err
@@ -182,6 +196,19 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 	return unpack(&argument.Type, elem.Addr().Interface(), marshalledValues)
 }
 
+// hasArgumentField reports whether the struct value has a field for the named
+// argument, either through an abi tag or by its camel-cased name.
+func hasArgumentField(value reflect.Value, name string) bool {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if tag, ok := typ.Field(i).Tag.Lookup("abi"); ok && tag == name {
+			return true
+		}
+	}
+	field := ToCamelCase(name)
+	return field != "" && value.FieldByName(field).IsValid()
+}
+
 // unpackTuple unpacks ( hexdata -> go ) a batch of values.
 func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interface{}) error {
 	var (
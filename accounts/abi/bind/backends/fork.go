@@ -0,0 +1,266 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ForkSource is the remote chain a forked simulated backend lazily pulls its
+// state from. It is satisfied by *ethclient.Client.
+type ForkSource interface {
+	ethereum.ChainStateReader
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+var (
+	// forkTombstone marks a locally deleted account or storage slot, preventing
+	// the fork from falling back to the remote value. An empty RLP list is never
+	// a valid encoding of either.
+	forkTombstone = []byte{0xc0}
+
+	emptyCodeHash = crypto.Keccak256(nil)
+)
+
+// forkDatabase is a state.Database that serves any account, storage slot or
+// code missing from the local tries from a remote chain at a pinned block. All
+// remote data is cached, so each item is fetched at most once.
+type forkDatabase struct {
+	state.Database
+
+	remote ForkSource
+	number *big.Int // Remote block the state is forked from
+
+	lock     sync.Mutex
+	accounts map[common.Address][]byte                 // RLP encoded remote accounts, nil if nonexistent
+	storage  map[common.Address]map[common.Hash][]byte // RLP encoded remote storage slots, nil if empty
+	codes    map[common.Hash][]byte                    // Remote contract codes by hash
+	addrs    map[common.Hash]common.Address            // Address preimages of remote accounts with storage fallback
+	err      error                                     // First remote failure, the state database swallows them
+}
+
+// newForkDatabase wraps db into a state database falling back to remote at the
+// given block for any data not present locally.
+func newForkDatabase(db state.Database, remote ForkSource, number *big.Int) *forkDatabase {
+	return &forkDatabase{
+		Database: db,
+		remote:   remote,
+		number:   number,
+		accounts: make(map[common.Address][]byte),
+		storage:  make(map[common.Address]map[common.Hash][]byte),
+		codes:    make(map[common.Hash][]byte),
+		addrs:    make(map[common.Hash]common.Address),
+	}
+}
+
+// Err returns the first error encountered while fetching remote state.
+func (db *forkDatabase) Err() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.err
+}
+
+// OpenTrie opens the main account trie, falling back to the remote chain.
+func (db *forkDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, db: db}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account, falling back to the
+// remote chain if the account originates from there.
+func (db *forkDatabase) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenStorageTrie(addrHash, root)
+	if err != nil {
+		return nil, err
+	}
+	db.lock.Lock()
+	addr, ok := db.addrs[addrHash]
+	db.lock.Unlock()
+
+	if !ok {
+		return tr, nil
+	}
+	return &forkTrie{Trie: tr, db: db, account: &addr}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *forkDatabase) CopyTrie(t state.Trie) state.Trie {
+	if ft, ok := t.(*forkTrie); ok {
+		return &forkTrie{Trie: db.Database.CopyTrie(ft.Trie), db: db, account: ft.account}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// ContractCode retrieves a particular contract's code.
+func (db *forkDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	db.lock.Lock()
+	code, ok := db.codes[codeHash]
+	db.lock.Unlock()
+
+	if ok {
+		return code, nil
+	}
+	return db.Database.ContractCode(addrHash, codeHash)
+}
+
+// ContractCodeSize retrieves a particular contracts code's size.
+func (db *forkDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// account retrieves the RLP encoded remote account at addr, or nil if it does
+// not exist on the remote chain.
+func (db *forkDatabase) account(addr common.Address) ([]byte, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if enc, ok := db.accounts[addr]; ok {
+		return enc, nil
+	}
+	if db.err != nil {
+		return nil, db.err
+	}
+	enc, err := db.fetchAccount(addr)
+	if err != nil {
+		db.err = err
+		return nil, err
+	}
+	db.accounts[addr] = enc
+	return enc, nil
+}
+
+// fetchAccount retrieves the balance, nonce and code of addr from the remote
+// chain and assembles them into an account with an empty storage trie.
+func (db *forkDatabase) fetchAccount(addr common.Address) ([]byte, error) {
+	ctx := context.Background()
+
+	balance, err := db.remote.BalanceAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := db.remote.NonceAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	code, err := db.remote.CodeAt(ctx, addr, db.number)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Sign() == 0 && nonce == 0 && len(code) == 0 {
+		return nil, nil
+	}
+	codeHash := emptyCodeHash
+	if len(code) > 0 {
+		codeHash = crypto.Keccak256(code)
+		db.codes[common.BytesToHash(codeHash)] = code
+	}
+	db.addrs[crypto.Keccak256Hash(addr[:])] = addr
+
+	return rlp.EncodeToBytes(&state.Account{
+		Nonce:    nonce,
+		Balance:  balance,
+		Root:     types.EmptyRootHash,
+		CodeHash: codeHash,
+	})
+}
+
+// slot retrieves the RLP encoded remote storage slot of addr, or nil if empty.
+func (db *forkDatabase) slot(addr common.Address, key common.Hash) ([]byte, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if enc, ok := db.storage[addr][key]; ok {
+		return enc, nil
+	}
+	if db.err != nil {
+		return nil, db.err
+	}
+	val, err := db.remote.StorageAt(context.Background(), addr, key, db.number)
+	if err != nil {
+		db.err = err
+		return nil, err
+	}
+	var enc []byte
+	if val = bytes.TrimLeft(val, "\x00"); len(val) > 0 {
+		enc, _ = rlp.EncodeToBytes(val) // Encoding []byte cannot fail
+	}
+	if db.storage[addr] == nil {
+		db.storage[addr] = make(map[common.Hash][]byte)
+	}
+	db.storage[addr][key] = enc
+	return enc, nil
+}
+
+// forget drops the storage fallback of a locally deleted account, so that a
+// recreated one starts out empty.
+func (db *forkDatabase) forget(addr common.Address) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	delete(db.addrs, crypto.Keccak256Hash(addr[:]))
+}
+
+// forkTrie is a state trie that serves keys missing locally from the remote
+// chain. It wraps the account trie if account is nil, or the storage trie of
+// the given account otherwise.
+type forkTrie struct {
+	state.Trie
+	db      *forkDatabase
+	account *common.Address
+}
+
+// TryGet returns the value for key stored locally, retrieving it from the
+// remote chain if it was never written or deleted.
+func (t *forkTrie) TryGet(key []byte) ([]byte, error) {
+	enc, err := t.Trie.TryGet(key)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(enc, forkTombstone):
+		return nil, nil
+	case enc != nil:
+		return enc, nil
+	case t.account == nil:
+		return t.db.account(common.BytesToAddress(key))
+	default:
+		return t.db.slot(*t.account, common.BytesToHash(key))
+	}
+}
+
+// TryDelete removes key locally, shadowing any remote value.
+func (t *forkTrie) TryDelete(key []byte) error {
+	if t.account == nil {
+		t.db.forget(common.BytesToAddress(key))
+	}
+	return t.Trie.TryUpdate(key, forkTombstone)
+}
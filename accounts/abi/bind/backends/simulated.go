@@ -45,19 +45,36 @@ import (
 // This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
 
-var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
-var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
+// These nil assignments ensure compile time that SimulatedBackend implements the
+// chain, transaction and state reader interfaces of a full node client.
+var (
+	_ ethereum.ChainReader        = (*SimulatedBackend)(nil)
+	_ ethereum.TransactionReader  = (*SimulatedBackend)(nil)
+	_ ethereum.ChainStateReader   = (*SimulatedBackend)(nil)
+	_ ethereum.PendingStateReader = (*SimulatedBackend)(nil)
+)
+
+var (
+	errBlockDoesNotExist       = errors.New("block does not exist in blockchain")
+	errTransactionDoesNotExist = errors.New("transaction does not exist")
+	errGasEstimationFailed     = errors.New("gas required exceeds allowance or always failing transaction")
+	errTimeBeforeParent        = errors.New("simulated clock cannot move before the parent block")
+)
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
 // the background. Its main purpose is to allow easily testing contract bindings.
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	fork       *forkDatabase    // Remote state fallback if forked from a live chain
 
-	mu           sync.Mutex
-	pendingBlock *types.Block   // Currently pending block that will be imported on request
-	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	mu              sync.Mutex
+	pendingBlock    *types.Block   // Currently pending block that will be imported on request
+	pendingState    *state.StateDB // Currently pending state that will be the active on on request
+	pendingReceipts types.Receipts // Receipts of the transactions in the pending block
+	pendingTime     int64          // Simulated clock shift of the pending block, in seconds
 
+	mux    *event.TypeMux       // Event mux to announce pending logs on
 	events *filters.EventSystem // Event system for filtering log events live
 
 	config *params.ChainConfig
@@ -66,21 +83,79 @@ type SimulatedBackend struct {
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc, gasLimit uint64) *SimulatedBackend {
+	genesis := &core.Genesis{Config: params.AllEthashProtocolChanges, GasLimit: gasLimit, Alloc: alloc}
+	return newSimulatedBackend(ethdb.NewMemDatabase(), genesis, nil)
+}
+
+// NewForkedSimulatedBackend creates a new binding backend whose state is forked
+// from a remote chain at the given block, or at its head if blockNumber is nil.
+//
+// Accounts, storage and code are fetched from the remote chain on first access
+// and cached, while the accounts in alloc replace their remote counterparts. The
+// simulated chain starts from a genesis block with the timestamp, difficulty,
+// coinbase and base fee of the forked block, so local block numbers and hashes
+// have no relation to the remote ones.
+//
+// Remote failures are sticky: once a fetch fails, every method touching state
+// returns that error.
+func NewForkedSimulatedBackend(ctx context.Context, remote ForkSource, blockNumber *big.Int, alloc core.GenesisAlloc, gasLimit uint64) (*SimulatedBackend, error) {
+	header, err := remote.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	genesis := &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		Timestamp:  header.Time.Uint64(),
+		Difficulty: header.Difficulty,
+		Coinbase:   header.Coinbase,
+		BaseFee:    header.BaseFee,
+		GasLimit:   gasLimit,
+		Alloc:      alloc,
+	}
 	database := ethdb.NewMemDatabase()
-	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, GasLimit: gasLimit, Alloc: alloc}
+	fork := newForkDatabase(state.NewDatabase(database), remote, header.Number)
+
+	backend := newSimulatedBackend(database, genesis, fork)
+	if err := backend.forkErr(); err != nil {
+		return nil, err
+	}
+	return backend, nil
+}
+
+// newSimulatedBackend creates a simulated backend on top of the given genesis,
+// reading all state through fork if it is non-nil.
+func newSimulatedBackend(database ethdb.Database, genesis *core.Genesis, fork *forkDatabase) *SimulatedBackend {
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil)
 
+	var cacheConfig *core.CacheConfig
+	if fork != nil {
+		cacheConfig = &core.CacheConfig{
+			TrieCleanLimit: 256,
+			TrieDirtyLimit: 256,
+			TrieTimeLimit:  5 * time.Minute,
+			StateDatabase:  fork,
+		}
+	}
+	blockchain, _ := core.NewBlockChain(database, cacheConfig, genesis.Config, ethash.NewFaker(), vm.Config{}, nil)
+
+	mux := new(event.TypeMux)
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
+		fork:       fork,
 		config:     genesis.Config,
-		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		mux:        mux,
+		events:     filters.NewEventSystem(mux, &filterBackend{database, blockchain}, false),
 	}
 	backend.rollback()
 	return backend
 }
 
+// Blockchain returns the underlying blockchain of the simulated backend.
+func (b *SimulatedBackend) Blockchain() *core.BlockChain {
+	return b.blockchain
+}
+
 // Commit imports all the pending transactions as a single block and starts a
 // fresh new state.
 func (b *SimulatedBackend) Commit() {
@@ -102,11 +177,45 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
-	statedb, _ := b.blockchain.State()
+	b.pendingTime = 0
+	b.setPendingBlock(nil)
+}
 
+// setPendingBlock regenerates the pending block on top of the current head with
+// the given transactions, shifting its timestamp by the simulated clock first.
+func (b *SimulatedBackend) setPendingBlock(txs types.Transactions) {
+	blocks, receipts := core.GenerateChainWithState(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.blockchain.StateCache(), 1, func(number int, block *core.BlockGen) {
+		if b.pendingTime != 0 {
+			block.OffsetTime(b.pendingTime)
+		}
+		for _, tx := range txs {
+			block.AddTxWithChain(b.blockchain, tx)
+		}
+	})
 	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingReceipts = receipts[0]
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.blockchain.StateCache())
+}
+
+// forkErr returns the first failure to retrieve remote state, if forked.
+func (b *SimulatedBackend) forkErr() error {
+	if b.fork == nil {
+		return nil
+	}
+	return b.fork.Err()
+}
+
+// stateByBlockNumber retrieves the state at a given block number, or at the
+// current head if blockNumber is nil.
+func (b *SimulatedBackend) stateByBlockNumber(ctx context.Context, blockNumber *big.Int) (*state.StateDB, error) {
+	if blockNumber == nil || blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) == 0 {
+		return b.blockchain.State()
+	}
+	block, err := b.blockByNumberNoLock(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return b.blockchain.StateAt(block.Root())
 }
 
 // CodeAt returns the code associated with a certain account in the blockchain.
@@ -114,11 +223,11 @@ func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetCode(contract), nil
+	return statedb.GetCode(contract), b.forkErr()
 }
 
 // BalanceAt returns the wei balance of a certain account in the blockchain.
@@ -126,11 +235,11 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetBalance(contract), nil
+	return statedb.GetBalance(contract), b.forkErr()
 }
 
 // NonceAt returns the nonce of a certain account in the blockchain.
@@ -138,11 +247,11 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return 0, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return 0, err
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetNonce(contract), nil
+	return statedb.GetNonce(contract), b.forkErr()
 }
 
 // StorageAt returns the value of key in the storage of an account in the blockchain.
@@ -150,12 +259,12 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	statedb, err := b.stateByBlockNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, err
 	}
-	statedb, _ := b.blockchain.State()
 	val := statedb.GetState(contract, key)
-	return val[:], nil
+	return val[:], b.forkErr()
 }
 
 // TransactionReceipt returns the receipt of a transaction.
@@ -164,12 +273,154 @@ func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common
 	return receipt, nil
 }
 
+// TransactionByHash checks the pool of pending transactions in addition to the
+// blockchain. The isPending return value indicates whether the transaction has
+// been mined yet. Note that the transaction may not be part of the canonical
+// chain even if it's not pending.
+func (b *SimulatedBackend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if tx := b.pendingBlock.Transaction(txHash); tx != nil {
+		return tx, true, nil
+	}
+	if tx, _, _, _ := rawdb.ReadTransaction(b.database, txHash); tx != nil {
+		return tx, false, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+// BlockByHash retrieves a block based on the block hash.
+func (b *SimulatedBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockByHashNoLock(ctx, hash)
+}
+
+// blockByHashNoLock retrieves a block based on the block hash without locking,
+// including the pending one.
+func (b *SimulatedBackend) blockByHashNoLock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if hash == b.pendingBlock.Hash() {
+		return b.pendingBlock, nil
+	}
+	if block := b.blockchain.GetBlockByHash(hash); block != nil {
+		return block, nil
+	}
+	return nil, errBlockDoesNotExist
+}
+
+// BlockByNumber retrieves a block from the database by number, taking care of
+// the nil number meaning the current head.
+func (b *SimulatedBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockByNumberNoLock(ctx, number)
+}
+
+// blockByNumberNoLock retrieves a block from the database by number without
+// locking, taking care of the nil number meaning the current head.
+func (b *SimulatedBackend) blockByNumberNoLock(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if number == nil || number.Cmp(b.blockchain.CurrentBlock().Number()) == 0 {
+		return b.blockchain.CurrentBlock(), nil
+	}
+	if !number.IsUint64() {
+		return nil, errBlockDoesNotExist
+	}
+	if block := b.blockchain.GetBlockByNumber(number.Uint64()); block != nil {
+		return block, nil
+	}
+	return nil, errBlockDoesNotExist
+}
+
+// HeaderByHash returns a block header from the current canonical chain.
+func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if hash == b.pendingBlock.Hash() {
+		return b.pendingBlock.Header(), nil
+	}
+	if header := b.blockchain.GetHeaderByHash(hash); header != nil {
+		return header, nil
+	}
+	return nil, errBlockDoesNotExist
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If
+// number is nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block, err := b.blockByNumberNoLock(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+// TransactionCount returns the number of transactions in a given block.
+func (b *SimulatedBackend) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block, err := b.blockByHashNoLock(ctx, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	return uint(block.Transactions().Len()), nil
+}
+
+// TransactionInBlock returns the transaction for a specific block at a specific index.
+func (b *SimulatedBackend) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block, err := b.blockByHashNoLock(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if uint(len(txs)) <= index {
+		return nil, errTransactionDoesNotExist
+	}
+	return txs[index], nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	return b.pendingState.GetCode(contract), nil
+	return b.pendingState.GetCode(contract), b.forkErr()
+}
+
+// PendingBalanceAt returns the wei balance of an account in the pending state.
+func (b *SimulatedBackend) PendingBalanceAt(ctx context.Context, contract common.Address) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pendingState.GetBalance(contract), b.forkErr()
+}
+
+// PendingStorageAt returns the value of key in the storage of an account in the
+// pending state.
+func (b *SimulatedBackend) PendingStorageAt(ctx context.Context, contract common.Address, key common.Hash) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	val := b.pendingState.GetState(contract, key)
+	return val[:], b.forkErr()
+}
+
+// PendingTransactionCount returns the number of transactions in the pending block.
+func (b *SimulatedBackend) PendingTransactionCount(ctx context.Context) (uint, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return uint(b.pendingBlock.Transactions().Len()), nil
 }
 
 // CallContract executes a contract call.
@@ -177,15 +428,19 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallM
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	block, err := b.blockByNumberNoLock(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	state, err := b.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
 	}
-	state, err := b.blockchain.State()
+	rval, _, _, err := b.callContract(ctx, call, block, state)
 	if err != nil {
 		return nil, err
 	}
-	rval, _, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
-	return rval, err
+	return rval, b.forkErr()
 }
 
 // PendingCallContract executes a contract call on the pending state.
@@ -195,7 +450,10 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call ethereu
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
 	rval, _, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
-	return rval, err
+	if err != nil {
+		return nil, err
+	}
+	return rval, b.forkErr()
 }
 
 // PendingNonceAt implements PendingStateReader.PendingNonceAt, retrieving
@@ -204,7 +462,7 @@ func (b *SimulatedBackend) PendingNonceAt(ctx context.Context, account common.Ad
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	return b.pendingState.GetOrNewStateObject(account).Nonce(), nil
+	return b.pendingState.GetOrNewStateObject(account).Nonce(), b.forkErr()
 }
 
 // SuggestGasPrice implements ContractTransactor.SuggestGasPrice. Since the simulated
@@ -254,6 +512,10 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 			hi = mid
 		}
 	}
+	// Failed remote state lookups make every execution look broken, report them instead
+	if err := b.forkErr(); err != nil {
+		return 0, err
+	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
 		if !executable(hi) {
@@ -267,8 +529,30 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 // state is modified during execution, make sure to copy it if necessary.
 func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallMsg, block *types.Block, statedb *state.StateDB) ([]byte, uint64, bool, error) {
 	// Ensure message is initialized properly.
-	if call.GasPrice == nil {
-		call.GasPrice = big.NewInt(1)
+	if baseFee := block.BaseFee(); baseFee == nil {
+		if call.GasPrice == nil {
+			call.GasPrice = big.NewInt(1)
+		}
+		call.GasFeeCap, call.GasTipCap = call.GasPrice, call.GasPrice
+	} else {
+		// A base fee is provided, necessitating EIP-1559 style execution
+		if call.GasPrice != nil {
+			// User specified the legacy gas field, convert to 1559 gas typing
+			call.GasFeeCap, call.GasTipCap = call.GasPrice, call.GasPrice
+		} else {
+			// User specified 1559 gas fields (or none), use those
+			if call.GasFeeCap == nil {
+				call.GasFeeCap = new(big.Int)
+			}
+			if call.GasTipCap == nil {
+				call.GasTipCap = new(big.Int)
+			}
+			// Backfill the legacy gasPrice for EVM execution, unless we're all zeroes
+			call.GasPrice = new(big.Int)
+			if call.GasFeeCap.BitLen() > 0 || call.GasTipCap.BitLen() > 0 {
+				call.GasPrice = math.BigMin(new(big.Int).Add(call.GasTipCap, baseFee), call.GasFeeCap)
+			}
+		}
 	}
 	if call.Gas == 0 {
 		call.Gas = 50000000
@@ -285,37 +569,41 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	evmContext := core.NewEVMContext(msg, block.Header(), b.blockchain, nil)
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{})
+	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{NoBaseFee: true})
 	gaspool := new(core.GasPool).AddGas(math.MaxUint64)
 
 	return core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
 }
 
-// SendTransaction updates the pending block to include the given transaction.
-// It panics if the transaction is invalid.
+// SendTransaction updates the pending block to include the given transaction
+// and announces its logs to pending log subscribers. It panics if the
+// transaction is invalid.
 func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(types.LatestSigner(b.config), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
 	nonce := b.pendingState.GetNonce(sender)
+	if err := b.forkErr(); err != nil {
+		return err
+	}
 	if tx.Nonce() != nonce {
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
+	txs := make(types.Transactions, 0, len(b.pendingBlock.Transactions())+1)
+	txs = append(append(txs, b.pendingBlock.Transactions()...), tx)
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
-		}
-		block.AddTxWithChain(b.blockchain, tx)
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.setPendingBlock(txs)
+	if err := b.forkErr(); err != nil {
+		return err
+	}
+	// Announce the new logs the same way the miner does for its pending block
+	if logs := b.pendingReceipts[len(b.pendingReceipts)-1].Logs; len(logs) > 0 {
+		go b.mux.Post(core.PendingLogsEvent{Logs: logs})
+	}
 	return nil
 }
 
@@ -387,22 +675,49 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethere
 	}), nil
 }
 
-// AdjustTime adds a time shift to the simulated clock.
+// SubscribeNewHead returns an event subscription for a new header.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	// Subscribe to new chain heads
+	sink := make(chan *types.Header)
+	sub := b.events.SubscribeNewHeads(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case head := <-sink:
+				select {
+				case ch <- head:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// AdjustTime adds a time shift to the simulated clock. The pending block is
+// regenerated with the shifted timestamp, re-executing its transactions so the
+// pending state reflects the new time. The shift persists through Commit via
+// the sealed block's timestamp, and is dropped by Rollback.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
-	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	offset := int64(adjustment.Seconds())
+	if new(big.Int).Add(b.pendingBlock.Time(), big.NewInt(offset)).Cmp(b.blockchain.CurrentBlock().Time()) <= 0 {
+		return errTimeBeforeParent
+	}
+	b.pendingTime += offset
+	b.setPendingBlock(b.pendingBlock.Transactions())
 
-	return nil
+	return b.forkErr()
 }
 
 // callmsg implements core.Message to allow passing it as a transaction simulator.
@@ -410,14 +725,17 @@ type callmsg struct {
 	ethereum.CallMsg
 }
 
-func (m callmsg) From() common.Address { return m.CallMsg.From }
-func (m callmsg) Nonce() uint64        { return 0 }
-func (m callmsg) CheckNonce() bool     { return false }
-func (m callmsg) To() *common.Address  { return m.CallMsg.To }
-func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
-func (m callmsg) Gas() uint64          { return m.CallMsg.Gas }
-func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
-func (m callmsg) Data() []byte         { return m.CallMsg.Data }
+func (m callmsg) From() common.Address         { return m.CallMsg.From }
+func (m callmsg) Nonce() uint64                { return 0 }
+func (m callmsg) CheckNonce() bool             { return false }
+func (m callmsg) To() *common.Address          { return m.CallMsg.To }
+func (m callmsg) GasPrice() *big.Int           { return m.CallMsg.GasPrice }
+func (m callmsg) GasFeeCap() *big.Int          { return m.CallMsg.GasFeeCap }
+func (m callmsg) GasTipCap() *big.Int          { return m.CallMsg.GasTipCap }
+func (m callmsg) Gas() uint64                  { return m.CallMsg.Gas }
+func (m callmsg) Value() *big.Int              { return m.CallMsg.Value }
+func (m callmsg) Data() []byte                 { return m.CallMsg.Data }
+func (m callmsg) AccessList() types.AccessList { return m.CallMsg.AccessList }
 
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
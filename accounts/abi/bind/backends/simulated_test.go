@@ -0,0 +1,301 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// This nil assignment ensures compile time that an RPC client can be forked from.
+var _ ForkSource = (*ethclient.Client)(nil)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbea8e32bb")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(params.Ether)
+
+	// timeAddr holds code returning the current block timestamp.
+	timeAddr = common.Address{0x01}
+	timeCode = common.FromHex("0x4260005260206000f3")
+
+	// storeAddr holds code storing the first calldata word into slot 0.
+	storeAddr = common.Address{0x02}
+	storeCode = common.FromHex("0x60003560005500")
+
+	// logAddr holds code emitting an empty log.
+	logAddr = common.Address{0x03}
+	logCode = common.FromHex("0x60006000a000")
+)
+
+func newTestBackend() *SimulatedBackend {
+	return NewSimulatedBackend(core.GenesisAlloc{
+		testAddr:  {Balance: testBalance},
+		timeAddr:  {Code: timeCode, Balance: new(big.Int)},
+		storeAddr: {Code: storeCode, Balance: new(big.Int), Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}},
+		logAddr:   {Code: logCode, Balance: new(big.Int)},
+	}, 10000000)
+}
+
+// sendTx signs and submits a transaction from the test account.
+func sendTx(t *testing.T, sim *SimulatedBackend, to common.Address, value *big.Int, data []byte) *types.Transaction {
+	nonce, err := sim.PendingNonceAt(context.Background(), testAddr)
+	if err != nil {
+		t.Fatalf("failed to retrieve nonce: %v", err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, value, 100000, big.NewInt(1), data), types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	return tx
+}
+
+func TestSimulatedBackendChainReader(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	tx := sendTx(t, sim, common.Address{0xff}, big.NewInt(1000), nil)
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || !pending {
+		t.Fatalf("pending transaction lookup: pending %v, err %v", pending, err)
+	}
+	if n, _ := sim.PendingTransactionCount(ctx); n != 1 {
+		t.Fatalf("pending transaction count mismatch: have %d, want 1", n)
+	}
+	sim.Commit()
+
+	if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err != nil || pending {
+		t.Fatalf("mined transaction lookup: pending %v, err %v", pending, err)
+	}
+	if _, _, err := sim.TransactionByHash(ctx, common.Hash{1}); err != ethereum.NotFound {
+		t.Fatalf("unknown transaction lookup error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+	block, err := sim.BlockByNumber(ctx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to retrieve block 1: %v", err)
+	}
+	if head, _ := sim.BlockByNumber(ctx, nil); head.Hash() != block.Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head.Hash(), block.Hash())
+	}
+	if header, err := sim.HeaderByHash(ctx, block.Hash()); err != nil || header.Number.Uint64() != 1 {
+		t.Fatalf("header by hash mismatch: %v, err %v", header, err)
+	}
+	if _, err := sim.BlockByNumber(ctx, big.NewInt(2)); err != errBlockDoesNotExist {
+		t.Fatalf("future block error mismatch: have %v, want %v", err, errBlockDoesNotExist)
+	}
+	if n, _ := sim.TransactionCount(ctx, block.Hash()); n != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want 1", n)
+	}
+	if have, err := sim.TransactionInBlock(ctx, block.Hash(), 0); err != nil || have.Hash() != tx.Hash() {
+		t.Fatalf("transaction in block mismatch: %v, err %v", have, err)
+	}
+	if _, err := sim.TransactionInBlock(ctx, block.Hash(), 1); err != errTransactionDoesNotExist {
+		t.Fatalf("out of range transaction error mismatch: have %v, want %v", err, errTransactionDoesNotExist)
+	}
+	// Historical state must remain accessible
+	if balance, _ := sim.BalanceAt(ctx, common.Address{0xff}, big.NewInt(0)); balance.Sign() != 0 {
+		t.Fatalf("genesis balance mismatch: have %v, want 0", balance)
+	}
+	if balance, _ := sim.BalanceAt(ctx, common.Address{0xff}, nil); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("head balance mismatch: have %v, want 1000", balance)
+	}
+}
+
+func TestSimulatedBackendAdjustTime(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	parent, _ := sim.HeaderByNumber(ctx, nil)
+	sendTx(t, sim, common.Address{0xff}, big.NewInt(1), nil)
+
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	want := new(big.Int).Add(parent.Time, big.NewInt(3610))
+	out, err := sim.PendingCallContract(ctx, ethereum.CallMsg{To: &timeAddr})
+	if err != nil {
+		t.Fatalf("failed to call contract: %v", err)
+	}
+	if have := new(big.Int).SetBytes(out); have.Cmp(want) != 0 {
+		t.Fatalf("pending timestamp mismatch: have %v, want %v", have, want)
+	}
+	if n, _ := sim.PendingTransactionCount(ctx); n != 1 {
+		t.Fatalf("pending transactions dropped: have %d, want 1", n)
+	}
+	if err := sim.AdjustTime(-2 * time.Hour); err != errTimeBeforeParent {
+		t.Fatalf("backwards time error mismatch: have %v, want %v", err, errTimeBeforeParent)
+	}
+	sim.Commit()
+
+	if head, _ := sim.HeaderByNumber(ctx, nil); head.Time.Cmp(want) != 0 {
+		t.Fatalf("committed timestamp mismatch: have %v, want %v", head.Time, want)
+	}
+}
+
+func TestSimulatedBackendSubscriptions(t *testing.T) {
+	sim := newTestBackend()
+	ctx := context.Background()
+
+	logs := make(chan types.Log)
+	pending := big.NewInt(rpc.PendingBlockNumber.Int64())
+	logSub, err := sim.SubscribeFilterLogs(ctx, ethereum.FilterQuery{FromBlock: pending, ToBlock: pending}, logs)
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending logs: %v", err)
+	}
+	defer logSub.Unsubscribe()
+
+	heads := make(chan *types.Header)
+	headSub, err := sim.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		t.Fatalf("failed to subscribe to new heads: %v", err)
+	}
+	defer headSub.Unsubscribe()
+
+	sendTx(t, sim, logAddr, nil, nil)
+	select {
+	case log := <-logs:
+		if log.Address != logAddr {
+			t.Fatalf("pending log address mismatch: have %x, want %x", log.Address, logAddr)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("pending log not delivered")
+	}
+	sim.Commit()
+	select {
+	case head := <-heads:
+		if head.Number.Uint64() != 1 {
+			t.Fatalf("new head number mismatch: have %v, want 1", head.Number)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("new head not delivered")
+	}
+}
+
+// countingSource is a fork source counting remote lookups, optionally failing them.
+type countingSource struct {
+	ForkSource
+
+	lock  sync.Mutex
+	calls int
+	fail  bool
+}
+
+func (s *countingSource) count() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls++
+	if s.fail {
+		return errors.New("remote unavailable")
+	}
+	return nil
+}
+
+func (s *countingSource) Calls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.calls
+}
+
+func (s *countingSource) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	if err := s.count(); err != nil {
+		return nil, err
+	}
+	return s.ForkSource.BalanceAt(ctx, account, number)
+}
+
+func (s *countingSource) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	if err := s.count(); err != nil {
+		return nil, err
+	}
+	return s.ForkSource.StorageAt(ctx, account, key, number)
+}
+
+func TestForkedSimulatedBackend(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a remote chain and fork it before some further changes
+	remote := newTestBackend()
+	sendTx(t, remote, common.Address{0xff}, big.NewInt(1000), nil)
+	remote.Commit()
+	sendTx(t, remote, storeAddr, nil, common.LeftPadBytes([]byte{9}, 32))
+	remote.Commit()
+
+	source := &countingSource{ForkSource: remote}
+	override := common.Address{0xee}
+	sim, err := NewForkedSimulatedBackend(ctx, source, big.NewInt(1), core.GenesisAlloc{override: {Balance: big.NewInt(42)}}, 10000000)
+	if err != nil {
+		t.Fatalf("failed to fork remote chain: %v", err)
+	}
+	if head, _ := remote.HeaderByNumber(ctx, big.NewInt(1)); sim.Blockchain().Genesis().Time().Cmp(head.Time) != 0 {
+		t.Fatalf("forked genesis time mismatch: have %v, want %v", sim.Blockchain().Genesis().Time(), head.Time)
+	}
+	// Remote state must be served as of the fork block and cached
+	if balance, err := sim.BalanceAt(ctx, common.Address{0xff}, nil); err != nil || balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("forked balance mismatch: have %v, want 1000, err %v", balance, err)
+	}
+	if code, err := sim.CodeAt(ctx, storeAddr, nil); err != nil || string(code) != string(storeCode) {
+		t.Fatalf("forked code mismatch: have %x, want %x, err %v", code, storeCode, err)
+	}
+	if val, err := sim.StorageAt(ctx, storeAddr, common.Hash{}, nil); err != nil || new(big.Int).SetBytes(val).Uint64() != 7 {
+		t.Fatalf("forked storage mismatch: have %x, want 7, err %v", val, err)
+	}
+	calls := source.Calls()
+	sim.BalanceAt(ctx, common.Address{0xff}, nil)
+	sim.StorageAt(ctx, storeAddr, common.Hash{}, nil)
+	if source.Calls() != calls {
+		t.Fatalf("cached state refetched: %d remote calls, want %d", source.Calls(), calls)
+	}
+	// Genesis allocations must override the remote chain
+	if balance, _ := sim.BalanceAt(ctx, override, nil); balance.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("overridden balance mismatch: have %v, want 42", balance)
+	}
+	// Local transactions spend remote funds and shadow remote storage, including clearing it
+	sendTx(t, sim, storeAddr, nil, make([]byte, 32))
+	sim.Commit()
+
+	if nonce, _ := sim.NonceAt(ctx, testAddr, nil); nonce != 2 {
+		t.Fatalf("forked nonce mismatch: have %d, want 2", nonce)
+	}
+	if val, _ := sim.StorageAt(ctx, storeAddr, common.Hash{}, nil); new(big.Int).SetBytes(val).Sign() != 0 {
+		t.Fatalf("cleared storage mismatch: have %x, want 0", val)
+	}
+	if val, _ := sim.StorageAt(ctx, storeAddr, common.Hash{}, big.NewInt(0)); new(big.Int).SetBytes(val).Uint64() != 7 {
+		t.Fatalf("historical storage mismatch: have %x, want 7", val)
+	}
+	// Remote failures must be surfaced instead of reading as empty state
+	source.fail = true
+	if _, err := sim.BalanceAt(ctx, common.Address{0xdd}, nil); err == nil {
+		t.Fatalf("remote failure not reported")
+	}
+}
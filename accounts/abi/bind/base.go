@@ -254,7 +254,7 @@ func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]int
 	// Append the event selector to the query parameters and construct the topic set
 	query = append([][]interface{}{{c.abi.Events[name].Id()}}, query...)
 
-	topics, err := makeTopics(query...)
+	topics, err := MakeTopics(query...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -303,7 +303,7 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	// Append the event selector to the query parameters and construct the topic set
 	query = append([][]interface{}{{c.abi.Events[name].Id()}}, query...)
 
-	topics, err := makeTopics(query...)
+	topics, err := MakeTopics(query...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -22,12 +22,13 @@ package bind
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"go/format"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
-	"unicode"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 )
@@ -47,7 +48,10 @@ const (
 // manually maintain hard coded strings that break on runtime.
 func Bind(types []string, abis []string, bytecodes []string, pkg string, lang Lang) (string, error) {
 	// Process each individual contract requested binding
-	contracts := make(map[string]*tmplContract)
+	var (
+		contracts = make(map[string]*tmplContract)
+		structs   = make(map[string]*tmplStruct)
+	)
 
 	for i := 0; i < len(types); i++ {
 		// Parse the actual ABI to generate the binding for
@@ -55,13 +59,13 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 		if err != nil {
 			return "", err
 		}
-		// Strip any whitespace from the JSON ABI
-		strippedABI := strings.Map(func(r rune) rune {
-			if unicode.IsSpace(r) {
-				return -1
-			}
-			return r
-		}, abis[i])
+		// Strip any whitespace from the JSON ABI, keeping the contents of strings
+		// (e.g. struct internal types) intact
+		stripped := new(bytes.Buffer)
+		if err := json.Compact(stripped, []byte(abis[i])); err != nil {
+			return "", err
+		}
+		strippedABI := stripped.String()
 
 		// Extract the call and transact methods; events; and sort them alphabetically
 		var (
@@ -69,7 +73,9 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 			transacts = make(map[string]*tmplMethod)
 			events    = make(map[string]*tmplEvent)
 		)
-		for _, original := range evmABI.Methods {
+		for _, name := range sortedMethods(evmABI.Methods) {
+			original := evmABI.Methods[name]
+
 			// Normalize the method for capital cases and non-anonymous inputs/outputs
 			normalized := original
 			normalized.Name = methodNormalizer[lang](original.Name)
@@ -88,6 +94,10 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 					normalized.Outputs[j].Name = capitalise(output.Name)
 				}
 			}
+			// Bind any struct types used by the method, so they get a stable name
+			for _, arg := range append(normalized.Inputs, normalized.Outputs...) {
+				bindType[lang](arg.Type, structs)
+			}
 			// Append the methods to the call or transact lists
 			if original.Const {
 				calls[original.Name] = &tmplMethod{Original: original, Normalized: normalized, Structured: structured(original.Outputs)}
@@ -95,7 +105,9 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 				transacts[original.Name] = &tmplMethod{Original: original, Normalized: normalized, Structured: structured(original.Outputs)}
 			}
 		}
-		for _, original := range evmABI.Events {
+		for _, name := range sortedEvents(evmABI.Events) {
+			original := evmABI.Events[name]
+
 			// Skip anonymous events as they don't support explicit filtering
 			if original.Anonymous {
 				continue
@@ -114,6 +126,10 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 					}
 				}
 			}
+			// Bind any struct types used by the event, so they get a stable name
+			for _, arg := range normalized.Inputs {
+				bindType[lang](arg.Type, structs)
+			}
 			// Append the event to the accumulator list
 			events[original.Name] = &tmplEvent{Original: original, Normalized: normalized}
 		}
@@ -131,6 +147,7 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	data := &tmplData{
 		Package:   pkg,
 		Contracts: contracts,
+		Structs:   structs,
 	}
 	buffer := new(bytes.Buffer)
 
@@ -157,9 +174,31 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 	return buffer.String(), nil
 }
 
+// sortedMethods returns the names of the given methods in alphabetical order, so
+// that bindings are generated deterministically.
+func sortedMethods(methods map[string]abi.Method) []string {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedEvents returns the names of the given events in alphabetical order, so
+// that bindings are generated deterministically.
+func sortedEvents(events map[string]abi.Event) []string {
+	names := make([]string, 0, len(events))
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // bindType is a set of type binders that convert Solidity types to some supported
 // programming language types.
-var bindType = map[Lang]func(kind abi.Type) string{
+var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTypeGo,
 	LangJava: bindTypeJava,
 }
@@ -198,13 +237,65 @@ func arrayBindingGo(inner string, arraySizes []string) string {
 
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
 // from all Solidity types to Go ones (e.g. uint17), those that cannot be exactly
-// mapped will use an upscaled type (e.g. *big.Int).
-func bindTypeGo(kind abi.Type) string {
+// mapped will use an upscaled type (e.g. *big.Int). Tuples are mapped to named
+// struct types, which are collected into structs.
+func bindTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	switch kind.T {
+	case abi.TupleTy:
+		return bindStructTypeGo(kind, structs)
+	case abi.ArrayTy:
+		if hasTuple(kind) {
+			return fmt.Sprintf("[%d]", kind.Size) + bindTypeGo(*kind.Elem, structs)
+		}
+	case abi.SliceTy:
+		if hasTuple(kind) {
+			return "[]" + bindTypeGo(*kind.Elem, structs)
+		}
+	}
 	stringKind := kind.String()
 	innerLen, innerMapping := bindUnnestedTypeGo(stringKind)
 	return arrayBindingGo(wrapArray(stringKind, innerLen, innerMapping))
 }
 
+// hasTuple reports whether the innermost element of a (nested) array or slice
+// type is a tuple.
+func hasTuple(kind abi.Type) bool {
+	for kind.T == abi.ArrayTy || kind.T == abi.SliceTy {
+		kind = *kind.Elem
+	}
+	return kind.T == abi.TupleTy
+}
+
+// bindStructTypeGo converts a Solidity tuple to a named Go struct, registering
+// its definition in structs if it was not seen before. The struct is named after
+// the Solidity one when the ABI carries internal type information, otherwise a
+// name is derived from the order in which tuples are encountered.
+func bindStructTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	// Old compilers don't report struct names, so the canonical expression is
+	// also part of the identifier to tell apart different unnamed tuples.
+	id := kind.TupleRawName + kind.String()
+	if s, exist := structs[id]; exist {
+		return s.Name
+	}
+	var fields []*tmplField
+	for i, elem := range kind.TupleElems {
+		fields = append(fields, &tmplField{
+			Type:    bindTypeGo(*elem, structs),
+			Name:    capitalise(kind.TupleRawNames[i]),
+			SolKind: *elem,
+		})
+	}
+	name := kind.TupleRawName
+	if name == "" {
+		name = fmt.Sprintf("Struct%d", len(structs))
+	}
+	structs[id] = &tmplStruct{
+		Name:   name,
+		Fields: fields,
+	}
+	return name
+}
+
 // The inner function of bindTypeGo, this finds the inner type of stringKind.
 // (Or just the type itself if it is not an array or slice)
 // The length of the matched part is returned, with the translated type.
@@ -226,6 +317,10 @@ func bindUnnestedTypeGo(stringKind string) (int, string) {
 		}
 		return len(parts[0]), "*big.Int"
 
+	case strings.HasPrefix(stringKind, "fixed") || strings.HasPrefix(stringKind, "ufixed"):
+		parts := regexp.MustCompile(`u?fixed[0-9]+x[0-9]+`).FindStringSubmatch(stringKind)
+		return len(parts[0]), "*big.Rat"
+
 	case strings.HasPrefix(stringKind, "bool"):
 		return len("bool"), "bool"
 
@@ -247,7 +342,7 @@ func arrayBindingJava(inner string, arraySizes []string) string {
 // bindTypeJava converts a Solidity type to a Java one. Since there is no clear mapping
 // from all Solidity types to Java ones (e.g. uint17), those that cannot be exactly
 // mapped will use an upscaled type (e.g. BigDecimal).
-func bindTypeJava(kind abi.Type) string {
+func bindTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
 	stringKind := kind.String()
 	innerLen, innerMapping := bindUnnestedTypeJava(stringKind)
 	return arrayBindingJava(wrapArray(stringKind, innerLen, innerMapping))
@@ -310,25 +405,28 @@ func bindUnnestedTypeJava(stringKind string) (int, string) {
 
 // bindTopicType is a set of type binders that convert Solidity types to some
 // supported programming language topic types.
-var bindTopicType = map[Lang]func(kind abi.Type) string{
+var bindTopicType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
 	LangGo:   bindTopicTypeGo,
 	LangJava: bindTopicTypeJava,
 }
 
-// bindTypeGo converts a Solidity topic type to a Go one. It is almost the same
+// bindTopicTypeGo converts a Solidity topic type to a Go one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeGo(kind abi.Type) string {
-	bound := bindTypeGo(kind)
-	if bound == "string" || bound == "[]byte" {
-		bound = "common.Hash"
+//
+// Indexed values which are not value types (strings, bytes, arrays and structs)
+// are not stored in the topics directly, only the Keccak256 hash of their encoding.
+func bindTopicTypeGo(kind abi.Type, structs map[string]*tmplStruct) string {
+	switch kind.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return "common.Hash"
 	}
-	return bound
+	return bindTypeGo(kind, structs)
 }
 
 // bindTypeGo converts a Solidity topic type to a Java one. It is almost the same
 // funcionality as for simple types, but dynamic types get converted to hashes.
-func bindTopicTypeJava(kind abi.Type) string {
-	bound := bindTypeJava(kind)
+func bindTopicTypeJava(kind abi.Type, structs map[string]*tmplStruct) string {
+	bound := bindTypeJava(kind, structs)
 	if bound == "String" || bound == "Bytes" {
 		bound = "Hash"
 	}
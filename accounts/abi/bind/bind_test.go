@@ -921,6 +921,173 @@ var bindTests = []struct {
 			}
 		`,
 	},
+	// Test that tuples are bound to named structs, which can be packed and unpacked
+	{
+		`Structs`, ``, ``,
+		`
+			[
+				{"type":"function","name":"F","constant":true,"inputs":[],"outputs":[{"name":"a","type":"tuple[]","internalType":"struct Structs.A[]","components":[{"name":"B","type":"bytes32","internalType":"bytes32"}]},{"name":"c","type":"uint256[]","internalType":"uint256[]"}]},
+				{"type":"function","name":"G","constant":true,"inputs":[],"outputs":[{"name":"a","type":"tuple[]","internalType":"struct Structs.A[]","components":[{"name":"B","type":"bytes32","internalType":"bytes32"}]}]},
+				{"type":"function","name":"setPoint","constant":false,"inputs":[{"name":"p","type":"tuple","internalType":"struct Structs.Point","components":[{"name":"x","type":"uint256","internalType":"uint256"},{"name":"y","type":"uint256","internalType":"uint256"},{"name":"tag","type":"tuple","internalType":"struct Structs.Tag","components":[{"name":"label","type":"string","internalType":"string"}]}]}],"outputs":[]},
+				{"type":"function","name":"getPoint","constant":true,"inputs":[],"outputs":[{"name":"p","type":"tuple","internalType":"struct Structs.Point","components":[{"name":"x","type":"uint256","internalType":"uint256"},{"name":"y","type":"uint256","internalType":"uint256"},{"name":"tag","type":"tuple","internalType":"struct Structs.Tag","components":[{"name":"label","type":"string","internalType":"string"}]}]}]},
+				{"type":"function","name":"legacy","constant":true,"inputs":[],"outputs":[{"name":"","type":"tuple","components":[{"name":"value","type":"int64"}]}]}
+			]
+		`,
+		`
+			"fmt"
+			"math/big"
+			"reflect"
+			"strings"
+
+			"github.com/ethereum/go-ethereum/accounts/abi"
+			"github.com/ethereum/go-ethereum/common"
+		`,
+		`if b, err := NewStructs(common.Address{}, nil); b == nil || err != nil {
+			 t.Fatalf("binding (%v) nil or error (%v) not nil", b, nil)
+		 } else if false { // Don't run, just compile and test types
+			 var (
+				 a   []StructsA
+				 p   StructsPoint
+				 l   Struct3
+				 err error
+			 )
+			 res, err := b.F(nil)
+			 a = res.A
+			 a, err = b.G(nil)
+			 p, err = b.GetPoint(nil)
+			 _, err = b.SetPoint(nil, StructsPoint{X: big.NewInt(1), Y: big.NewInt(2), Tag: StructsTag{Label: "origin"}})
+			 l, err = b.Legacy(nil)
+
+			 fmt.Println(a, p, l.Value, res.C, err)
+		 }
+		 // Ensure the generated structs round trip through the ABI encoding
+		 parsed, err := abi.JSON(strings.NewReader(StructsABI))
+		 if err != nil {
+			 t.Fatalf("Failed to parse ABI: %v", err)
+		 }
+		 want := StructsPoint{X: big.NewInt(1), Y: big.NewInt(2), Tag: StructsTag{Label: "origin"}}
+		 packed, err := parsed.Methods["setPoint"].Inputs.Pack(want)
+		 if err != nil {
+			 t.Fatalf("Failed to pack struct: %v", err)
+		 }
+		 var have StructsPoint
+		 if err := parsed.Unpack(&have, "getPoint", packed); err != nil {
+			 t.Fatalf("Failed to unpack struct: %v", err)
+		 }
+		 if !reflect.DeepEqual(have, want) {
+			 t.Fatalf("Struct mismatch: have %+v, want %+v", have, want)
+		 }`,
+	},
+	// Test that overloaded methods and events get deterministic suffixed bindings
+	{
+		`Overload`, ``, ``,
+		`
+			[
+				{"type":"function","name":"foo","constant":false,"inputs":[{"name":"i","type":"uint256"},{"name":"j","type":"uint256"}],"outputs":[]},
+				{"type":"function","name":"foo","constant":false,"inputs":[{"name":"i","type":"uint256"}],"outputs":[]},
+				{"type":"event","name":"bar","inputs":[{"name":"i","type":"uint256","indexed":true}]},
+				{"type":"event","name":"bar","inputs":[{"name":"i","type":"uint256","indexed":true},{"name":"j","type":"uint256","indexed":true}]}
+			]
+		`,
+		`
+			"fmt"
+			"math/big"
+			"strings"
+
+			"github.com/ethereum/go-ethereum/accounts/abi"
+			"github.com/ethereum/go-ethereum/common"
+			"github.com/ethereum/go-ethereum/crypto"
+		`,
+		`if b, err := NewOverload(common.Address{}, nil); b == nil || err != nil {
+			 t.Fatalf("binding (%v) nil or error (%v) not nil", b, nil)
+		 } else if false { // Don't run, just compile and test types
+			 _, err = b.Foo(nil, big.NewInt(1), big.NewInt(2))
+			 _, err = b.Foo0(nil, big.NewInt(1))
+
+			 it, err := b.FilterBar(nil, []*big.Int{})
+			 it0, err := b.FilterBar0(nil, []*big.Int{}, []*big.Int{})
+
+			 fmt.Println(it.Event.I, it0.Event.I, it0.Event.J, err)
+		 }
+		 // Ensure the bindings call the overload they are named after
+		 parsed, err := abi.JSON(strings.NewReader(OverloadABI))
+		 if err != nil {
+			 t.Fatalf("Failed to parse ABI: %v", err)
+		 }
+		 if id := parsed.Methods["foo0"].Id(); string(id) != string(crypto.Keccak256([]byte("foo(uint256)"))[:4]) {
+			 t.Fatalf("Foo0 selector mismatch: %x", id)
+		 }
+		 if id := parsed.Events["bar0"].Id(); id != crypto.Keccak256Hash([]byte("bar(uint256,uint256)")) {
+			 t.Fatalf("Bar0 topic mismatch: %x", id)
+		 }`,
+	},
+	// Test that events with indexed dynamic types can be filtered and watched
+	{
+		`DynamicEventChecker`, ``, ``,
+		`
+			[
+				{"type":"event","name":"moved","inputs":[{"name":"from","type":"tuple","indexed":true,"internalType":"struct Points.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]},{"name":"path","type":"uint256[2]","indexed":true},{"name":"owners","type":"address[]","indexed":true},{"name":"to","type":"tuple","internalType":"struct Points.Point","components":[{"name":"x","type":"uint256"},{"name":"y","type":"uint256"}]}]}
+			]
+		`,
+		`
+			"fmt"
+			"math/big"
+
+			"github.com/ethereum/go-ethereum/common"
+		`,
+		`if e, err := NewDynamicEventChecker(common.Address{}, nil); e == nil || err != nil {
+			 t.Fatalf("binding (%v) nil or error (%v) not nil", e, nil)
+		 } else if false { // Don't run, just compile and test types
+			 var (
+				 hash common.Hash
+				 to   PointsPoint
+			 )
+			 it, err := e.FilterMoved(nil, []PointsPoint{{X: big.NewInt(1), Y: big.NewInt(2)}}, [][2]*big.Int{}, [][]common.Address{})
+
+			 hash = it.Event.From   // Make sure indexed structs turn into hashes
+			 hash = it.Event.Path   // Make sure indexed arrays turn into hashes
+			 hash = it.Event.Owners // Make sure indexed slices turn into hashes
+			 to = it.Event.To       // Make sure non-indexed structs retain their type
+
+			 sink := make(chan *DynamicEventCheckerMoved)
+			 sub, err := e.WatchMoved(nil, sink, []PointsPoint{}, [][2]*big.Int{}, [][]common.Address{})
+			 defer sub.Unsubscribe()
+
+			 fmt.Println(hash, to, err)
+		 }`,
+	},
+	// Tests that fixed point numbers and nested arrays of structs are bound correctly
+	{
+		`FixedPointChecker`, ``, ``,
+		`
+			[
+				{"type":"function","name":"price","constant":true,"inputs":[{"name":"amount","type":"ufixed128x18"}],"outputs":[{"name":"","type":"fixed128x18"}]},
+				{"type":"function","name":"prices","constant":true,"inputs":[],"outputs":[{"name":"","type":"fixed64x10[]"},{"name":"","type":"ufixed8x1[2]"}]},
+				{"type":"function","name":"quotes","constant":true,"inputs":[],"outputs":[{"name":"","type":"tuple[][]","internalType":"struct Market.Quote[][]","components":[{"name":"symbol","type":"string"},{"name":"price","type":"fixed128x18"}]}]}
+			]
+		`,
+		`
+			"fmt"
+			"math/big"
+
+			"github.com/ethereum/go-ethereum/common"
+		`,
+		`if b, err := NewFixedPointChecker(common.Address{}, nil); b == nil || err != nil {
+			 t.Fatalf("binding (%v) nil or error (%v) not nil", b, nil)
+		 } else if false { // Don't run, just compile and test types
+			 var (
+				 price  *big.Rat
+				 prices []*big.Rat
+				 pair   [2]*big.Rat
+				 quotes [][]MarketQuote
+			 )
+			 price, err = b.Price(nil, big.NewRat(1, 2))
+			 prices, pair, err = b.Prices(nil)
+			 quotes, err = b.Quotes(nil)
+
+			 fmt.Println(price, prices, pair, quotes[0][0].Symbol, quotes[0][0].Price, err)
+		 }`,
+	},
 }
 
 // Tests that packages generated by the binder can be successfully compiled and
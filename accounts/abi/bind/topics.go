@@ -27,8 +27,13 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// makeTopics converts a filter query argument list into a filter topic set.
-func makeTopics(query ...[]interface{}) ([][]common.Hash, error) {
+// MakeTopics converts a filter query argument list into a filter topic set.
+//
+// Indexed strings, byte slices, arrays and structs are not stored in the topics
+// directly, but as the Keccak256 hash of their encoding. Arrays and structs are
+// encoded in place, i.e. as the concatenation of their elements, each padded to
+// a multiple of 32 bytes and without any length prefix.
+func MakeTopics(query ...[]interface{}) ([][]common.Hash, error) {
 	topics := make([][]common.Hash, len(query))
 	for i, filter := range query {
 		for _, rule := range filter {
@@ -86,6 +91,13 @@ func makeTopics(query ...[]interface{}) ([][]common.Hash, error) {
 				case val.Kind() == reflect.Array && reflect.TypeOf(rule).Elem().Kind() == reflect.Uint8:
 					reflect.Copy(reflect.ValueOf(topic[common.HashLength-val.Len():]), val)
 
+				case val.Kind() == reflect.Array || val.Kind() == reflect.Slice || val.Kind() == reflect.Struct:
+					blob, err := encodeTopic(val)
+					if err != nil {
+						return nil, err
+					}
+					topic = crypto.Keccak256Hash(blob)
+
 				default:
 					return nil, fmt.Errorf("unsupported indexed type: %T", rule)
 				}
@@ -96,6 +108,75 @@ func makeTopics(query ...[]interface{}) ([][]common.Hash, error) {
 	return topics, nil
 }
 
+// encodeTopic returns the in-place encoding of an indexed array or struct value,
+// which is the concatenation of the encodings of its elements, each padded to a
+// multiple of 32 bytes and without any length prefix.
+func encodeTopic(val reflect.Value) ([]byte, error) {
+	// Handle the types which have a special encoding first
+	switch v := val.Interface().(type) {
+	case common.Address:
+		return common.LeftPadBytes(v[:], 32), nil
+	case common.Hash:
+		return v[:], nil
+	case *big.Int:
+		return abi.U256(new(big.Int).Set(v)), nil
+	case string:
+		return padTopicData([]byte(v)), nil
+	case []byte:
+		return padTopicData(v), nil
+	}
+	// Otherwise encode based on the kind of the value
+	switch val.Kind() {
+	case reflect.Bool:
+		if val.Bool() {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return abi.U256(big.NewInt(val.Int())), nil
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return abi.U256(new(big.Int).SetUint64(val.Uint())), nil
+
+	case reflect.Array, reflect.Slice:
+		// Fixed size byte arrays are value types, padded on the right
+		if val.Kind() == reflect.Array && val.Type().Elem().Kind() == reflect.Uint8 {
+			blob := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(blob), val)
+			return common.RightPadBytes(blob, 32), nil
+		}
+		var blob []byte
+		for i := 0; i < val.Len(); i++ {
+			enc, err := encodeTopic(val.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			blob = append(blob, enc...)
+		}
+		return blob, nil
+
+	case reflect.Struct:
+		var blob []byte
+		for i := 0; i < val.NumField(); i++ {
+			enc, err := encodeTopic(val.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			blob = append(blob, enc...)
+		}
+		return blob, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported indexed type: %v", val.Type())
+	}
+}
+
+// padTopicData pads dynamic data with zeroes on the right to a multiple of 32 bytes.
+func padTopicData(data []byte) []byte {
+	return common.RightPadBytes(data, (len(data)+31)/32*32)
+}
+
 // Big batch of reflect types for topic reconstruction.
 var (
 	reflectHash    = reflect.TypeOf(common.Hash{})
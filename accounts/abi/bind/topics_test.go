@@ -0,0 +1,84 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+
+package bind
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// word returns the 32 byte big endian encoding of a small number.
+func word(n byte) []byte {
+	return common.LeftPadBytes([]byte{n}, 32)
+}
+
+func TestMakeTopics(t *testing.T) {
+	type point struct {
+		X *big.Int
+		Y *big.Int
+	}
+	type tag struct {
+		Label string
+		Kind  uint8
+		Flags [2]byte
+	}
+	var (
+		alice = common.HexToAddress("0xa11ce")
+		bob   = common.HexToAddress("0xb0b")
+		label = common.RightPadBytes([]byte("a label longer than a single word"), 64)
+	)
+	tests := []struct {
+		rule interface{}
+		want common.Hash
+	}{
+		// Value types are stored in the topics directly
+		{alice, common.BytesToHash(alice[:])},
+		{big.NewInt(1), common.BytesToHash(word(1))},
+		{true, common.BytesToHash(word(1))},
+		// Dynamic types are hashed
+		{"hello", crypto.Keccak256Hash([]byte("hello"))},
+		{[]byte{1, 2, 3}, crypto.Keccak256Hash([]byte{1, 2, 3})},
+		// Arrays and structs are hashed from their padded in-place encoding
+		{point{big.NewInt(1), big.NewInt(2)}, crypto.Keccak256Hash(word(1), word(2))},
+		{[2]*big.Int{big.NewInt(1), big.NewInt(2)}, crypto.Keccak256Hash(word(1), word(2))},
+		{[]common.Address{alice, bob}, crypto.Keccak256Hash(common.LeftPadBytes(alice[:], 32), common.LeftPadBytes(bob[:], 32))},
+		{[]int8{-1}, crypto.Keccak256Hash(common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))},
+		{tag{"a label longer than a single word", 3, [2]byte{1, 2}}, crypto.Keccak256Hash(label, word(3), common.RightPadBytes([]byte{1, 2}, 32))},
+		{[]point{}, crypto.Keccak256Hash()},
+	}
+	for i, tt := range tests {
+		topics, err := MakeTopics([]interface{}{tt.rule})
+		if err != nil {
+			t.Errorf("test %d: failed to make topics: %v", i, err)
+			continue
+		}
+		if len(topics) != 1 || len(topics[0]) != 1 {
+			t.Errorf("test %d: topic count mismatch: have %v", i, topics)
+			continue
+		}
+		if topics[0][0] != tt.want {
+			t.Errorf("test %d: topic mismatch: have %x, want %x", i, topics[0][0], tt.want)
+		}
+	}
+	if _, err := MakeTopics([]interface{}{[]float64{1}}); err == nil {
+		t.Errorf("unsupported nested type accepted")
+	}
+}
@@ -25,20 +25,36 @@ import (
 )
 
 var (
-	bigT      = reflect.TypeOf(&big.Int{})
-	derefbigT = reflect.TypeOf(big.Int{})
-	uint8T    = reflect.TypeOf(uint8(0))
-	uint16T   = reflect.TypeOf(uint16(0))
-	uint32T   = reflect.TypeOf(uint32(0))
-	uint64T   = reflect.TypeOf(uint64(0))
-	int8T     = reflect.TypeOf(int8(0))
-	int16T    = reflect.TypeOf(int16(0))
-	int32T    = reflect.TypeOf(int32(0))
-	int64T    = reflect.TypeOf(int64(0))
-	addressT  = reflect.TypeOf(common.Address{})
+	bigT           = reflect.TypeOf(&big.Int{})
+	derefbigT      = reflect.TypeOf(big.Int{})
+	bigRatT        = reflect.TypeOf(&big.Rat{})
+	derefbigRatT   = reflect.TypeOf(big.Rat{})
+	bigFloatT      = reflect.TypeOf(&big.Float{})
+	derefbigFloatT = reflect.TypeOf(big.Float{})
+	uint8T         = reflect.TypeOf(uint8(0))
+	uint16T        = reflect.TypeOf(uint16(0))
+	uint32T        = reflect.TypeOf(uint32(0))
+	uint64T        = reflect.TypeOf(uint64(0))
+	int8T          = reflect.TypeOf(int8(0))
+	int16T         = reflect.TypeOf(int16(0))
+	int32T         = reflect.TypeOf(int32(0))
+	int64T         = reflect.TypeOf(int64(0))
+	addressT       = reflect.TypeOf(common.Address{})
 )
 
-// U256 converts a big Int into a 256bit EVM number.
+// isBigNumber reports whether the given (dereferenced) type is one of the
+// arbitrary precision number types, which are always handled through pointers.
+func isBigNumber(t reflect.Type) bool {
+	return t == derefbigT || t == derefbigRatT || t == derefbigFloatT
+}
+
+// fixedScale returns the scaling factor (10^decimals) of a fixed point number.
+func fixedScale(decimals int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// U256 converts a big Int into a 256bit EVM number. The given number is left
+// unmodified.
 func U256(n *big.Int) []byte {
-	return math.PaddedBigBytes(math.U256(n), 32)
+	return math.PaddedBigBytes(math.U256(new(big.Int).Set(n)), 32)
 }
@@ -17,6 +17,7 @@
 package abi
 
 import (
+	"fmt"
 	"math/big"
 	"reflect"
 
@@ -65,6 +66,41 @@ func packElement(t Type, reflectValue reflect.Value) []byte {
 	}
 }
 
+// packFixed packs the given *big.Rat or *big.Float as a fixed point number of
+// type t. Values that are not exactly representable with t.Decimals decimal
+// places or don't fit into t.Size bits are rejected rather than rounded.
+func packFixed(t Type, v reflect.Value) ([]byte, error) {
+	var value *big.Rat
+	switch n := v.Interface().(type) {
+	case *big.Rat:
+		value = n
+	case *big.Float:
+		if n != nil {
+			value, _ = n.Rat(nil) // nil for infinities
+		}
+	default:
+		return nil, typeErr(t.Type, v.Type())
+	}
+	if value == nil {
+		return nil, fmt.Errorf("abi: cannot use %v as %v", v.Interface(), t)
+	}
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(fixedScale(t.Decimals)))
+	if !scaled.IsInt() {
+		return nil, fmt.Errorf("abi: %v cannot be represented as %v without losing precision", value.RatString(), t)
+	}
+	num := scaled.Num()
+
+	min, max := new(big.Int), new(big.Int).Lsh(common.Big1, uint(t.Size))
+	if !t.unsigned() {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if num.Cmp(min) < 0 || num.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("abi: %v overflows %v", value.RatString(), t)
+	}
+	return U256(num), nil
+}
+
 // packNum packs the given number (using the reflect value) and will cast it to appropriate number representation
 func packNum(value reflect.Value) []byte {
 	switch kind := value.Kind(); kind {
@@ -612,8 +612,67 @@ func TestPack(t *testing.T) {
 				"0000000000000000000000000000000000000000000000000000000000000001" + // tuple[1].A[0]
 				"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), // tuple[1].A[1]
 		},
+		{
+			// nested dynamic array of static tuples
+			"tuple[][]",
+			[]ArgumentMarshaling{{Name: "a", Type: "uint256"}},
+			[][]struct {
+				A *big.Int
+			}{
+				{{big.NewInt(1)}},
+				{{big.NewInt(2)}, {big.NewInt(3)}},
+			},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000002" + // len(array) = 2
+				"0000000000000000000000000000000000000000000000000000000000000040" + // offset 64 to i = 0
+				"0000000000000000000000000000000000000000000000000000000000000080" + // offset 128 to i = 1
+				"0000000000000000000000000000000000000000000000000000000000000001" + // len(array[0]) = 1
+				"0000000000000000000000000000000000000000000000000000000000000001" + // array[0][0].A
+				"0000000000000000000000000000000000000000000000000000000000000002" + // len(array[1]) = 2
+				"0000000000000000000000000000000000000000000000000000000000000002" + // array[1][0].A
+				"0000000000000000000000000000000000000000000000000000000000000003"), // array[1][1].A
+		},
+		{
+			// static array of static tuples
+			"tuple[2][2]",
+			[]ArgumentMarshaling{{Name: "a", Type: "uint256"}},
+			[2][2]struct {
+				A *big.Int
+			}{
+				{{big.NewInt(1)}, {big.NewInt(2)}},
+				{{big.NewInt(3)}, {big.NewInt(4)}},
+			},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // array[0][0].A
+				"0000000000000000000000000000000000000000000000000000000000000002" + // array[0][1].A
+				"0000000000000000000000000000000000000000000000000000000000000003" + // array[1][0].A
+				"0000000000000000000000000000000000000000000000000000000000000004"), // array[1][1].A
+		},
+		{
+			"fixed128x18",
+			nil,
+			big.NewRat(1, 10),
+			common.Hex2Bytes("000000000000000000000000000000000000000000000000016345785d8a0000"),
+		},
+		{
+			"fixed128x18",
+			nil,
+			big.NewRat(-3, 2),
+			common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffeb2eedf284ea0000"),
+		},
+		{
+			"ufixed8x1",
+			nil,
+			big.NewFloat(25.5),
+			common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000000000ff"),
+		},
+		{
+			"fixed128x18[]",
+			nil,
+			[]*big.Rat{big.NewRat(1, 10)},
+			common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000001" + // len(array) = 1
+				"000000000000000000000000000000000000000000000000016345785d8a0000"), // array[0]
+		},
 	} {
-		typ, err := NewType(test.typ, test.components)
+		typ, err := NewType(test.typ, "", test.components)
 		if err != nil {
 			t.Fatalf("%v failed. Unexpected parse error: %v", i, err)
 		}
@@ -781,3 +840,34 @@ func TestPackNumber(t *testing.T) {
 		}
 	}
 }
+
+func TestPackFixedErrors(t *testing.T) {
+	tests := []struct {
+		typ   string
+		input interface{}
+		err   string
+	}{
+		{"fixed128x1", big.NewRat(1, 100), "abi: 1/100 cannot be represented as fixed128x1 without losing precision"},
+		{"fixed8x1", big.NewRat(128, 10), "abi: 64/5 overflows fixed8x1"},
+		{"fixed8x1", big.NewRat(-129, 10), "abi: -129/10 overflows fixed8x1"},
+		{"ufixed8x1", big.NewRat(256, 10), "abi: 128/5 overflows ufixed8x1"},
+		{"ufixed8x1", big.NewRat(-1, 10), "abi: -1/10 overflows ufixed8x1"},
+		{"fixed128x18", big.NewInt(1), "abi: cannot use *big.Int as type *big.Rat as argument"},
+	}
+	for i, tt := range tests {
+		typ, err := NewType(tt.typ, "", nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to parse type: %v", i, err)
+		}
+		if _, err := typ.pack(reflect.ValueOf(tt.input)); err == nil || err.Error() != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The bounds themselves are representable
+	typ, _ := NewType("fixed8x1", "", nil)
+	for _, v := range []*big.Rat{big.NewRat(127, 10), big.NewRat(-128, 10)} {
+		if _, err := typ.pack(reflect.ValueOf(v)); err != nil {
+			t.Errorf("failed to pack %v: %v", v.RatString(), err)
+		}
+	}
+}
@@ -18,14 +18,15 @@ package abi
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 )
 
 // indirect recursively dereferences the value until it either gets the value
-// or finds a big.Int
+// or finds a big.Int, big.Rat or big.Float
 func indirect(v reflect.Value) reflect.Value {
-	if v.Kind() == reflect.Ptr && v.Elem().Type() != derefbigT {
+	if v.Kind() == reflect.Ptr && !isBigNumber(v.Type().Elem()) {
 		return indirect(v.Elem())
 	}
 	return v
@@ -76,12 +77,17 @@ func set(dst, src reflect.Value) error {
 	switch {
 	case dstType.Kind() == reflect.Interface:
 		return set(dst.Elem(), src)
-	case dstType.Kind() == reflect.Ptr && dstType.Elem() != derefbigT:
+	case dstType == bigFloatT && srcType == bigRatT && dst.CanSet():
+		// Fixed point numbers are decoded exactly, but may be requested as floats
+		dst.Set(reflect.ValueOf(new(big.Float).SetRat(src.Interface().(*big.Rat))))
+	case dstType.Kind() == reflect.Ptr && !isBigNumber(dstType.Elem()):
 		return set(dst.Elem(), src)
 	case srcType.AssignableTo(dstType) && dst.CanSet():
 		dst.Set(src)
 	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
 		return setSlice(dst, src)
+	case dstType.Kind() == reflect.Array && srcType.Kind() == reflect.Array && dst.Len() == src.Len():
+		return setArray(dst, src)
 	default:
 		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
@@ -93,14 +99,38 @@ func set(dst, src reflect.Value) error {
 func setSlice(dst, src reflect.Value) error {
 	slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
 	for i := 0; i < src.Len(); i++ {
-		v := src.Index(i)
-		reflect.Copy(slice.Index(i), v)
+		if err := setElem(slice.Index(i), src.Index(i)); err != nil {
+			return err
+		}
 	}
-
 	dst.Set(slice)
 	return nil
 }
 
+// setArray attempts to assign src to dst when arrays of the same length are not
+// assignable by default, e.g. src: [2]*big.Rat -> dst: [2]*big.Float
+func setArray(dst, src reflect.Value) error {
+	array := reflect.New(dst.Type()).Elem()
+	for i := 0; i < src.Len(); i++ {
+		if err := setElem(array.Index(i), src.Index(i)); err != nil {
+			return err
+		}
+	}
+	dst.Set(array)
+	return nil
+}
+
+// setElem assigns a single slice or array element, copying byte slices and
+// arrays into byte arrays and falling back to set for everything else.
+func setElem(dst, src reflect.Value) error {
+	srcKind := src.Kind()
+	if dst.Kind() == reflect.Array && (srcKind == reflect.Slice || srcKind == reflect.Array) && dst.Type().Elem() == src.Type().Elem() {
+		reflect.Copy(dst, src)
+		return nil
+	}
+	return set(dst, src)
+}
+
 // requireAssignable assures that `dest` is a pointer and it's not an interface.
 func requireAssignable(dst, src reflect.Value) error {
 	if dst.Kind() != reflect.Ptr && dst.Kind() != reflect.Interface {
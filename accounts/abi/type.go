@@ -50,9 +50,12 @@ type Type struct {
 	Size int
 	T    byte // Our own type checking
 
+	Decimals int // Number of decimal places of fixed point types
+
 	stringKind string // holds the unparsed string for deriving signatures
 
 	// Tuple relative fields
+	TupleRawName  string   // Raw struct name defined in source code, may be empty.
 	TupleElems    []*Type  // Type information of all tuple fields
 	TupleRawNames []string // Raw field name of all tuple fields
 }
@@ -62,21 +65,36 @@ var (
 	typeRegex = regexp.MustCompile("([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?")
 )
 
-// NewType creates a new reflection type of abi type given in t.
-func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
+// structPrefix is the prefix of the internal type of Solidity structs, e.g.
+// "struct Contract.Struct[]".
+const structPrefix = "struct "
+
+// NewType creates a new reflection type of abi type given in t. The internal type
+// is the type name used in the Solidity source code as reported by the compiler,
+// and may be empty for ABIs produced by older compilers.
+func NewType(t string, internalType string, components []ArgumentMarshaling) (typ Type, err error) {
 	// check that array brackets are equal if they exist
 	if strings.Count(t, "[") != strings.Count(t, "]") {
 		return Type{}, fmt.Errorf("invalid arg type in abi")
 	}
 
+	// fixed and ufixed are aliases for fixed128x18 and ufixed128x18
+	if base := strings.SplitN(t, "[", 2)[0]; base == "fixed" || base == "ufixed" {
+		t = base + "128x18" + t[len(base):]
+	}
 	typ.stringKind = t
 
 	// if there are brackets, get ready to go into slice/array mode and
 	// recursively create the type
 	if strings.Count(t, "[") != 0 {
 		i := strings.LastIndex(t, "[")
+		// strip the same array brackets from the internal type too, if any
+		subInternal := internalType
+		if j := strings.LastIndex(internalType, "["); j != -1 {
+			subInternal = internalType[:j]
+		}
 		// recursively embed the type
-		embeddedType, err := NewType(t[:i], components)
+		embeddedType, err := NewType(t[:i], subInternal, components)
 		if err != nil {
 			return Type{}, err
 		}
@@ -124,7 +142,7 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 	var varSize int
 	if len(parsedType[3]) > 0 {
 		var err error
-		varSize, err = strconv.Atoi(parsedType[2])
+		varSize, err = strconv.Atoi(parsedType[3])
 		if err != nil {
 			return Type{}, fmt.Errorf("abi: error parsing variable size: %v", err)
 		}
@@ -145,6 +163,20 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		typ.Kind, typ.Type = reflectIntKindAndType(true, varSize)
 		typ.Size = varSize
 		typ.T = UintTy
+	case "fixed", "ufixed":
+		// fixed<M>x<N>: 8 <= M <= 256, M % 8 == 0 and 0 < N <= 80
+		if varSize < 8 || varSize > 256 || varSize%8 != 0 || len(parsedType[5]) == 0 {
+			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+		}
+		decimals, err := strconv.Atoi(parsedType[5])
+		if err != nil || decimals == 0 || decimals > 80 {
+			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+		}
+		typ.Kind = reflect.Ptr
+		typ.Type = bigRatT
+		typ.Size = varSize
+		typ.Decimals = decimals
+		typ.T = FixedPointTy
 	case "bool":
 		typ.Kind = reflect.Bool
 		typ.T = BoolTy
@@ -178,7 +210,7 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		)
 		expression += "("
 		for idx, c := range components {
-			cType, err := NewType(c.Type, c.Components)
+			cType, err := NewType(c.Type, c.InternalType, c.Components)
 			if err != nil {
 				return Type{}, err
 			}
@@ -203,6 +235,12 @@ func NewType(t string, components []ArgumentMarshaling) (typ Type, err error) {
 		typ.TupleRawNames = names
 		typ.T = TupleTy
 		typ.stringKind = expression
+
+		if strings.HasPrefix(internalType, structPrefix) {
+			// Nested struct names (Contract.Struct) are not valid Go identifiers,
+			// so drop the separators (ContractStruct).
+			typ.TupleRawName = strings.Replace(internalType[len(structPrefix):], ".", "", -1)
+		}
 	case "function":
 		typ.Kind = reflect.Array
 		typ.T = FunctionTy
@@ -296,11 +334,19 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 		}
 		return append(ret, tail...), nil
 
+	case FixedPointTy:
+		return packFixed(t, v)
+
 	default:
 		return packElement(t, v), nil
 	}
 }
 
+// unsigned reports whether a fixed point type is an unsigned ufixed<M>x<N>.
+func (t Type) unsigned() bool {
+	return strings.HasPrefix(t.stringKind, "ufixed")
+}
+
 // requireLengthPrefix returns whether the type requires any sort of length
 // prefixing.
 func (t Type) requiresLengthPrefix() bool {
@@ -336,8 +382,8 @@ func isDynamicType(t Type) bool {
 // to store the location reference for actual value storage.
 func getTypeSize(t Type) int {
 	if t.T == ArrayTy && !isDynamicType(*t.Elem) {
-		// Recursively calculate type size if it is a nested array
-		if t.Elem.T == ArrayTy {
+		// Recursively calculate type size if it is a nested array or an array of tuples
+		if t.Elem.T == ArrayTy || t.Elem.T == TupleTy {
 			return t.Size * getTypeSize(*t.Elem)
 		}
 		return t.Size * 32
@@ -88,19 +88,19 @@ func TestTypeRegexp(t *testing.T) {
 		{"address", nil, Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}},
 		{"address[]", nil, Type{T: SliceTy, Kind: reflect.Slice, Type: reflect.TypeOf([]common.Address{}), Elem: &Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[]"}},
 		{"address[2]", nil, Type{Kind: reflect.Array, T: ArrayTy, Size: 2, Type: reflect.TypeOf([2]common.Address{}), Elem: &Type{Kind: reflect.Array, Type: addressT, Size: 20, T: AddressTy, stringKind: "address"}, stringKind: "address[2]"}},
-		// TODO when fixed types are implemented properly
-		// {"fixed", nil, Type{}},
-		// {"fixed128x128", nil, Type{}},
-		// {"fixed[]", nil, Type{}},
-		// {"fixed[2]", nil, Type{}},
-		// {"fixed128x128[]", nil, Type{}},
-		// {"fixed128x128[2]", nil, Type{}},
+		{"fixed128x18", nil, Type{Kind: reflect.Ptr, Type: bigRatT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "fixed128x18"}},
+		{"ufixed8x1", nil, Type{Kind: reflect.Ptr, Type: bigRatT, Size: 8, Decimals: 1, T: FixedPointTy, stringKind: "ufixed8x1"}},
+		{"fixed256x80", nil, Type{Kind: reflect.Ptr, Type: bigRatT, Size: 256, Decimals: 80, T: FixedPointTy, stringKind: "fixed256x80"}},
+		{"fixed", nil, Type{Kind: reflect.Ptr, Type: bigRatT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "fixed128x18"}},
+		{"ufixed[]", nil, Type{T: SliceTy, Kind: reflect.Slice, Type: reflect.TypeOf([]*big.Rat{}), Elem: &Type{Kind: reflect.Ptr, Type: bigRatT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "ufixed128x18"}, stringKind: "ufixed128x18[]"}},
+		{"fixed128x18[]", nil, Type{T: SliceTy, Kind: reflect.Slice, Type: reflect.TypeOf([]*big.Rat{}), Elem: &Type{Kind: reflect.Ptr, Type: bigRatT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "fixed128x18"}, stringKind: "fixed128x18[]"}},
+		{"ufixed128x18[2]", nil, Type{Kind: reflect.Array, T: ArrayTy, Size: 2, Type: reflect.TypeOf([2]*big.Rat{}), Elem: &Type{Kind: reflect.Ptr, Type: bigRatT, Size: 128, Decimals: 18, T: FixedPointTy, stringKind: "ufixed128x18"}, stringKind: "ufixed128x18[2]"}},
 		{"tuple", []ArgumentMarshaling{{Name: "a", Type: "int64"}}, Type{Kind: reflect.Struct, T: TupleTy, Type: reflect.TypeOf(struct{ A int64 }{}), stringKind: "(int64)",
 			TupleElems: []*Type{{Kind: reflect.Int64, T: IntTy, Type: reflect.TypeOf(int64(0)), Size: 64, stringKind: "int64"}}, TupleRawNames: []string{"a"}}},
 	}
 
 	for _, tt := range tests {
-		typ, err := NewType(tt.blob, tt.components)
+		typ, err := NewType(tt.blob, "", tt.components)
 		if err != nil {
 			t.Errorf("type %q: failed to parse type string: %v", tt.blob, err)
 		}
@@ -110,6 +110,33 @@ func TestTypeRegexp(t *testing.T) {
 	}
 }
 
+func TestInternalType(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "a", Type: "int64"}}
+	tests := []struct {
+		typ          string
+		internalType string
+		name         string
+	}{
+		{"tuple", "", ""},
+		{"tuple", "struct Point", "Point"},
+		{"tuple", "struct Contract.Point", "ContractPoint"},
+		{"tuple[]", "struct Contract.Point[]", "ContractPoint"},
+		{"tuple[2][]", "struct Contract.Point[2][]", "ContractPoint"},
+	}
+	for i, tt := range tests {
+		typ, err := NewType(tt.typ, tt.internalType, components)
+		if err != nil {
+			t.Fatalf("test %d: failed to create type: %v", i, err)
+		}
+		for typ.T == SliceTy || typ.T == ArrayTy {
+			typ = *typ.Elem
+		}
+		if typ.TupleRawName != tt.name {
+			t.Errorf("test %d: struct name mismatch: have %q, want %q", i, typ.TupleRawName, tt.name)
+		}
+	}
+}
+
 func TestTypeCheck(t *testing.T) {
 	for i, test := range []struct {
 		typ        string
@@ -119,12 +146,23 @@ func TestTypeCheck(t *testing.T) {
 	}{
 		{"uint", nil, big.NewInt(1), "unsupported arg type: uint"},
 		{"int", nil, big.NewInt(1), "unsupported arg type: int"},
+		{"fixed", nil, big.NewRat(1, 1), ""},
+		{"ufixed", nil, big.NewRat(1, 1), ""},
+		{"fixed128", nil, big.NewRat(1, 1), "unsupported arg type: fixed128"},
+		{"fixed7x1", nil, big.NewRat(1, 1), "unsupported arg type: fixed7x1"},
+		{"fixed264x1", nil, big.NewRat(1, 1), "unsupported arg type: fixed264x1"},
+		{"fixed128x0", nil, big.NewRat(1, 1), "unsupported arg type: fixed128x0"},
+		{"ufixed128x81", nil, big.NewRat(1, 1), "unsupported arg type: ufixed128x81"},
+		{"fixed128x18", nil, big.NewRat(1, 10), ""},
+		{"fixed128x18", nil, big.NewFloat(0.5), ""},
+		{"fixed128x18[]", nil, []*big.Rat{big.NewRat(1, 10)}, ""},
 		{"uint256", nil, big.NewInt(1), ""},
 		{"uint256[][3][]", nil, [][3][]*big.Int{{{}}}, ""},
 		{"uint256[][][3]", nil, [3][][]*big.Int{{{}}}, ""},
 		{"uint256[3][][]", nil, [][][3]*big.Int{{{}}}, ""},
 		{"uint256[3][3][3]", nil, [3][3][3]*big.Int{{{}}}, ""},
 		{"uint8[][]", nil, [][]uint8{}, ""},
+		{"uint8[2][]", nil, [][2]uint8{}, ""},
 		{"int256", nil, big.NewInt(1), ""},
 		{"uint8", nil, uint8(1), ""},
 		{"uint16", nil, uint16(1), ""},
@@ -275,7 +313,7 @@ func TestTypeCheck(t *testing.T) {
 			B *big.Int
 		}{{big.NewInt(0), big.NewInt(0)}, {big.NewInt(0), big.NewInt(0)}}, ""},
 	} {
-		typ, err := NewType(test.typ, test.components)
+		typ, err := NewType(test.typ, "", test.components)
 		if err != nil && len(test.err) == 0 {
 			t.Fatal("unexpected parse error:", err)
 		} else if err != nil && len(test.err) != 0 {
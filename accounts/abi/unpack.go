@@ -88,6 +88,17 @@ func readBool(word []byte) (bool, error) {
 	}
 }
 
+// reads a fixed point number, scaling the raw integer down by the number of
+// decimals of the type
+func readFixed(t Type, word []byte) *big.Rat {
+	kind := IntTy
+	if t.unsigned() {
+		kind = UintTy
+	}
+	num := readInteger(kind, reflect.Ptr, word).(*big.Int)
+	return new(big.Rat).SetFrac(num, fixedScale(t.Decimals))
+}
+
 // A function type is simply the address with the function selection signature at the end.
 // This enforces that standard by always presenting it as a 24-array (address + sig = 24 bytes)
 func readFunctionType(t Type, word []byte) (funcTy [24]byte, err error) {
@@ -120,8 +131,11 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 	if size < 0 {
 		return nil, fmt.Errorf("cannot marshal input to array, size is negative (%d)", size)
 	}
-	if start+32*size > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+32*size)
+	// Arrays have packed elements, resulting in longer unpack steps.
+	// Slices have just 32 bytes per element (pointing to the contents).
+	elemSize := getTypeSize(*t.Elem)
+	if start+elemSize*size > len(output) {
+		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", start+elemSize*size, len(output))
 	}
 
 	// this value will become our slice or our array, depending on the type
@@ -137,10 +151,6 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
 	}
 
-	// Arrays have packed elements, resulting in longer unpack steps.
-	// Slices have just 32 bytes per element (pointing to the contents).
-	elemSize := getTypeSize(*t.Elem)
-
 	for i, j := start, 0; j < size; i, j = i+elemSize, j+1 {
 		inter, err := toGoType(i, *t.Elem, output)
 		if err != nil {
@@ -223,7 +233,10 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) {
 		return forEachUnpack(t, output[begin:], 0, length)
 	case ArrayTy:
 		if isDynamicType(*t.Elem) {
-			offset := int64(binary.BigEndian.Uint64(returnOutput[len(returnOutput)-8:]))
+			offset, err := tuplePointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
 			return forEachUnpack(t, output[offset:], 0, t.Size)
 		}
 		return forEachUnpack(t, output[index:], 0, t.Size)
@@ -231,6 +244,8 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) {
 		return string(output[begin : begin+length]), nil
 	case IntTy, UintTy:
 		return readInteger(t.T, t.Kind, returnOutput), nil
+	case FixedPointTy:
+		return readFixed(t, returnOutput), nil
 	case BoolTy:
 		return readBool(returnOutput)
 	case AddressTy:
@@ -280,7 +295,7 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	return
 }
 
-// tuplePointsTo resolves the location reference for dynamic tuple.
+// tuplePointsTo resolves the location reference for dynamic tuple or array.
 func tuplePointsTo(index int, output []byte) (start int, err error) {
 	offset := big.NewInt(0).SetBytes(output[index : index+32])
 	outputLen := big.NewInt(int64(len(output)))
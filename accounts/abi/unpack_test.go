@@ -21,10 +21,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"math/rand"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/quick"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
@@ -316,6 +318,27 @@ var unpackTests = []unpackTest{
 		enc:  "000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000003",
 		want: [3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
 	},
+	{
+		def:  `[{"type": "fixed128x18"}]`,
+		enc:  "ffffffffffffffffffffffffffffffffffffffffffffffffeb2eedf284ea0000",
+		want: big.NewRat(-3, 2),
+	},
+	{
+		def:  `[{"type": "ufixed128x18"}]`,
+		enc:  "000000000000000000000000000000000000000000000000016345785d8a0000",
+		want: big.NewRat(1, 10),
+	},
+	{
+		def:  `[{"type": "fixed128x18"}]`,
+		enc:  "000000000000000000000000000000000000000000000000016345785d8a0000",
+		want: (*big.Int)(nil),
+		err:  "abi: cannot unmarshal *big.Rat in to *big.Int",
+	},
+	{
+		def:  `[{"type": "ufixed8x1[2]"}]`,
+		enc:  "000000000000000000000000000000000000000000000000000000000000000500000000000000000000000000000000000000000000000000000000000000ff",
+		want: [2]*big.Rat{big.NewRat(1, 2), big.NewRat(51, 2)},
+	},
 	// struct outputs
 	{
 		def: `[{"name":"int1","type":"int256"},{"name":"int2","type":"int256"}]`,
@@ -971,6 +994,16 @@ func TestUnpackTuple(t *testing.T) {
 			t.Errorf("unexpected value unpacked: want %x, got %x", v.Ret.B, -1)
 		}
 	}
+	// The same tuple can be unpacked directly into a struct of its own shape
+	var direct struct {
+		A *big.Int
+		B *big.Int
+	}
+	if err := abi.Unpack(&direct, "tuple", buff.Bytes()); err != nil {
+		t.Error(err)
+	} else if direct.A.Cmp(big.NewInt(1)) != 0 || direct.B.Cmp(big.NewInt(-1)) != 0 {
+		t.Errorf("unexpected value unpacked directly: want (1, -1), got (%v, %v)", direct.A, direct.B)
+	}
 
 	// Test nested tuple
 	const nestedTuple = `[{"name":"tuple","constant":false,"outputs":[
@@ -1041,6 +1074,244 @@ func TestUnpackTuple(t *testing.T) {
 	}
 }
 
+func TestUnpackNestedTupleArrays(t *testing.T) {
+	type S struct {
+		A *big.Int
+		B []byte
+		C []string
+	}
+	s := func(a int64, b string, c ...string) S {
+		return S{big.NewInt(a), []byte(b), c}
+	}
+	const components = `[{"name":"a","type":"uint256"},{"name":"b","type":"bytes"},{"name":"c","type":"string[]"}]`
+
+	tests := []struct {
+		typ   string
+		value interface{}
+	}{
+		{"tuple[]", []S{s(1, "a", "x"), s(2, "")}},
+		{"tuple[][]", [][]S{{s(1, "a")}, {}, {s(2, "bb", "x", "y"), s(3, "ccc")}}},
+		{"tuple[2][]", [][2]S{{s(1, "a"), s(2, "b", "z")}, {s(3, ""), s(4, "dddd")}}},
+		{"tuple[][2]", [2][]S{{s(1, "a", "x")}, {s(2, "b"), s(3, "c")}}},
+		{"tuple[2][2]", [2][2]S{{s(1, "a"), s(2, "b")}, {s(3, "c"), s(4, "d", "w")}}},
+		{"tuple[][][]", [][][]S{{{s(1, "a")}, {}}, {{s(2, "b"), s(3, "c", "v")}}}},
+	}
+	for i, tt := range tests {
+		def := fmt.Sprintf(`[{"name":"f","outputs":[{"name":"a","type":"%s","components":%s}]}]`, tt.typ, components)
+		abi, err := JSON(strings.NewReader(def))
+		if err != nil {
+			t.Fatalf("test %d (%s): invalid ABI definition: %v", i, tt.typ, err)
+		}
+		packed, err := abi.Methods["f"].Outputs.Pack(tt.value)
+		if err != nil {
+			t.Fatalf("test %d (%s): failed to pack: %v", i, tt.typ, err)
+		}
+		out := reflect.New(reflect.TypeOf(tt.value))
+		if err := abi.Unpack(out.Interface(), "f", packed); err != nil {
+			t.Fatalf("test %d (%s): failed to unpack: %v", i, tt.typ, err)
+		}
+		if !equalValues(out.Elem(), reflect.ValueOf(tt.value)) {
+			t.Errorf("test %d (%s): unpack mismatch: have %v, want %v", i, tt.typ, out.Elem().Interface(), tt.value)
+		}
+	}
+}
+
+func TestUnpackFixedIntoFloat(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"name":"f","outputs":[{"name":"a","type":"fixed128x18"},{"name":"b","type":"ufixed8x1[]"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := abi.Methods["f"].Outputs.Pack(big.NewRat(-3, 2), []*big.Rat{big.NewRat(1, 2), big.NewRat(51, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		A *big.Float
+		B []*big.Float
+	}
+	if err := abi.Unpack(&out, "f", packed); err != nil {
+		t.Fatal(err)
+	}
+	if out.A.Cmp(big.NewFloat(-1.5)) != 0 {
+		t.Errorf("fixed mismatch: have %v, want %v", out.A, -1.5)
+	}
+	if len(out.B) != 2 || out.B[0].Cmp(big.NewFloat(0.5)) != 0 || out.B[1].Cmp(big.NewFloat(25.5)) != 0 {
+		t.Errorf("fixed array mismatch: have %v, want [0.5 25.5]", out.B)
+	}
+}
+
+// roundTripArgs is a randomly generated list of ABI arguments together with
+// random values of the matching Go types, used to fuzz the packer against the
+// unpacker.
+type roundTripArgs struct {
+	args   Arguments
+	values []interface{}
+}
+
+// Generate implements quick.Generator.
+func (roundTripArgs) Generate(rand *rand.Rand, size int) reflect.Value {
+	var rt roundTripArgs
+	for i := 0; i < 1+rand.Intn(3); i++ {
+		marshal := randomArgument(rand, fmt.Sprintf("arg%d", i), 3)
+		typ, err := NewType(marshal.Type, "", marshal.Components)
+		if err != nil {
+			panic(fmt.Sprintf("invalid generated type %s: %v", marshal.Type, err))
+		}
+		rt.args = append(rt.args, Argument{Name: marshal.Name, Type: typ})
+		rt.values = append(rt.values, randomValue(rand, typ).Interface())
+	}
+	return reflect.ValueOf(rt)
+}
+
+// randomArgument creates a random (possibly nested) argument definition.
+func randomArgument(rand *rand.Rand, name string, depth int) ArgumentMarshaling {
+	elementary := []string{
+		"uint8", "uint24", "uint64", "uint256", "int8", "int32", "int104", "int256",
+		"bool", "address", "string", "bytes", "bytes1", "bytes7", "bytes32",
+		"fixed128x18", "fixed8x1", "ufixed256x80", "ufixed64x10",
+	}
+	if depth == 0 || rand.Intn(3) == 0 {
+		return ArgumentMarshaling{Name: name, Type: elementary[rand.Intn(len(elementary))]}
+	}
+	switch rand.Intn(3) {
+	case 0:
+		arg := randomArgument(rand, name, depth-1)
+		arg.Type += "[]"
+		return arg
+	case 1:
+		arg := randomArgument(rand, name, depth-1)
+		arg.Type += fmt.Sprintf("[%d]", 1+rand.Intn(3))
+		return arg
+	default:
+		arg := ArgumentMarshaling{Name: name, Type: "tuple"}
+		for i := 0; i < 1+rand.Intn(3); i++ {
+			arg.Components = append(arg.Components, randomArgument(rand, fmt.Sprintf("field%d", i), depth-1))
+		}
+		return arg
+	}
+}
+
+// randomValue creates a random value which can be packed as the given type.
+func randomValue(rand *rand.Rand, t Type) reflect.Value {
+	switch t.T {
+	case IntTy, UintTy:
+		n := new(big.Int).Rand(rand, new(big.Int).Lsh(common.Big1, uint(t.Size)))
+		if t.T == IntTy {
+			n.Sub(n, new(big.Int).Lsh(common.Big1, uint(t.Size-1)))
+		}
+		switch t.Kind {
+		case reflect.Ptr:
+			return reflect.ValueOf(n)
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.ValueOf(n.Int64()).Convert(t.Type)
+		default:
+			return reflect.ValueOf(n.Uint64()).Convert(t.Type)
+		}
+	case FixedPointTy:
+		n := new(big.Int).Rand(rand, new(big.Int).Lsh(common.Big1, uint(t.Size)))
+		if !t.unsigned() {
+			n.Sub(n, new(big.Int).Lsh(common.Big1, uint(t.Size-1)))
+		}
+		return reflect.ValueOf(new(big.Rat).SetFrac(n, fixedScale(t.Decimals)))
+	case BoolTy:
+		return reflect.ValueOf(rand.Intn(2) == 1)
+	case StringTy:
+		blob := make([]byte, rand.Intn(70))
+		for i := range blob {
+			blob[i] = byte('a' + rand.Intn(26))
+		}
+		return reflect.ValueOf(string(blob))
+	case BytesTy:
+		blob := make([]byte, rand.Intn(70))
+		rand.Read(blob)
+		return reflect.ValueOf(blob)
+	case AddressTy, FixedBytesTy:
+		val := reflect.New(t.Type).Elem()
+		for i := 0; i < val.Len(); i++ {
+			val.Index(i).SetUint(uint64(rand.Intn(256)))
+		}
+		return val
+	case SliceTy:
+		val := reflect.MakeSlice(t.Type, 0, 0)
+		for i := rand.Intn(4); i > 0; i-- {
+			val = reflect.Append(val, randomValue(rand, *t.Elem))
+		}
+		return val
+	case ArrayTy:
+		val := reflect.New(t.Type).Elem()
+		for i := 0; i < val.Len(); i++ {
+			val.Index(i).Set(randomValue(rand, *t.Elem))
+		}
+		return val
+	case TupleTy:
+		val := reflect.New(t.Type).Elem()
+		for i, elem := range t.TupleElems {
+			val.Field(i).Set(randomValue(rand, *elem))
+		}
+		return val
+	default:
+		panic(fmt.Sprintf("unsupported type %v", t))
+	}
+}
+
+// equalValues compares two unpacked values, treating arbitrary precision
+// numbers as equal if they represent the same number.
+func equalValues(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface {
+		return b.Kind() == reflect.Interface && equalValues(a.Elem(), b.Elem())
+	}
+	switch x := a.Interface().(type) {
+	case *big.Int:
+		return x.Cmp(b.Interface().(*big.Int)) == 0
+	case *big.Rat:
+		return x.Cmp(b.Interface().(*big.Rat)) == 0
+	}
+	switch a.Kind() {
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Interface() == b.Interface()
+	}
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	check := func(rt roundTripArgs) bool {
+		packed, err := rt.args.Pack(rt.values...)
+		if err != nil {
+			t.Logf("failed to pack %v: %v", rt.args, err)
+			return false
+		}
+		unpacked, err := rt.args.UnpackValues(packed)
+		if err != nil {
+			t.Logf("failed to unpack %v: %v", rt.args, err)
+			return false
+		}
+		if !equalValues(reflect.ValueOf(unpacked), reflect.ValueOf(rt.values)) {
+			t.Logf("round trip mismatch for %v:\nhave %v\nwant %v", rt.args, unpacked, rt.values)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(check, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestOOMMaliciousInput(t *testing.T) {
 	oomTests := []unpackTest{
 		{
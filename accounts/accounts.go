@@ -18,11 +18,13 @@
 package accounts
 
 import (
+	"fmt"
 	"math/big"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -75,17 +77,21 @@ type Wallet interface {
 	// to the wallet's tracked account list.
 	Derive(path DerivationPath, pin bool) (Account, error)
 
-	// SelfDerive sets a base account derivation path from which the wallet attempts
-	// to discover non zero accounts and automatically add them to list of tracked
-	// accounts.
+	// SelfDerive sets the account derivation path iterators along which the wallet
+	// attempts to discover non zero accounts and automatically add them to list of
+	// tracked accounts.
 	//
-	// Note, self derivaton will increment the last component of the specified path
-	// opposed to decending into a child path to allow discovering accounts starting
-	// from non zero components.
+	// Note, the iterators decide how self derivation progresses from their base paths,
+	// e.g. DefaultIterator increments the last component opposed to decending into a
+	// child path, and LedgerLiveIterator increments the account component.
+	//
+	// Some hardware wallets switched derivation paths through their evolution, so
+	// multiple iterators may be given to discover old user accounts too. Only the
+	// last one will be used to derive the next empty account.
 	//
 	// You can disable automatic account discovery by calling SelfDerive with a nil
 	// chain state reader.
-	SelfDerive(base DerivationPath, chain ethereum.ChainStateReader)
+	SelfDerive(iterators []func() DerivationPath, chain ethereum.ChainStateReader)
 
 	// SignHash requests the wallet to sign the given hash.
 	//
@@ -128,6 +134,39 @@ type Wallet interface {
 	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 }
 
+// TextSigner is an optional interface for wallets which cannot sign arbitrary
+// hashes, but are able to sign data with the personal-message prefix, such as
+// external signers which want to show the user the data being signed, or
+// hardware wallets.
+type TextSigner interface {
+	// SignText requests the wallet to sign the hash of the given data, prefixed
+	// by "\x19Ethereum Signed Message:\n" and the length of the data. The produced
+	// signature is in the [R || S || V] format where V is 0 or 1.
+	SignText(account Account, text []byte) ([]byte, error)
+}
+
+// TextHash is a helper function that calculates a hash for the given message that
+// can be safely used to calculate a signature from.
+//
+// The hash is calculated as
+//   keccak256("\x19Ethereum Signed Message:\n"${message length}${message}).
+//
+// This gives context to the signed message and prevents signing of transactions.
+func TextHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
+
+// TypedDataSigner is an optional interface for wallets able to sign EIP-712
+// typed data given its domain separator and the hash of the message struct,
+// such as hardware wallets which cannot sign arbitrary hashes.
+type TypedDataSigner interface {
+	// SignTypedData requests the wallet to sign the EIP-712 digest of the given
+	// domain separator and message hash. The produced signature is in the
+	// [R || S || V] format where V is 0 or 1.
+	SignTypedData(account Account, domainSeparator, messageHash []byte) ([]byte, error)
+}
+
 // Backend is a "wallet provider" that may contain a batch of accounts they can
 // sign transactions with and upon request, do so.
 type Backend interface {
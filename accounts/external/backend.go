@@ -0,0 +1,303 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// ExternalScheme is the protocol scheme prefixing account and wallet URLs.
+const ExternalScheme = "extapi"
+
+// ErrNotSupported is returned for operations which the external signer does
+// not expose on its API, such as signing arbitrary hashes.
+var ErrNotSupported = errors.New("operation not supported on external signers")
+
+// ExternalBackend is an accounts.Backend exposing a single wallet backed by an
+// external signer (e.g. clef), reached over its external API.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given IPC or HTTP
+// endpoint and creates a backend around it.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{
+		signers: []accounts.Wallet{signer},
+	}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The external signer is static, so no
+// wallet events are ever fired.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is an accounts.Wallet proxying all signing requests to an
+// external signer. The signer is responsible for authenticating the requests,
+// so any passphrases supplied by the node are ignored.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	status   string
+
+	cacheMu sync.RWMutex
+	cache   []accounts.Account
+}
+
+// NewExternalSigner connects to the external signer at the given endpoint and
+// ensures it exposes the external account API.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newExternalSigner(client, endpoint)
+}
+
+// newExternalSigner creates an external signer around an already established
+// client connection.
+func newExternalSigner(client *rpc.Client, endpoint string) (*ExternalSigner, error) {
+	modules, err := client.SupportedModules()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to query external signer: %v", err)
+	}
+	version, ok := modules["account"]
+	if !ok {
+		client.Close()
+		return nil, errors.New("external signer doesn't expose the account API")
+	}
+	return &ExternalSigner{
+		client:   client,
+		endpoint: endpoint,
+		status:   fmt.Sprintf("ok [api=%s]", version),
+	}, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the external signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{
+		Scheme: ExternalScheme,
+		Path:   api.endpoint,
+	}
+}
+
+// Status implements accounts.Wallet, returning the status of the connection.
+func (api *ExternalSigner) Status() (string, error) {
+	return api.status, nil
+}
+
+// Open implements accounts.Wallet. The connection is established when the signer
+// is created, so this is a noop.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet, terminating the connection to the signer.
+func (api *ExternalSigner) Close() error {
+	api.client.Close()
+	return nil
+}
+
+// Accounts implements accounts.Wallet, retrieving the accounts the external
+// signer is willing to expose. Note, the signer may ask its user for approval.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	var accnts []accounts.Account
+
+	res, err := api.listAccounts()
+	if err != nil {
+		log.Error("Account listing failed", "endpoint", api.endpoint, "err", err)
+		return accnts
+	}
+	for _, addr := range res {
+		accnts = append(accnts, accounts.Account{
+			URL:     api.URL(),
+			Address: addr,
+		})
+	}
+	api.cacheMu.Lock()
+	api.cache = accnts
+	api.cacheMu.Unlock()
+
+	return accnts
+}
+
+// Contains implements accounts.Wallet, returning whether the account was listed
+// by the external signer. The listing is cached to avoid prompting the signer's
+// user on every lookup.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	api.cacheMu.RLock()
+	cached := api.cache != nil
+	api.cacheMu.RUnlock()
+
+	if !cached {
+		api.Accounts()
+	}
+	api.cacheMu.RLock()
+	defer api.cacheMu.RUnlock()
+
+	for _, a := range api.cache {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but derivation is not supported by
+// external signers.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but derivation is not supported by
+// external signers.
+func (api *ExternalSigner) SelfDerive(iterators []func() accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation not supported on external signers")
+}
+
+// SignHash implements accounts.Wallet, but signing arbitrary hashes is not
+// supported by external signers.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but signing arbitrary hashes
+// is not supported by external signers.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+// SignText implements accounts.TextSigner, requesting the external signer to
+// sign the given data with the personal-message prefix.
+func (api *ExternalSigner) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := api.client.Call(&res, "account_sign", account.Address, hexutil.Bytes(text)); err != nil {
+		return nil, err
+	}
+	if len(res) != 65 || (res[64] != 27 && res[64] != 28) {
+		return nil, fmt.Errorf("invalid signature from external signer: %x", res)
+	}
+	signature := common.CopyBytes(res)
+	signature[64] -= 27 // Transform V from 27/28 to 0/1, as for SignHash
+	return signature, nil
+}
+
+// SignTx implements accounts.Wallet, requesting the external signer to sign the
+// given transaction. The signed transaction is checked to be signed by account
+// for the given chain, as the external signer uses its own chain configuration.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &core.SendTxArgs{
+		From:  common.NewMixedcaseAddress(account.Address),
+		Gas:   hexutil.Uint64(tx.Gas()),
+		Value: hexutil.Big(*tx.Value()),
+		Nonce: hexutil.Uint64(tx.Nonce()),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		args.GasPrice = hexutil.Big(*tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", tx.Type())
+	}
+	// Request the chain we're signing for, unless the transaction commits to one
+	if chainID != nil {
+		args.ChainID = (*hexutil.Big)(chainID)
+	}
+	if tx.Type() != types.LegacyTxType {
+		txType := hexutil.Uint64(tx.Type())
+		args.Type = &txType
+
+		if id := tx.ChainId(); id.Sign() != 0 {
+			args.ChainID = (*hexutil.Big)(id)
+		}
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	}
+	if data := tx.Data(); len(data) > 0 {
+		input := hexutil.Bytes(data)
+		args.Data = &input
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	var res ethapi.SignTransactionResult
+	if err := api.client.Call(&res, "account_signTransaction", args, nil); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res.Raw); err != nil {
+		return nil, fmt.Errorf("invalid transaction from external signer: %v", err)
+	}
+	if signed.Type() != tx.Type() {
+		return nil, fmt.Errorf("external signer returned transaction of type %d instead of %d", signed.Type(), tx.Type())
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("external signer signed for a different chain: %v", err)
+	}
+	if sender != account.Address {
+		return nil, fmt.Errorf("external signer signed with %x instead of %x", sender, account.Address)
+	}
+	return signed, nil
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, requesting the external signer
+// to sign the given transaction. The passphrase is ignored, it's up to the
+// signer to authenticate the request.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return api.SignTx(account, tx, chainID)
+}
+
+// listAccounts requests the accounts the external signer is willing to expose.
+func (api *ExternalSigner) listAccounts() ([]common.Address, error) {
+	var res []common.Address
+	if err := api.client.Call(&res, "account_list"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// TestSignerAPI mimics the external API of clef, signing with a single key.
+type TestSignerAPI struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+}
+
+func (s *TestSignerAPI) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *TestSignerAPI) SignTransaction(args core.SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error) {
+	if args.ChainID != nil && args.ChainID.ToInt().Cmp(s.chainID) != 0 {
+		return nil, fmt.Errorf("requested chain id %d, signing for %d", args.ChainID.ToInt(), s.chainID)
+	}
+	if args.ChainID == nil && args.TxType() != types.LegacyTxType {
+		args.ChainID = (*hexutil.Big)(s.chainID)
+	}
+	signed, err := types.SignTx(args.ToTransaction(), types.LatestSignerForChainID(s.chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &ethapi.SignTransactionResult{Raw: raw, Tx: signed}, nil
+}
+
+func (s *TestSignerAPI) Sign(addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	hash, _ := core.SignHash(data)
+	signature, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27
+	return signature, nil
+}
+
+func newTestSigner(t *testing.T, chainID int64) (*ExternalSigner, *TestSignerAPI) {
+	key, _ := crypto.GenerateKey()
+	service := &TestSignerAPI{key: key, chainID: big.NewInt(chainID)}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", service); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	signer, err := newExternalSigner(rpc.DialInProc(server), "test")
+	if err != nil {
+		t.Fatalf("failed to create external signer: %v", err)
+	}
+	return signer, service
+}
+
+func TestExternalSignerAccounts(t *testing.T) {
+	signer, service := newTestSigner(t, 1)
+	defer signer.Close()
+
+	addr := crypto.PubkeyToAddress(service.key.PublicKey)
+	accs := signer.Accounts()
+	if len(accs) != 1 || accs[0].Address != addr || accs[0].URL != signer.URL() {
+		t.Fatalf("account list mismatch: have %v, want %x", accs, addr)
+	}
+	if !signer.Contains(accounts.Account{Address: addr}) {
+		t.Errorf("listed account not contained")
+	}
+	if signer.Contains(accounts.Account{Address: common.Address{0x01}}) {
+		t.Errorf("unknown account contained")
+	}
+}
+
+func TestExternalSignerSignTx(t *testing.T) {
+	signer, service := newTestSigner(t, 1)
+	defer signer.Close()
+
+	account := accounts.Account{Address: crypto.PubkeyToAddress(service.key.PublicKey)}
+	tx := types.NewTransaction(3, common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(2), []byte{0xde, 0xad})
+
+	signed, err := signer.SignTx(account, tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if signed.Nonce() != tx.Nonce() || *signed.To() != *tx.To() || !bytes.Equal(signed.Data(), tx.Data()) {
+		t.Errorf("signed transaction mismatch: have %v, want %v", signed, tx)
+	}
+	// A signer configured for another chain must be rejected
+	if _, err := signer.SignTx(account, tx, big.NewInt(4)); err == nil {
+		t.Errorf("transaction signed for the wrong chain accepted")
+	}
+}
+
+func TestExternalSignerSignTypedTx(t *testing.T) {
+	signer, service := newTestSigner(t, 1)
+	defer signer.Close()
+
+	var (
+		account    = accounts.Account{Address: crypto.PubkeyToAddress(service.key.PublicKey)}
+		to         = common.Address{0xaa}
+		accessList = types.AccessList{{Address: common.Address{0xbb}, StorageKeys: []common.Hash{{0x01}}}}
+	)
+	txs := []*types.Transaction{
+		types.NewTx(&types.AccessListTx{
+			ChainID:    big.NewInt(1),
+			Nonce:      3,
+			GasPrice:   big.NewInt(2),
+			Gas:        25000,
+			To:         &to,
+			Value:      big.NewInt(1),
+			AccessList: accessList,
+		}),
+		types.NewTx(&types.DynamicFeeTx{
+			ChainID:    big.NewInt(1),
+			Nonce:      4,
+			GasTipCap:  big.NewInt(1),
+			GasFeeCap:  big.NewInt(5),
+			Gas:        25000,
+			To:         &to,
+			Value:      big.NewInt(1),
+			Data:       []byte{0xde, 0xad},
+			AccessList: accessList,
+		}),
+	}
+	for i, tx := range txs {
+		signed, err := signer.SignTx(account, tx, big.NewInt(1))
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign transaction: %v", i, err)
+		}
+		unsigned := types.LatestSignerForChainID(big.NewInt(1)).Hash(tx)
+		if have := types.LatestSignerForChainID(big.NewInt(1)).Hash(signed); have != unsigned {
+			t.Errorf("tx %d: signed transaction mismatch: have %x, want %x", i, have, unsigned)
+		}
+		if signed.Type() != tx.Type() {
+			t.Errorf("tx %d: type mismatch: have %d, want %d", i, signed.Type(), tx.Type())
+		}
+		// A transaction committing to another chain must be rejected
+		if _, err := signer.SignTx(account, tx, big.NewInt(4)); err == nil {
+			t.Errorf("tx %d: transaction signed for the wrong chain accepted", i)
+		}
+	}
+}
+
+func TestExternalSignerSignText(t *testing.T) {
+	signer, service := newTestSigner(t, 1)
+	defer signer.Close()
+
+	account := accounts.Account{Address: crypto.PubkeyToAddress(service.key.PublicKey)}
+	signature, err := signer.SignText(account, []byte("EHLO world"))
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	hash, _ := core.SignHash([]byte("EHLO world"))
+	pubkey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != account.Address {
+		t.Errorf("signer mismatch: have %x, want %x", addr, account.Address)
+	}
+	if _, err := signer.SignHash(account, hash); err != ErrNotSupported {
+		t.Errorf("hash signing error mismatch: have %v, want %v", err, ErrNotSupported)
+	}
+}
+
+func TestExternalSignerMissingAPI(t *testing.T) {
+	if _, err := newExternalSigner(rpc.DialInProc(rpc.NewServer()), "test"); err == nil {
+		t.Fatalf("signer without account API accepted")
+	}
+}
@@ -34,10 +34,15 @@ var DefaultRootDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60,
 // at m/44'/60'/0'/0/1, etc.
 var DefaultBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0, 0}
 
-// DefaultLedgerBaseDerivationPath is the base path from which custom derivation endpoints
-// are incremented. As such, the first account will be at m/44'/60'/0'/0, the second
-// at m/44'/60'/0'/1, etc.
-var DefaultLedgerBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
+// LegacyLedgerBaseDerivationPath is the legacy base path from which custom derivation
+// endpoints are incremented. As such, the first account will be at m/44'/60'/0'/0, the
+// second at m/44'/60'/0'/1, etc.
+var LegacyLedgerBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
+
+// DefaultLedgerBaseDerivationPath is the base path used by old Ledger wallets.
+//
+// Deprecated: use LegacyLedgerBaseDerivationPath, which is the same path.
+var DefaultLedgerBaseDerivationPath = LegacyLedgerBaseDerivationPath
 
 // DerivationPath represents the computer friendly version of a hierarchical
 // deterministic wallet account derivaion path.
@@ -133,3 +138,58 @@ func (path DerivationPath) String() string {
 	}
 	return result
 }
+
+// DefaultIterator creates a BIP-32 path iterator, which progresses by increasing
+// the last component, i.e. m/44'/60'/0'/0/0, m/44'/60'/0'/0/1, m/44'/60'/0'/0/2, ...
+// Every call returns a new path, which the caller may retain.
+func DefaultIterator(base DerivationPath) func() DerivationPath {
+	return componentIterator(base, len(base)-1)
+}
+
+// LedgerLiveIterator creates a BIP-44 path iterator for the layout of Ledger Live,
+// which progresses by increasing the account (third) component rather than the
+// last one, i.e. m/44'/60'/0'/0/0, m/44'/60'/1'/0/0, m/44'/60'/2'/0/0, ...
+// Every call returns a new path, which the caller may retain.
+func LedgerLiveIterator(base DerivationPath) func() DerivationPath {
+	return componentIterator(base, 2)
+}
+
+// DiscoveryIterators creates the path iterators along which the accounts of a
+// hardware wallet with the given URL scheme are discovered. Configured bases
+// progress along the Ledger Live layout if ledgerLive is set, and along their
+// last component otherwise. Without bases the standard layout is used, preceded
+// by the legacy and Ledger Live layouts for Ledger devices.
+func DiscoveryIterators(scheme string, bases []DerivationPath, ledgerLive bool) []func() DerivationPath {
+	var iterators []func() DerivationPath
+	if bases != nil {
+		for _, base := range bases {
+			if ledgerLive {
+				iterators = append(iterators, LedgerLiveIterator(base))
+			} else {
+				iterators = append(iterators, DefaultIterator(base))
+			}
+		}
+		return iterators
+	}
+	if scheme == "ledger" {
+		iterators = append(iterators,
+			DefaultIterator(LegacyLedgerBaseDerivationPath),
+			LedgerLiveIterator(DefaultBaseDerivationPath),
+		)
+	}
+	return append(iterators, DefaultIterator(DefaultBaseDerivationPath))
+}
+
+// componentIterator creates a path iterator increasing the component at the given
+// index, starting from the base path itself.
+func componentIterator(base DerivationPath, index int) func() DerivationPath {
+	next := make(DerivationPath, len(base))
+	copy(next, base)
+
+	return func() DerivationPath {
+		path := make(DerivationPath, len(next))
+		copy(path, next)
+		next[index]++
+		return path
+	}
+}
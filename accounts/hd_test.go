@@ -77,3 +77,73 @@ func TestHDPathParsing(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the path iterators progress along the expected components, and that
+// the returned paths are not modified by subsequent iterations.
+func TestHDPathIterators(t *testing.T) {
+	tests := []struct {
+		iterator func() DerivationPath
+		paths    []string
+	}{
+		{DefaultIterator(DefaultBaseDerivationPath), []string{"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1", "m/44'/60'/0'/0/2"}},
+		{DefaultIterator(LegacyLedgerBaseDerivationPath), []string{"m/44'/60'/0'/0", "m/44'/60'/0'/1", "m/44'/60'/0'/2"}},
+		{LedgerLiveIterator(DefaultBaseDerivationPath), []string{"m/44'/60'/0'/0/0", "m/44'/60'/1'/0/0", "m/44'/60'/2'/0/0"}},
+	}
+	for i, tt := range tests {
+		var paths []DerivationPath
+		for range tt.paths {
+			paths = append(paths, tt.iterator())
+		}
+		for j, path := range paths {
+			if path.String() != tt.paths[j] {
+				t.Errorf("test %d, path %d: have %v, want %v", i, j, path, tt.paths[j])
+			}
+		}
+	}
+	if DefaultBaseDerivationPath.String() != "m/44'/60'/0'/0/0" {
+		t.Errorf("base path modified by iteration: %v", DefaultBaseDerivationPath)
+	}
+}
+
+// Tests that the discovery iterators follow the configured bases and layout, or
+// the standard layouts of the wallet scheme if no bases were configured.
+func TestDiscoveryIterators(t *testing.T) {
+	custom := DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 5, 0, 0}
+
+	tests := []struct {
+		scheme     string
+		bases      []DerivationPath
+		ledgerLive bool
+		paths      [][]string
+	}{
+		{"trezor", nil, false, [][]string{
+			{"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1"},
+		}},
+		{"ledger", nil, false, [][]string{
+			{"m/44'/60'/0'/0", "m/44'/60'/0'/1"},
+			{"m/44'/60'/0'/0/0", "m/44'/60'/1'/0/0"},
+			{"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/1"},
+		}},
+		{"ledger", []DerivationPath{custom}, false, [][]string{
+			{"m/44'/60'/5'/0/0", "m/44'/60'/5'/0/1"},
+		}},
+		{"ledger", []DerivationPath{custom, DefaultBaseDerivationPath}, true, [][]string{
+			{"m/44'/60'/5'/0/0", "m/44'/60'/6'/0/0"},
+			{"m/44'/60'/0'/0/0", "m/44'/60'/1'/0/0"},
+		}},
+	}
+	for i, tt := range tests {
+		iterators := DiscoveryIterators(tt.scheme, tt.bases, tt.ledgerLive)
+		if len(iterators) != len(tt.paths) {
+			t.Errorf("test %d: iterator count mismatch: have %d, want %d", i, len(iterators), len(tt.paths))
+			continue
+		}
+		for j, next := range iterators {
+			for k, want := range tt.paths[j] {
+				if path := next(); path.String() != want {
+					t.Errorf("test %d, iterator %d, path %d: have %v, want %v", i, j, k, path, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errInvalidChild is returned in the astronomically unlikely case that a BIP-32
+// derivation step yields an invalid private key.
+var errInvalidChild = errors.New("invalid derived key, use the next index")
+
+// hardenedOffset is the first index of hardened BIP-32 child keys.
+const hardenedOffset = 0x80000000
+
+// extendedKey is a BIP-32 extended private key.
+type extendedKey struct {
+	key   []byte // 32 byte secp256k1 private key
+	chain []byte // 32 byte chain code
+}
+
+// newMasterKey creates the root extended key of a BIP-32 tree from a seed.
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	if k := new(big.Int).SetBytes(sum[:32]); k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidChild
+	}
+	return &extendedKey{key: sum[:32], chain: sum[32:]}, nil
+}
+
+// child derives the extended private key at the given index below k.
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	// Hardened children commit to the private key, normal ones to the public key
+	data := make([]byte, 0, 37)
+	if index >= hardenedOffset {
+		data = append(append(data, 0), k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, crypto.CompressPubkey(&priv.PublicKey)...)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, k.chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// The child key is the parent key tweaked by the left half of the digest
+	n := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+	key := tweak.Add(tweak, new(big.Int).SetBytes(k.key))
+	if key.Mod(key, n).Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &extendedKey{key: math.PaddedBigBytes(key, 32), chain: sum[32:]}, nil
+}
+
+// copy returns an independent copy of the extended key.
+func (k *extendedKey) copy() *extendedKey {
+	return &extendedKey{
+		key:   append([]byte{}, k.key...),
+		chain: append([]byte{}, k.chain...),
+	}
+}
+
+// zero clears the extended key in memory.
+func (k *extendedKey) zero() {
+	for i := range k.key {
+		k.key[i] = 0
+	}
+	for i := range k.chain {
+		k.chain[i] = 0
+	}
+}
+
+// derive walks the given derivation path from k, returning the private key at
+// its end.
+func (k *extendedKey) derive(path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	var err error
+	for _, index := range path {
+		if k, err = k.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(k.key)
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrInvalidMnemonic is returned if a mnemonic contains unknown words, has an
+	// unsupported length or fails its checksum.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// wordIndices maps every mnemonic word to its index in the word list.
+	wordIndices = make(map[string]int, len(wordlist))
+)
+
+func init() {
+	for i, word := range wordlist {
+		wordIndices[word] = i
+	}
+}
+
+// NewMnemonic generates a random BIP-39 mnemonic encoding the given amount of
+// entropy, which must be a multiple of 32 bits between 128 and 256. The result
+// has 3 words for every 32 bits.
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("invalid entropy size %d: must be a multiple of 32 in [128, 256]", bits)
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return entropyToMnemonic(entropy), nil
+}
+
+// entropyToMnemonic encodes the entropy with its checksum appended into words
+// of 11 bits each.
+func entropyToMnemonic(entropy []byte) string {
+	checksumBits := uint(len(entropy) / 4)
+	hash := sha256.Sum256(entropy)
+
+	// Append the leading bits of the entropy hash to the entropy itself
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	// Split the concatenation into words from the right
+	words := make([]string, (len(entropy)*8+int(checksumBits))/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = wordlist[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " ")
+}
+
+// mnemonicToEntropy decodes a mnemonic into the entropy it encodes, verifying
+// its length and checksum.
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndices[word]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(data, big.NewInt(int64(1)<<checksumBits-1))
+	data.Rsh(data, checksumBits)
+
+	entropy := math.PaddedBigBytes(data, int(checksumBits)*4)
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, ErrInvalidMnemonic
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic checks that the mnemonic consists of known words and that its
+// checksum is correct.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := mnemonicToEntropy(mnemonic)
+	return err
+}
+
+// NewSeed derives the 64 byte BIP-39 seed of a valid mnemonic, protected by an
+// optional passphrase. Different passphrases result in unrelated seeds, any of
+// them being valid.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	words := strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+
+	return pbkdf2.Key([]byte(words), []byte(salt), 2048, 64, sha512.New), nil
+}
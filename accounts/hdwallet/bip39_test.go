@@ -0,0 +1,137 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// Tests that the word list matches the one in the BIP-39 specification.
+func TestWordlist(t *testing.T) {
+	if len(wordlist) != 2048 {
+		t.Fatalf("word count mismatch: have %d, want 2048", len(wordlist))
+	}
+	// $ curl https://raw.githubusercontent.com/bitcoin/bips/master/bip-0039/english.txt | crc32 /dev/stdin
+	list := strings.Join(wordlist, "\n") + "\n"
+	if have := fmt.Sprintf("%x", crc32.ChecksumIEEE([]byte(list))); have != "c1dbd296" {
+		t.Fatalf("checksum mismatch: have %s, want c1dbd296", have)
+	}
+}
+
+// Tests mnemonic encoding and seed derivation against the reference vectors of
+// https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+func TestMnemonicVectors(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			"808080808080808080808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+			"107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+			"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+		},
+		{
+			"9e885d952ad362caeb4efe34a8e91bd2",
+			"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+			"274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+		},
+	}
+	for i, tt := range tests {
+		entropy, _ := hex.DecodeString(tt.entropy)
+		if mnemonic := entropyToMnemonic(entropy); mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		if decoded, err := mnemonicToEntropy(tt.mnemonic); err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x, err %v", i, decoded, entropy, err)
+		}
+		seed, err := NewSeed(tt.mnemonic, "TREZOR")
+		if err != nil {
+			t.Errorf("test %d: failed to create seed: %v", i, err)
+			continue
+		}
+		if have := hex.EncodeToString(seed); have != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %s, want %s", i, have, tt.seed)
+		}
+	}
+}
+
+// Tests that generated mnemonics have the requested length and are valid.
+func TestNewMnemonic(t *testing.T) {
+	for bits := 128; bits <= 256; bits += 32 {
+		mnemonic, err := NewMnemonic(bits)
+		if err != nil {
+			t.Fatalf("%d bits: failed to generate mnemonic: %v", bits, err)
+		}
+		if words := len(strings.Fields(mnemonic)); words != bits/32*3 {
+			t.Errorf("%d bits: word count mismatch: have %d, want %d", bits, words, bits/32*3)
+		}
+		if err := ValidateMnemonic(mnemonic); err != nil {
+			t.Errorf("%d bits: generated mnemonic invalid: %v", bits, err)
+		}
+	}
+	for _, bits := range []int{0, 96, 130, 288} {
+		if _, err := NewMnemonic(bits); err == nil {
+			t.Errorf("%d bits: invalid entropy size accepted", bits)
+		}
+	}
+}
+
+// Tests that malformed mnemonics are rejected, while whitespace is tolerated.
+func TestValidateMnemonic(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		valid    bool
+	}{
+		{"  legal winner thank year wave sausage\tworth useful legal winner thank yellow\n", true},
+		{"legal winner thank year wave sausage worth useful legal winner thank", false},         // Too short
+		{"legal winner thank year wave sausage worth useful legal winner thank legal", false},   // Bad checksum
+		{"legal winner thank year wave sausage worth useful legal winner thank yellows", false}, // Unknown word
+		{"Legal winner thank year wave sausage worth useful legal winner thank yellow", false},  // Case sensitive
+		{"", false},
+	}
+	for i, tt := range tests {
+		if err := ValidateMnemonic(tt.mnemonic); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
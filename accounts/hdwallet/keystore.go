@@ -0,0 +1,346 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdwallet implements a software hierarchical deterministic wallet
+// backend, deriving accounts from encrypted BIP-39 seeds in the keystore folder.
+package hdwallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/pborman/uuid"
+)
+
+// Scheme is the protocol scheme prefixing wallet and account URLs.
+const Scheme = "hdwallet"
+
+// KeyStoreType is the reflect type of an HD keystore backend.
+var KeyStoreType = reflect.TypeOf(&KeyStore{})
+
+const (
+	// seedFilePrefix is the file name prefix of seed files in the keystore folder.
+	seedFilePrefix = "hdwallet--"
+
+	// seedVersion is the current version of the seed file format.
+	seedVersion = 1
+
+	// refreshCycle is the maximum time between wallet refreshes.
+	refreshCycle = 3 * time.Second
+
+	// refreshThrottling is the minimum time between wallet refreshes to avoid
+	// rescanning the keystore folder on every request.
+	refreshThrottling = time.Second
+)
+
+// seedJSON is the on-disk format of an HD wallet.
+type seedJSON struct {
+	ID       string              `json:"id"`
+	Version  int                 `json:"version"`
+	Crypto   keystore.CryptoJSON `json:"crypto"`
+	Accounts []pinnedJSON        `json:"accounts"`
+}
+
+// pinnedJSON is an account derived from the seed and pinned by the user.
+type pinnedJSON struct {
+	Address string `json:"address"`
+	Path    string `json:"path"`
+}
+
+// KeyStore is an accounts.Backend managing the HD wallets in a keystore folder.
+// Each wallet is a seed file holding an encrypted BIP-39 seed along with the
+// derivation paths of its pinned accounts.
+type KeyStore struct {
+	keydir  string // Keystore folder the seed files are stored in
+	scryptN int    // Scrypt memory cost of newly encrypted seeds
+	scryptP int    // Scrypt parallelism of newly encrypted seeds
+
+	refreshed   time.Time               // Time instance when the list of wallets was last refreshed
+	wallets     []*wallet               // List of HD wallets currently tracking, sorted by URL
+	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+	updating    bool                    // Whether the event notification loop is running
+
+	stateLock sync.RWMutex // Protects the internals of the keystore from racey access
+}
+
+// NewKeyStore creates an HD wallet backend for the seed files in keydir, using
+// the given scrypt parameters to encrypt new seeds.
+func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{
+		keydir:  keydir,
+		scryptN: scryptN,
+		scryptP: scryptP,
+	}
+	ks.refreshWallets()
+	return ks
+}
+
+// Wallets implements accounts.Backend, returning all the HD wallets found in
+// the keystore folder.
+func (ks *KeyStore) Wallets() []accounts.Wallet {
+	// Make sure the list of wallets is up to date
+	ks.refreshWallets()
+
+	ks.stateLock.RLock()
+	defer ks.stateLock.RUnlock()
+
+	cpy := make([]accounts.Wallet, len(ks.wallets))
+	for i, wallet := range ks.wallets {
+		cpy[i] = wallet
+	}
+	return cpy
+}
+
+// refreshWallets scans the keystore folder for seed files and updates the list
+// of wallets based on the found ones.
+func (ks *KeyStore) refreshWallets() {
+	// Don't rescan the folder like crazy if the user fetches wallets in a loop
+	ks.stateLock.RLock()
+	elapsed := time.Since(ks.refreshed)
+	ks.stateLock.RUnlock()
+
+	if elapsed < refreshThrottling {
+		return
+	}
+	files, err := ioutil.ReadDir(ks.keydir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to scan HD wallets", "dir", ks.keydir, "err", err)
+		return
+	}
+	var paths []string
+	for _, fi := range files {
+		if !fi.IsDir() && strings.HasPrefix(fi.Name(), seedFilePrefix) {
+			paths = append(paths, filepath.Join(ks.keydir, fi.Name()))
+		}
+	}
+	// Transform the current list of wallets into the new one, keeping known ones
+	ks.stateLock.Lock()
+
+	known := make(map[string]*wallet, len(ks.wallets))
+	for _, wallet := range ks.wallets {
+		known[wallet.url.Path] = wallet
+	}
+	wallets := make([]*wallet, 0, len(paths))
+	events := []accounts.WalletEvent{}
+
+	for _, path := range paths {
+		if wallet, ok := known[path]; ok {
+			wallets = append(wallets, wallet)
+			delete(known, path)
+			continue
+		}
+		wallet, err := loadWallet(ks, path)
+		if err != nil {
+			log.Warn("Failed to load HD wallet", "path", path, "err", err)
+			continue
+		}
+		events = append(events, accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+		wallets = append(wallets, wallet)
+	}
+	// Drop any wallets whose seed file disappeared and set the new batch
+	for _, wallet := range ks.wallets {
+		if _, ok := known[wallet.url.Path]; ok {
+			events = append(events, accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletDropped})
+		}
+	}
+	ks.refreshed = time.Now()
+	ks.wallets = wallets
+	ks.stateLock.Unlock()
+
+	// Fire all wallet events and return
+	for _, event := range events {
+		ks.updateFeed.Send(event)
+	}
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or removal of HD wallets.
+func (ks *KeyStore) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	// We need the mutex to reliably start/stop the update loop
+	ks.stateLock.Lock()
+	defer ks.stateLock.Unlock()
+
+	// Subscribe the caller and track the subscriber count
+	sub := ks.updateScope.Track(ks.updateFeed.Subscribe(sink))
+
+	// Subscribers require an active notification loop, start it
+	if !ks.updating {
+		ks.updating = true
+		go ks.updater()
+	}
+	return sub
+}
+
+// updater is responsible for maintaining an up-to-date list of wallets stored in
+// the keystore folder, and for firing wallet addition/removal events.
+func (ks *KeyStore) updater() {
+	for {
+		time.Sleep(refreshCycle)
+
+		// Run the wallet refresher
+		ks.refreshWallets()
+
+		// If all our subscribers left, stop the updater
+		ks.stateLock.Lock()
+		if ks.updateScope.Count() == 0 {
+			ks.updating = false
+			ks.stateLock.Unlock()
+			return
+		}
+		ks.stateLock.Unlock()
+	}
+}
+
+// Find returns the wallet stored in the given seed file, which may be given
+// either as a file system path or as a wallet URL.
+func (ks *KeyStore) Find(file string) (accounts.Wallet, error) {
+	if strings.HasPrefix(file, Scheme+"://") {
+		file = strings.TrimPrefix(file, Scheme+"://")
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, wallet := range ks.Wallets() {
+		if wallet.URL().Path == file {
+			return wallet, nil
+		}
+	}
+	return nil, accounts.ErrUnknownWallet
+}
+
+// NewWallet stores a new HD wallet for the given BIP-39 mnemonic and optional
+// mnemonic passphrase, encrypting its seed with passphrase. The first account
+// along the default derivation path is pinned and returned.
+//
+// The mnemonic itself is not stored, so it must be backed up by the caller.
+func (ks *KeyStore) NewWallet(mnemonic, mnemonicPassphrase, passphrase string) (accounts.Wallet, accounts.Account, error) {
+	seed, err := NewSeed(mnemonic, mnemonicPassphrase)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	master, err := newMasterKey(seed)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	key, err := master.derive(accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	// Refuse to store the same seed twice, the wallets would shadow each other
+	ks.refreshWallets()
+
+	ks.stateLock.RLock()
+	for _, wallet := range ks.wallets {
+		if wallet.Contains(accounts.Account{Address: address}) {
+			ks.stateLock.RUnlock()
+			return nil, accounts.Account{}, fmt.Errorf("HD wallet already exists: %s", wallet.url)
+		}
+	}
+	ks.stateLock.RUnlock()
+
+	// Encrypt the seed and store it along with the first account
+	cryptoStruct, err := keystore.EncryptDataV3(seed, []byte(passphrase), ks.scryptN, ks.scryptP)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	file := &seedJSON{
+		ID:       uuid.NewRandom().String(),
+		Version:  seedVersion,
+		Crypto:   cryptoStruct,
+		Accounts: []pinnedJSON{{Address: address.Hex(), Path: accounts.DefaultBaseDerivationPath.String()}},
+	}
+	path := filepath.Join(ks.keydir, seedFileName(file.ID))
+	if err := writeSeedFile(path, file); err != nil {
+		return nil, accounts.Account{}, err
+	}
+	wallet, err := newWallet(ks, path, file)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	// Track the new wallet right away instead of waiting for the next refresh
+	ks.stateLock.Lock()
+	ks.wallets = append(ks.wallets, wallet)
+	sort.Slice(ks.wallets, func(i, j int) bool { return ks.wallets[i].url.Cmp(ks.wallets[j].url) < 0 })
+	ks.stateLock.Unlock()
+
+	ks.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	return wallet, wallet.accounts[0], nil
+}
+
+// seedFileName returns the file name of a seed, sorting by creation time.
+func seedFileName(id string) string {
+	return fmt.Sprintf("%s%s--%s", seedFilePrefix, time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), id)
+}
+
+// loadWallet reads the seed file at path and wraps it into a locked wallet.
+func loadWallet(ks *KeyStore, path string) (*wallet, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := new(seedJSON)
+	if err := json.Unmarshal(blob, file); err != nil {
+		return nil, err
+	}
+	if file.Version != seedVersion {
+		return nil, fmt.Errorf("unsupported seed file version: %d", file.Version)
+	}
+	return newWallet(ks, path, file)
+}
+
+// writeSeedFile atomically writes a seed file readable only by the user.
+func writeSeedFile(path string, file *seedJSON) error {
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	// Create the keystore directory in case it is not present yet
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Atomic write: create a temporary hidden file first then move it into place.
+	// TempFile assigns mode 0600.
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,435 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// selfDeriveThrottling is the minimum time between account discoveries, to
+// avoid hammering the chain on every account listing.
+const selfDeriveThrottling = time.Second
+
+// wallet is an accounts.Wallet deriving its accounts from an encrypted BIP-39
+// seed. Pinned accounts are listed even while the wallet is closed, but deriving
+// new ones or signing without a passphrase requires opening it first.
+type wallet struct {
+	store *KeyStore    // Keystore the wallet's seed file is stored in
+	url   accounts.URL // Location of the seed file
+
+	stateLock sync.RWMutex // Protects read and write access to the wallet internals
+
+	file     *seedJSON                                  // Seed file contents, rewritten on pinning
+	master   *extendedKey                               // Decrypted master key, nil while closed
+	accounts []accounts.Account                         // List of derived accounts pinned or discovered
+	paths    map[common.Address]accounts.DerivationPath // Known derivation paths for signing operations
+
+	deriveIterators []func() accounts.DerivationPath // Derivation path iterators for account auto-discovery (multiple bases supported)
+	deriveNextPaths []accounts.DerivationPath        // Next derivation paths for account auto-discovery (multiple bases supported)
+	deriveChain     ethereum.ChainStateReader        // Blockchain state reader to discover used account with
+	deriveTime      time.Time                        // Time instance of the last account discovery
+	deriveEpoch     uint64                           // Counter of SelfDerive calls, to detect replaced iterators
+	deriving        bool                             // Whether a discovery is running, advancing the iterators
+}
+
+// newWallet wraps the contents of a seed file into a closed wallet.
+func newWallet(ks *KeyStore, path string, file *seedJSON) (*wallet, error) {
+	w := &wallet{
+		store: ks,
+		url:   accounts.URL{Scheme: Scheme, Path: path},
+		file:  file,
+		paths: make(map[common.Address]accounts.DerivationPath),
+	}
+	for _, pinned := range file.Accounts {
+		path, err := accounts.ParseDerivationPath(pinned.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !common.IsHexAddress(pinned.Address) {
+			return nil, fmt.Errorf("invalid pinned address: %s", pinned.Address)
+		}
+		w.track(common.HexToAddress(pinned.Address), path)
+	}
+	return w, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the seed file.
+func (w *wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the seed of the wallet
+// is decrypted or not.
+func (w *wallet) Status() (string, error) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	if w.master != nil {
+		return "Unlocked", nil
+	}
+	return "Locked", nil
+}
+
+// Open implements accounts.Wallet, decrypting the seed with the passphrase to
+// allow account derivation and signing without further authentication.
+//
+// An empty passphrase only opens wallets encrypted without one, others are left
+// closed without an error, so that automatically opening every arriving wallet
+// doesn't fail on them.
+func (w *wallet) Open(passphrase string) error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.master != nil {
+		return accounts.ErrWalletAlreadyOpen
+	}
+	master, err := decryptSeed(w.file.Crypto, passphrase)
+	if err != nil {
+		if err == keystore.ErrDecrypt && passphrase == "" {
+			return nil
+		}
+		return err
+	}
+	w.master = master
+
+	// Notify anyone listening for wallet events that a new device is accessible
+	go w.store.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+
+	return nil
+}
+
+// Close implements accounts.Wallet, dropping the decrypted seed.
+func (w *wallet) Close() error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.master != nil {
+		w.master.zero()
+		w.master = nil
+	}
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned to
+// the wallet. If self-derivation was enabled and the wallet is open, the list is
+// extended with the used accounts found on the chain.
+func (w *wallet) Accounts() []accounts.Account {
+	w.selfDerive()
+
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// selfDerive discovers the used accounts along the self-derivation paths, up to
+// and including the first empty account on the last path.
+func (w *wallet) selfDerive() {
+	// Derivation needs an open wallet and a chain, skip if either unavailable
+	w.stateLock.Lock()
+	if w.master == nil || w.deriveChain == nil || w.deriving || time.Since(w.deriveTime) < selfDeriveThrottling {
+		w.stateLock.Unlock()
+		return
+	}
+	w.deriveTime = time.Now()
+	w.deriving = true
+
+	var (
+		master    = w.master.copy()
+		chain     = w.deriveChain
+		epoch     = w.deriveEpoch
+		iterators = w.deriveIterators
+		nextPaths = make([]accounts.DerivationPath, len(w.deriveNextPaths))
+	)
+	for i, path := range w.deriveNextPaths {
+		nextPaths[i] = append(accounts.DerivationPath{}, path...)
+	}
+	w.stateLock.Unlock()
+	defer master.zero()
+
+	// Query the chain without holding the lock, it may be remote
+	var (
+		addrs []common.Address
+		paths []accounts.DerivationPath
+
+		context = context.Background()
+	)
+discovery:
+	for i := 0; i < len(nextPaths); i++ {
+		for empty := false; !empty; {
+			key, err := master.derive(nextPaths[i])
+			if err != nil {
+				log.Warn("HD wallet account derivation failed", "url", w.url, "err", err)
+				break discovery
+			}
+			address := crypto.PubkeyToAddress(key.PublicKey)
+			zeroKey(key)
+
+			// Check the account's status against the current chain state
+			balance, err := chain.BalanceAt(context, address, nil)
+			if err != nil {
+				log.Warn("HD wallet balance retrieval failed", "url", w.url, "err", err)
+				break discovery
+			}
+			nonce, err := chain.NonceAt(context, address, nil)
+			if err != nil {
+				log.Warn("HD wallet nonce retrieval failed", "url", w.url, "err", err)
+				break discovery
+			}
+			// If the next account is empty, stop self-derivation. Only track it on
+			// the last base though, older bases are only searched for used accounts.
+			if balance.Sign() == 0 && nonce == 0 {
+				empty = true
+				if i < len(nextPaths)-1 {
+					break
+				}
+			}
+			addrs = append(addrs, address)
+			paths = append(paths, append(accounts.DerivationPath{}, nextPaths[i]...))
+
+			if !empty {
+				nextPaths[i] = iterators[i]()
+			}
+		}
+	}
+	// Insert any accounts successfully derived and shift the derivation forward,
+	// even on failure, as the iterators already progressed
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	w.deriving = false
+	for i, address := range addrs {
+		if _, known := w.paths[address]; !known {
+			log.Info("HD wallet discovered new account", "url", w.url, "address", address, "path", paths[i])
+			w.track(address, paths[i])
+		}
+	}
+	if w.deriveEpoch == epoch && w.deriveChain == chain {
+		w.deriveNextPaths = nextPaths
+	}
+}
+
+// track adds a derived account to the wallet if not yet known. The caller must
+// hold the state lock.
+func (w *wallet) track(address common.Address, path accounts.DerivationPath) accounts.Account {
+	account := accounts.Account{
+		Address: address,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+	if _, known := w.paths[address]; !known {
+		w.accounts = append(w.accounts, account)
+		w.paths[address] = path
+	}
+	return account
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not derived from this wallet.
+func (w *wallet) Contains(account accounts.Account) bool {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	return w.contains(account)
+}
+
+// contains is the lock-free version of Contains.
+func (w *wallet) contains(account accounts.Account) bool {
+	path, exists := w.paths[account.Address]
+	if !exists {
+		return false
+	}
+	return account.URL == (accounts.URL{}) || account.URL.Path == fmt.Sprintf("%s/%s", w.url.Path, path)
+}
+
+// Derive implements accounts.Wallet, deriving the account at the given path from
+// the decrypted seed. If pin is set, the account is added to the wallet and its
+// path is persisted in the seed file.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.master == nil {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	key, err := w.master.derive(path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	zeroKey(key)
+
+	if !pin {
+		return accounts.Account{
+			Address: address,
+			URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+		}, nil
+	}
+	path = append(accounts.DerivationPath{}, path...)
+	account := w.track(address, path)
+
+	// Persist the account unless already pinned
+	for _, pinned := range w.file.Accounts {
+		if common.HexToAddress(pinned.Address) == address {
+			return account, nil
+		}
+	}
+	file := *w.file
+	file.Accounts = append(append([]pinnedJSON{}, w.file.Accounts...), pinnedJSON{Address: address.Hex(), Path: path.String()})
+	if err := writeSeedFile(w.url.Path, &file); err != nil {
+		return accounts.Account{}, err
+	}
+	w.file = &file
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet, setting the derivation path iterators
+// along which used accounts are discovered while the wallet is open.
+func (w *wallet) SelfDerive(iterators []func() accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	w.deriveIterators = iterators
+	w.deriveNextPaths = make([]accounts.DerivationPath, len(iterators))
+	for i, next := range iterators {
+		w.deriveNextPaths[i] = next()
+	}
+	w.deriveChain = chain
+	w.deriveTime = time.Time{}
+	w.deriveEpoch++
+}
+
+// SignHash implements accounts.Wallet, signing the hash with the key of the
+// given account derived from the open wallet.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	key, err := w.privateKey(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with the key of the
+// given account derived from the open wallet.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.privateKey(account, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	return signTx(tx, chainID, key)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, signing the hash with the
+// key of the given account derived from the seed decrypted with passphrase.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.privateKey(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	return crypto.Sign(hash, key)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, signing the transaction with
+// the key of the given account derived from the seed decrypted with passphrase.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.privateKey(account, &passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
+
+	return signTx(tx, chainID, key)
+}
+
+// privateKey derives the private key of an account contained within the wallet,
+// decrypting the seed with the passphrase if given, or requiring the wallet to
+// be open otherwise.
+func (w *wallet) privateKey(account accounts.Account, passphrase *string) (*ecdsa.PrivateKey, error) {
+	w.stateLock.RLock()
+	if !w.contains(account) {
+		w.stateLock.RUnlock()
+		return nil, accounts.ErrUnknownAccount
+	}
+	path := w.paths[account.Address]
+
+	// Derive from the open wallet if no passphrase was given
+	if passphrase == nil {
+		defer w.stateLock.RUnlock()
+
+		if w.master == nil {
+			return nil, accounts.ErrWalletClosed
+		}
+		return w.master.derive(path)
+	}
+	// Otherwise decrypt the seed without holding the lock, scrypt is slow
+	cryptoJSON := w.file.Crypto
+	w.stateLock.RUnlock()
+
+	master, err := decryptSeed(cryptoJSON, *passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer master.zero()
+
+	return master.derive(path)
+}
+
+// decryptSeed decrypts a seed and creates its master key.
+func decryptSeed(cryptoJSON keystore.CryptoJSON, passphrase string) (*extendedKey, error) {
+	seed, err := keystore.DecryptDataV3(cryptoJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return newMasterKey(seed)
+}
+
+// signTx signs a transaction with EIP155 or homestead rules, depending on the
+// presence of the chain ID.
+func signTx(tx *types.Transaction, chainID *big.Int, key *ecdsa.PrivateKey) (*types.Transaction, error) {
+	if chainID != nil {
+		return types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, key)
+}
+
+// zeroKey zeroes a private key in memory.
+func zeroKey(k *ecdsa.PrivateKey) {
+	b := k.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
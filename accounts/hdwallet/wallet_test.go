@@ -0,0 +1,283 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+var testAddress = common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+
+func tmpKeyStore(t *testing.T) (string, *KeyStore) {
+	dir, err := ioutil.TempDir("", "hdwallet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+}
+
+// Tests that wallets are stored in the keystore folder and reloaded from there.
+func TestNewWallet(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	wallet, account, err := ks.NewWallet(testMnemonic, "", "foo")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if account.Address != testAddress {
+		t.Fatalf("account mismatch: have %x, want %x", account.Address, testAddress)
+	}
+	if !wallet.Contains(account) {
+		t.Fatalf("wallet doesn't contain its first account")
+	}
+	if wallets := ks.Wallets(); len(wallets) != 1 || wallets[0] != wallet {
+		t.Fatalf("wallet not tracked: %v", wallets)
+	}
+	for _, file := range []string{wallet.URL().Path, wallet.URL().String()} {
+		if found, err := ks.Find(file); err != nil || found != wallet {
+			t.Fatalf("wallet lookup by %s mismatch: have %v, err %v", file, found, err)
+		}
+	}
+	if _, err := ks.Find(dir); err != accounts.ErrUnknownWallet {
+		t.Fatalf("unknown wallet error mismatch: have %v, want %v", err, accounts.ErrUnknownWallet)
+	}
+	if _, _, err := ks.NewWallet(testMnemonic, "", "bar"); err == nil {
+		t.Fatalf("duplicate wallet stored")
+	}
+	if _, _, err := ks.NewWallet(testMnemonic+" about", "", "bar"); err != ErrInvalidMnemonic {
+		t.Fatalf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	// A different mnemonic passphrase is a different wallet
+	if _, other, err := ks.NewWallet(testMnemonic, "TREZOR", "bar"); err != nil || other.Address == testAddress {
+		t.Fatalf("passphrase protected wallet mismatch: have %x, err %v", other.Address, err)
+	}
+	// Reload the folder and check that the pinned accounts are listed while locked
+	wallets := NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP).Wallets()
+	if len(wallets) != 2 {
+		t.Fatalf("reloaded wallet count mismatch: have %d, want 2", len(wallets))
+	}
+	if wallets[0].URL().Cmp(wallets[1].URL()) >= 0 {
+		t.Fatalf("wallets not sorted: %v, %v", wallets[0].URL(), wallets[1].URL())
+	}
+	if accs := wallets[0].Accounts(); len(accs) != 1 || accs[0] != account {
+		t.Fatalf("reloaded accounts mismatch: have %v, want %v", accs, account)
+	}
+	if status, _ := wallets[0].Status(); status != "Locked" {
+		t.Fatalf("reloaded wallet status mismatch: have %s, want Locked", status)
+	}
+}
+
+// Tests opening, deriving and signing with a wallet.
+func TestWalletDeriveAndSign(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	wallet, account, err := ks.NewWallet(testMnemonic, "", "foo")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	path, _ := accounts.ParseDerivationPath("m/44'/60'/0'/0/1")
+	hash := crypto.Keccak256([]byte("hello"))
+
+	// Closed wallets may only sign with a passphrase
+	if _, err := wallet.Derive(path, true); err != accounts.ErrWalletClosed {
+		t.Fatalf("closed derivation error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if _, err := wallet.SignHash(account, hash); err != accounts.ErrWalletClosed {
+		t.Fatalf("closed signing error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if _, err := wallet.SignHashWithPassphrase(account, "bar", hash); err != keystore.ErrDecrypt {
+		t.Fatalf("bad passphrase error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	sig, err := wallet.SignHashWithPassphrase(account, "foo", hash)
+	if err != nil {
+		t.Fatalf("failed to sign with passphrase: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Fatalf("signer mismatch: have %v, err %v", pub, err)
+	}
+	// Empty passphrases leave the wallet closed, bad ones fail
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("empty passphrase open failed: %v", err)
+	}
+	if status, _ := wallet.Status(); status != "Locked" {
+		t.Fatalf("wallet opened with empty passphrase")
+	}
+	if err := wallet.Open("bar"); err != keystore.ErrDecrypt {
+		t.Fatalf("bad passphrase open error mismatch: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if err := wallet.Open("foo"); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	if err := wallet.Open("foo"); err != accounts.ErrWalletAlreadyOpen {
+		t.Fatalf("reopen error mismatch: have %v, want %v", err, accounts.ErrWalletAlreadyOpen)
+	}
+	// Unpinned derivations must not be tracked, pinned ones must be persisted
+	derived, err := wallet.Derive(path, false)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if wallet.Contains(derived) {
+		t.Fatalf("unpinned account tracked")
+	}
+	if pinned, err := wallet.Derive(path, true); err != nil || pinned != derived {
+		t.Fatalf("pinned account mismatch: have %v, want %v, err %v", pinned, derived, err)
+	}
+	reloaded := NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP).Wallets()[0]
+	if !reloaded.Contains(derived) {
+		t.Fatalf("pinned account not persisted")
+	}
+	// Open wallets sign without a passphrase
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := wallet.SignTx(derived, tx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signed); err != nil || sender != derived.Address {
+		t.Fatalf("transaction sender mismatch: have %x, want %x, err %v", sender, derived.Address, err)
+	}
+	if _, err := wallet.SignHash(accounts.Account{Address: common.Address{1}}, hash); err != accounts.ErrUnknownAccount {
+		t.Fatalf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	wallet.Close()
+	if _, err := wallet.SignTx(derived, tx, nil); err != accounts.ErrWalletClosed {
+		t.Fatalf("closed signing error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+}
+
+// testChain is a chain state reader with a fixed set of used accounts.
+type testChain struct {
+	used map[common.Address]bool
+}
+
+func (c *testChain) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	if c.used[account] {
+		return big.NewInt(1), nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *testChain) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return 0, nil
+}
+
+func (c *testChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+// Tests that open wallets discover the used accounts along the derivation bases.
+func TestWalletSelfDerive(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	wallet, _, err := ks.NewWallet(testMnemonic, "", "")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	// Mark the first three accounts as used
+	var derived []accounts.Account
+	next := accounts.DefaultIterator(accounts.DefaultBaseDerivationPath)
+	for i := 0; i < 4; i++ {
+		account, err := wallet.Derive(next(), false)
+		if err != nil {
+			t.Fatalf("failed to derive account %d: %v", i, err)
+		}
+		derived = append(derived, account)
+	}
+	chain := &testChain{used: map[common.Address]bool{
+		derived[0].Address: true,
+		derived[1].Address: true,
+		derived[2].Address: true,
+	}}
+	wallet.SelfDerive([]func() accounts.DerivationPath{accounts.DefaultIterator(accounts.DefaultBaseDerivationPath)}, chain)
+
+	// The used accounts and the first empty one must be discovered
+	accs := wallet.Accounts()
+	if len(accs) != len(derived) {
+		t.Fatalf("discovered account count mismatch: have %d, want %d", len(accs), len(derived))
+	}
+	for i, account := range accs {
+		if account != derived[i] {
+			t.Errorf("account %d mismatch: have %v, want %v", i, account, derived[i])
+		}
+	}
+	// Using the empty account must extend the list on the next discovery
+	extra, _ := wallet.Derive(next(), false)
+	chain.used[derived[3].Address] = true
+
+	time.Sleep(selfDeriveThrottling)
+	if accs := wallet.Accounts(); len(accs) != len(derived)+1 || accs[len(derived)] != extra {
+		t.Fatalf("extended accounts mismatch: have %v, want last %v", accs, extra)
+	}
+}
+
+// Tests that wallet additions and removals are announced.
+func TestKeyStoreEvents(t *testing.T) {
+	dir, ks := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	events := make(chan accounts.WalletEvent, 4)
+	sub := ks.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	wallet, _, err := ks.NewWallet(testMnemonic, "", "")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if event := <-events; event.Kind != accounts.WalletArrived || event.Wallet != wallet {
+		t.Fatalf("arrival event mismatch: %v", event)
+	}
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	if event := <-events; event.Kind != accounts.WalletOpened || event.Wallet != wallet {
+		t.Fatalf("open event mismatch: %v", event)
+	}
+	os.Remove(wallet.URL().Path)
+
+	ks.stateLock.Lock()
+	ks.refreshed = time.Time{}
+	ks.stateLock.Unlock()
+
+	if wallets := ks.Wallets(); len(wallets) != 0 {
+		t.Fatalf("removed wallet still tracked: %v", wallets)
+	}
+	if event := <-events; event.Kind != accounts.WalletDropped || event.Wallet != wallet {
+		t.Fatalf("drop event mismatch: %v", event)
+	}
+}
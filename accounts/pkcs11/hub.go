@@ -0,0 +1,189 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pkcs11 implements an account backend signing with secp256k1 keys held
+// in a hardware security module, accessed through its PKCS#11 library.
+//
+// Tokens compute plain ECDSA signatures. The recovery id carried by Ethereum
+// signatures is derived on the Go side from the public key of the signing key.
+package pkcs11
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Scheme is the protocol scheme prefixing account and wallet URLs.
+const Scheme = "pkcs11"
+
+// secp256k1Params is the DER encoded object identifier of the secp256k1 curve,
+// the CKA_EC_PARAMS value of the keys on it.
+var secp256k1Params = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// errUnsupportedCurve is returned when reading an EC key on a curve other than
+// secp256k1.
+var errUnsupportedCurve = errors.New("not a secp256k1 key")
+
+// Hub is an accounts.Backend signing with the secp256k1 keys stored on a single
+// PKCS#11 token. The keys are identified by their labels, and looked up once
+// when the hub is created.
+type Hub struct {
+	wallet *wallet // Wallet wrapping the token in the configured slot
+
+	updateFeed  event.Feed              // Event feed to notify wallet state changes
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+}
+
+// NewHub loads the PKCS#11 library at module and creates a backend for the
+// token in the given slot. If labels are given, the keys with those labels are
+// used, failing if any is missing. Otherwise all secp256k1 keys of the token are.
+func NewHub(module string, slot uint, labels []string) (*Hub, error) {
+	tok, err := openToken(module, slot)
+	if err != nil {
+		return nil, err
+	}
+	hub, err := newHub(tok, slot, labels)
+	if err != nil {
+		tok.Close()
+		return nil, err
+	}
+	return hub, nil
+}
+
+// newHub creates a backend for the token open in the given slot.
+func newHub(tok token, slot uint, labels []string) (*Hub, error) {
+	url := accounts.URL{Scheme: Scheme, Path: fmt.Sprintf("%d", slot)}
+
+	keys, err := findKeys(tok, url, labels)
+	if err != nil {
+		return nil, err
+	}
+	hub := new(Hub)
+	hub.wallet = &wallet{hub: hub, url: url, token: tok, keys: keys}
+	return hub, nil
+}
+
+// Wallets implements accounts.Backend, returning the wallet of the token.
+func (hub *Hub) Wallets() []accounts.Wallet {
+	return []accounts.Wallet{hub.wallet}
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications when the wallet is opened. The wallet of the token
+// never arrives nor drops.
+func (hub *Hub) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return hub.updateScope.Track(hub.updateFeed.Subscribe(sink))
+}
+
+// Close logs out of the token and releases the PKCS#11 library.
+func (hub *Hub) Close() error {
+	hub.updateScope.Close()
+	return hub.wallet.release()
+}
+
+// key is a secp256k1 key pair stored on the token.
+type key struct {
+	account accounts.Account
+	label   string // Label shared by the public and private key objects
+	pubkey  []byte // Uncompressed public key, to compute recovery ids against
+}
+
+// findKeys looks up the secp256k1 public keys with the given labels on the
+// token, or all of them if no labels are given.
+func findKeys(tok token, url accounts.URL, labels []string) ([]key, error) {
+	template := []attribute{{ckaClass, uint(ckoPublicKey)}, {ckaKeyType, uint(ckkEC)}}
+
+	if len(labels) == 0 {
+		objects, err := tok.FindObjects(template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %v", err)
+		}
+		keys := make([]key, 0, len(objects))
+		for _, object := range objects {
+			key, err := readKey(tok, url, object)
+			if err == errUnsupportedCurve {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if key.label == "" {
+				log.Warn("Ignoring unlabelled PKCS#11 key", "url", url, "address", key.account.Address)
+				continue
+			}
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].label < keys[j].label })
+		return keys, nil
+	}
+	keys := make([]key, 0, len(labels))
+	for _, label := range labels {
+		objects, err := tok.FindObjects(append(template, attribute{ckaLabel, []byte(label)}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find key %q: %v", label, err)
+		}
+		switch len(objects) {
+		case 0:
+			return nil, fmt.Errorf("no public key labelled %q in slot %s", label, url.Path)
+		case 1:
+		default:
+			return nil, fmt.Errorf("multiple public keys labelled %q in slot %s", label, url.Path)
+		}
+		key, err := readKey(tok, url, objects[0])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", label, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// readKey reads the label and the public key of an EC public key object.
+func readKey(tok token, url accounts.URL, object uint) (key, error) {
+	values, err := tok.Attributes(object, []uint{ckaLabel, ckaECParams, ckaECPoint})
+	if err != nil {
+		return key{}, err
+	}
+	label, params, point := string(values[0]), values[1], values[2]
+	if !bytes.Equal(params, secp256k1Params) {
+		return key{}, errUnsupportedCurve
+	}
+	// The point is a DER encoded octet string, but some modules return it raw
+	if len(point) != 65 || point[0] != 0x04 {
+		var raw []byte
+		if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+			return key{}, fmt.Errorf("invalid EC point: %x", point)
+		}
+		point = raw
+	}
+	pubkey, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return key{}, err
+	}
+	account := accounts.Account{
+		Address: crypto.PubkeyToAddress(*pubkey),
+		URL:     accounts.URL{Scheme: url.Scheme, Path: url.Path + "/" + label},
+	}
+	return key{account: account, label: label, pubkey: point}, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build softhsm
+
+// The tests in this file exercise the Cryptoki bindings against a real token.
+// They require a token provisioned with a secp256k1 key, e.g. with SoftHSM:
+//
+//	softhsm2-util --init-token --free --label test --so-pin 0000 --pin 1234
+//	pkcs11-tool --module /usr/lib/softhsm/libsofthsm2.so --token-label test --login --pin 1234 \
+//	  --keypairgen --key-type EC:secp256k1 --label signer
+//
+// The token is configured through the PKCS11_TEST_MODULE, PKCS11_TEST_SLOT,
+// PKCS11_TEST_PIN and PKCS11_TEST_LABEL environment variables:
+//
+//	go test -tags softhsm ./accounts/pkcs11
+
+package pkcs11
+
+import (
+	"math/big"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// softhsmToken returns the PKCS#11 library and slot of the token to test with.
+func softhsmToken(t *testing.T) (string, uint) {
+	module := os.Getenv("PKCS11_TEST_MODULE")
+	if module == "" {
+		t.Fatalf("PKCS11_TEST_MODULE not set")
+	}
+	slot, err := strconv.ParseUint(os.Getenv("PKCS11_TEST_SLOT"), 10, 0)
+	if err != nil {
+		t.Fatalf("invalid PKCS11_TEST_SLOT: %v", err)
+	}
+	return module, uint(slot)
+}
+
+// Tests the session, login, search and attribute operations of the bindings.
+func TestSoftHSMToken(t *testing.T) {
+	module, slot := softhsmToken(t)
+
+	tok, err := openToken(module, slot)
+	if err != nil {
+		t.Fatalf("failed to open token: %v", err)
+	}
+	defer tok.Close()
+
+	if err := tok.Login("not the pin"); err == nil {
+		t.Fatalf("logged in with wrong PIN")
+	}
+	if err := tok.Login(os.Getenv("PKCS11_TEST_PIN")); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	defer tok.Logout()
+
+	template := []attribute{{ckaClass, uint(ckoPublicKey)}, {ckaLabel, []byte(os.Getenv("PKCS11_TEST_LABEL"))}}
+	objects, err := tok.FindObjects(template)
+	if err != nil {
+		t.Fatalf("failed to find public key: %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("public key count mismatch: have %d, want 1", len(objects))
+	}
+	if values, err := tok.Attributes(objects[0], nil); values != nil || err != nil {
+		t.Errorf("empty attribute query: have %v, %v, want nil, nil", values, err)
+	}
+	values, err := tok.Attributes(objects[0], []uint{ckaLabel, ckaECParams, ckaECPoint})
+	if err != nil {
+		t.Fatalf("failed to retrieve attributes: %v", err)
+	}
+	if string(values[0]) != os.Getenv("PKCS11_TEST_LABEL") || string(values[1]) != string(secp256k1Params) || len(values[2]) == 0 {
+		t.Errorf("attribute mismatch: have %x", values)
+	}
+}
+
+// Tests signing transactions with a key stored on the token.
+func TestSoftHSM(t *testing.T) {
+	module, slot := softhsmToken(t)
+
+	hub, err := NewHub(module, slot, []string{os.Getenv("PKCS11_TEST_LABEL")})
+	if err != nil {
+		t.Fatalf("failed to open token: %v", err)
+	}
+	defer hub.Close()
+
+	wallet := hub.Wallets()[0]
+	account := wallet.Accounts()[0]
+
+	tx := types.NewTransaction(0, common.Address{0x11}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	for i := 0; i < 8; i++ {
+		signed, err := wallet.SignTxWithPassphrase(account, os.Getenv("PKCS11_TEST_PIN"), tx, big.NewInt(1))
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		if from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), signed); err != nil || from != account.Address {
+			t.Fatalf("transaction sender mismatch: have %x (%v), want %x", from, err, account.Address)
+		}
+	}
+	// Signatures must be recoverable to the account's address
+	hash := crypto.Keccak256([]byte("softhsm"))
+	sig, err := wallet.SignHashWithPassphrase(account, os.Getenv("PKCS11_TEST_PIN"), hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if from := crypto.PubkeyToAddress(*pubkey); from != account.Address {
+		t.Errorf("hash signer mismatch: have %x, want %x", from, account.Address)
+	}
+}
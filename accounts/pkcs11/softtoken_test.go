@@ -0,0 +1,183 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// p256Params is the DER encoded object identifier of the NIST P-256 curve.
+var p256Params = []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+
+// softObject is an object stored on a software token.
+type softObject struct {
+	attrs map[uint]interface{}
+	key   *ecdsa.PrivateKey // Signing key of private key objects
+}
+
+// softToken is an in-memory token behaving like SoftHSM: private objects are
+// only visible once logged in, and ECDSA signatures are returned as raw r || s,
+// with S not normalized.
+type softToken struct {
+	pin      string
+	objects  map[uint]*softObject
+	next     uint
+	loggedIn bool
+	closed   bool
+}
+
+func newSoftToken(pin string) *softToken {
+	return &softToken{pin: pin, objects: make(map[uint]*softObject), next: 1}
+}
+
+// generateKey creates an EC key pair labelled label on the token, returning the
+// private key.
+func (t *softToken) generateKey(label string, curve elliptic.Curve, params []byte) *ecdsa.PrivateKey {
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	point, err := asn1.Marshal(elliptic.Marshal(curve, priv.X, priv.Y))
+	if err != nil {
+		panic(err)
+	}
+	t.store(&softObject{attrs: map[uint]interface{}{
+		ckaClass:    uint(ckoPublicKey),
+		ckaKeyType:  uint(ckkEC),
+		ckaLabel:    []byte(label),
+		ckaECParams: params,
+		ckaECPoint:  point,
+	}})
+	t.store(&softObject{attrs: map[uint]interface{}{
+		ckaClass:    uint(ckoPrivateKey),
+		ckaKeyType:  uint(ckkEC),
+		ckaLabel:    []byte(label),
+		ckaECParams: params,
+	}, key: priv})
+	return priv
+}
+
+func (t *softToken) store(obj *softObject) {
+	t.objects[t.next] = obj
+	t.next++
+}
+
+// visible returns the object with the given handle, if the session can see it.
+func (t *softToken) visible(handle uint) (*softObject, bool) {
+	obj, ok := t.objects[handle]
+	if !ok || (obj.key != nil && !t.loggedIn) {
+		return nil, false
+	}
+	return obj, true
+}
+
+func (t *softToken) Login(pin string) error {
+	if t.closed {
+		return returnValue(ckrSessionHandleInvalid)
+	}
+	if t.loggedIn {
+		return returnValue(ckrUserAlreadyLoggedIn)
+	}
+	if pin != t.pin {
+		return returnValue(ckrPINIncorrect)
+	}
+	t.loggedIn = true
+	return nil
+}
+
+func (t *softToken) Logout() error {
+	if !t.loggedIn {
+		return returnValue(ckrUserNotLoggedIn)
+	}
+	t.loggedIn = false
+	return nil
+}
+
+func (t *softToken) FindObjects(template []attribute) ([]uint, error) {
+	if t.closed {
+		return nil, returnValue(ckrSessionHandleInvalid)
+	}
+	var found []uint
+	for handle := uint(1); handle < t.next; handle++ {
+		obj, ok := t.visible(handle)
+		if !ok {
+			continue
+		}
+		match := true
+		for _, attr := range template {
+			switch want := attr.value.(type) {
+			case uint:
+				match = match && obj.attrs[attr.typ] == want
+			case []byte:
+				have, ok := obj.attrs[attr.typ].([]byte)
+				match = match && ok && bytes.Equal(have, want)
+			}
+		}
+		if match {
+			found = append(found, handle)
+		}
+	}
+	return found, nil
+}
+
+func (t *softToken) Attributes(object uint, types []uint) ([][]byte, error) {
+	obj, ok := t.visible(object)
+	if !ok {
+		return nil, returnValue(ckrObjectHandleInvalid)
+	}
+	values := make([][]byte, len(types))
+	for i, typ := range types {
+		value, ok := obj.attrs[typ].([]byte)
+		if !ok {
+			return nil, returnValue(ckrAttributeTypeInvalid)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (t *softToken) Sign(key uint, digest []byte) ([]byte, error) {
+	if !t.loggedIn {
+		return nil, returnValue(ckrUserNotLoggedIn)
+	}
+	obj, ok := t.visible(key)
+	if !ok || obj.key == nil {
+		return nil, returnValue(ckrKeyHandleInvalid)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, obj.key, digest)
+	if err != nil {
+		return nil, returnValue(ckrFunctionFailed)
+	}
+	return append(math.PaddedBigBytes(r, 32), math.PaddedBigBytes(s, 32)...), nil
+}
+
+func (t *softToken) Close() error {
+	t.closed, t.loggedIn = true, false
+	return nil
+}
+
+// newSecp256k1Key creates a secp256k1 key pair labelled label on the token.
+func (t *softToken) newSecp256k1Key(label string) *ecdsa.PrivateKey {
+	return t.generateKey(label, crypto.S256(), secp256k1Params)
+}
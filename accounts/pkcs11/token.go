@@ -0,0 +1,136 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs11
+
+import "fmt"
+
+// PKCS#11 constants used by the backend, as defined by the Cryptoki standard.
+const (
+	ckoPublicKey  = 0x02 // CKO_PUBLIC_KEY object class
+	ckoPrivateKey = 0x03 // CKO_PRIVATE_KEY object class
+	ckkEC         = 0x03 // CKK_EC key type
+
+	ckaClass    = 0x000 // CKA_CLASS attribute
+	ckaLabel    = 0x003 // CKA_LABEL attribute
+	ckaKeyType  = 0x100 // CKA_KEY_TYPE attribute
+	ckaECParams = 0x180 // CKA_EC_PARAMS attribute
+	ckaECPoint  = 0x181 // CKA_EC_POINT attribute
+
+	ckmECDSA = 0x1041 // CKM_ECDSA mechanism, signing a precomputed digest
+)
+
+// PKCS#11 return values the backend reacts to or reports by name.
+const (
+	ckrOK                         = 0x000
+	ckrSlotIDInvalid              = 0x003
+	ckrGeneralError               = 0x005
+	ckrFunctionFailed             = 0x006
+	ckrArgumentsBad               = 0x007
+	ckrAttributeSensitive         = 0x011
+	ckrAttributeTypeInvalid       = 0x012
+	ckrDeviceError                = 0x030
+	ckrDeviceRemoved              = 0x032
+	ckrKeyHandleInvalid           = 0x060
+	ckrKeyFunctionNotPermitted    = 0x068
+	ckrMechanismInvalid           = 0x070
+	ckrObjectHandleInvalid        = 0x082
+	ckrPINIncorrect               = 0x0a0
+	ckrPINLenRange                = 0x0a2
+	ckrPINLocked                  = 0x0a4
+	ckrSessionClosed              = 0x0b0
+	ckrSessionHandleInvalid       = 0x0b3
+	ckrTokenNotPresent            = 0x0e0
+	ckrTokenNotRecognized         = 0x0e1
+	ckrUserAlreadyLoggedIn        = 0x100
+	ckrUserNotLoggedIn            = 0x101
+	ckrUserPINNotInitialized      = 0x102
+	ckrBufferTooSmall             = 0x150
+	ckrCryptokiNotInitialized     = 0x190
+	ckrCryptokiAlreadyInitialized = 0x191
+)
+
+// returnValue is a PKCS#11 error code returned by a module.
+type returnValue uint
+
+var returnValueNames = map[returnValue]string{
+	ckrSlotIDInvalid:              "CKR_SLOT_ID_INVALID",
+	ckrGeneralError:               "CKR_GENERAL_ERROR",
+	ckrFunctionFailed:             "CKR_FUNCTION_FAILED",
+	ckrArgumentsBad:               "CKR_ARGUMENTS_BAD",
+	ckrAttributeSensitive:         "CKR_ATTRIBUTE_SENSITIVE",
+	ckrAttributeTypeInvalid:       "CKR_ATTRIBUTE_TYPE_INVALID",
+	ckrDeviceError:                "CKR_DEVICE_ERROR",
+	ckrDeviceRemoved:              "CKR_DEVICE_REMOVED",
+	ckrKeyHandleInvalid:           "CKR_KEY_HANDLE_INVALID",
+	ckrKeyFunctionNotPermitted:    "CKR_KEY_FUNCTION_NOT_PERMITTED",
+	ckrMechanismInvalid:           "CKR_MECHANISM_INVALID",
+	ckrObjectHandleInvalid:        "CKR_OBJECT_HANDLE_INVALID",
+	ckrPINIncorrect:               "CKR_PIN_INCORRECT",
+	ckrPINLenRange:                "CKR_PIN_LEN_RANGE",
+	ckrPINLocked:                  "CKR_PIN_LOCKED",
+	ckrSessionClosed:              "CKR_SESSION_CLOSED",
+	ckrSessionHandleInvalid:       "CKR_SESSION_HANDLE_INVALID",
+	ckrTokenNotPresent:            "CKR_TOKEN_NOT_PRESENT",
+	ckrTokenNotRecognized:         "CKR_TOKEN_NOT_RECOGNIZED",
+	ckrUserAlreadyLoggedIn:        "CKR_USER_ALREADY_LOGGED_IN",
+	ckrUserNotLoggedIn:            "CKR_USER_NOT_LOGGED_IN",
+	ckrUserPINNotInitialized:      "CKR_USER_PIN_NOT_INITIALIZED",
+	ckrBufferTooSmall:             "CKR_BUFFER_TOO_SMALL",
+	ckrCryptokiNotInitialized:     "CKR_CRYPTOKI_NOT_INITIALIZED",
+	ckrCryptokiAlreadyInitialized: "CKR_CRYPTOKI_ALREADY_INITIALIZED",
+}
+
+func (rv returnValue) Error() string {
+	if name, ok := returnValueNames[rv]; ok {
+		return "pkcs11: " + name
+	}
+	return fmt.Sprintf("pkcs11: error 0x%x", uint(rv))
+}
+
+// attribute is a PKCS#11 object attribute in a search template. The value is
+// either a uint, encoded as a CK_ULONG, or a byte slice.
+type attribute struct {
+	typ   uint
+	value interface{}
+}
+
+// token is the subset of the PKCS#11 API needed to sign with the keys stored on
+// a single token, wrapping a session opened in its slot. Implementations need
+// not be safe for concurrent use.
+type token interface {
+	// Login authenticates the normal user of the token with the given PIN,
+	// granting access to its private keys.
+	Login(pin string) error
+
+	// Logout ends the authenticated state of the token.
+	Logout() error
+
+	// FindObjects returns the handles of the objects matching all attributes
+	// of the template.
+	FindObjects(template []attribute) ([]uint, error)
+
+	// Attributes retrieves the byte string values of the given attributes of
+	// an object.
+	Attributes(object uint, types []uint) ([][]byte, error)
+
+	// Sign signs a digest with an EC private key using CKM_ECDSA, returning the
+	// raw r || s signature.
+	Sign(key uint, digest []byte) ([]byte, error)
+
+	// Close closes the session and releases the module if it is no longer used.
+	Close() error
+}
@@ -0,0 +1,415 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux,cgo darwin,!ios,cgo freebsd,cgo
+
+package pkcs11
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef unsigned char CK_BYTE;
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+
+typedef struct {
+	CK_ULONG type;
+	void    *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void    *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	void    *CreateMutex;
+	void    *DestroyMutex;
+	void    *LockMutex;
+	void    *UnlockMutex;
+	CK_ULONG flags;
+	void    *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+// CK_FUNCTION_LIST mirrors the head of the Cryptoki function list up to the
+// last function used. The list is only ever accessed through the pointer the
+// module returns, so the trailing entries need not be declared.
+typedef struct {
+	CK_BYTE version[2];
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo;
+	void *C_GetFunctionList;
+	void *C_GetSlotList;
+	void *C_GetSlotInfo;
+	void *C_GetTokenInfo;
+	void *C_GetMechanismList;
+	void *C_GetMechanismInfo;
+	void *C_InitToken;
+	void *C_InitPIN;
+	void *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*C_CloseSession)(CK_ULONG);
+	void *C_CloseAllSessions;
+	void *C_GetSessionInfo;
+	void *C_GetOperationState;
+	void *C_SetOperationState;
+	CK_RV (*C_Login)(CK_ULONG, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*C_Logout)(CK_ULONG);
+	void *C_CreateObject;
+	void *C_CopyObject;
+	void *C_DestroyObject;
+	void *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_ULONG, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_ULONG);
+	void *C_EncryptInit;
+	void *C_Encrypt;
+	void *C_EncryptUpdate;
+	void *C_EncryptFinal;
+	void *C_DecryptInit;
+	void *C_Decrypt;
+	void *C_DecryptUpdate;
+	void *C_DecryptFinal;
+	void *C_DigestInit;
+	void *C_Digest;
+	void *C_DigestUpdate;
+	void *C_DigestKey;
+	void *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*C_Sign)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+#define CKF_OS_LOCKING_OK  0x2
+#define CKF_SERIAL_SESSION 0x4
+#define CKU_USER           1
+
+// ck_load opens the module library and retrieves its function list. On failure
+// to load the library, the dynamic linker error is returned in err.
+static CK_RV ck_load(const char *path, void **handle, CK_FUNCTION_LIST **funcs, const char **err) {
+	*handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (*handle == NULL) {
+		*err = dlerror();
+		return 0;
+	}
+	CK_RV (*getFunctionList)(CK_FUNCTION_LIST **) = dlsym(*handle, "C_GetFunctionList");
+	if (getFunctionList == NULL) {
+		*err = dlerror();
+		dlclose(*handle);
+		return 0;
+	}
+	CK_RV rv = getFunctionList(funcs);
+	if (rv != 0) {
+		dlclose(*handle);
+	}
+	return rv;
+}
+
+static CK_RV ck_initialize(CK_FUNCTION_LIST *f) {
+	CK_C_INITIALIZE_ARGS args;
+	memset(&args, 0, sizeof(args));
+	args.flags = CKF_OS_LOCKING_OK;
+	return f->C_Initialize(&args);
+}
+
+static CK_RV ck_finalize(CK_FUNCTION_LIST *f) {
+	return f->C_Finalize(NULL);
+}
+
+static CK_RV ck_open_session(CK_FUNCTION_LIST *f, CK_ULONG slot, CK_ULONG *session) {
+	return f->C_OpenSession(slot, CKF_SERIAL_SESSION, NULL, NULL, session);
+}
+
+static CK_RV ck_close_session(CK_FUNCTION_LIST *f, CK_ULONG session) {
+	return f->C_CloseSession(session);
+}
+
+static CK_RV ck_login(CK_FUNCTION_LIST *f, CK_ULONG session, CK_BYTE *pin, CK_ULONG len) {
+	return f->C_Login(session, CKU_USER, pin, len);
+}
+
+static CK_RV ck_logout(CK_FUNCTION_LIST *f, CK_ULONG session) {
+	return f->C_Logout(session);
+}
+
+static CK_RV ck_get_attribute_value(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG object, CK_ATTRIBUTE *attrs, CK_ULONG count) {
+	return f->C_GetAttributeValue(session, object, attrs, count);
+}
+
+static CK_RV ck_find_objects_init(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ATTRIBUTE *attrs, CK_ULONG count) {
+	return f->C_FindObjectsInit(session, attrs, count);
+}
+
+static CK_RV ck_find_objects(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG *objects, CK_ULONG max, CK_ULONG *count) {
+	return f->C_FindObjects(session, objects, max, count);
+}
+
+static CK_RV ck_find_objects_final(CK_FUNCTION_LIST *f, CK_ULONG session) {
+	return f->C_FindObjectsFinal(session);
+}
+
+static CK_RV ck_sign(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG key, CK_BYTE *data, CK_ULONG len, CK_BYTE *sig, CK_ULONG *siglen) {
+	CK_MECHANISM mech = {0x1041, NULL, 0}; // CKM_ECDSA
+	CK_RV rv = f->C_SignInit(session, &mech, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return f->C_Sign(session, data, len, sig, siglen);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// module is a loaded PKCS#11 library, shared by all the tokens opened from it.
+type module struct {
+	path   string
+	handle unsafe.Pointer
+	funcs  *C.CK_FUNCTION_LIST
+	refs   int // Number of open tokens using the module
+}
+
+var (
+	modules     = make(map[string]*module) // Loaded modules, keyed by library path
+	modulesLock sync.Mutex                 // Lock protecting the loaded modules
+)
+
+// loadModule loads and initializes the PKCS#11 library at path, or returns it
+// if it was already loaded.
+func loadModule(path string) (*module, error) {
+	if m, ok := modules[path]; ok {
+		return m, nil
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	var (
+		handle unsafe.Pointer
+		funcs  *C.CK_FUNCTION_LIST
+		cerr   *C.char
+	)
+	if rv := C.ck_load(cpath, &handle, &funcs, &cerr); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	if cerr != nil {
+		return nil, errors.New(C.GoString(cerr))
+	}
+	// Initialize the library. Another component of the process may have done
+	// so already, which is fine as long as it's not finalized under our feet.
+	if rv := C.ck_initialize(funcs); rv != ckrOK && rv != ckrCryptokiAlreadyInitialized {
+		C.dlclose(handle)
+		return nil, returnValue(rv)
+	}
+	m := &module{path: path, handle: handle, funcs: funcs}
+	modules[path] = m
+	return m, nil
+}
+
+// release drops a reference to the module, finalizing and unloading it when it
+// is no longer used.
+func (m *module) release() error {
+	if m.refs--; m.refs > 0 {
+		return nil
+	}
+	delete(modules, m.path)
+
+	rv := C.ck_finalize(m.funcs)
+	C.dlclose(m.handle)
+	if rv != ckrOK {
+		return returnValue(rv)
+	}
+	return nil
+}
+
+// cgoToken is a token accessed through a session opened via a PKCS#11 library.
+type cgoToken struct {
+	module  *module
+	session C.CK_ULONG
+}
+
+// openToken loads the PKCS#11 library at path and opens a session with the token
+// in the given slot.
+func openToken(path string, slot uint) (token, error) {
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+
+	m, err := loadModule(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module: %v", err)
+	}
+	var session C.CK_ULONG
+	if rv := C.ck_open_session(m.funcs, C.CK_ULONG(slot), &session); rv != ckrOK {
+		if m.refs == 0 {
+			m.refs++
+			m.release()
+		}
+		return nil, fmt.Errorf("failed to open slot %d: %v", slot, returnValue(rv))
+	}
+	m.refs++
+	return &cgoToken{module: m, session: session}, nil
+}
+
+func (t *cgoToken) Login(pin string) error {
+	cpin := C.CBytes([]byte(pin))
+	defer C.free(cpin)
+
+	if rv := C.ck_login(t.module.funcs, t.session, (*C.CK_BYTE)(cpin), C.CK_ULONG(len(pin))); rv != ckrOK {
+		return returnValue(rv)
+	}
+	return nil
+}
+
+func (t *cgoToken) Logout() error {
+	if rv := C.ck_logout(t.module.funcs, t.session); rv != ckrOK {
+		return returnValue(rv)
+	}
+	return nil
+}
+
+func (t *cgoToken) FindObjects(template []attribute) ([]uint, error) {
+	attrs, free := newTemplate(template)
+	defer free()
+
+	if rv := C.ck_find_objects_init(t.module.funcs, t.session, attrs, C.CK_ULONG(len(template))); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	var (
+		objects []uint
+		batch   [16]C.CK_ULONG
+		count   C.CK_ULONG
+	)
+	for {
+		if rv := C.ck_find_objects(t.module.funcs, t.session, &batch[0], C.CK_ULONG(len(batch)), &count); rv != ckrOK {
+			C.ck_find_objects_final(t.module.funcs, t.session)
+			return nil, returnValue(rv)
+		}
+		for i := 0; i < int(count); i++ {
+			objects = append(objects, uint(batch[i]))
+		}
+		if count == 0 {
+			break
+		}
+	}
+	if rv := C.ck_find_objects_final(t.module.funcs, t.session); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	return objects, nil
+}
+
+func (t *cgoToken) Attributes(object uint, types []uint) ([][]byte, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	// Query the lengths of the attribute values first
+	attrs := (*[1 << 20]C.CK_ATTRIBUTE)(C.calloc(C.size_t(len(types)), C.sizeof_CK_ATTRIBUTE))[:len(types):len(types)]
+	defer func() {
+		for i := range attrs {
+			C.free(attrs[i].pValue)
+		}
+		C.free(unsafe.Pointer(&attrs[0]))
+	}()
+	for i, typ := range types {
+		attrs[i]._type = C.CK_ULONG(typ)
+	}
+	if rv := C.ck_get_attribute_value(t.module.funcs, t.session, C.CK_ULONG(object), &attrs[0], C.CK_ULONG(len(attrs))); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	// Allocate the buffers and retrieve the values
+	for i := range attrs {
+		if attrs[i].ulValueLen == ^C.CK_ULONG(0) {
+			return nil, fmt.Errorf("attribute 0x%x unavailable", types[i])
+		}
+		attrs[i].pValue = C.malloc(C.size_t(attrs[i].ulValueLen) + 1)
+	}
+	if rv := C.ck_get_attribute_value(t.module.funcs, t.session, C.CK_ULONG(object), &attrs[0], C.CK_ULONG(len(attrs))); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	values := make([][]byte, len(attrs))
+	for i := range attrs {
+		values[i] = C.GoBytes(attrs[i].pValue, C.int(attrs[i].ulValueLen))
+	}
+	return values, nil
+}
+
+func (t *cgoToken) Sign(key uint, digest []byte) ([]byte, error) {
+	cdigest := C.CBytes(digest)
+	defer C.free(cdigest)
+
+	var (
+		sig    [128]C.CK_BYTE // Large enough for any curve the backend supports
+		siglen = C.CK_ULONG(len(sig))
+	)
+	if rv := C.ck_sign(t.module.funcs, t.session, C.CK_ULONG(key), (*C.CK_BYTE)(cdigest), C.CK_ULONG(len(digest)), &sig[0], &siglen); rv != ckrOK {
+		return nil, returnValue(rv)
+	}
+	return C.GoBytes(unsafe.Pointer(&sig[0]), C.int(siglen)), nil
+}
+
+func (t *cgoToken) Close() error {
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+
+	rv := C.ck_close_session(t.module.funcs, t.session)
+	if err := t.module.release(); err != nil {
+		return err
+	}
+	if rv != ckrOK {
+		return returnValue(rv)
+	}
+	return nil
+}
+
+// newTemplate copies a search template into C memory, returning it along with
+// a function releasing it.
+func newTemplate(template []attribute) (*C.CK_ATTRIBUTE, func()) {
+	if len(template) == 0 {
+		return nil, func() {}
+	}
+	attrs := (*[1 << 20]C.CK_ATTRIBUTE)(C.calloc(C.size_t(len(template)), C.sizeof_CK_ATTRIBUTE))[:len(template):len(template)]
+	for i, attr := range template {
+		attrs[i]._type = C.CK_ULONG(attr.typ)
+		switch value := attr.value.(type) {
+		case uint:
+			ptr := (*C.CK_ULONG)(C.malloc(C.sizeof_CK_ULONG))
+			*ptr = C.CK_ULONG(value)
+			attrs[i].pValue, attrs[i].ulValueLen = unsafe.Pointer(ptr), C.sizeof_CK_ULONG
+		case []byte:
+			attrs[i].pValue, attrs[i].ulValueLen = C.CBytes(value), C.CK_ULONG(len(value))
+		default:
+			panic(fmt.Sprintf("unsupported attribute value %T", value))
+		}
+	}
+	free := func() {
+		for i := range attrs {
+			C.free(attrs[i].pValue)
+		}
+		C.free(unsafe.Pointer(&attrs[0]))
+	}
+	return &attrs[0], free
+}
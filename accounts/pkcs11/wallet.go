@@ -0,0 +1,334 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs11
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errWalletOpen is returned when signing with a passphrase on an open wallet:
+// the token is already logged in, so the PIN can't be verified.
+var errWalletOpen = errors.New("wallet open, sign without passphrase")
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1halfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// wallet implements accounts.Wallet for a PKCS#11 token, with one account per
+// secp256k1 key. The PIN of the token is the passphrase of the wallet: opening
+// the wallet logs into the token until it's closed, whereas signing with a
+// passphrase logs in only for the duration of the signature.
+type wallet struct {
+	hub   *Hub         // Backend the wallet belongs to, for event notifications
+	url   accounts.URL // Canonical URL of the wallet, identifying the slot
+	token token        // Session with the token in the slot
+	keys  []key        // Keys on the token, as accounts
+
+	loggedIn bool // Whether the wallet was opened with the PIN of the token
+	closed   bool // Whether the session with the token was closed
+
+	stateLock sync.Mutex // Lock serializing the use of the token session
+}
+
+// URL implements accounts.Wallet, returning the URL of the token slot.
+func (w *wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, returning whether the token is logged in.
+func (w *wallet) Status() (string, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.closed {
+		return "Closed", accounts.ErrWalletClosed
+	}
+	if w.loggedIn {
+		return "Unlocked", nil
+	}
+	return "Locked", nil
+}
+
+// Open implements accounts.Wallet, logging into the token with the PIN given as
+// the passphrase. Opening with an empty passphrase is a noop, leaving the wallet
+// locked for signing with passphrases.
+func (w *wallet) Open(passphrase string) error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.closed {
+		return accounts.ErrWalletClosed
+	}
+	if w.loggedIn {
+		return accounts.ErrWalletAlreadyOpen
+	}
+	if passphrase == "" {
+		return nil
+	}
+	if err := w.login(passphrase); err != nil {
+		return err
+	}
+	w.loggedIn = true
+
+	go w.hub.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletOpened})
+	return nil
+}
+
+// Close implements accounts.Wallet, logging out of the token.
+func (w *wallet) Close() error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if !w.loggedIn {
+		return nil
+	}
+	w.loggedIn = false
+	return w.token.Logout()
+}
+
+// release logs out of the token and closes the session with it.
+func (w *wallet) release() error {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	if w.loggedIn {
+		w.token.Logout()
+		w.loggedIn = false
+	}
+	w.closed = true
+	return w.token.Close()
+}
+
+// login logs into the token, reporting a wrong PIN as an invalid passphrase.
+func (w *wallet) login(pin string) error {
+	switch err := w.token.Login(pin); err {
+	case returnValue(ckrPINIncorrect), returnValue(ckrPINLenRange):
+		return accounts.ErrInvalidPassphrase
+	default:
+		return err
+	}
+}
+
+// Accounts implements accounts.Wallet, returning the accounts of the keys on
+// the token.
+func (w *wallet) Accounts() []accounts.Account {
+	accs := make([]accounts.Account, len(w.keys))
+	for i, key := range w.keys {
+		accs[i] = key.account
+	}
+	return accs
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account
+// is or is not stored on the token.
+func (w *wallet) Contains(account accounts.Account) bool {
+	_, ok := w.key(account)
+	return ok
+}
+
+// key returns the key backing an account, if it is stored on the token.
+func (w *wallet) key(account accounts.Account) (key, bool) {
+	for _, key := range w.keys {
+		if key.account.Address == account.Address && (account.URL == (accounts.URL{}) || key.account.URL == account.URL) {
+			return key, true
+		}
+	}
+	return key{}, false
+}
+
+// Derive implements accounts.Wallet, but is a noop for PKCS#11 tokens since the
+// keys are managed on the token itself.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for PKCS#11 tokens since
+// the keys are managed on the token itself.
+func (w *wallet) SelfDerive(iterators []func() accounts.DerivationPath, chain ethereum.ChainStateReader) {
+}
+
+// SignHash implements accounts.Wallet, signing the hash with the key of the
+// account on the open token.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	key, err := w.openKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return w.signHash(key, hash)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with the key of
+// the account on the open token.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	key, err := w.openKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return w.signTx(key, tx, chainID)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, logging into the token
+// with the PIN given as passphrase to sign the hash.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	key, err := w.lockedKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer w.token.Logout()
+
+	return w.signHash(key, hash)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, logging into the token with
+// the PIN given as passphrase to sign the transaction.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	key, err := w.lockedKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer w.token.Logout()
+
+	return w.signTx(key, tx, chainID)
+}
+
+// openKey returns the key of an account for signing on the open token.
+//
+// The caller must hold w.stateLock.
+func (w *wallet) openKey(account accounts.Account) (key, error) {
+	if w.closed || !w.loggedIn {
+		return key{}, accounts.ErrWalletClosed
+	}
+	key, ok := w.key(account)
+	if !ok {
+		return key, accounts.ErrUnknownAccount
+	}
+	return key, nil
+}
+
+// lockedKey returns the key of an account after logging into the locked token
+// with the given PIN. The caller is responsible for logging out.
+//
+// The caller must hold w.stateLock.
+func (w *wallet) lockedKey(account accounts.Account, pin string) (key, error) {
+	if w.closed {
+		return key{}, accounts.ErrWalletClosed
+	}
+	if w.loggedIn {
+		return key{}, errWalletOpen
+	}
+	key, ok := w.key(account)
+	if !ok {
+		return key, accounts.ErrUnknownAccount
+	}
+	return key, w.login(pin)
+}
+
+// signTx signs the transaction with the key, as configured for the chain.
+//
+// The caller must hold w.stateLock.
+func (w *wallet) signTx(key key, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		signer = types.LatestSignerForChainID(chainID)
+	}
+	sig, err := w.signHash(key, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// signHash signs the hash with the private key matching the label of the key
+// on the logged in token.
+//
+// The caller must hold w.stateLock.
+func (w *wallet) signHash(key key, hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("hash is required to be exactly 32 bytes (%d)", len(hash))
+	}
+	objects, err := w.token.FindObjects([]attribute{
+		{ckaClass, uint(ckoPrivateKey)},
+		{ckaKeyType, uint(ckkEC)},
+		{ckaLabel, []byte(key.label)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch len(objects) {
+	case 0:
+		return nil, fmt.Errorf("no private key labelled %q", key.label)
+	case 1:
+	default:
+		return nil, fmt.Errorf("multiple private keys labelled %q", key.label)
+	}
+	raw, err := w.token.Sign(objects[0], hash)
+	if err != nil {
+		return nil, err
+	}
+	return recoverableSignature(hash, raw, key.pubkey)
+}
+
+// recoverableSignature converts a raw r || s signature computed by a token into
+// the [R || S || V] format used by Ethereum. S is moved into the lower half of
+// the curve order, as required for transactions since Homestead, and the
+// recovery id V is found by recovering the public key of the signer.
+func recoverableSignature(hash, raw, pubkey []byte) ([]byte, error) {
+	if len(raw) != 64 {
+		return nil, fmt.Errorf("invalid signature length %d", len(raw))
+	}
+	s := new(big.Int).SetBytes(raw[32:])
+	if s.Cmp(secp256k1halfN) > 0 {
+		s.Sub(secp256k1N, s)
+	}
+	sig := make([]byte, 65)
+	copy(sig, raw[:32])
+	copy(sig[32:], math.PaddedBigBytes(s, 32))
+
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if recovered, err := crypto.Ecrecover(hash, sig); err == nil && bytes.Equal(recovered, pubkey) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("signature does not match the public key")
+}
@@ -0,0 +1,222 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs11
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testPIN = "1234"
+
+// Tests that the keys of a token are looked up by label, ignoring keys on other
+// curves, and that missing keys are reported.
+func TestHubKeys(t *testing.T) {
+	tok := newSoftToken(testPIN)
+	bob := tok.newSecp256k1Key("bob")
+	alice := tok.newSecp256k1Key("alice")
+	tok.generateKey("nist", elliptic.P256(), p256Params)
+
+	// Without labels, all secp256k1 keys are used
+	hub, err := newHub(tok, 3, nil)
+	if err != nil {
+		t.Fatalf("failed to create hub: %v", err)
+	}
+	wallets := hub.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	if url := wallets[0].URL().String(); url != "pkcs11://3" {
+		t.Errorf("wallet URL mismatch: have %s, want pkcs11://3", url)
+	}
+	want := []accounts.Account{
+		{Address: crypto.PubkeyToAddress(alice.PublicKey), URL: accounts.URL{Scheme: Scheme, Path: "3/alice"}},
+		{Address: crypto.PubkeyToAddress(bob.PublicKey), URL: accounts.URL{Scheme: Scheme, Path: "3/bob"}},
+	}
+	accs := wallets[0].Accounts()
+	if len(accs) != len(want) {
+		t.Fatalf("account count mismatch: have %d, want %d", len(accs), len(want))
+	}
+	for i := range want {
+		if accs[i] != want[i] {
+			t.Errorf("account %d mismatch: have %v, want %v", i, accs[i], want[i])
+		}
+		if !wallets[0].Contains(accounts.Account{Address: want[i].Address}) {
+			t.Errorf("account %d not contained", i)
+		}
+	}
+	// With labels, only the requested keys are used
+	hub, err = newHub(tok, 3, []string{"bob"})
+	if err != nil {
+		t.Fatalf("failed to create hub: %v", err)
+	}
+	if accs := hub.Wallets()[0].Accounts(); len(accs) != 1 || accs[0] != want[1] {
+		t.Errorf("labelled accounts mismatch: have %v, want %v", accs, want[1:])
+	}
+	if _, err := newHub(tok, 3, []string{"bob", "carol"}); err == nil || !strings.Contains(err.Error(), `"carol"`) {
+		t.Errorf("missing key error mismatch: have %v", err)
+	}
+	if _, err := newHub(tok, 3, []string{"nist"}); err == nil || !strings.Contains(err.Error(), errUnsupportedCurve.Error()) {
+		t.Errorf("unsupported curve error mismatch: have %v", err)
+	}
+}
+
+// Tests that an open wallet signs without passphrase and a locked one only with
+// the PIN of the token.
+func TestWalletSign(t *testing.T) {
+	tok := newSoftToken(testPIN)
+	key := tok.newSecp256k1Key("signer")
+
+	hub, err := newHub(tok, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to create hub: %v", err)
+	}
+	wallet := hub.Wallets()[0]
+	account := wallet.Accounts()[0]
+
+	events := make(chan accounts.WalletEvent, 1)
+	sub := hub.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// Opening without a PIN leaves the wallet locked
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet without PIN: %v", err)
+	}
+	if status, _ := wallet.Status(); status != "Locked" {
+		t.Errorf("status mismatch: have %s, want Locked", status)
+	}
+	hash := crypto.Keccak256([]byte("hello"))
+	if _, err := wallet.SignHash(account, hash); err != accounts.ErrWalletClosed {
+		t.Errorf("locked signing error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	// Sign with the PIN, leaving the wallet locked
+	if _, err := wallet.SignHashWithPassphrase(account, "4321", hash); err != accounts.ErrInvalidPassphrase {
+		t.Errorf("wrong PIN error mismatch: have %v, want %v", err, accounts.ErrInvalidPassphrase)
+	}
+	if _, err := wallet.SignHashWithPassphrase(accounts.Account{Address: common.Address{1}}, testPIN, hash); err != accounts.ErrUnknownAccount {
+		t.Errorf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	sig, err := wallet.SignHashWithPassphrase(account, testPIN, hash)
+	if err != nil {
+		t.Fatalf("failed to sign with PIN: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != account.Address {
+		t.Errorf("signer mismatch: have %v (%v), want %x", pub, err, account.Address)
+	}
+	if tok.loggedIn {
+		t.Errorf("token left logged in after signing with PIN")
+	}
+	// Open the wallet and sign without PIN
+	if err := wallet.Open("4321"); err != accounts.ErrInvalidPassphrase {
+		t.Errorf("wrong PIN open error mismatch: have %v, want %v", err, accounts.ErrInvalidPassphrase)
+	}
+	if err := wallet.Open(testPIN); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Kind != accounts.WalletOpened || event.Wallet != wallet {
+			t.Errorf("event mismatch: have %v %v, want WalletOpened", event.Kind, event.Wallet.URL())
+		}
+	case <-time.After(time.Second):
+		t.Errorf("wallet opened event timeout")
+	}
+	if status, _ := wallet.Status(); status != "Unlocked" {
+		t.Errorf("status mismatch: have %s, want Unlocked", status)
+	}
+	if err := wallet.Open(testPIN); err != accounts.ErrWalletAlreadyOpen {
+		t.Errorf("reopen error mismatch: have %v, want %v", err, accounts.ErrWalletAlreadyOpen)
+	}
+	if _, err := wallet.SignHashWithPassphrase(account, testPIN, hash); err != errWalletOpen {
+		t.Errorf("open wallet PIN signing error mismatch: have %v, want %v", err, errWalletOpen)
+	}
+	tx := types.NewTransaction(0, common.Address{0x11}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	for _, chainID := range []*big.Int{nil, big.NewInt(1337)} {
+		signed, err := wallet.SignTx(account, tx, chainID)
+		if err != nil {
+			t.Fatalf("failed to sign transaction for chain %v: %v", chainID, err)
+		}
+		var signer types.Signer = types.HomesteadSigner{}
+		if chainID != nil {
+			signer = types.NewEIP155Signer(chainID)
+		}
+		if from, err := types.Sender(signer, signed); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("transaction sender mismatch for chain %v: have %x (%v), want %x", chainID, from, err, account.Address)
+		}
+	}
+	// Close the wallet and check that it's locked again
+	if err := wallet.Close(); err != nil {
+		t.Fatalf("failed to close wallet: %v", err)
+	}
+	if _, err := wallet.SignTx(account, tx, nil); err != accounts.ErrWalletClosed {
+		t.Errorf("closed signing error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if _, err := wallet.SignTxWithPassphrase(account, testPIN, tx, big.NewInt(1)); err != nil {
+		t.Errorf("failed to sign transaction with PIN: %v", err)
+	}
+	// Release the token and check that the wallet is unusable
+	if err := hub.Close(); err != nil {
+		t.Fatalf("failed to close hub: %v", err)
+	}
+	if !tok.closed {
+		t.Errorf("token session not closed")
+	}
+	if _, err := wallet.Status(); err != accounts.ErrWalletClosed {
+		t.Errorf("released status error mismatch: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+}
+
+// Tests that raw token signatures are converted into recoverable signatures in
+// the lower half of the curve order.
+func TestRecoverableSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pubkey := crypto.FromECDSAPub(&key.PublicKey)
+
+	for i := 0; i < 16; i++ {
+		hash := crypto.Keccak256([]byte{byte(i)})
+		want, err := crypto.Sign(hash, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		// Tokens may return either S or its negation
+		low := want[:64]
+		high := append(common.CopyBytes(want[:32]), math.PaddedBigBytes(new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(want[32:64])), 32)...)
+
+		for _, raw := range [][]byte{low, high} {
+			sig, err := recoverableSignature(hash, raw, pubkey)
+			if err != nil {
+				t.Fatalf("failed to convert signature %x: %v", raw, err)
+			}
+			if !crypto.VerifySignature(pubkey, hash, sig[:64]) || string(sig) != string(want) {
+				t.Errorf("signature mismatch: have %x, want %x", sig, want)
+			}
+		}
+	}
+	other, _ := crypto.GenerateKey()
+	if _, err := recoverableSignature(crypto.Keccak256(nil), make([]byte, 64), crypto.FromECDSAPub(&other.PublicKey)); err == nil {
+		t.Errorf("invalid signature converted")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trezor
+
+import "github.com/golang/protobuf/proto"
+
+// The messages below implement EIP-1559 dynamic fee transaction signing, which
+// is only available in recent Trezor firmware. They are not part of the bundled
+// protocol definitions, so they are maintained by hand.
+
+const MessageType_MessageType_EthereumSignTxEIP1559 MessageType = 452
+
+func init() {
+	MessageType_name[452] = "MessageType_EthereumSignTxEIP1559"
+	MessageType_value["MessageType_EthereumSignTxEIP1559"] = 452
+}
+
+// *
+// Request: Ask device to sign an EIP-1559 dynamic fee transaction
+// Note: the first at most 1024 bytes of data MUST be transmitted as part of this message.
+// @next EthereumTxRequest
+// @next Failure
+type EthereumSignTxEIP1559 struct {
+	AddressN         []uint32                                    `protobuf:"varint,1,rep,name=address_n,json=addressN" json:"address_n,omitempty"`
+	Nonce            []byte                                      `protobuf:"bytes,2,req,name=nonce" json:"nonce,omitempty"`
+	MaxGasFee        []byte                                      `protobuf:"bytes,3,req,name=max_gas_fee,json=maxGasFee" json:"max_gas_fee,omitempty"`
+	MaxPriorityFee   []byte                                      `protobuf:"bytes,4,req,name=max_priority_fee,json=maxPriorityFee" json:"max_priority_fee,omitempty"`
+	GasLimit         []byte                                      `protobuf:"bytes,5,req,name=gas_limit,json=gasLimit" json:"gas_limit,omitempty"`
+	To               *string                                     `protobuf:"bytes,6,opt,name=to" json:"to,omitempty"`
+	Value            []byte                                      `protobuf:"bytes,7,req,name=value" json:"value,omitempty"`
+	DataInitialChunk []byte                                      `protobuf:"bytes,8,opt,name=data_initial_chunk,json=dataInitialChunk" json:"data_initial_chunk,omitempty"`
+	DataLength       *uint32                                     `protobuf:"varint,9,req,name=data_length,json=dataLength" json:"data_length,omitempty"`
+	ChainId          *uint64                                     `protobuf:"varint,10,req,name=chain_id,json=chainId" json:"chain_id,omitempty"`
+	AccessList       []*EthereumSignTxEIP1559_EthereumAccessList `protobuf:"bytes,11,rep,name=access_list,json=accessList" json:"access_list,omitempty"`
+	XXX_unrecognized []byte                                      `json:"-"`
+}
+
+func (m *EthereumSignTxEIP1559) Reset()         { *m = EthereumSignTxEIP1559{} }
+func (m *EthereumSignTxEIP1559) String() string { return proto.CompactTextString(m) }
+func (*EthereumSignTxEIP1559) ProtoMessage()    {}
+
+func (m *EthereumSignTxEIP1559) GetAddressN() []uint32 {
+	if m != nil {
+		return m.AddressN
+	}
+	return nil
+}
+
+func (m *EthereumSignTxEIP1559) GetChainId() uint64 {
+	if m != nil && m.ChainId != nil {
+		return *m.ChainId
+	}
+	return 0
+}
+
+func (m *EthereumSignTxEIP1559) GetAccessList() []*EthereumSignTxEIP1559_EthereumAccessList {
+	if m != nil {
+		return m.AccessList
+	}
+	return nil
+}
+
+// *
+// Access list entry of an EIP-1559 transaction
+type EthereumSignTxEIP1559_EthereumAccessList struct {
+	Address          *string  `protobuf:"bytes,1,req,name=address" json:"address,omitempty"`
+	StorageKeys      [][]byte `protobuf:"bytes,2,rep,name=storage_keys,json=storageKeys" json:"storage_keys,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *EthereumSignTxEIP1559_EthereumAccessList) Reset() {
+	*m = EthereumSignTxEIP1559_EthereumAccessList{}
+}
+func (m *EthereumSignTxEIP1559_EthereumAccessList) String() string { return proto.CompactTextString(m) }
+func (*EthereumSignTxEIP1559_EthereumAccessList) ProtoMessage()    {}
+
+func (m *EthereumSignTxEIP1559_EthereumAccessList) GetAddress() string {
+	if m != nil && m.Address != nil {
+		return *m.Address
+	}
+	return ""
+}
+
+func (m *EthereumSignTxEIP1559_EthereumAccessList) GetStorageKeys() [][]byte {
+	if m != nil {
+		return m.StorageKeys
+	}
+	return nil
+}
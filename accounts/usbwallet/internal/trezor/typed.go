@@ -0,0 +1,99 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trezor
+
+import "github.com/golang/protobuf/proto"
+
+// The messages below implement EIP-712 typed data signing over precomputed
+// hashes, which is only available in recent Trezor One firmware. They are not
+// part of the bundled protocol definitions, so they are maintained by hand.
+
+const (
+	MessageType_MessageType_EthereumTypedDataSignature MessageType = 469
+	MessageType_MessageType_EthereumSignTypedHash      MessageType = 470
+)
+
+func init() {
+	MessageType_name[469] = "MessageType_EthereumTypedDataSignature"
+	MessageType_name[470] = "MessageType_EthereumSignTypedHash"
+
+	MessageType_value["MessageType_EthereumTypedDataSignature"] = 469
+	MessageType_value["MessageType_EthereumSignTypedHash"] = 470
+}
+
+// *
+// Request: Ask device to sign the EIP-712 hashes of typed data
+// @next EthereumTypedDataSignature
+// @next Failure
+type EthereumSignTypedHash struct {
+	AddressN            []uint32 `protobuf:"varint,1,rep,name=address_n,json=addressN" json:"address_n,omitempty"`
+	DomainSeparatorHash []byte   `protobuf:"bytes,2,req,name=domain_separator_hash,json=domainSeparatorHash" json:"domain_separator_hash,omitempty"`
+	MessageHash         []byte   `protobuf:"bytes,3,opt,name=message_hash,json=messageHash" json:"message_hash,omitempty"`
+	XXX_unrecognized    []byte   `json:"-"`
+}
+
+func (m *EthereumSignTypedHash) Reset()         { *m = EthereumSignTypedHash{} }
+func (m *EthereumSignTypedHash) String() string { return proto.CompactTextString(m) }
+func (*EthereumSignTypedHash) ProtoMessage()    {}
+
+func (m *EthereumSignTypedHash) GetAddressN() []uint32 {
+	if m != nil {
+		return m.AddressN
+	}
+	return nil
+}
+
+func (m *EthereumSignTypedHash) GetDomainSeparatorHash() []byte {
+	if m != nil {
+		return m.DomainSeparatorHash
+	}
+	return nil
+}
+
+func (m *EthereumSignTypedHash) GetMessageHash() []byte {
+	if m != nil {
+		return m.MessageHash
+	}
+	return nil
+}
+
+// *
+// Response: Signed typed data
+// @prev EthereumSignTypedHash
+type EthereumTypedDataSignature struct {
+	Signature        []byte  `protobuf:"bytes,1,req,name=signature" json:"signature,omitempty"`
+	Address          *string `protobuf:"bytes,2,req,name=address" json:"address,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *EthereumTypedDataSignature) Reset()         { *m = EthereumTypedDataSignature{} }
+func (m *EthereumTypedDataSignature) String() string { return proto.CompactTextString(m) }
+func (*EthereumTypedDataSignature) ProtoMessage()    {}
+
+func (m *EthereumTypedDataSignature) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *EthereumTypedDataSignature) GetAddress() string {
+	if m != nil && m.Address != nil {
+		return *m.Address
+	}
+	return ""
+}
@@ -48,13 +48,18 @@ type ledgerParam1 byte
 type ledgerParam2 byte
 
 const (
-	ledgerOpRetrieveAddress  ledgerOpcode = 0x02 // Returns the public key and Ethereum address for a given BIP 32 path
-	ledgerOpSignTransaction  ledgerOpcode = 0x04 // Signs an Ethereum transaction after having the user validate the parameters
-	ledgerOpGetConfiguration ledgerOpcode = 0x06 // Returns specific wallet application configuration
+	ledgerOpRetrieveAddress     ledgerOpcode = 0x02 // Returns the public key and Ethereum address for a given BIP 32 path
+	ledgerOpSignTransaction     ledgerOpcode = 0x04 // Signs an Ethereum transaction after having the user validate the parameters
+	ledgerOpGetConfiguration    ledgerOpcode = 0x06 // Returns specific wallet application configuration
+	ledgerOpSignPersonalMessage ledgerOpcode = 0x08 // Signs a message with the personal message prefix after having the user validate it
+	ledgerOpSignTypedMessage    ledgerOpcode = 0x0c // Signs an EIP-712 typed message after having the user validate the hashes
 
 	ledgerP1DirectlyFetchAddress    ledgerParam1 = 0x00 // Return address directly from the wallet
 	ledgerP1InitTransactionData     ledgerParam1 = 0x00 // First transaction data block for signing
 	ledgerP1ContTransactionData     ledgerParam1 = 0x80 // Subsequent transaction data block for signing
+	ledgerP1InitPersonalMessageData ledgerParam1 = 0x00 // First chunk of personal message data
+	ledgerP1ContPersonalMessageData ledgerParam1 = 0x80 // Subsequent chunk of personal message data
+	ledgerP1InitTypedMessageData    ledgerParam1 = 0x00 // First chunk of typed message data
 	ledgerP2DiscardAddressChainCode ledgerParam2 = 0x00 // Do not return the chain code along with the address
 )
 
@@ -164,10 +169,44 @@ func (w *ledgerDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	if chainID != nil && w.version[0] <= 1 && w.version[1] <= 0 && w.version[2] <= 2 {
 		return common.Address{}, nil, fmt.Errorf("Ledger v%d.%d.%d doesn't support signing this transaction, please update to v1.0.3 at least", w.version[0], w.version[1], w.version[2])
 	}
+	if tx.Type() != types.LegacyTxType && (w.version[0] < 1 || (w.version[0] == 1 && w.version[1] < 9)) {
+		return common.Address{}, nil, fmt.Errorf("Ledger v%d.%d.%d doesn't support signing typed transactions, please update to v1.9.0 at least", w.version[0], w.version[1], w.version[2])
+	}
 	// All infos gathered and metadata checks out, request signing
 	return w.ledgerSign(path, tx, chainID)
 }
 
+// SignTypedMessage implements usbwallet.driver, sending the EIP-712 hashes to the
+// Ledger and waiting for the user to confirm or deny the signature.
+func (w *ledgerDriver) SignTypedMessage(path accounts.DerivationPath, domainSeparator []byte, messageHash []byte) ([]byte, error) {
+	// If the Ethereum app doesn't run, abort
+	if w.offline() {
+		return nil, accounts.ErrWalletClosed
+	}
+	// Ensure the wallet is capable of signing typed messages
+	if w.version[0] < 1 || (w.version[0] == 1 && w.version[1] < 5) {
+		return nil, fmt.Errorf("Ledger v%d.%d.%d doesn't support signing typed data, please update to v1.5.0 at least", w.version[0], w.version[1], w.version[2])
+	}
+	// All infos gathered and metadata checks out, request signing
+	return w.ledgerSignTypedMessage(path, domainSeparator, messageHash)
+}
+
+// SignText implements usbwallet.driver, sending the message to the Ledger to be
+// signed with the personal message prefix, and waiting for the user to confirm
+// or deny the signature.
+func (w *ledgerDriver) SignText(path accounts.DerivationPath, text []byte) ([]byte, error) {
+	// If the Ethereum app doesn't run, abort
+	if w.offline() {
+		return nil, accounts.ErrWalletClosed
+	}
+	// Ensure the wallet is capable of signing personal messages
+	if w.version[0] < 1 || (w.version[0] == 1 && w.version[1] == 0 && w.version[2] < 8) {
+		return nil, fmt.Errorf("Ledger v%d.%d.%d doesn't support signing messages, please update to v1.0.8 at least", w.version[0], w.version[1], w.version[2])
+	}
+	// All infos gathered and metadata checks out, request signing
+	return w.ledgerSignPersonalMessage(path, text)
+}
+
 // ledgerVersion retrieves the current version of the Ethereum wallet app running
 // on the Ledger wallet.
 //
@@ -284,6 +323,9 @@ func (w *ledgerDriver) ledgerDerive(derivationPath []uint32) (common.Address, er
 //   Last derivation index (big endian)               | 4 bytes
 //   RLP transaction chunk                            | arbitrary
 //
+// For EIP-2718 typed transactions the RLP transaction is replaced by the type
+// byte followed by the RLP encoding of the unsigned transaction payload.
+//
 // And the input for subsequent transaction blocks (first 255 bytes) are:
 //
 //   Description           | Length
@@ -304,19 +346,10 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	for i, component := range derivationPath {
 		binary.BigEndian.PutUint32(path[1+4*i:], component)
 	}
-	// Create the transaction RLP based on whether legacy or EIP155 signing was requested
-	var (
-		txrlp []byte
-		err   error
-	)
-	if chainID == nil {
-		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}); err != nil {
-			return common.Address{}, nil, err
-		}
-	} else {
-		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), chainID, big.NewInt(0), big.NewInt(0)}); err != nil {
-			return common.Address{}, nil, err
-		}
+	// Create the transaction RLP based on whether legacy, EIP155 or typed signing was requested
+	txrlp, err := ledgerTxPayload(tx, chainID)
+	if err != nil {
+		return common.Address{}, nil, err
 	}
 	payload := append(path, txrlp...)
 
@@ -346,13 +379,17 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	}
 	signature := append(reply[1:], reply[0])
 
-	// Create the correct signer and signature transform based on the chain ID
+	// Create the correct signer and signature transform based on the chain ID.
+	// Typed transactions carry the bare recovery id in V, no transform needed.
 	var signer types.Signer
 	if chainID == nil {
 		signer = new(types.HomesteadSigner)
+		signature[64] -= 27 // Transform V from 27/28 to 0/1
 	} else {
-		signer = types.NewEIP155Signer(chainID)
-		signature[64] -= byte(chainID.Uint64()*2 + 35)
+		signer = types.LatestSignerForChainID(chainID)
+		if tx.Type() == types.LegacyTxType {
+			signature[64] -= byte(chainID.Uint64()*2 + 35)
+		}
 	}
 	signed, err := tx.WithSignature(signer, signature)
 	if err != nil {
@@ -365,6 +402,166 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// ledgerTxPayload creates the encoding of the transaction the Ledger expects to
+// sign: the RLP list of the legacy fields (extended with the EIP-155 chain ID and
+// two zeroes if a chain ID is given), or the type byte followed by the RLP list
+// of the unsigned payload for typed transactions.
+func ledgerTxPayload(tx *types.Transaction, chainID *big.Int) ([]byte, error) {
+	var fields []interface{}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		fields = []interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+		if chainID != nil {
+			fields = append(fields, chainID, big.NewInt(0), big.NewInt(0))
+		}
+		return rlp.EncodeToBytes(fields)
+
+	case types.AccessListTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+
+	case types.DynamicFeeTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+
+	default:
+		return nil, fmt.Errorf("ledger: transaction type %d not supported", tx.Type())
+	}
+	if chainID == nil {
+		return nil, fmt.Errorf("ledger: transaction type %d requires a chain ID", tx.Type())
+	}
+	txrlp, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{tx.Type()}, txrlp...), nil
+}
+
+// ledgerSignPersonalMessage sends the message to the Ledger wallet to be signed
+// with the personal message prefix, and waits for the user to confirm or deny
+// the signature.
+//
+// The personal message signing protocol is defined as follows:
+//
+//   CLA | INS | P1 | P2 | Lc  | Le
+//   ----+-----+----+----+-----+---
+//    E0 | 08  | 00: first message data block
+//               80: subsequent message data block
+//                  | 00 | variable | variable
+//
+// Where the input for the first message block (first 255 bytes) is:
+//
+//   Description                                      | Length
+//   -------------------------------------------------+----------
+//   Number of BIP 32 derivations to perform (max 10) | 1 byte
+//   First derivation index (big endian)              | 4 bytes
+//   ...                                              | 4 bytes
+//   Last derivation index (big endian)               | 4 bytes
+//   Message length (big endian)                      | 4 bytes
+//   Message chunk                                    | arbitrary
+//
+// And the input for subsequent message blocks (first 255 bytes) are:
+//
+//   Description   | Length
+//   --------------+----------
+//   Message chunk | arbitrary
+//
+// And the output data is:
+//
+//   Description | Length
+//   ------------+---------
+//   signature V | 1 byte
+//   signature R | 32 bytes
+//   signature S | 32 bytes
+func (w *ledgerDriver) ledgerSignPersonalMessage(derivationPath []uint32, text []byte) ([]byte, error) {
+	// Flatten the derivation path and message length into the Ledger request
+	payload := make([]byte, 1+4*len(derivationPath)+4)
+	payload[0] = byte(len(derivationPath))
+	for i, component := range derivationPath {
+		binary.BigEndian.PutUint32(payload[1+4*i:], component)
+	}
+	binary.BigEndian.PutUint32(payload[1+4*len(derivationPath):], uint32(len(text)))
+	payload = append(payload, text...)
+
+	// Send the request and wait for the response
+	var (
+		op    = ledgerP1InitPersonalMessageData
+		reply []byte
+		err   error
+	)
+	for len(payload) > 0 {
+		// Calculate the size of the next data chunk
+		chunk := 255
+		if chunk > len(payload) {
+			chunk = len(payload)
+		}
+		// Send the chunk over, ensuring it's processed correctly
+		reply, err = w.ledgerExchange(ledgerOpSignPersonalMessage, op, 0, payload[:chunk])
+		if err != nil {
+			return nil, err
+		}
+		// Shift the payload and ensure subsequent chunks are marked as such
+		payload = payload[chunk:]
+		op = ledgerP1ContPersonalMessageData
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	if len(reply) != 65 {
+		return nil, errors.New("reply lacks signature")
+	}
+	signature := append(reply[1:], reply[0])
+	signature[64] -= 27 // Transform V from 27/28 to 0/1
+	return signature, nil
+}
+
+// ledgerSignTypedMessage sends the EIP-712 domain separator and message hash to
+// the Ledger wallet, and waits for the user to confirm or deny the signature.
+//
+// The typed message signing protocol is defined as follows:
+//
+//   CLA | INS | P1 | P2 | Lc  | Le
+//   ----+-----+----+----+-----+---
+//    E0 | 0C  | 00 | 00 | variable | variable
+//
+// Where the input is:
+//
+//   Description                                      | Length
+//   -------------------------------------------------+----------
+//   Number of BIP 32 derivations to perform (max 10) | 1 byte
+//   First derivation index (big endian)              | 4 bytes
+//   ...                                              | 4 bytes
+//   Last derivation index (big endian)               | 4 bytes
+//   domain separator                                 | 32 bytes
+//   message hash                                     | 32 bytes
+//
+// And the output data is:
+//
+//   Description | Length
+//   ------------+---------
+//   signature V | 1 byte
+//   signature R | 32 bytes
+//   signature S | 32 bytes
+func (w *ledgerDriver) ledgerSignTypedMessage(derivationPath []uint32, domainSeparator []byte, messageHash []byte) ([]byte, error) {
+	// Flatten the derivation path into the Ledger request
+	path := make([]byte, 1+4*len(derivationPath))
+	path[0] = byte(len(derivationPath))
+	for i, component := range derivationPath {
+		binary.BigEndian.PutUint32(path[1+4*i:], component)
+	}
+	payload := append(path, domainSeparator...)
+	payload = append(payload, messageHash...)
+
+	// Send the request and wait for the response
+	reply, err := w.ledgerExchange(ledgerOpSignTypedMessage, ledgerP1InitTypedMessageData, 0, payload)
+	if err != nil {
+		return nil, err
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	if len(reply) != 65 {
+		return nil, errors.New("reply lacks signature")
+	}
+	signature := append(reply[1:], reply[0])
+	signature[64] -= 27 // Transform V from 27/28 to 0/1
+	return signature, nil
+}
+
 // ledgerExchange performs a data exchange with the Ledger wallet, sending it a
 // message and retrieving the response.
 //
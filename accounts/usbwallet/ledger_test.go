@@ -0,0 +1,270 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ledgerDevice is a fake Ledger USB connection, reassembling the APDUs streamed
+// by the driver and framing the replies produced by a callback.
+type ledgerDevice struct {
+	apdus   [][]byte                 // APDUs received (CLA, INS, P1, P2, Lc, data)
+	pending []byte                   // Partially received APDU (with length prefix)
+	reply   func(apdu []byte) []byte // Callback producing the reply data for an APDU
+	out     []byte                   // Framed reply chunks waiting to be read
+}
+
+func (d *ledgerDevice) Write(chunk []byte) (int, error) {
+	if len(chunk) < 5 || chunk[0] != 0x01 || chunk[1] != 0x01 || chunk[2] != 0x05 {
+		return 0, errors.New("invalid chunk header")
+	}
+	d.pending = append(d.pending, chunk[5:]...)
+	if size := 2 + int(binary.BigEndian.Uint16(d.pending)); len(d.pending) >= size {
+		apdu := common.CopyBytes(d.pending[2:size])
+		d.apdus, d.pending = append(d.apdus, apdu), nil
+
+		// Frame the reply data, terminated by the success status word
+		data := append(d.reply(apdu), 0x90, 0x00)
+		payload := make([]byte, 2, 2+len(data))
+		binary.BigEndian.PutUint16(payload, uint16(len(data)))
+		payload = append(payload, data...)
+
+		for i := 0; len(payload) > 0; i++ {
+			frame := make([]byte, 64)
+			copy(frame, []byte{0x01, 0x01, 0x05})
+			binary.BigEndian.PutUint16(frame[3:], uint16(i))
+			n := copy(frame[5:], payload)
+			payload = payload[n:]
+			d.out = append(d.out, frame...)
+		}
+	}
+	return len(chunk), nil
+}
+
+func (d *ledgerDevice) Read(buf []byte) (int, error) {
+	if len(d.out) == 0 {
+		return 0, errors.New("no reply pending")
+	}
+	n := copy(buf, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// ledgerPath flattens a derivation path the way the Ledger protocol expects it.
+func ledgerPath(path accounts.DerivationPath) []byte {
+	blob := []byte{byte(len(path))}
+	for _, component := range path {
+		blob = append(blob, byte(component>>24), byte(component>>16), byte(component>>8), byte(component))
+	}
+	return blob
+}
+
+// ledgerReply assembles a Ledger signature reply (V || R || S) from a canonical
+// [R || S || V] signature, with V shifted by the given offset.
+func ledgerReply(sig []byte, offset byte) []byte {
+	return append([]byte{sig[64] + offset}, sig[:64]...)
+}
+
+// Tests that personal messages are streamed in 255 byte chunks, with the first
+// one flagged as such, and that the recovery id is normalised to 0/1.
+func TestLedgerSignTextChunking(t *testing.T) {
+	var (
+		path = accounts.DefaultBaseDerivationPath
+		text = bytes.Repeat([]byte{0xaa}, 600)
+		sig  = append(bytes.Repeat([]byte{0x11}, 32), append(bytes.Repeat([]byte{0x22}, 32), 0x01)...)
+	)
+	device := &ledgerDevice{}
+	device.reply = func(apdu []byte) []byte {
+		if len(device.apdus) < 3 {
+			return nil
+		}
+		return ledgerReply(sig, 27)
+	}
+	driver := &ledgerDriver{device: device, version: [3]byte{1, 5, 0}, log: log.Root()}
+
+	signature, err := driver.SignText(path, text)
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	if !bytes.Equal(signature, sig) {
+		t.Errorf("signature mismatch: have %x, want %x", signature, sig)
+	}
+	// 1 + 5*4 path bytes and 4 length bytes precede the 600 byte message
+	want := append(ledgerPath(path), 0x00, 0x00, 0x02, 0x58)
+	want = append(want, text...)
+
+	if len(device.apdus) != 3 {
+		t.Fatalf("chunk count mismatch: have %d, want %d", len(device.apdus), 3)
+	}
+	var (
+		sizes = []int{255, 255, 115}
+		flags = []byte{0x00, 0x80, 0x80}
+		have  []byte
+	)
+	for i, apdu := range device.apdus {
+		if apdu[0] != 0xe0 || apdu[1] != byte(ledgerOpSignPersonalMessage) || apdu[2] != flags[i] || apdu[3] != 0x00 {
+			t.Errorf("chunk %d: header mismatch: have %x", i, apdu[:4])
+		}
+		if int(apdu[4]) != sizes[i] || len(apdu)-5 != sizes[i] {
+			t.Errorf("chunk %d: size mismatch: have %d (Lc %d), want %d", i, len(apdu)-5, apdu[4], sizes[i])
+		}
+		have = append(have, apdu[5:]...)
+	}
+	if !bytes.Equal(have, want) {
+		t.Errorf("payload mismatch:\nhave %x\nwant %x", have, want)
+	}
+}
+
+// Tests that typed message hashes are sent in a single request after the path,
+// and that the recovery id is normalised to 0/1.
+func TestLedgerSignTypedMessage(t *testing.T) {
+	var (
+		path   = accounts.DefaultBaseDerivationPath
+		domain = bytes.Repeat([]byte{0x01}, 32)
+		hash   = bytes.Repeat([]byte{0x02}, 32)
+		sig    = append(bytes.Repeat([]byte{0x11}, 32), append(bytes.Repeat([]byte{0x22}, 32), 0x00)...)
+	)
+	device := &ledgerDevice{reply: func([]byte) []byte { return ledgerReply(sig, 27) }}
+	driver := &ledgerDriver{device: device, version: [3]byte{1, 5, 0}, log: log.Root()}
+
+	signature, err := driver.SignTypedMessage(path, domain, hash)
+	if err != nil {
+		t.Fatalf("failed to sign typed message: %v", err)
+	}
+	if !bytes.Equal(signature, sig) {
+		t.Errorf("signature mismatch: have %x, want %x", signature, sig)
+	}
+	if len(device.apdus) != 1 {
+		t.Fatalf("request count mismatch: have %d, want %d", len(device.apdus), 1)
+	}
+	want := append(append(ledgerPath(path), domain...), hash...)
+	if apdu := device.apdus[0]; apdu[1] != byte(ledgerOpSignTypedMessage) || apdu[2] != 0x00 || !bytes.Equal(apdu[5:], want) {
+		t.Errorf("request mismatch: have %x, want ins %x, data %x", apdu, ledgerOpSignTypedMessage, want)
+	}
+	// Older firmware must be rejected without contacting the device
+	driver.version = [3]byte{1, 4, 9}
+	if _, err := driver.SignTypedMessage(path, domain, hash); err == nil {
+		t.Errorf("typed message signed on unsupported firmware")
+	}
+	if len(device.apdus) != 1 {
+		t.Errorf("device contacted on unsupported firmware")
+	}
+}
+
+// Tests that legacy and typed transactions are encoded into the payload the
+// Ledger hashes and signs, and that the returned signatures are recovered with
+// the matching signer.
+func TestLedgerSignTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		data    = bytes.Repeat([]byte{0xcc}, 300) // Spans multiple APDUs
+		list    = types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}}
+		chainID = big.NewInt(5)
+	)
+	tests := []struct {
+		tx      *types.Transaction
+		chainID *big.Int
+		signer  types.Signer
+	}{
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(2), Gas: 21000, To: &to, Value: big.NewInt(3), Data: data}), nil, types.HomesteadSigner{}},
+		{types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(2), Gas: 21000, To: &to, Value: big.NewInt(3), Data: data}), chainID, types.NewEIP155Signer(chainID)},
+		{types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 1, GasPrice: big.NewInt(2), Gas: 21000, To: &to, Value: big.NewInt(3), Data: data, AccessList: list}), chainID, types.LatestSignerForChainID(chainID)},
+		{types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, Value: big.NewInt(3), Data: data, AccessList: list}), chainID, types.LatestSignerForChainID(chainID)},
+	}
+	path := accounts.DefaultBaseDerivationPath
+	for i, tt := range tests {
+		// Sign whatever payload the device receives, as a Ledger would
+		var payload []byte
+
+		device := &ledgerDevice{}
+		device.reply = func(apdu []byte) []byte {
+			payload = append(payload, apdu[5:]...)
+			if len(payload) < len(ledgerPath(path))+len(mustLedgerPayload(t, tt.tx, tt.chainID)) {
+				return nil
+			}
+			sig, err := crypto.Sign(crypto.Keccak256(payload[len(ledgerPath(path)):]), key)
+			if err != nil {
+				t.Fatalf("test %d: failed to sign payload: %v", i, err)
+			}
+			switch {
+			case tt.tx.Type() != types.LegacyTxType:
+				return ledgerReply(sig, 0)
+			case tt.chainID != nil:
+				return ledgerReply(sig, byte(tt.chainID.Uint64()*2+35))
+			default:
+				return ledgerReply(sig, 27)
+			}
+		}
+		driver := &ledgerDriver{device: device, version: [3]byte{1, 9, 0}, log: log.Root()}
+
+		sender, signed, err := driver.SignTx(path, tt.tx, tt.chainID)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign transaction: %v", i, err)
+		}
+		if want := tt.signer.Hash(tt.tx); crypto.Keccak256Hash(payload[len(ledgerPath(path)):]) != want {
+			t.Errorf("test %d: payload hash mismatch: have %x, want %x", i, crypto.Keccak256(payload[len(ledgerPath(path)):]), want)
+		}
+		if want := crypto.PubkeyToAddress(key.PublicKey); sender != want {
+			t.Errorf("test %d: sender mismatch: have %x, want %x", i, sender, want)
+		}
+		if signed.Type() != tt.tx.Type() {
+			t.Errorf("test %d: type mismatch: have %d, want %d", i, signed.Type(), tt.tx.Type())
+		}
+		if from, err := types.Sender(tt.signer, signed); err != nil || from != sender {
+			t.Errorf("test %d: signed sender mismatch: have %x, %v, want %x", i, from, err, sender)
+		}
+	}
+}
+
+// Tests that typed transactions are rejected on firmware predating them and
+// without a chain ID, instead of signing an incorrect payload.
+func TestLedgerSignTxRejected(t *testing.T) {
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, Value: big.NewInt(1)})
+
+	device := &ledgerDevice{reply: func([]byte) []byte { return nil }}
+	driver := &ledgerDriver{device: device, version: [3]byte{1, 8, 9}, log: log.Root()}
+	if _, _, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, big.NewInt(1)); err == nil {
+		t.Errorf("typed transaction signed on unsupported firmware")
+	}
+	driver.version = [3]byte{1, 9, 0}
+	if _, _, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, nil); err == nil {
+		t.Errorf("typed transaction signed without chain ID")
+	}
+	if len(device.apdus) != 0 {
+		t.Errorf("device contacted for rejected transaction")
+	}
+}
+
+func mustLedgerPayload(t *testing.T, tx *types.Transaction, chainID *big.Int) []byte {
+	payload, err := ledgerTxPayload(tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	return payload
+}
@@ -159,6 +159,43 @@ func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	return w.trezorSign(path, tx, chainID)
 }
 
+// SignTypedMessage implements usbwallet.driver, sending the EIP-712 hashes to the
+// Trezor to be signed, and waiting for the user to confirm or deny the signature.
+// Only recent Trezor One firmware supports signing typed data hashes.
+func (w *trezorDriver) SignTypedMessage(path accounts.DerivationPath, domainSeparator []byte, messageHash []byte) ([]byte, error) {
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	request := &trezor.EthereumSignTypedHash{
+		AddressN:            path,
+		DomainSeparatorHash: domainSeparator,
+		MessageHash:         messageHash,
+	}
+	response := new(trezor.EthereumTypedDataSignature)
+	if _, err := w.trezorExchange(request, response); err != nil {
+		return nil, err
+	}
+	return trezorSignature(response.GetSignature())
+}
+
+// SignText implements usbwallet.driver, sending the message to the Trezor to be
+// signed with the personal message prefix, and waiting for the user to confirm
+// or deny the signature.
+func (w *trezorDriver) SignText(path accounts.DerivationPath, text []byte) ([]byte, error) {
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	request := &trezor.EthereumSignMessage{
+		AddressN: path,
+		Message:  text,
+	}
+	response := new(trezor.EthereumMessageSignature)
+	if _, err := w.trezorExchange(request, response); err != nil {
+		return nil, err
+	}
+	return trezorSignature(response.GetSignature())
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
@@ -170,31 +207,72 @@ func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, er
 }
 
 // trezorSign sends the transaction to the Trezor wallet, and waits for the user
-// to confirm or deny the transaction.
+// to confirm or deny the transaction. Legacy and EIP-1559 dynamic fee transactions
+// are supported, the latter only if a chain ID is given.
 func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
 	// Create the transaction initiation message
 	data := tx.Data()
 	length := uint32(len(data))
 
-	request := &trezor.EthereumSignTx{
-		AddressN:   derivationPath,
-		Nonce:      new(big.Int).SetUint64(tx.Nonce()).Bytes(),
-		GasPrice:   tx.GasPrice().Bytes(),
-		GasLimit:   new(big.Int).SetUint64(tx.Gas()).Bytes(),
-		Value:      tx.Value().Bytes(),
-		DataLength: &length,
-	}
-	if to := tx.To(); to != nil {
-		request.To = (*to)[:] // Non contract deploy, set recipient explicitly
-	}
+	var initial []byte
 	if length > 1024 { // Send the data chunked if that was requested
-		request.DataInitialChunk, data = data[:1024], data[1024:]
+		initial, data = data[:1024], data[1024:]
 	} else {
-		request.DataInitialChunk, data = data, nil
+		initial, data = data, nil
 	}
-	if chainID != nil { // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported!?)
-		id := uint32(chainID.Int64())
-		request.ChainId = &id
+	var request proto.Message
+	switch tx.Type() {
+	case types.LegacyTxType:
+		legacy := &trezor.EthereumSignTx{
+			AddressN:         derivationPath,
+			Nonce:            new(big.Int).SetUint64(tx.Nonce()).Bytes(),
+			GasPrice:         tx.GasPrice().Bytes(),
+			GasLimit:         new(big.Int).SetUint64(tx.Gas()).Bytes(),
+			Value:            tx.Value().Bytes(),
+			DataInitialChunk: initial,
+			DataLength:       &length,
+		}
+		if to := tx.To(); to != nil {
+			legacy.To = (*to)[:] // Non contract deploy, set recipient explicitly
+		}
+		if chainID != nil { // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported!?)
+			id := uint32(chainID.Int64())
+			legacy.ChainId = &id
+		}
+		request = legacy
+
+	case types.DynamicFeeTxType:
+		if chainID == nil {
+			return common.Address{}, nil, errors.New("trezor: dynamic fee transaction requires a chain ID")
+		}
+		id := chainID.Uint64()
+		dynamic := &trezor.EthereumSignTxEIP1559{
+			AddressN:         derivationPath,
+			Nonce:            new(big.Int).SetUint64(tx.Nonce()).Bytes(),
+			MaxGasFee:        tx.GasFeeCap().Bytes(),
+			MaxPriorityFee:   tx.GasTipCap().Bytes(),
+			GasLimit:         new(big.Int).SetUint64(tx.Gas()).Bytes(),
+			Value:            tx.Value().Bytes(),
+			DataInitialChunk: initial,
+			DataLength:       &length,
+			ChainId:          &id,
+		}
+		if to := tx.To(); to != nil {
+			hex := to.Hex() // Non contract deploy, set recipient explicitly
+			dynamic.To = &hex
+		}
+		for _, tuple := range tx.AccessList() {
+			entry := &trezor.EthereumSignTxEIP1559_EthereumAccessList{Address: new(string)}
+			*entry.Address = tuple.Address.Hex()
+			for _, key := range tuple.StorageKeys {
+				entry.StorageKeys = append(entry.StorageKeys, common.CopyBytes(key[:]))
+			}
+			dynamic.AccessList = append(dynamic.AccessList, entry)
+		}
+		request = dynamic
+
+	default:
+		return common.Address{}, nil, fmt.Errorf("trezor: transaction type %d not supported", tx.Type())
 	}
 	// Send the initiation message and stream content until a signature is returned
 	response := new(trezor.EthereumTxRequest)
@@ -209,19 +287,26 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 			return common.Address{}, nil, err
 		}
 	}
-	// Extract the Ethereum signature and do a sanity validation
-	if len(response.GetSignatureR()) == 0 || len(response.GetSignatureS()) == 0 || response.GetSignatureV() == 0 {
+	// Extract the Ethereum signature and do a sanity validation. Typed transactions
+	// carry the bare recovery id in V, so zero is valid for them.
+	if len(response.GetSignatureR()) == 0 || len(response.GetSignatureS()) == 0 || (tx.Type() == types.LegacyTxType && response.GetSignatureV() == 0) {
 		return common.Address{}, nil, errors.New("reply lacks signature")
 	}
-	signature := append(append(response.GetSignatureR(), response.GetSignatureS()...), byte(response.GetSignatureV()))
+	signature := make([]byte, 65)
+	copy(signature[32-len(response.GetSignatureR()):32], response.GetSignatureR())
+	copy(signature[64-len(response.GetSignatureS()):64], response.GetSignatureS())
+	signature[64] = byte(response.GetSignatureV())
 
 	// Create the correct signer and signature transform based on the chain ID
 	var signer types.Signer
 	if chainID == nil {
 		signer = new(types.HomesteadSigner)
+		signature[64] -= 27 // Transform V from 27/28 to 0/1
 	} else {
-		signer = types.NewEIP155Signer(chainID)
-		signature[64] -= byte(chainID.Uint64()*2 + 35)
+		signer = types.LatestSignerForChainID(chainID)
+		if tx.Type() == types.LegacyTxType {
+			signature[64] -= byte(chainID.Uint64()*2 + 35)
+		}
 	}
 	// Inject the final signature into the transaction and sanity check the sender
 	signed, err := tx.WithSignature(signer, signature)
@@ -235,6 +320,17 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// trezorSignature validates a signature returned by the Trezor, and converts its
+// recovery id from 27/28 to 0/1.
+func trezorSignature(sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, errors.New("reply lacks signature")
+	}
+	signature := common.CopyBytes(sig)
+	signature[64] -= 27
+	return signature, nil
+}
+
 // trezorExchange performs a data exchange with the Trezor wallet, sending it a
 // message and retrieving the response. If multiple responses are possible, the
 // method will also return the index of the destination object used.
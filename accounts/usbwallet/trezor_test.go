@@ -0,0 +1,204 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet/internal/trezor"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/protobuf/proto"
+)
+
+// trezorDevice is a fake Trezor USB connection, reassembling the messages streamed
+// by the driver and framing the replies produced by a callback.
+type trezorDevice struct {
+	kinds    []uint16                                    // Message types received
+	requests [][]byte                                    // Message bodies received
+	pending  []byte                                      // Partially received message (with header)
+	reply    func(kind uint16, msg []byte) proto.Message // Callback producing the reply to a message
+	out      []byte                                      // Framed reply chunks waiting to be read
+}
+
+func (d *trezorDevice) Write(chunk []byte) (int, error) {
+	if len(chunk) != 64 || chunk[0] != 0x3f {
+		return 0, errors.New("invalid chunk header")
+	}
+	if len(d.pending) == 0 && (chunk[1] != 0x23 || chunk[2] != 0x23) {
+		return 0, errors.New("invalid message header")
+	}
+	d.pending = append(d.pending, chunk[1:]...)
+	if size := 8 + int(binary.BigEndian.Uint32(d.pending[4:8])); len(d.pending) >= size {
+		kind, msg := binary.BigEndian.Uint16(d.pending[2:4]), common.CopyBytes(d.pending[8:size])
+		d.kinds, d.requests, d.pending = append(d.kinds, kind), append(d.requests, msg), nil
+
+		// Frame the reply message into zero padded chunks
+		res := d.reply(kind, msg)
+		data, err := proto.Marshal(res)
+		if err != nil {
+			return 0, err
+		}
+		payload := make([]byte, 8+len(data))
+		copy(payload, []byte{0x23, 0x23})
+		binary.BigEndian.PutUint16(payload[2:], trezor.Type(res))
+		binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+		copy(payload[8:], data)
+
+		for len(payload) > 0 {
+			frame := make([]byte, 64)
+			frame[0] = 0x3f
+			n := copy(frame[1:], payload)
+			payload = payload[n:]
+			d.out = append(d.out, frame...)
+		}
+	}
+	return len(chunk), nil
+}
+
+func (d *trezorDevice) Read(buf []byte) (int, error) {
+	if len(d.out) == 0 {
+		return 0, errors.New("no reply pending")
+	}
+	n := copy(buf, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// Tests that typed message hashes are sent in an EthereumSignTypedHash request,
+// and that the recovery id of the reply is normalised to 0/1.
+func TestTrezorSignTypedMessage(t *testing.T) {
+	var (
+		path   = accounts.DefaultBaseDerivationPath
+		domain = bytes.Repeat([]byte{0x01}, 32)
+		hash   = bytes.Repeat([]byte{0x02}, 32)
+		sig    = append(bytes.Repeat([]byte{0x11}, 32), append(bytes.Repeat([]byte{0x22}, 32), 0x01)...)
+	)
+	device := &trezorDevice{reply: func(uint16, []byte) proto.Message {
+		address := "0x0000000000000000000000000000000000000000"
+		return &trezor.EthereumTypedDataSignature{Signature: append(common.CopyBytes(sig[:64]), 28), Address: &address}
+	}}
+	driver := &trezorDriver{device: device, log: log.Root()}
+
+	signature, err := driver.SignTypedMessage(path, domain, hash)
+	if err != nil {
+		t.Fatalf("failed to sign typed message: %v", err)
+	}
+	if !bytes.Equal(signature, sig) {
+		t.Errorf("signature mismatch: have %x, want %x", signature, sig)
+	}
+	if len(device.kinds) != 1 {
+		t.Fatalf("request count mismatch: have %d, want %d", len(device.kinds), 1)
+	}
+	if want := trezor.Type(new(trezor.EthereumSignTypedHash)); device.kinds[0] != want {
+		t.Fatalf("request type mismatch: have %d, want %d", device.kinds[0], want)
+	}
+	request := new(trezor.EthereumSignTypedHash)
+	if err := proto.Unmarshal(device.requests[0], request); err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+	if !reflect.DeepEqual(request.GetAddressN(), []uint32(path)) {
+		t.Errorf("path mismatch: have %v, want %v", request.GetAddressN(), path)
+	}
+	if !bytes.Equal(request.GetDomainSeparatorHash(), domain) || !bytes.Equal(request.GetMessageHash(), hash) {
+		t.Errorf("hash mismatch: have %x/%x, want %x/%x", request.GetDomainSeparatorHash(), request.GetMessageHash(), domain, hash)
+	}
+}
+
+// Tests that dynamic fee transactions are sent in an EthereumSignTxEIP1559
+// request, and that the returned signature is recovered with the London signer.
+func TestTrezorSignDynamicFeeTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		to      = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		chainID = big.NewInt(5)
+		signer  = types.LatestSignerForChainID(chainID)
+		tx      = types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			GasTipCap:  big.NewInt(1),
+			GasFeeCap:  big.NewInt(2),
+			Gas:        21000,
+			To:         &to,
+			Value:      new(big.Int),
+			Data:       []byte{0xca, 0xfe},
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}},
+		})
+	)
+	device := &trezorDevice{reply: func(uint16, []byte) proto.Message {
+		sig, err := crypto.Sign(signer.Hash(tx).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		v := uint32(sig[64])
+		return &trezor.EthereumTxRequest{SignatureV: &v, SignatureR: sig[:32], SignatureS: sig[32:64]}
+	}}
+	driver := &trezorDriver{device: device, log: log.Root()}
+
+	sender, signed, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); sender != want {
+		t.Errorf("sender mismatch: have %x, want %x", sender, want)
+	}
+	if signed.Type() != types.DynamicFeeTxType {
+		t.Errorf("type mismatch: have %d, want %d", signed.Type(), types.DynamicFeeTxType)
+	}
+	if want := trezor.Type(new(trezor.EthereumSignTxEIP1559)); len(device.kinds) != 1 || device.kinds[0] != want {
+		t.Fatalf("request type mismatch: have %v, want [%d]", device.kinds, want)
+	}
+	request := new(trezor.EthereumSignTxEIP1559)
+	if err := proto.Unmarshal(device.requests[0], request); err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+	if request.GetChainId() != 5 || request.To == nil || *request.To != to.Hex() {
+		t.Errorf("recipient mismatch: have chain %d, to %v", request.GetChainId(), request.To)
+	}
+	if !bytes.Equal(request.MaxGasFee, []byte{2}) || !bytes.Equal(request.MaxPriorityFee, []byte{1}) {
+		t.Errorf("fee mismatch: have %x/%x, want 02/01", request.MaxGasFee, request.MaxPriorityFee)
+	}
+	if list := request.GetAccessList(); len(list) != 1 || list[0].GetAddress() != to.Hex() || len(list[0].GetStorageKeys()) != 1 {
+		t.Errorf("access list mismatch: have %v", list)
+	}
+}
+
+// Tests that transaction types the Trezor cannot sign are rejected without
+// contacting the device.
+func TestTrezorSignTxRejected(t *testing.T) {
+	device := &trezorDevice{reply: func(uint16, []byte) proto.Message { return new(trezor.Failure) }}
+	driver := &trezorDriver{device: device, log: log.Root()}
+
+	tx := types.NewTx(&types.AccessListTx{ChainID: big.NewInt(1), GasPrice: big.NewInt(1), Gas: 21000, Value: big.NewInt(1)})
+	if _, _, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, big.NewInt(1)); err == nil {
+		t.Errorf("access list transaction signed")
+	}
+	tx = types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000, Value: big.NewInt(1)})
+	if _, _, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, nil); err == nil {
+		t.Errorf("dynamic fee transaction signed without chain ID")
+	}
+	if len(device.kinds) != 0 {
+		t.Errorf("device contacted for rejected transaction")
+	}
+}
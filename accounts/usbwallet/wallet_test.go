@@ -0,0 +1,152 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/karalabe/hid"
+)
+
+// testDriver is a fake hardware wallet driver, deriving a deterministic address
+// from the textual form of each derivation path.
+type testDriver struct{}
+
+func (testDriver) Status() (string, error)                            { return "ok", nil }
+func (testDriver) Open(device io.ReadWriter, passphrase string) error { return nil }
+func (testDriver) Close() error                                       { return nil }
+func (testDriver) Heartbeat() error                                   { return nil }
+
+func (testDriver) Derive(path accounts.DerivationPath) (common.Address, error) {
+	return testAddress(path), nil
+}
+
+func (testDriver) SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
+	return common.Address{}, nil, accounts.ErrNotSupported
+}
+
+func (testDriver) SignTypedMessage(path accounts.DerivationPath, domainSeparator []byte, messageHash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (testDriver) SignText(path accounts.DerivationPath, text []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func testAddress(path accounts.DerivationPath) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte(path.String())))
+}
+
+// testChain is a chain state reader with a fixed set of used accounts.
+type testChain struct {
+	used map[common.Address]bool
+}
+
+func (c *testChain) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	if c.used[account] {
+		return big.NewInt(1), nil
+	}
+	return new(big.Int), nil
+}
+
+func (c *testChain) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return 0, nil
+}
+
+func (c *testChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testChain) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+// Tests that self-derivation walks every base along its own layout, picking up
+// the used accounts of each, and tracks the first empty account of the last one.
+func TestWalletSelfDeriveBases(t *testing.T) {
+	used := []string{
+		"m/44'/60'/0'/0",   // legacy Ledger layout
+		"m/44'/60'/0'/1",   // legacy Ledger layout
+		"m/44'/60'/0'/0/0", // standard and Ledger Live layouts
+		"m/44'/60'/1'/0/0", // Ledger Live layout
+		"m/44'/60'/0'/0/1", // standard layout
+	}
+	chain := &testChain{used: make(map[common.Address]bool)}
+	for _, path := range used {
+		chain.used[testAddress(mustParsePath(t, path))] = true
+	}
+	w := &wallet{
+		driver:     testDriver{},
+		url:        &accounts.URL{Scheme: "ledger", Path: "test"},
+		device:     new(hid.Device),
+		paths:      make(map[common.Address]accounts.DerivationPath),
+		deriveReq:  make(chan chan struct{}),
+		deriveQuit: make(chan chan error),
+		commsLock:  make(chan struct{}, 1),
+		log:        log.Root(),
+	}
+	w.commsLock <- struct{}{}
+	go w.selfDerive()
+
+	w.SelfDerive(accounts.DiscoveryIterators("ledger", nil, false), chain)
+
+	reqc := make(chan struct{})
+	w.deriveReq <- reqc
+	<-reqc
+
+	errc := make(chan error)
+	w.deriveQuit <- errc
+	if err := <-errc; err != nil {
+		t.Fatalf("self-derivation failed: %v", err)
+	}
+	want := []string{
+		"m/44'/60'/0'/0",
+		"m/44'/60'/0'/1",
+		"m/44'/60'/0'/0/0",
+		"m/44'/60'/1'/0/0",
+		"m/44'/60'/0'/0/1",
+		"m/44'/60'/0'/0/2", // first empty account of the last base
+	}
+	accs := w.Accounts()
+	if len(accs) != len(want) {
+		t.Fatalf("account count mismatch: have %d, want %d", len(accs), len(want))
+	}
+	for i, path := range want {
+		if addr := testAddress(mustParsePath(t, path)); accs[i].Address != addr {
+			t.Errorf("account %d: address mismatch: have %x, want %x (%s)", i, accs[i].Address, addr, path)
+		}
+		if accs[i].URL.Path != "test/"+path {
+			t.Errorf("account %d: url mismatch: have %s, want test/%s", i, accs[i].URL.Path, path)
+		}
+	}
+}
+
+func mustParsePath(t *testing.T, path string) accounts.DerivationPath {
+	parsed, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		t.Fatalf("failed to parse path %q: %v", path, err)
+	}
+	return parsed
+}
@@ -0,0 +1,248 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+var commandDeploy = cli.Command{
+	Name:  "deploy",
+	Usage: "Deploy a new checkpoint oracle contract",
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		keyFileFlag,
+		passwordFileFlag,
+		signersFlag,
+		thresholdFlag,
+	},
+	Action: utils.MigrateFlags(deploy),
+}
+
+var commandSign = cli.Command{
+	Name:  "sign",
+	Usage: "Sign the checkpoint with the specified key",
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		oracleFlag,
+		indexFlag,
+		hashFlag,
+		keyFileFlag,
+		passwordFileFlag,
+	},
+	Action: utils.MigrateFlags(sign),
+}
+
+var commandPublish = cli.Command{
+	Name:  "publish",
+	Usage: "Publish a checkpoint into the oracle",
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		oracleFlag,
+		indexFlag,
+		signaturesFlag,
+		keyFileFlag,
+		passwordFileFlag,
+	},
+	Action: utils.MigrateFlags(publish),
+}
+
+// deploy deploys the checkpoint registrar contract.
+//
+// Note the network where the contract is deployed depends on
+// the network where the connected node is located.
+func deploy(ctx *cli.Context) error {
+	// Gather all the addresses that should be permitted to sign
+	var addrs []common.Address
+	for _, account := range strings.Split(ctx.String(signersFlag.Name), ",") {
+		if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+			utils.Fatalf("Invalid account in --signers: '%s'", trimmed)
+		}
+		addrs = append(addrs, common.HexToAddress(account))
+	}
+	// Retrieve and validate the signing threshold
+	needed := ctx.Int64(thresholdFlag.Name)
+	if needed == 0 || needed > int64(len(addrs)) {
+		utils.Fatalf("Invalid signature threshold %d", needed)
+	}
+	// Print a summary to ensure the user understands what they're signing
+	fmt.Printf("Deploying new checkpoint oracle:\n\n")
+	for i, addr := range addrs {
+		fmt.Printf("Admin %d => %s\n", i+1, addr.Hex())
+	}
+	fmt.Printf("\nSignatures needed to publish: %d\n", needed)
+
+	// Deploy the checkpoint oracle with the requested parameters
+	client := newClient(ctx)
+	key := getKey(ctx)
+
+	oracle, tx, _, err := contract.DeployCheckpointOracle(bind.NewKeyedTransactor(key.PrivateKey), client, addrs, big.NewInt(int64(params.CHTFrequencyClient)),
+		big.NewInt(int64(params.HelperTrieProcessConfirmations)), big.NewInt(needed))
+	if err != nil {
+		utils.Fatalf("Failed to deploy checkpoint oracle %v", err)
+	}
+	log.Info("Deployed checkpoint oracle", "address", oracle, "tx", tx.Hash().Hex())
+
+	// Wait for the deployment to be mined
+	if _, err := bind.WaitDeployed(context.Background(), client, tx); err != nil {
+		utils.Fatalf("Failed to deploy checkpoint oracle %v", err)
+	}
+	return nil
+}
+
+// sign creates the signature for specific checkpoint with local key. Only
+// contract admins have the permission to sign checkpoint.
+func sign(ctx *cli.Context) error {
+	var (
+		oracle common.Address
+		index  uint64
+		hash   common.Hash
+	)
+	if ctx.GlobalIsSet(oracleFlag.Name) && ctx.IsSet(indexFlag.Name) && ctx.IsSet(hashFlag.Name) {
+		// Offline mode, sign the given checkpoint without a node
+		oracle = common.HexToAddress(ctx.GlobalString(oracleFlag.Name))
+		index = uint64(ctx.Int64(indexFlag.Name))
+		hash = common.HexToHash(ctx.String(hashFlag.Name))
+	} else {
+		// Online mode, retrieve the checkpoint and oracle from the node
+		client := newRPCClient(ctx.GlobalString(nodeURLFlag.Name))
+
+		checkpoint := getCheckpoint(ctx, client)
+		if checkpoint.Empty() {
+			utils.Fatalf("Local checkpoint is not available")
+		}
+		oracle, index, hash = getContractAddr(ctx, client), checkpoint.SectionIndex, checkpoint.Hash()
+		if oracle == (common.Address{}) {
+			utils.Fatalf("No specified registrar contract address")
+		}
+		fmt.Printf("Checkpoint %d:\n\n", index)
+		fmt.Printf("Section head => %s\n", checkpoint.SectionHead.Hex())
+		fmt.Printf("CHT root     => %s\n", checkpoint.CHTRoot.Hex())
+		fmt.Printf("Bloom root   => %s\n\n", checkpoint.BloomRoot.Hex())
+	}
+	fmt.Printf("Oracle %s, index %d => %s\n", oracle.Hex(), index, hash.Hex())
+
+	// Sign the checkpoint with the admin key
+	key := getKey(ctx)
+	sig, err := crypto.Sign(signedHash(oracle, index, hash), key.PrivateKey)
+	if err != nil {
+		utils.Fatalf("Failed to sign checkpoint %v", err)
+	}
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+
+	fmt.Printf("Signer    => %s\n", key.Address.Hex())
+	fmt.Printf("Signature => %s\n", hexutil.Encode(sig))
+	return nil
+}
+
+// publish registers the specified checkpoint which generated by connected node
+// with an authorised private key.
+func publish(ctx *cli.Context) error {
+	// Retrieve the checkpoint we want to publish
+	client := newRPCClient(ctx.GlobalString(nodeURLFlag.Name))
+
+	checkpoint := getCheckpoint(ctx, client)
+	if checkpoint.Empty() {
+		utils.Fatalf("Local checkpoint is not available")
+	}
+	addr, oracle := newContract(ctx, client)
+	hash := checkpoint.Hash()
+
+	// Recover the signers of the signatures and make sure they are all admins
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve oracle admins: %v", err)
+	}
+	type signature struct {
+		signer common.Address
+		sig    []byte
+	}
+	var sigs []signature
+	for _, sig := range parseSignatures(ctx.String(signaturesFlag.Name)) {
+		signer, err := recoverSigner(addr, checkpoint.SectionIndex, hash, sig)
+		if err != nil {
+			utils.Fatalf("Failed to recover signer: %v", err)
+		}
+		var admin bool
+		for _, a := range admins {
+			if a == signer {
+				admin = true
+				break
+			}
+		}
+		if !admin {
+			utils.Fatalf("Signer %s is not an oracle admin", signer.Hex())
+		}
+		sigs = append(sigs, signature{signer, sig})
+	}
+	// The contract requires the signatures sorted by signer address
+	sort.Slice(sigs, func(i, j int) bool {
+		return bytes.Compare(sigs[i].signer.Bytes(), sigs[j].signer.Bytes()) < 0
+	})
+	var sorted [][]byte
+	for i, s := range sigs {
+		if i > 0 && s.signer == sigs[i-1].signer {
+			utils.Fatalf("Duplicate signature from %s", s.signer.Hex())
+		}
+		sorted = append(sorted, s.sig)
+	}
+	fmt.Printf("Publishing checkpoint %d => %s with %d signatures\n", checkpoint.SectionIndex, hash.Hex(), len(sorted))
+
+	// Use the latest block as replay protection
+	eclient := ethclient.NewClient(client)
+	head, err := eclient.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve latest header: %v", err)
+	}
+	key := getKey(ctx)
+	tx, err := oracle.RegisterCheckpoint(bind.NewKeyedTransactor(key.PrivateKey), checkpoint.SectionIndex, hash.Bytes(), head.Number, head.Hash(), sorted)
+	if err != nil {
+		utils.Fatalf("Register contract failed %v", err)
+	}
+	log.Info("Successfully registered checkpoint", "number", checkpoint.SectionIndex, "hash", hash, "tx", tx.Hash().Hex())
+
+	// Wait for the transaction to be mined
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	receipt, err := bind.WaitMined(timeout, eclient, tx)
+	if err != nil {
+		utils.Fatalf("Failed to wait for the registration %v", err)
+	}
+	log.Info("Registration mined", "status", receipt.Status, "gas", receipt.GasUsed)
+	return nil
+}
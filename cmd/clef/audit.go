@@ -0,0 +1,177 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	auditAccountFlag = cli.StringFlag{
+		Name:  "account",
+		Usage: "Only show entries concerning the given account",
+	}
+	auditFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Only show entries received at or after the given time (RFC3339 or YYYY-MM-DD)",
+	}
+	auditToFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Only show entries received at or before the given time (RFC3339 or YYYY-MM-DD)",
+	}
+	auditCommand = cli.Command{
+		Name:      "audit",
+		Usage:     "Inspect the audit log",
+		ArgsUsage: "",
+		Description: `
+Clef records every request made on the external API in the audit log, one JSON object per line.
+Each entry contains the hash of the previous one, so that the removal or modification of entries
+can be detected.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(verifyAuditLog),
+				Name:      "verify",
+				Usage:     "Verify the integrity of the audit log",
+				ArgsUsage: "[<file>]",
+				Flags: []cli.Flag{
+					auditLogFlag,
+				},
+				Description: `
+    clef audit verify [<file>]
+
+Checks the hash chain of the audit log given as argument, or by --auditlog. The command
+fails at the first entry which has been modified, or which does not follow its predecessor.`,
+			},
+			{
+				Action:    utils.MigrateFlags(listAuditLog),
+				Name:      "list",
+				Usage:     "Show the entries of the audit log",
+				ArgsUsage: "[<file>]",
+				Flags: []cli.Flag{
+					auditLogFlag,
+					auditAccountFlag,
+					auditFromFlag,
+					auditToFlag,
+				},
+				Description: `
+    clef audit list [--account <address>] [--from <time>] [--to <time>] [<file>]
+
+Prints the entries of the audit log given as argument, or by --auditlog, as JSON lines. The
+entries can be filtered by account and time range. The integrity of the log is verified too.`,
+			},
+		},
+	}
+)
+
+// openAuditLog opens the audit log given as argument, falling back to --auditlog.
+func openAuditLog(ctx *cli.Context) *os.File {
+	path := ctx.Args().First()
+	if path == "" {
+		path = ctx.GlobalString(auditLogFlag.Name)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Failed to open audit log: %v", err)
+	}
+	return f
+}
+
+// parseAuditTime parses a time given either in RFC3339 format, or as a date. When
+// endOfDay is set, a date denotes the last moment of that day instead of the first.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or YYYY-MM-DD", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func verifyAuditLog(ctx *cli.Context) error {
+	f := openAuditLog(ctx)
+	defer f.Close()
+
+	count, err := core.VerifyAuditLog(f)
+	if err != nil {
+		utils.Fatalf("Audit log verification failed after %d valid entries: %v", count, err)
+	}
+	fmt.Printf("Audit log %s is intact, %d entries\n", f.Name(), count)
+	return nil
+}
+
+func listAuditLog(ctx *cli.Context) error {
+	var filter core.AuditFilter
+	if account := ctx.String(auditAccountFlag.Name); account != "" {
+		if !common.IsHexAddress(account) {
+			utils.Fatalf("Invalid account address: %v", account)
+		}
+		addr := common.HexToAddress(account)
+		filter.Account = &addr
+	}
+	var err error
+	if from := ctx.String(auditFromFlag.Name); from != "" {
+		if filter.From, err = parseAuditTime(from, false); err != nil {
+			utils.Fatalf(err.Error())
+		}
+	}
+	if to := ctx.String(auditToFlag.Name); to != "" {
+		if filter.To, err = parseAuditTime(to, true); err != nil {
+			utils.Fatalf(err.Error())
+		}
+	}
+	f := openAuditLog(ctx)
+	defer f.Close()
+
+	// Print the selected entries, but verify the entire log while at it
+	var (
+		verifier = core.NewAuditVerifier()
+		broken   error
+	)
+	err = core.IterateAuditLog(f, func(entry *core.AuditEntry) error {
+		if err := verifier.Check(entry); err != nil && broken == nil {
+			broken = err
+		}
+		if filter.Match(entry) {
+			blob, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(blob))
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Fatalf("Failed to read audit log: %v", err)
+	}
+	if broken != nil {
+		utils.Fatalf("Audit log verification failed: %v", broken)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	mnemonicPassphraseFlag = cli.BoolFlag{
+		Name:  "mnemonic.passphrase",
+		Usage: "Prompt for an optional BIP-39 passphrase extending the mnemonic",
+	}
+	hdwalletCommand = cli.Command{
+		Name:      "hdwallet",
+		Usage:     "Manage hierarchical deterministic wallets",
+		ArgsUsage: "",
+		Description: `
+HD wallets derive any number of accounts from a single BIP-39 mnemonic, which is all that needs to
+be backed up. The seed of the mnemonic is stored encrypted in the keystore, along with the derivation
+paths of the accounts in use. Clef signs with them like with keystore accounts, asking for the
+password of the wallet.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(newHDWallet),
+				Name:      "new",
+				Usage:     "Create a new HD wallet",
+				ArgsUsage: "",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					keystoreFlag,
+					utils.LightKDFFlag,
+					mnemonicPassphraseFlag,
+				},
+				Description: `
+    clef hdwallet new
+
+Generates a new 24 word mnemonic, stores its seed in the keystore and prints the mnemonic along with
+the first account of the wallet. The mnemonic is not stored anywhere: write it down, it is the only
+way to recover the accounts of the wallet if the keystore is lost.`,
+			},
+			{
+				Action:    utils.MigrateFlags(importHDWallet),
+				Name:      "import",
+				Usage:     "Import a BIP-39 mnemonic into a new HD wallet",
+				ArgsUsage: "[<mnemonicFile>]",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					keystoreFlag,
+					utils.LightKDFFlag,
+					mnemonicPassphraseFlag,
+				},
+				Description: `
+    clef hdwallet import [<mnemonicFile>]
+
+Imports the mnemonic contained in <mnemonicFile>, or prompted for if no file is given, and prints the
+first account of the wallet. Use --mnemonic.passphrase if the mnemonic is protected by a BIP-39
+passphrase.`,
+			},
+			{
+				Action:    utils.MigrateFlags(deriveHDWallet),
+				Name:      "derive",
+				Usage:     "Derive and add an account to an HD wallet",
+				ArgsUsage: "<wallet> <path>",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					keystoreFlag,
+				},
+				Description: `
+    clef hdwallet derive <wallet> <path>
+
+Derives the account at <path> from the HD wallet stored in the seed file or URL <wallet>, and adds
+it to the accounts Clef can sign with. Relative paths are appended to m/44'/60'/0'/0.`,
+			},
+		},
+	}
+)
+
+// openHDKeystore opens the HD wallets in the keystore folder given by the flags.
+func openHDKeystore(c *cli.Context) *hdwallet.KeyStore {
+	n, p := keystore.StandardScryptN, keystore.StandardScryptP
+	if c.GlobalBool(utils.LightKDFFlag.Name) {
+		n, p = keystore.LightScryptN, keystore.LightScryptP
+	}
+	return hdwallet.NewKeyStore(c.GlobalString(keystoreFlag.Name), n, p)
+}
+
+// storeHDWallet stores the mnemonic as a new HD wallet, prompting for the
+// passwords protecting it.
+func storeHDWallet(c *cli.Context, mnemonic string, confirmation bool) (accounts.Wallet, accounts.Account) {
+	var mnemonicPassphrase string
+	if c.GlobalBool(mnemonicPassphraseFlag.Name) {
+		mnemonicPassphrase = getPassPhrase("Please give the BIP-39 passphrase of the mnemonic.", confirmation)
+	}
+	password := getPassPhrase("The new wallet is locked with a password. Please give a password. Do not forget this password.", true)
+	if err := core.ValidatePasswordFormat(password); err != nil {
+		utils.Fatalf("Invalid password: %v", err)
+	}
+	wallet, account, err := openHDKeystore(c).NewWallet(mnemonic, mnemonicPassphrase, password)
+	if err != nil {
+		utils.Fatalf("Failed to store wallet: %v", err)
+	}
+	return wallet, account
+}
+
+func newHDWallet(c *cli.Context) error {
+	if err := initialize(c); err != nil {
+		return err
+	}
+	mnemonic, err := hdwallet.NewMnemonic(256)
+	if err != nil {
+		utils.Fatalf("Failed to generate mnemonic: %v", err)
+	}
+	wallet, account := storeHDWallet(c, mnemonic, true)
+
+	fmt.Printf("Mnemonic: %s\n\n", mnemonic)
+	fmt.Println("Write the mnemonic down and keep it safe. It is not stored anywhere, and is the")
+	fmt.Println("only way to recover the accounts of the wallet if the keystore is lost.")
+	fmt.Println()
+	log.Info("HD wallet created", "url", wallet.URL(), "address", account.Address)
+	return nil
+}
+
+func importHDWallet(c *cli.Context) error {
+	if err := initialize(c); err != nil {
+		return err
+	}
+	var mnemonic string
+	if file := c.Args().First(); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = string(blob)
+	} else {
+		input, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = input
+	}
+	mnemonic = strings.TrimSpace(mnemonic)
+	if err := hdwallet.ValidateMnemonic(mnemonic); err != nil {
+		utils.Fatalf("Failed to import the mnemonic: %v", err)
+	}
+	wallet, account := storeHDWallet(c, mnemonic, false)
+
+	log.Info("HD wallet imported", "url", wallet.URL(), "address", account.Address)
+	return nil
+}
+
+func deriveHDWallet(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		utils.Fatalf("This command requires a wallet and a derivation path.")
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	path, err := accounts.ParseDerivationPath(c.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	wallet, err := openHDKeystore(c).Find(c.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not find the wallet: %v", err)
+	}
+	password := getPassPhrase("Please enter the password of the wallet.", false)
+	if err := wallet.Open(password); err != nil {
+		utils.Fatalf("Failed to unlock the wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	if status, _ := wallet.Status(); status != "Unlocked" {
+		utils.Fatalf("Failed to unlock the wallet: %v", keystore.ErrDecrypt)
+	}
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		utils.Fatalf("Failed to derive the account: %v", err)
+	}
+	log.Info("HD wallet account added", "url", wallet.URL(), "path", path, "address", account.Address)
+	return nil
+}
@@ -35,25 +35,25 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/pkcs11"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/rules"
-	"github.com/ethereum/go-ethereum/signer/storage"
 	"gopkg.in/urfave/cli.v1"
 )
 
 // ExternalAPIVersion -- see extapi_changelog.md
-const ExternalAPIVersion = "4.0.0"
+const ExternalAPIVersion = "4.2.0"
 
 // InternalAPIVersion -- see intapi_changelog.md
-const InternalAPIVersion = "3.0.0"
+const InternalAPIVersion = "3.1.0"
 
 const legalWarning = `
 WARNING! 
@@ -172,6 +172,84 @@ Clef that the file is 'safe' to execute.`,
 remove any stored credential for that address (keyfile)
 `,
 	}
+	delCredentialCommand = cli.Command{
+		Action:    utils.MigrateFlags(removeCredential),
+		Name:      "delpw",
+		Usage:     "Remove a credential for a keystore file",
+		ArgsUsage: "<address>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+		},
+		Description: `
+The delpw command revokes the stored password for a given address (keyfile), after which the
+rule engine can no longer sign with it.`,
+	}
+	listCredentialsCommand = cli.Command{
+		Action:    utils.MigrateFlags(listCredentials),
+		Name:      "listpw",
+		Usage:     "List the keystore files with stored credentials",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+		},
+		Description: `
+The listpw command lists the addresses (keyfiles) which have a password stored. The passwords
+themselves are not shown.`,
+	}
+	attestationsCommand = cli.Command{
+		Action:    utils.MigrateFlags(listAttestations),
+		Name:      "attestations",
+		Usage:     "List the history of rule file attestations",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+		},
+		Description: `
+The attestations command lists every sha256 attested via 'clef attest', along with its version and
+the time of attestation. The latest attestation is the one Clef accepts.`,
+	}
+	exportCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportVault),
+		Name:      "export",
+		Usage:     "Export the master seed and vault into an encrypted file",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+			ruleFlag,
+			auditLogFlag,
+			utils.LightKDFFlag,
+		},
+		Description: `
+The export command bundles the master seed, the stored credentials, the rule engine storage, the
+history of rule attestations, the attested rule file and the audit log settings into a single file,
+encrypted with a password of your choosing. The bundle can be restored on another machine with
+'clef import'.`,
+	}
+	importCommand = cli.Command{
+		Action:    utils.MigrateFlags(importVault),
+		Name:      "import",
+		Usage:     "Import a master seed and vault exported by 'clef export'",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+			ruleFlag,
+			utils.LightKDFFlag,
+		},
+		Description: `
+The import command restores a bundle created by 'clef export'. The master seed is encrypted with a
+new password, and will not overwrite an existing one. The attested rule file is written to the
+location given by --rules, unless that file already exists.`,
+	}
 )
 
 func init() {
@@ -184,6 +262,11 @@ func init() {
 		utils.NetworkIdFlag,
 		utils.LightKDFFlag,
 		utils.NoUSBFlag,
+		utils.USBBasePathsFlag,
+		utils.USBLedgerLiveFlag,
+		utils.PKCS11ModuleFlag,
+		utils.PKCS11SlotFlag,
+		utils.PKCS11LabelsFlag,
 		utils.RPCListenAddrFlag,
 		utils.RPCVirtualHostsFlag,
 		utils.IPCDisabledFlag,
@@ -200,7 +283,18 @@ func init() {
 		advancedMode,
 	}
 	app.Action = signer
-	app.Commands = []cli.Command{initCommand, attestCommand, setCredentialCommand}
+	app.Commands = []cli.Command{
+		initCommand,
+		attestCommand,
+		attestationsCommand,
+		setCredentialCommand,
+		delCredentialCommand,
+		listCredentialsCommand,
+		exportCommand,
+		importCommand,
+		auditCommand,
+		hdwalletCommand,
+	}
 
 }
 func main() {
@@ -225,6 +319,30 @@ func initializeSecrets(c *cli.Context) error {
 		return fmt.Errorf("failed to read enough random")
 	}
 
+	location := filepath.Join(configDir, "masterseed.json")
+	if _, err := os.Stat(location); err == nil {
+		return fmt.Errorf("file %v already exists, will not overwrite", location)
+	}
+	if err := writeMasterSeed(c, location, masterSeed); err != nil {
+		return err
+	}
+	fmt.Printf("A master seed has been generated into %s\n", location)
+	fmt.Printf(`
+This is required to be able to store credentials, such as : 
+* Passwords for keystores (used by rule engine)
+* Storage for javascript rules
+* Hash of rule-file
+
+You should treat that file with utmost secrecy, and make a backup of it. 
+NOTE: This file does not contain your accounts. Those need to be backed up separately!
+
+`)
+	return nil
+}
+
+// writeMasterSeed encrypts the master seed with a password requested from the
+// user, and stores it at the given location.
+func writeMasterSeed(c *cli.Context, location string, masterSeed []byte) error {
 	n, p := keystore.StandardScryptN, keystore.StandardScryptP
 	if c.GlobalBool(utils.LightKDFFlag.Name) {
 		n, p = keystore.LightScryptN, keystore.LightScryptP
@@ -243,32 +361,12 @@ func initializeSecrets(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to encrypt master seed: %v", err)
 	}
-
-	err = os.Mkdir(configDir, 0700)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-	location := filepath.Join(configDir, "masterseed.json")
-	if _, err := os.Stat(location); err == nil {
-		return fmt.Errorf("file %v already exists, will not overwrite", location)
-	}
-	err = ioutil.WriteFile(location, cipherSeed, 0400)
-	if err != nil {
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
 		return err
 	}
-	fmt.Printf("A master seed has been generated into %s\n", location)
-	fmt.Printf(`
-This is required to be able to store credentials, such as : 
-* Passwords for keystores (used by rule engine)
-* Storage for javascript rules
-* Hash of rule-file
-
-You should treat that file with utmost secrecy, and make a backup of it. 
-NOTE: This file does not contain your accounts. Those need to be backed up separately!
-
-`)
-	return nil
+	return ioutil.WriteFile(location, cipherSeed, 0400)
 }
+
 func attestFile(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -277,19 +375,19 @@ func attestFile(ctx *cli.Context) error {
 		return err
 	}
 
-	stretchedKey, err := readMasterKey(ctx, nil)
+	val := strings.ToLower(ctx.Args().First())
+	if hash, err := hex.DecodeString(val); err != nil || len(hash) != sha256.Size {
+		utils.Fatalf("Invalid sha256 checksum: %v", ctx.Args().First())
+	}
+	_, v, err := unlockVault(ctx, nil)
 	if err != nil {
 		utils.Fatalf(err.Error())
 	}
-	configDir := ctx.GlobalString(configdirFlag.Name)
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-	confKey := crypto.Keccak256([]byte("config"), stretchedKey)
-
-	// Initialize the encrypted storages
-	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
-	val := ctx.Args().First()
-	configStorage.Put("ruleset_sha256", val)
-	log.Info("Ruleset attestation updated", "sha256", val)
+	entry, err := v.attest(val)
+	if err != nil {
+		utils.Fatalf("Failed to record attestation: %v", err)
+	}
+	log.Info("Ruleset attestation updated", "sha256", val, "version", entry.Version)
 	return nil
 }
 
@@ -304,17 +402,16 @@ func setCredential(ctx *cli.Context) error {
 	address := ctx.Args().First()
 	password := getPassPhrase("Enter a passphrase to store with this address.", true)
 
-	stretchedKey, err := readMasterKey(ctx, nil)
+	_, v, err := unlockVault(ctx, nil)
 	if err != nil {
 		utils.Fatalf(err.Error())
 	}
-	configDir := ctx.GlobalString(configdirFlag.Name)
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-	pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
-
-	// Initialize the encrypted storages
-	pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
-	pwStorage.Put(address, password)
+	if password == "" {
+		v.credentials.Del(address)
+		log.Info("Credential removed", "key", address)
+		return nil
+	}
+	v.credentials.Put(address, password)
 	log.Info("Credential store updated", "key", address)
 	return nil
 }
@@ -350,6 +447,9 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
+	// Record the decisions made in the UI (and by the rules, below) in the audit log
+	ui = core.NewAuditUI(ui, core.AuditStageUI)
+
 	fourByteDb := c.GlobalString(dBFlag.Name)
 	fourByteLocal := c.GlobalString(customDBFlag.Name)
 	db, err := core.NewAbiDBFromFiles(fourByteDb, fourByteLocal)
@@ -363,59 +463,57 @@ func signer(c *cli.Context) error {
 	)
 
 	configDir := c.GlobalString(configdirFlag.Name)
-	if stretchedKey, err := readMasterKey(c, ui); err != nil {
+	_, v, err := unlockVault(c, ui)
+	if err != nil {
 		log.Info("No master seed provided, rules disabled", "error", err)
 	} else {
-
-		if err != nil {
-			utils.Fatalf(err.Error())
-		}
-		vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-
-		// Generate domain specific keys
-		pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
-		jskey := crypto.Keccak256([]byte("jsstorage"), stretchedKey)
-		confkey := crypto.Keccak256([]byte("config"), stretchedKey)
-
-		// Initialize the encrypted storages
-		pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
-		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
-		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
-
 		//Do we have a rule-file?
-		ruleJS, err := ioutil.ReadFile(c.GlobalString(ruleFlag.Name))
+		shasum, ruleJS, err := sha256File(c.GlobalString(ruleFlag.Name))
 		if err != nil {
 			log.Info("Could not load rulefile, rules not enabled", "file", "rulefile")
 		} else {
-			hasher := sha256.New()
-			hasher.Write(ruleJS)
-			shasum := hasher.Sum(nil)
-			storedShasum := configStorage.Get("ruleset_sha256")
-			if storedShasum != hex.EncodeToString(shasum) {
-				log.Info("Could not validate ruleset hash, rules not enabled", "got", hex.EncodeToString(shasum), "expected", storedShasum)
+			storedShasum := v.config.Get(rulesetHashKey)
+			if storedShasum != shasum {
+				log.Info("Could not validate ruleset hash, rules not enabled", "got", shasum, "expected", storedShasum)
 			} else {
 				// Initialize rules
-				ruleEngine, err := rules.NewRuleEvaluator(ui, jsStorage, pwStorage)
+				ruleEngine, err := rules.NewRuleEvaluator(ui, v.jsStorage, v.credentials)
 				if err != nil {
 					utils.Fatalf(err.Error())
 				}
 				ruleEngine.Init(string(ruleJS))
-				ui = ruleEngine
+				ui = core.NewAuditUI(ruleEngine, core.AuditStageRules)
 				log.Info("Rule engine configured", "file", c.String(ruleFlag.Name))
 			}
 		}
 	}
 
+	var hsms []accounts.Backend
+	if module := c.GlobalString(utils.PKCS11ModuleFlag.Name); module != "" {
+		var labels []string
+		if c.GlobalIsSet(utils.PKCS11LabelsFlag.Name) {
+			labels = splitAndTrim(c.GlobalString(utils.PKCS11LabelsFlag.Name))
+		}
+		hub, err := pkcs11.NewHub(module, c.GlobalUint(utils.PKCS11SlotFlag.Name), labels)
+		if err != nil {
+			utils.Fatalf("Failed to open PKCS#11 token: %v", err)
+		}
+		log.Info("PKCS#11 token configured", "url", hub.Wallets()[0].URL(), "accounts", len(hub.Wallets()[0].Accounts()))
+		hsms = append(hsms, hub)
+	}
 	apiImpl := core.NewSignerAPI(
 		c.GlobalInt64(utils.NetworkIdFlag.Name),
 		c.GlobalString(keystoreFlag.Name),
 		c.GlobalBool(utils.NoUSBFlag.Name),
+		utils.MakeUSBBasePaths(c),
+		c.GlobalBool(utils.USBLedgerLiveFlag.Name),
 		ui, db,
 		c.GlobalBool(utils.LightKDFFlag.Name),
-		c.GlobalBool(advancedMode.Name))
+		c.GlobalBool(advancedMode.Name),
+		hsms...)
 	api = apiImpl
 	// Audit logging
-	if logfile := c.GlobalString(auditLogFlag.Name); logfile != "" {
+	if logfile := auditLogPath(c, v); logfile != "" {
 		api, err = core.NewAuditLogger(logfile, api)
 		if err != nil {
 			utils.Fatalf(err.Error())
@@ -532,16 +630,17 @@ func homeDir() string {
 	}
 	return ""
 }
-func readMasterKey(ctx *cli.Context, ui core.SignerUI) ([]byte, error) {
-	var (
-		file      string
-		configDir = ctx.GlobalString(configdirFlag.Name)
-	)
+
+// masterSeedLocation returns the file containing the encrypted master seed.
+func masterSeedLocation(ctx *cli.Context) string {
 	if ctx.GlobalIsSet(signerSecretFlag.Name) {
-		file = ctx.GlobalString(signerSecretFlag.Name)
-	} else {
-		file = filepath.Join(configDir, "masterseed.json")
+		return ctx.GlobalString(signerSecretFlag.Name)
 	}
+	return filepath.Join(ctx.GlobalString(configdirFlag.Name), "masterseed.json")
+}
+
+func readMasterKey(ctx *cli.Context, ui core.SignerUI) ([]byte, error) {
+	file := masterSeedLocation(ctx)
 	if err := checkFile(file); err != nil {
 		return nil, err
 	}
@@ -570,13 +669,6 @@ func readMasterKey(ctx *cli.Context, ui core.SignerUI) ([]byte, error) {
 	if len(masterSeed) < 256 {
 		return nil, fmt.Errorf("master seed of insufficient length, expected >255 bytes, got %d", len(masterSeed))
 	}
-
-	// Create vault location
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), masterSeed)[:10]))
-	err = os.Mkdir(vaultLocation, 0700)
-	if err != nil && !os.IsExist(err) {
-		return nil, err
-	}
 	return masterSeed, nil
 }
 
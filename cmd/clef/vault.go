@@ -0,0 +1,463 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Keys of the settings stored in the config storage of the vault.
+const (
+	rulesetHashKey    = "ruleset_sha256"
+	rulesetHistoryKey = "ruleset_history"
+	auditLogKey       = "auditlog"
+)
+
+// vault is the set of encrypted storages of clef, located in a directory
+// derived from the master seed and encrypted with keys derived from it.
+type vault struct {
+	location    string
+	credentials *storage.AESEncryptedStorage
+	jsStorage   *storage.AESEncryptedStorage
+	config      *storage.AESEncryptedStorage
+}
+
+// openVault creates the vault directory belonging to the master seed, if needed,
+// and sets up the encrypted storages within.
+func openVault(configDir string, masterSeed []byte) (*vault, error) {
+	location := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), masterSeed)[:10]))
+	if err := os.MkdirAll(location, 0700); err != nil {
+		return nil, err
+	}
+	// Generate domain specific keys
+	pwkey := crypto.Keccak256([]byte("credentials"), masterSeed)
+	jskey := crypto.Keccak256([]byte("jsstorage"), masterSeed)
+	confkey := crypto.Keccak256([]byte("config"), masterSeed)
+
+	return &vault{
+		location:    location,
+		credentials: storage.NewAESEncryptedStorage(filepath.Join(location, "credentials.json"), pwkey),
+		jsStorage:   storage.NewAESEncryptedStorage(filepath.Join(location, "jsstorage.json"), jskey),
+		config:      storage.NewAESEncryptedStorage(filepath.Join(location, "config.json"), confkey),
+	}, nil
+}
+
+// setting retrieves a value from the config storage, and whether it was set.
+func (v *vault) setting(key string) (string, bool) {
+	keys, err := v.config.Keys()
+	if err != nil {
+		return "", false
+	}
+	for _, k := range keys {
+		if k == key {
+			return v.config.Get(key), true
+		}
+	}
+	return "", false
+}
+
+// attestation is an entry in the history of attested rule files.
+type attestation struct {
+	Version uint64    `json:"version"`
+	Sha256  string    `json:"sha256"`
+	Time    time.Time `json:"time"`
+}
+
+// attestations returns the history of rule attestations, oldest first. Vaults
+// attested before the history was recorded report their single attestation
+// with an unknown (zero) time.
+func (v *vault) attestations() ([]attestation, error) {
+	if blob, ok := v.setting(rulesetHistoryKey); ok {
+		var history []attestation
+		if err := json.Unmarshal([]byte(blob), &history); err != nil {
+			return nil, fmt.Errorf("corrupt attestation history: %v", err)
+		}
+		return history, nil
+	}
+	if hash, ok := v.setting(rulesetHashKey); ok && hash != "" {
+		return []attestation{{Version: 1, Sha256: hash}}, nil
+	}
+	return nil, nil
+}
+
+// attest records the hash of a new rule file as the one to be executed, and
+// appends it to the attestation history.
+func (v *vault) attest(hash string) (attestation, error) {
+	history, err := v.attestations()
+	if err != nil {
+		return attestation{}, err
+	}
+	entry := attestation{
+		Version: uint64(len(history)) + 1,
+		Sha256:  hash,
+		Time:    time.Now().UTC().Truncate(time.Second),
+	}
+	if err := v.setAttestations(append(history, entry)); err != nil {
+		return attestation{}, err
+	}
+	return entry, nil
+}
+
+// setAttestations stores the given attestation history, making its latest entry
+// the attested rule file.
+func (v *vault) setAttestations(history []attestation) error {
+	blob, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	v.config.Put(rulesetHistoryKey, string(blob))
+	if len(history) > 0 {
+		v.config.Put(rulesetHashKey, history[len(history)-1].Sha256)
+	} else {
+		v.config.Del(rulesetHashKey)
+	}
+	return nil
+}
+
+// vaultBundle is the content of an exported vault, allowing clef's configuration
+// to be moved to another machine.
+type vaultBundle struct {
+	MasterSeed   hexutil.Bytes     `json:"masterseed"`
+	Credentials  map[string]string `json:"credentials"`
+	JSStorage    map[string]string `json:"jsstorage"`
+	Attestations []attestation     `json:"attestations"`
+	Rules        string            `json:"rules,omitempty"`    // Source of the attested rule file, if available
+	AuditLog     *string           `json:"auditlog,omitempty"` // Audit log location, if configured
+}
+
+// export assembles a bundle with the master seed and the content of the vault.
+func (v *vault) export(masterSeed []byte) (*vaultBundle, error) {
+	credentials, err := dumpStorage(v.credentials)
+	if err != nil {
+		return nil, err
+	}
+	jsStorage, err := dumpStorage(v.jsStorage)
+	if err != nil {
+		return nil, err
+	}
+	history, err := v.attestations()
+	if err != nil {
+		return nil, err
+	}
+	bundle := &vaultBundle{
+		MasterSeed:   masterSeed,
+		Credentials:  credentials,
+		JSStorage:    jsStorage,
+		Attestations: history,
+	}
+	if path, ok := v.setting(auditLogKey); ok {
+		bundle.AuditLog = &path
+	}
+	return bundle, nil
+}
+
+// restore populates the vault with the content of an exported bundle.
+func (v *vault) restore(bundle *vaultBundle) error {
+	for key, value := range bundle.Credentials {
+		v.credentials.Put(key, value)
+	}
+	for key, value := range bundle.JSStorage {
+		v.jsStorage.Put(key, value)
+	}
+	if len(bundle.Attestations) > 0 {
+		if err := v.setAttestations(bundle.Attestations); err != nil {
+			return err
+		}
+	}
+	if bundle.AuditLog != nil {
+		v.config.Put(auditLogKey, *bundle.AuditLog)
+	}
+	return nil
+}
+
+// dumpStorage decrypts all the values of an encrypted storage.
+func dumpStorage(s *storage.AESEncryptedStorage) (map[string]string, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		values[key] = s.Get(key)
+	}
+	return values, nil
+}
+
+// encryptBundle encrypts an exported vault with the same scheme as the master seed.
+func encryptBundle(bundle *vaultBundle, auth []byte, scryptN, scryptP int) ([]byte, error) {
+	plain, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	cryptoStruct, err := keystore.EncryptDataV3(plain, auth, scryptN, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&encryptedSeedStorage{"Clef vault", 1, cryptoStruct})
+}
+
+// decryptBundle decrypts an exported vault.
+func decryptBundle(blob []byte, auth string) (*vaultBundle, error) {
+	var enc encryptedSeedStorage
+	if err := json.Unmarshal(blob, &enc); err != nil {
+		return nil, err
+	}
+	if enc.Description != "Clef vault" || enc.Version != 1 {
+		return nil, fmt.Errorf("unsupported vault bundle: %q v%d", enc.Description, enc.Version)
+	}
+	plain, err := keystore.DecryptDataV3(enc.Params, auth)
+	if err != nil {
+		return nil, err
+	}
+	bundle := new(vaultBundle)
+	if err := json.Unmarshal(plain, bundle); err != nil {
+		return nil, err
+	}
+	if len(bundle.MasterSeed) < 256 {
+		return nil, errors.New("vault bundle lacks a valid master seed")
+	}
+	return bundle, nil
+}
+
+// unlockVault decrypts the master seed and opens the vault belonging to it.
+func unlockVault(c *cli.Context, ui core.SignerUI) ([]byte, *vault, error) {
+	masterSeed, err := readMasterKey(c, ui)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := openVault(c.GlobalString(configdirFlag.Name), masterSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return masterSeed, v, nil
+}
+
+// auditLogPath returns the audit log location set on the command line, falling
+// back to the one stored in the vault (if any) and then to the default.
+func auditLogPath(c *cli.Context, v *vault) string {
+	if !c.GlobalIsSet(auditLogFlag.Name) && v != nil {
+		if path, ok := v.setting(auditLogKey); ok {
+			return path
+		}
+	}
+	return c.GlobalString(auditLogFlag.Name)
+}
+
+// sha256File returns the hex encoded sha256 checksum of the given file.
+func sha256File(path string) (string, []byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), content, nil
+}
+
+func listAttestations(c *cli.Context) error {
+	if err := initialize(c); err != nil {
+		return err
+	}
+	_, v, err := unlockVault(c, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	history, err := v.attestations()
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	if len(history) == 0 {
+		fmt.Println("No rule files attested")
+		return nil
+	}
+	for i, entry := range history {
+		when := "unknown"
+		if !entry.Time.IsZero() {
+			when = entry.Time.Format(time.RFC3339)
+		}
+		current := ""
+		if i == len(history)-1 {
+			current = " (current)"
+		}
+		fmt.Printf("v%d  %s  %s%s\n", entry.Version, when, entry.Sha256, current)
+	}
+	return nil
+}
+
+func listCredentials(c *cli.Context) error {
+	if err := initialize(c); err != nil {
+		return err
+	}
+	_, v, err := unlockVault(c, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	keys, err := v.credentials.Keys()
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	if len(keys) == 0 {
+		fmt.Println("No credentials stored")
+	}
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	return nil
+}
+
+func removeCredential(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		utils.Fatalf("This command requires an address to be passed as an argument.")
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	_, v, err := unlockVault(c, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	address := c.Args().First()
+	keys, err := v.credentials.Keys()
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	for _, key := range keys {
+		if key == address {
+			v.credentials.Del(address)
+			log.Info("Credential removed", "key", address)
+			return nil
+		}
+	}
+	utils.Fatalf("No credential stored for %s", address)
+	return nil
+}
+
+func exportVault(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		utils.Fatalf("This command requires a file to export to.")
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	target := c.Args().First()
+	if _, err := os.Stat(target); err == nil {
+		utils.Fatalf("File %v already exists, will not overwrite", target)
+	}
+	masterSeed, v, err := unlockVault(c, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	bundle, err := v.export(masterSeed)
+	if err != nil {
+		utils.Fatalf("Failed to export vault: %v", err)
+	}
+	// Include the rule file too, but only if it's the attested one
+	if file := c.GlobalString(ruleFlag.Name); file != "" {
+		if hash, content, err := sha256File(file); err == nil {
+			if len(bundle.Attestations) > 0 && bundle.Attestations[len(bundle.Attestations)-1].Sha256 == hash {
+				bundle.Rules = string(content)
+			} else {
+				log.Warn("Rule file not attested, skipping", "file", file, "sha256", hash)
+			}
+		}
+	}
+	if c.GlobalIsSet(auditLogFlag.Name) {
+		path := c.GlobalString(auditLogFlag.Name)
+		bundle.AuditLog = &path
+	}
+	password := getPassPhrase("The exported vault is locked with a password. Please give a password. Do not forget this password.", true)
+	if err := core.ValidatePasswordFormat(password); err != nil {
+		utils.Fatalf("Invalid password: %v", err)
+	}
+	n, p := keystore.StandardScryptN, keystore.StandardScryptP
+	if c.GlobalBool(utils.LightKDFFlag.Name) {
+		n, p = keystore.LightScryptN, keystore.LightScryptP
+	}
+	blob, err := encryptBundle(bundle, []byte(password), n, p)
+	if err != nil {
+		utils.Fatalf("Failed to encrypt vault: %v", err)
+	}
+	if err := ioutil.WriteFile(target, blob, 0400); err != nil {
+		return err
+	}
+	log.Info("Vault exported", "file", target, "credentials", len(bundle.Credentials), "attestations", len(bundle.Attestations), "rules", bundle.Rules != "")
+	return nil
+}
+
+func importVault(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		utils.Fatalf("This command requires a file to import from.")
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	blob, err := ioutil.ReadFile(c.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read vault: %v", err)
+	}
+	bundle, err := decryptBundle(blob, getPassPhrase("Decrypt the exported vault", false))
+	if err != nil {
+		utils.Fatalf("Failed to decrypt vault: %v", err)
+	}
+	// Store the imported master seed, refusing to replace an existing one
+	location := masterSeedLocation(c)
+	if _, err := os.Stat(location); err == nil {
+		utils.Fatalf("File %v already exists, will not overwrite", location)
+	}
+	if err := writeMasterSeed(c, location, bundle.MasterSeed); err != nil {
+		utils.Fatalf(err.Error())
+	}
+	v, err := openVault(c.GlobalString(configdirFlag.Name), bundle.MasterSeed)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	if err := v.restore(bundle); err != nil {
+		utils.Fatalf("Failed to restore vault: %v", err)
+	}
+	// Restore the attested rule file, unless one is already present
+	if bundle.Rules != "" {
+		file := c.GlobalString(ruleFlag.Name)
+		if file == "" {
+			log.Warn("No rule file configured, not restoring attested rules")
+		} else if _, err := os.Stat(file); err == nil {
+			log.Warn("Rule file exists, not restoring attested rules", "file", file)
+		} else if err := ioutil.WriteFile(file, []byte(bundle.Rules), 0600); err != nil {
+			log.Warn("Failed to restore attested rules", "file", file, "err", err)
+		} else {
+			log.Info("Attested rules restored", "file", file)
+		}
+	}
+	log.Info("Vault imported", "seed", location, "credentials", len(bundle.Credentials), "attestations", len(bundle.Attestations))
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// tmpConfigDir creates a temporary clef config directory.
+func tmpConfigDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "clef-vault-test")
+	if err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	return dir
+}
+
+// Tests that a vault exported into an encrypted bundle and imported into a fresh
+// config directory reproduces the credentials, JS storage, attestation history
+// and audit log setting of the original.
+func TestVaultExportImport(t *testing.T) {
+	srcDir, dstDir := tmpConfigDir(t), tmpConfigDir(t)
+	defer os.RemoveAll(srcDir)
+	defer os.RemoveAll(dstDir)
+
+	seed := bytes.Repeat([]byte{0x5a}, 256)
+	src, err := openVault(srcDir, seed)
+	if err != nil {
+		t.Fatalf("failed to open source vault: %v", err)
+	}
+	src.credentials.Put("0x0000000000000000000000000000000000000001", "password")
+	src.jsStorage.Put("counter", "42")
+	if _, err := src.attest("aa"); err != nil {
+		t.Fatalf("failed to attest first rules: %v", err)
+	}
+	if _, err := src.attest("bb"); err != nil {
+		t.Fatalf("failed to attest second rules: %v", err)
+	}
+	src.config.Put(auditLogKey, "/var/log/clef-audit.log")
+
+	bundle, err := src.export(seed)
+	if err != nil {
+		t.Fatalf("failed to export vault: %v", err)
+	}
+	blob, err := encryptBundle(bundle, []byte("vault password"), keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt bundle: %v", err)
+	}
+	// Import the bundle into a fresh config directory and compare the contents
+	imported, err := decryptBundle(blob, "vault password")
+	if err != nil {
+		t.Fatalf("failed to decrypt bundle: %v", err)
+	}
+	if !bytes.Equal(imported.MasterSeed, seed) {
+		t.Fatalf("master seed mismatch: have %x, want %x", imported.MasterSeed, seed)
+	}
+	dst, err := openVault(dstDir, imported.MasterSeed)
+	if err != nil {
+		t.Fatalf("failed to open destination vault: %v", err)
+	}
+	if err := dst.restore(imported); err != nil {
+		t.Fatalf("failed to restore vault: %v", err)
+	}
+	for name, pair := range map[string][2]*storage.AESEncryptedStorage{
+		"credentials": {dst.credentials, src.credentials},
+		"jsstorage":   {dst.jsStorage, src.jsStorage},
+	} {
+		have, _ := dumpStorage(pair[0])
+		want, _ := dumpStorage(pair[1])
+		if len(want) == 0 || !reflect.DeepEqual(have, want) {
+			t.Errorf("%s mismatch: have %v, want %v", name, have, want)
+		}
+	}
+	have, err := dst.attestations()
+	if err != nil {
+		t.Fatalf("failed to read imported attestations: %v", err)
+	}
+	want, _ := src.attestations()
+	if len(want) != 2 || !reflect.DeepEqual(have, want) {
+		t.Errorf("attestation mismatch: have %+v, want %+v", have, want)
+	}
+	if hash, _ := dst.setting(rulesetHashKey); hash != "bb" {
+		t.Errorf("attested rules mismatch: have %q, want %q", hash, "bb")
+	}
+	if path, ok := dst.setting(auditLogKey); !ok || path != "/var/log/clef-audit.log" {
+		t.Errorf("audit log mismatch: have %q (set: %v), want %q", path, ok, "/var/log/clef-audit.log")
+	}
+}
+
+// Tests that bundles are rejected with a wrong password, or if they lack a
+// usable master seed.
+func TestVaultImportRejected(t *testing.T) {
+	encrypt := func(seed []byte) []byte {
+		blob, err := encryptBundle(&vaultBundle{MasterSeed: seed}, []byte("vault password"), keystore.LightScryptN, keystore.LightScryptP)
+		if err != nil {
+			t.Fatalf("failed to encrypt bundle: %v", err)
+		}
+		return blob
+	}
+	if _, err := decryptBundle(encrypt(bytes.Repeat([]byte{0x5a}, 256)), "wrong password"); err == nil {
+		t.Errorf("bundle decrypted with wrong password")
+	}
+	if _, err := decryptBundle(encrypt(bytes.Repeat([]byte{0x5a}, 255)), "vault password"); err == nil {
+		t.Errorf("bundle with short master seed accepted")
+	}
+	if _, err := decryptBundle(encrypt(nil), "vault password"); err == nil {
+		t.Errorf("bundle without master seed accepted")
+	}
+	if _, err := decryptBundle([]byte(`{"description":"Clef seed","version":1}`), "vault password"); err == nil {
+		t.Errorf("master seed file accepted as bundle")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/tests"
+
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var FillOutputFlag = cli.StringFlag{
+	Name:  "outdir",
+	Usage: "directory to write the filled tests to (default = stdout)",
+}
+
+var fillTestCommand = cli.Command{
+	Action:    fillTestCmd,
+	Name:      "filltest",
+	Usage:     "generates state and blockchain tests from the given fillers",
+	ArgsUsage: "<file> [<file> ...]",
+	Flags:     []cli.Flag{FillOutputFlag},
+	Description: `
+The filltest command executes the YAML or JSON test fillers in the given files
+and writes out the filled test fixtures. Each filler file must contain either
+state or blockchain tests only. The fixtures of a filler file named xyzFiller.yml
+are written to xyz.json in the output directory, or to stdout if none is given.`,
+}
+
+func fillTestCmd(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		return errors.New("path-to-filler argument required")
+	}
+	outdir := ctx.String(FillOutputFlag.Name)
+	if outdir != "" {
+		if err := os.MkdirAll(outdir, 0755); err != nil {
+			return err
+		}
+	}
+	for _, path := range ctx.Args() {
+		fixtures, err := fillTests(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		out, err := json.MarshalIndent(fixtures, "", "  ")
+		if err != nil {
+			return err
+		}
+		if outdir == "" {
+			fmt.Println(string(out))
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		name = strings.TrimSuffix(name, "Filler") + ".json"
+		if err := ioutil.WriteFile(filepath.Join(outdir, name), append(out, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillTests generates the test fixtures of all the fillers in a file.
+func fillTests(path string) (map[string]interface{}, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fillers, err := tests.ParseFillers(src)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		fixtures   = make(map[string]interface{})
+		stateTests int
+	)
+	for name, filler := range fillers {
+		if !filler.IsBlockTest() {
+			test, err := filler.FillStateTest()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			fixtures[name] = test
+			stateTests++
+			continue
+		}
+		blockTests, err := filler.FillBlockTests(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for key, test := range blockTests {
+			fixtures[key] = test
+		}
+	}
+	if stateTests > 0 && stateTests < len(fillers) {
+		return nil, errors.New("cannot mix state and blockchain test fillers")
+	}
+	return fixtures, nil
+}
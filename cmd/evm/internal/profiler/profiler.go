@@ -0,0 +1,407 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package profiler implements an EVM tracer aggregating gas usage per program
+// counter, per opcode and per call frame.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Stat is the execution count and gas consumption of a profiled item.
+type Stat struct {
+	Count uint64 // Number of times the item was executed
+	Gas   uint64 // Gas consumed by the item itself, excluding sub-calls
+}
+
+// pcKey identifies an instruction within a piece of bytecode.
+type pcKey struct {
+	code common.Hash
+	pc   uint64
+}
+
+// step is an executed instruction whose gas consumption is not yet known. The
+// gas used by an instruction is only settled when the next one in the same call
+// frame executes (or the frame terminates), since calls refund unused gas.
+type step struct {
+	key   pcKey
+	op    vm.OpCode
+	gas   uint64 // Gas available before executing the instruction
+	cost  uint64 // Gas cost reported by the interpreter
+	child uint64 // Gas consumed by the call frames spawned by this instruction
+	leaf  string // Folded stack of the instruction, including the leaf
+}
+
+// frame is a call frame being executed.
+type frame struct {
+	path  string // Folded stack of the frame, root first
+	last  *step  // Last executed instruction, pending settlement
+	used  uint64 // Gas consumed by the frame so far, including sub-calls
+	fault bool   // Whether the frame terminated with an exceptional halt
+}
+
+// Profiler is a vm.Tracer aggregating the gas usage and execution counts of a
+// run per program counter, per opcode and per call frame. If source maps are
+// registered for the executed bytecode, instructions are also attributed to
+// their source locations.
+//
+// Gas is attributed to the instruction consuming it, not the one paying for it:
+// the gas forwarded by a call is accounted to the instructions of the callee,
+// and only the remainder to the call itself.
+type Profiler struct {
+	maps map[common.Hash]*SourceMap // Source maps of the known bytecodes
+
+	root   string   // Label of the outermost call frame
+	frames []*frame // Call frames currently being executed
+
+	ops    map[vm.OpCode]*Stat
+	pcs    map[pcKey]*Stat
+	pcOps  map[pcKey]vm.OpCode
+	calls  map[string]*Stat // Per call frame, keyed by folded stack
+	folded map[string]*Stat // Per folded stack including leaf instruction
+	codes  []common.Hash    // Executed bytecodes in order of first execution
+}
+
+// New creates a new profiler.
+func New() *Profiler {
+	return &Profiler{
+		maps:   make(map[common.Hash]*SourceMap),
+		ops:    make(map[vm.OpCode]*Stat),
+		pcs:    make(map[pcKey]*Stat),
+		pcOps:  make(map[pcKey]vm.OpCode),
+		calls:  make(map[string]*Stat),
+		folded: make(map[string]*Stat),
+	}
+}
+
+// AddSourceMap registers a source map for the given bytecode. Executed
+// instructions belonging to it are reported with their source locations.
+func (p *Profiler) AddSourceMap(code []byte, sm *SourceMap) {
+	p.maps[crypto.Keccak256Hash(code)] = sm
+}
+
+// CaptureStart implements vm.Tracer, starting the outermost call frame.
+func (p *Profiler) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	p.root = to.Hex()
+	if create {
+		p.root = "CREATE " + p.root
+	}
+	return nil
+}
+
+// CaptureState implements vm.Tracer, accounting the gas used by the previous
+// instruction of the frame and queueing the current one.
+func (p *Profiler) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Terminate any call frames that returned since the last instruction
+	for len(p.frames) > depth {
+		p.leave()
+	}
+	// Settle the previous instruction of this frame, or enter a new frame
+	if len(p.frames) == depth {
+		f := p.frames[depth-1]
+		if f.last != nil {
+			var used uint64
+			if gas < f.last.gas {
+				used = f.last.gas - gas
+			}
+			p.settle(f, used)
+		}
+	} else {
+		label := p.root
+		if len(p.frames) > 0 {
+			parent := p.frames[len(p.frames)-1]
+			label = parent.path + ";" + fmt.Sprintf("%v %s", parent.last.op, contract.Address().Hex())
+		}
+		p.frames = append(p.frames, &frame{path: label})
+		p.stat(p.calls, label).Count++
+	}
+	// Queue up the current instruction for settlement
+	code := contract.CodeHash
+	if code == (common.Hash{}) {
+		code = crypto.Keccak256Hash(contract.Code)
+	}
+	f := p.frames[len(p.frames)-1]
+	s := &step{
+		key:  pcKey{code: code, pc: pc},
+		op:   op,
+		gas:  gas,
+		cost: cost,
+		leaf: f.path + ";" + p.leafLabel(code, pc, op),
+	}
+	f.last = s
+
+	if _, ok := p.pcOps[s.key]; !ok {
+		if !p.seen(code) {
+			p.codes = append(p.codes, code)
+		}
+		p.pcOps[s.key] = op
+	}
+	p.pcStat(s.key).Count++
+	p.opStat(op).Count++
+	p.stat(p.folded, s.leaf).Count++
+
+	// An instruction failing before execution consumes all remaining gas
+	if err != nil {
+		f.fault = true
+	}
+	return nil
+}
+
+// CaptureFault implements vm.Tracer, marking the current frame as exceptionally
+// halted. Reverts are not faults as they refund the remaining gas.
+func (p *Profiler) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if op != vm.REVERT && depth > 0 && depth <= len(p.frames) {
+		p.frames[depth-1].fault = true
+	}
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, terminating all pending call frames.
+func (p *Profiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	for len(p.frames) > 0 {
+		p.leave()
+	}
+	return nil
+}
+
+// leave terminates the innermost call frame, settling its last instruction and
+// charging its gas usage to the instruction of the parent frame spawning it.
+func (p *Profiler) leave() {
+	f := p.frames[len(p.frames)-1]
+	if f.last != nil {
+		used := f.last.cost
+		if f.fault {
+			used = f.last.gas
+		}
+		p.settle(f, used)
+	}
+	p.frames = p.frames[:len(p.frames)-1]
+
+	if len(p.frames) > 0 {
+		if parent := p.frames[len(p.frames)-1]; parent.last != nil {
+			parent.last.child += f.used
+		}
+	}
+}
+
+// settle accounts the gas used by the last instruction of a frame, excluding the
+// gas consumed by any sub-calls it made.
+func (p *Profiler) settle(f *frame, used uint64) {
+	s := f.last
+	f.last = nil
+	f.used += used
+
+	self := uint64(0)
+	if used > s.child {
+		self = used - s.child
+	}
+	p.pcStat(s.key).Gas += self
+	p.opStat(s.op).Gas += self
+	p.stat(p.folded, s.leaf).Gas += self
+	p.stat(p.calls, f.path).Gas += self
+}
+
+// leafLabel returns the name of an instruction within a folded stack, which is
+// its source location if known, or its opcode otherwise.
+func (p *Profiler) leafLabel(code common.Hash, pc uint64, op vm.OpCode) string {
+	if sm := p.maps[code]; sm != nil {
+		if loc := sm.Lookup(pc); loc != nil {
+			return loc.String()
+		}
+	}
+	return op.String()
+}
+
+func (p *Profiler) seen(code common.Hash) bool {
+	for _, c := range p.codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Profiler) stat(set map[string]*Stat, key string) *Stat {
+	if set[key] == nil {
+		set[key] = new(Stat)
+	}
+	return set[key]
+}
+
+func (p *Profiler) opStat(op vm.OpCode) *Stat {
+	if p.ops[op] == nil {
+		p.ops[op] = new(Stat)
+	}
+	return p.ops[op]
+}
+
+func (p *Profiler) pcStat(key pcKey) *Stat {
+	if p.pcs[key] == nil {
+		p.pcs[key] = new(Stat)
+	}
+	return p.pcs[key]
+}
+
+// Opcode returns the aggregated statistics of an opcode.
+func (p *Profiler) Opcode(op vm.OpCode) Stat {
+	if s := p.ops[op]; s != nil {
+		return *s
+	}
+	return Stat{}
+}
+
+// Instruction returns the aggregated statistics of the instruction at the given
+// program counter of a piece of bytecode.
+func (p *Profiler) Instruction(code common.Hash, pc uint64) Stat {
+	if s := p.pcs[pcKey{code: code, pc: pc}]; s != nil {
+		return *s
+	}
+	return Stat{}
+}
+
+// Frame returns the aggregated statistics of a call frame, identified by its
+// folded stack (e.g. "0x...01;CALL 0x...02"). The gas excludes sub-calls.
+func (p *Profiler) Frame(path string) Stat {
+	if s := p.calls[path]; s != nil {
+		return *s
+	}
+	return Stat{}
+}
+
+// GasUsed returns the total gas consumed by all profiled instructions.
+func (p *Profiler) GasUsed() uint64 {
+	var total uint64
+	for _, s := range p.ops {
+		total += s.Gas
+	}
+	return total
+}
+
+// WriteFolded writes the gas usage in the folded stack format consumed by
+// flame graph tools (e.g. flamegraph.pl or speedscope).
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack, s := range p.folded {
+		if s.Gas > 0 {
+			stacks = append(stacks, stack)
+		}
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.folded[stack].Gas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteReport writes a human readable summary of the profile, listing the
+// opcodes by gas usage, the call frames and every executed instruction.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	total := p.GasUsed()
+	percent := func(gas uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(gas) / float64(total)
+	}
+	fmt.Fprintf(w, "Total gas used: %d\n\n", total)
+
+	// Report the opcodes, most expensive first
+	ops := make([]vm.OpCode, 0, len(p.ops))
+	for op := range p.ops {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if p.ops[ops[i]].Gas != p.ops[ops[j]].Gas {
+			return p.ops[ops[i]].Gas > p.ops[ops[j]].Gas
+		}
+		return ops[i] < ops[j]
+	})
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPCODE\tCOUNT\tGAS\t%")
+	for _, op := range ops {
+		s := p.ops[op]
+		fmt.Fprintf(tw, "%v\t%d\t%d\t%.2f\n", op, s.Count, s.Gas, percent(s.Gas))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Report the call frames in stack order, with their inclusive gas usage
+	paths := make([]string, 0, len(p.calls))
+	for path := range p.calls {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CALL FRAME\tCALLS\tSELF GAS\tTOTAL GAS")
+	for _, path := range paths {
+		var inclusive uint64
+		for _, other := range paths {
+			if other == path || strings.HasPrefix(other, path+";") {
+				inclusive += p.calls[other].Gas
+			}
+		}
+		depth := strings.Count(path, ";")
+		name := path[strings.LastIndex(path, ";")+1:]
+		fmt.Fprintf(tw, "%s%s\t%d\t%d\t%d\n", strings.Repeat("  ", depth), name, p.calls[path].Count, p.calls[path].Gas, inclusive)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Report every executed instruction, grouped by bytecode
+	for _, code := range p.codes {
+		var pcs []uint64
+		for key := range p.pcOps {
+			if key.code == code {
+				pcs = append(pcs, key.pc)
+			}
+		}
+		sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+
+		fmt.Fprintf(w, "\nCode %x\n", code)
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "PC\tOPCODE\tCOUNT\tGAS\tSOURCE")
+		for _, pc := range pcs {
+			key := pcKey{code: code, pc: pc}
+			source := "-"
+			if sm := p.maps[code]; sm != nil {
+				if loc := sm.Lookup(pc); loc != nil {
+					source = loc.String()
+				}
+			}
+			s := p.pcs[key]
+			fmt.Fprintf(tw, "%d\t%v\t%d\t%d\t%s\n", pc, p.pcOps[key], s.Count, s.Gas, source)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
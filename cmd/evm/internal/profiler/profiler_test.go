@@ -0,0 +1,142 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	// callerCode calls the contract at 0xbb with 0xfffff gas and discards the result.
+	callerCode = common.Hex2Bytes("6000600060006000600060bb620fffff" + "f15000")
+	// calleeCode stores 1 in slot 0.
+	calleeCode = common.Hex2Bytes("600160005500")
+)
+
+// Tests that gas is attributed to the instructions and call frames consuming
+// it, summing up to the total gas used by the execution.
+func TestProfilerCallFrames(t *testing.T) {
+	var (
+		caller = common.HexToAddress("0xaa")
+		callee = common.HexToAddress("0xbb")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetCode(caller, callerCode)
+	statedb.SetCode(callee, calleeCode)
+
+	prof := New()
+	cfg := &runtime.Config{
+		State:     statedb,
+		GasLimit:  2000000,
+		EVMConfig: vm.Config{Debug: true, Tracer: prof},
+	}
+	_, leftOver, err := runtime.Call(caller, nil, cfg)
+	if err != nil {
+		t.Fatalf("failed to execute code: %v", err)
+	}
+	if used := cfg.GasLimit - leftOver; prof.GasUsed() != used {
+		t.Errorf("total gas mismatch: have %d, want %d", prof.GasUsed(), used)
+	}
+	// The call only pays for itself, the storage write is charged to the callee
+	if have, want := prof.Opcode(vm.CALL), (Stat{Count: 1, Gas: 700}); have != want {
+		t.Errorf("CALL stats mismatch: have %+v, want %+v", have, want)
+	}
+	if have, want := prof.Opcode(vm.SSTORE), (Stat{Count: 1, Gas: 20000}); have != want {
+		t.Errorf("SSTORE stats mismatch: have %+v, want %+v", have, want)
+	}
+	if have, want := prof.Instruction(crypto.Keccak256Hash(calleeCode), 4), (Stat{Count: 1, Gas: 20000}); have != want {
+		t.Errorf("callee instruction stats mismatch: have %+v, want %+v", have, want)
+	}
+	root := caller.Hex()
+	if have, want := prof.Frame(root), (Stat{Count: 1, Gas: 6*3 + 3 + 700 + 2}); have != want {
+		t.Errorf("caller frame mismatch: have %+v, want %+v", have, want)
+	}
+	if have, want := prof.Frame(root+";CALL "+callee.Hex()), (Stat{Count: 1, Gas: 3 + 3 + 20000}); have != want {
+		t.Errorf("callee frame mismatch: have %+v, want %+v", have, want)
+	}
+	// Ensure the folded stacks nest the callee into the caller
+	var folded bytes.Buffer
+	if err := prof.WriteFolded(&folded); err != nil {
+		t.Fatalf("failed to write folded stacks: %v", err)
+	}
+	if want := root + ";CALL " + callee.Hex() + ";SSTORE 20000\n"; !strings.Contains(folded.String(), want) {
+		t.Errorf("folded stacks missing %q:\n%s", want, folded.String())
+	}
+}
+
+// Tests that exceptional halts are charged all the remaining gas of the frame.
+func TestProfilerFault(t *testing.T) {
+	prof := New()
+	cfg := &runtime.Config{
+		GasLimit:  100000,
+		EVMConfig: vm.Config{Debug: true, Tracer: prof},
+	}
+	// PUSH1 1, followed by an invalid opcode
+	if _, _, err := runtime.Execute(common.Hex2Bytes("6001fe"), nil, cfg); err == nil {
+		t.Fatalf("expected invalid opcode failure")
+	}
+	if prof.GasUsed() != cfg.GasLimit {
+		t.Errorf("total gas mismatch: have %d, want %d", prof.GasUsed(), cfg.GasLimit)
+	}
+	if have, want := prof.Opcode(vm.PUSH1), (Stat{Count: 1, Gas: 3}); have != want {
+		t.Errorf("PUSH1 stats mismatch: have %+v, want %+v", have, want)
+	}
+}
+
+// Tests that compressed solc source maps are decoded and resolved to lines.
+func TestSourceMap(t *testing.T) {
+	sources := []*Source{
+		NewSource("a.sol", []byte("contract A {\n  uint x;\n  function f() { x = 1; }\n}\n")),
+	}
+	// PUSH1 1, PUSH1 0, SSTORE, STOP: the last instruction belongs to no source
+	code := common.Hex2Bytes("600160005500")
+	sm, err := ParseSourceMap(code, "0:50:0:-;39:5;;-1:0:-1", sources)
+	if err != nil {
+		t.Fatalf("failed to parse source map: %v", err)
+	}
+	tests := []struct {
+		pc   uint64
+		want string
+	}{
+		{0, "a.sol:1"},
+		{2, "a.sol:3"},
+		{4, "a.sol:3"},
+		{5, ""},
+		{1, ""}, // push data, not an instruction
+	}
+	for _, tt := range tests {
+		var have string
+		if loc := sm.Lookup(tt.pc); loc != nil {
+			have = loc.String()
+		}
+		if have != tt.want {
+			t.Errorf("pc %d: location mismatch: have %q, want %q", tt.pc, have, tt.want)
+		}
+	}
+	if _, err := ParseSourceMap(code, "1000:1:0", sources); err == nil {
+		t.Errorf("expected out of range source offset failure")
+	}
+}
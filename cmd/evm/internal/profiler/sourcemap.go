@@ -0,0 +1,124 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Source is a single source file referenced by a solc source map.
+type Source struct {
+	Name    string
+	Content []byte
+
+	lines []int // Byte offsets of the line starts within the content
+}
+
+// NewSource creates a source file with the given name and content.
+func NewSource(name string, content []byte) *Source {
+	lines := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &Source{Name: name, Content: content, lines: lines}
+}
+
+// line returns the 1-based line number containing the given byte offset.
+func (s *Source) line(offset int) int {
+	return sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+}
+
+// Location is a position within a source file.
+type Location struct {
+	File string
+	Line int
+}
+
+// String implements fmt.Stringer.
+func (l *Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// SourceMap maps the program counters of a piece of bytecode to locations in
+// the sources it was compiled from.
+type SourceMap struct {
+	locs []*Location    // Source location of each instruction, nil if unmapped
+	pcs  map[uint64]int // Instruction index of each program counter
+}
+
+// ParseSourceMap parses a compressed solc source map (the srcmap or
+// srcmap-runtime compiler output) belonging to the given bytecode. The sources
+// must be ordered by their solc source index.
+func ParseSourceMap(code []byte, srcmap string, sources []*Source) (*SourceMap, error) {
+	sm := &SourceMap{pcs: make(map[uint64]int)}
+
+	// Index the instructions of the bytecode, skipping over push data
+	for pc, idx := uint64(0), 0; pc < uint64(len(code)); idx++ {
+		sm.pcs[pc] = idx
+		if op := vm.OpCode(code[pc]); op.IsPush() {
+			pc += uint64(op - vm.PUSH1 + 1)
+		}
+		pc++
+	}
+	// Decompress the source map, every empty field inheriting the previous one
+	var start, file int
+	for i, entry := range strings.Split(strings.TrimSpace(srcmap), ";") {
+		fields := strings.Split(entry, ":")
+		if len(fields) > 0 && fields[0] != "" {
+			n, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: invalid source offset %q", i, fields[0])
+			}
+			start = n
+		}
+		if len(fields) > 2 && fields[2] != "" {
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: invalid source index %q", i, fields[2])
+			}
+			file = n
+		}
+		// Instructions not belonging to any of the sources remain unmapped
+		if file < 0 || file >= len(sources) {
+			sm.locs = append(sm.locs, nil)
+			continue
+		}
+		src := sources[file]
+		if start < 0 || start > len(src.Content) {
+			return nil, fmt.Errorf("entry %d: source offset %d out of range for %s", i, start, src.Name)
+		}
+		sm.locs = append(sm.locs, &Location{File: src.Name, Line: src.line(start)})
+	}
+	return sm, nil
+}
+
+// Lookup returns the source location of the instruction at the given program
+// counter, or nil if it is not covered by the source map.
+func (sm *SourceMap) Lookup(pc uint64) *Location {
+	idx, ok := sm.pcs[pc]
+	if !ok || idx >= len(sm.locs) {
+		return nil
+	}
+	return sm.locs[idx]
+}
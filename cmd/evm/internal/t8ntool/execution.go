@@ -0,0 +1,231 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"golang.org/x/crypto/sha3"
+)
+
+// Prestate is the state and environment a set of transactions is applied to.
+type Prestate struct {
+	Env stEnv             `json:"env"`
+	Pre core.GenesisAlloc `json:"pre"`
+}
+
+// ExecutionResult contains the execution status after applying a set of
+// transactions: the post-state root, the roots and bloom of the would-be block
+// and the receipts of the included transactions.
+type ExecutionResult struct {
+	StateRoot   common.Hash    `json:"stateRoot"`
+	TxRoot      common.Hash    `json:"txRoot"`
+	ReceiptRoot common.Hash    `json:"receiptRoot"`
+	LogsHash    common.Hash    `json:"logsHash"`
+	Bloom       types.Bloom    `json:"logsBloom"`
+	Receipts    types.Receipts `json:"receipts"`
+	Rejected    []*rejectedTx  `json:"rejected,omitempty"`
+}
+
+// rejectedTx is a transaction that could not be included, along with the
+// reason of its rejection.
+type rejectedTx struct {
+	Index int    `json:"index"`
+	Err   string `json:"error"`
+}
+
+type ommer struct {
+	Delta   uint64         `json:"delta"`
+	Address common.Address `json:"address"`
+}
+
+//go:generate gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
+type stEnv struct {
+	Coinbase    common.Address                      `json:"currentCoinbase"   gencodec:"required"`
+	Difficulty  *big.Int                            `json:"currentDifficulty" gencodec:"required"`
+	GasLimit    uint64                              `json:"currentGasLimit"   gencodec:"required"`
+	Number      uint64                              `json:"currentNumber"     gencodec:"required"`
+	Timestamp   uint64                              `json:"currentTimestamp"  gencodec:"required"`
+	BaseFee     *big.Int                            `json:"currentBaseFee,omitempty"`
+	BlockHashes map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
+	Ommers      []ommer                             `json:"ommers,omitempty"`
+}
+
+type stEnvMarshaling struct {
+	Coinbase   common.UnprefixedAddress
+	Difficulty *math.HexOrDecimal256
+	GasLimit   math.HexOrDecimal64
+	Number     math.HexOrDecimal64
+	Timestamp  math.HexOrDecimal64
+	BaseFee    *math.HexOrDecimal256
+}
+
+// chainContext implements core.ChainContext on top of the block hashes given in
+// the environment. Hashes not provided resolve to zero.
+type chainContext struct {
+	hashes map[math.HexOrDecimal64]common.Hash
+}
+
+// Engine implements core.ChainContext. The block author is always explicitly
+// given, so no consensus engine is needed.
+func (c *chainContext) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader implements core.ChainContext, returning a stub header linking to
+// its parent by hash, which is all the BLOCKHASH lookups need.
+func (c *chainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number == 0 {
+		return nil
+	}
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		ParentHash: c.hashes[math.HexOrDecimal64(number-1)],
+	}
+}
+
+// Apply applies a set of transactions to the pre-state, returning the post-state
+// and the result of the execution. Invalid transactions are skipped and reported
+// as rejected. If the mining reward is non-negative, it is credited to the
+// coinbase and the ommers of the environment.
+func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig, txs types.Transactions, miningReward int64, getTracer func(txIndex int, txHash common.Hash) (vm.Tracer, error)) (*state.StateDB, *ExecutionResult, error) {
+	var (
+		statedb  = MakePreState(ethdb.NewMemDatabase(), pre.Pre)
+		chain    = &chainContext{hashes: pre.Env.BlockHashes}
+		number   = new(big.Int).SetUint64(pre.Env.Number)
+		gaspool  = new(core.GasPool).AddGas(pre.Env.GasLimit)
+		gasUsed  = uint64(0)
+		included types.Transactions
+		receipts = make(types.Receipts, 0)
+		rejected []*rejectedTx
+	)
+	header := &types.Header{
+		ParentHash: chain.hashes[math.HexOrDecimal64(pre.Env.Number-1)],
+		Coinbase:   pre.Env.Coinbase,
+		Difficulty: pre.Env.Difficulty,
+		Number:     number,
+		GasLimit:   pre.Env.GasLimit,
+		Time:       new(big.Int).SetUint64(pre.Env.Timestamp),
+	}
+	if chainConfig.IsLondon(number) {
+		if pre.Env.BaseFee == nil {
+			return nil, nil, NewError(ErrorVMConfig, fmt.Errorf("EIP-1559 config but missing 'currentBaseFee' in env section"))
+		}
+		header.BaseFee = new(big.Int).Set(pre.Env.BaseFee)
+	}
+	// If the DAO hard-fork is enabled at this block, apply it before the
+	// transactions, just as the state processor does
+	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(number) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	blockHash := header.Hash()
+	for i, tx := range txs {
+		tracer, err := getTracer(len(included), tx.Hash())
+		if err != nil {
+			return nil, nil, err
+		}
+		vmConfig.Tracer = tracer
+		vmConfig.Debug = tracer != nil
+
+		statedb.Prepare(tx.Hash(), blockHash, len(included))
+		snapshot := statedb.Snapshot()
+
+		receipt, _, err := core.ApplyTransaction(chainConfig, chain, &pre.Env.Coinbase, gaspool, statedb, header, tx, &gasUsed, vmConfig)
+		if err != nil {
+			statedb.RevertToSnapshot(snapshot)
+			log.Info("Rejected transaction", "index", i, "hash", tx.Hash(), "err", err)
+			rejected = append(rejected, &rejectedTx{Index: i, Err: err.Error()})
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+	statedb.IntermediateRoot(chainConfig.IsEIP158(number))
+
+	// Credit the mining rewards. A zero reward still touches the coinbase, which
+	// matters if it self-destructed or no transaction paid it any fee.
+	if miningReward >= 0 {
+		var (
+			blockReward = big.NewInt(miningReward)
+			minerReward = new(big.Int).Set(blockReward)
+			perOmmer    = new(big.Int).Div(blockReward, big.NewInt(32))
+		)
+		for _, ommer := range pre.Env.Ommers {
+			// Add 1/32th for each ommer included
+			minerReward.Add(minerReward, perOmmer)
+
+			// Add (8-delta)/8 to the ommer miner
+			reward := new(big.Int).Sub(big.NewInt(8), new(big.Int).SetUint64(ommer.Delta))
+			reward.Mul(reward, blockReward)
+			reward.Div(reward, big.NewInt(8))
+			statedb.AddBalance(ommer.Address, reward)
+		}
+		statedb.AddBalance(pre.Env.Coinbase, minerReward)
+	}
+	root, err := statedb.Commit(chainConfig.IsEIP158(number))
+	if err != nil {
+		return nil, nil, NewError(ErrorEVM, fmt.Errorf("could not commit state: %v", err))
+	}
+	result := &ExecutionResult{
+		StateRoot:   root,
+		TxRoot:      types.DeriveSha(included),
+		ReceiptRoot: types.DeriveSha(receipts),
+		LogsHash:    rlpHash(statedb.Logs()),
+		Bloom:       types.CreateBloom(receipts),
+		Receipts:    receipts,
+		Rejected:    rejected,
+	}
+	return statedb, result, nil
+}
+
+// MakePreState creates a state database from the given allocation.
+func MakePreState(db ethdb.Database, accounts core.GenesisAlloc) *state.StateDB {
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+	for addr, a := range accounts {
+		statedb.SetCode(addr, a.Code)
+		statedb.SetNonce(addr, a.Nonce)
+		statedb.SetBalance(addr, a.Balance)
+		for k, v := range a.Storage {
+			statedb.SetState(addr, k, v)
+		}
+	}
+	// Commit and re-open to start with a clean state.
+	root, _ := statedb.Commit(false)
+	statedb, _ = state.New(root, sdb)
+	return statedb
+}
+
+func rlpHash(x interface{}) (h common.Hash) {
+	hw := sha3.NewLegacyKeccak256()
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// loadPrestate reads the alloc, env and txs files of a test case.
+func loadPrestate(t *testing.T, dir string) (*Prestate, types.Transactions) {
+	var (
+		pre Prestate
+		txs types.Transactions
+	)
+	for name, v := range map[string]interface{}{"alloc.json": &pre.Pre, "env.json": &pre.Env, "txs.json": &txs} {
+		blob, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if err := json.Unmarshal(blob, v); err != nil {
+			t.Fatalf("failed to decode %s: %v", name, err)
+		}
+	}
+	return &pre, txs
+}
+
+func noTracer(int, common.Hash) (vm.Tracer, error) { return nil, nil }
+
+// Tests that invalid transactions are rejected without aborting the transition,
+// and that the mining reward is credited.
+func TestApplyRejected(t *testing.T) {
+	pre, txs := loadPrestate(t, filepath.Join("..", "..", "testdata", "1"))
+
+	config := *tests.Forks["Byzantium"]
+	config.ChainID = big.NewInt(1)
+
+	statedb, result, err := pre.Apply(vm.Config{}, &config, txs, 2000000000000000000, noTracer)
+	if err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	if len(result.Receipts) != 1 {
+		t.Fatalf("receipt count mismatch: have %d, want 1", len(result.Receipts))
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Index != 1 {
+		t.Fatalf("rejected transactions mismatch: have %+v", result.Rejected)
+	}
+	if root := statedb.IntermediateRoot(true); root != result.StateRoot {
+		t.Errorf("state root mismatch: have %x, want %x", root, result.StateRoot)
+	}
+	// The coinbase receives the block reward plus the fee of the included transaction
+	want := new(big.Int).Add(big.NewInt(2000000000000000000), big.NewInt(21000*2))
+	if have := statedb.GetBalance(pre.Env.Coinbase); have.Cmp(want) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that the BLOCKHASH opcode resolves the hashes given in the environment.
+func TestApplyBlockHash(t *testing.T) {
+	pre, txs := loadPrestate(t, filepath.Join("..", "..", "testdata", "3"))
+
+	logger := vm.NewStructLogger(nil)
+	getTracer := func(int, common.Hash) (vm.Tracer, error) { return logger, nil }
+
+	if _, _, err := pre.Apply(vm.Config{}, tests.Forks["Istanbul"], txs, -1, getTracer); err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	// The code is PUSH1 1, BLOCKHASH, STOP: check the stack when stopping
+	logs := logger.StructLogs()
+	if len(logs) != 3 || len(logs[2].Stack) != 1 {
+		t.Fatalf("unexpected execution trace: %+v", logs)
+	}
+	hash := common.BigToHash(logs[2].Stack[0])
+	if want := pre.Env.BlockHashes[1]; hash != want {
+		t.Errorf("block hash mismatch: have %x, want %x", hash, want)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	ErrorEVM      = 2
+	ErrorVMConfig = 3
+
+	ErrorJson = 10
+	ErrorIO   = 11
+
+	stdinSelector = "stdin"
+)
+
+// NumberedError is an error carrying the exit code the tool should terminate
+// with, allowing callers to tell failure classes apart.
+type NumberedError struct {
+	errorCode int
+	err       error
+}
+
+// NewError wraps an error with the given exit code.
+func NewError(errorCode int, err error) *NumberedError {
+	return &NumberedError{errorCode, err}
+}
+
+func (n *NumberedError) Error() string {
+	return fmt.Sprintf("ERROR(%d): %v", n.errorCode, n.err.Error())
+}
+
+// Code returns the exit code of the error.
+func (n *NumberedError) Code() int {
+	return n.errorCode
+}
+
+type input struct {
+	Alloc core.GenesisAlloc  `json:"alloc,omitempty"`
+	Env   *stEnv             `json:"env,omitempty"`
+	Txs   types.Transactions `json:"txs,omitempty"`
+}
+
+// Main is the entry point of the transition tool: it loads the prestate, the
+// environment and the transactions, applies them and writes out the results.
+func Main(ctx *cli.Context) error {
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.Int(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	var (
+		baseDir   = ""
+		getTracer func(txIndex int, txHash common.Hash) (vm.Tracer, error)
+	)
+	// If user specified a basedir, make sure it exists
+	if ctx.IsSet(OutputBasedir.Name) {
+		if base := ctx.String(OutputBasedir.Name); len(base) > 0 {
+			if err := os.MkdirAll(base, 0755); err != nil {
+				return NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
+			}
+			baseDir = base
+		}
+	}
+	if ctx.Bool(TraceFlag.Name) {
+		// Configure the EVM logger, writing one trace file per transaction
+		logConfig := &vm.LogConfig{
+			DisableStack:  ctx.Bool(TraceDisableStackFlag.Name),
+			DisableMemory: ctx.Bool(TraceDisableMemoryFlag.Name),
+			Debug:         true,
+		}
+		var prevFile *os.File
+		defer func() {
+			if prevFile != nil {
+				prevFile.Close()
+			}
+		}()
+		getTracer = func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+			if prevFile != nil {
+				prevFile.Close()
+			}
+			traceFile, err := os.Create(path.Join(baseDir, fmt.Sprintf("trace-%d-%v.jsonl", txIndex, txHash.String())))
+			if err != nil {
+				return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace-file: %v", err))
+			}
+			prevFile = traceFile
+			return vm.NewJSONLogger(logConfig, traceFile), nil
+		}
+	} else {
+		getTracer = func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+			return nil, nil
+		}
+	}
+	// We need to load three things: alloc, env and transactions. Each may be
+	// either in a file or in a combined json object on stdin.
+	var (
+		allocStr  = ctx.String(InputAllocFlag.Name)
+		envStr    = ctx.String(InputEnvFlag.Name)
+		txStr     = ctx.String(InputTxsFlag.Name)
+		inputData = &input{}
+	)
+	if allocStr == stdinSelector || envStr == stdinSelector || txStr == stdinSelector {
+		if err := json.NewDecoder(os.Stdin).Decode(inputData); err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed unmarshaling stdin: %v", err))
+		}
+	}
+	if allocStr != stdinSelector {
+		if err := readFile(allocStr, "alloc", &inputData.Alloc); err != nil {
+			return err
+		}
+	}
+	if envStr != stdinSelector {
+		var env stEnv
+		if err := readFile(envStr, "env", &env); err != nil {
+			return err
+		}
+		inputData.Env = &env
+	}
+	if txStr != stdinSelector {
+		if err := readFile(txStr, "txs", &inputData.Txs); err != nil {
+			return err
+		}
+	}
+	if inputData.Env == nil {
+		return NewError(ErrorJson, fmt.Errorf("missing env section"))
+	}
+	prestate := &Prestate{
+		Env: *inputData.Env,
+		Pre: inputData.Alloc,
+	}
+	// Construct the chain configuration from the requested fork
+	fork := ctx.String(ForknameFlag.Name)
+	config, ok := tests.Forks[fork]
+	if !ok {
+		return NewError(ErrorVMConfig, tests.UnsupportedForkError{Name: fork})
+	}
+	chainConfig := new(params.ChainConfig)
+	*chainConfig = *config
+	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
+
+	// Run the transactions and dump the execution result
+	statedb, result, err := prestate.Apply(vm.Config{}, chainConfig, inputData.Txs, ctx.Int64(RewardFlag.Name), getTracer)
+	if err != nil {
+		return err
+	}
+	return dispatchOutput(ctx, baseDir, result, dumpAlloc(statedb))
+}
+
+// readFile decodes the json contents of the named file into v.
+func readFile(filename, kind string, v interface{}) error {
+	inFile, err := os.Open(filename)
+	if err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed reading %s file: %v", kind, err))
+	}
+	defer inFile.Close()
+
+	if err := json.NewDecoder(inFile).Decode(v); err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed unmarshaling %s-file: %v", kind, err))
+	}
+	return nil
+}
+
+// dumpAlloc converts the content of a state database into genesis format.
+func dumpAlloc(statedb *state.StateDB) core.GenesisAlloc {
+	alloc := make(core.GenesisAlloc)
+	for addr, account := range statedb.RawDump().Accounts {
+		balance, _ := new(big.Int).SetString(account.Balance, 10)
+		genesisAccount := core.GenesisAccount{
+			Code:    common.FromHex(account.Code),
+			Balance: balance,
+			Nonce:   account.Nonce,
+		}
+		if len(account.Storage) > 0 {
+			genesisAccount.Storage = make(map[common.Hash]common.Hash)
+			for k, v := range account.Storage {
+				// Storage values are kept rlp encoded in the trie
+				_, content, _, _ := rlp.Split(common.FromHex(v))
+				genesisAccount.Storage[common.HexToHash(k)] = common.BytesToHash(content)
+			}
+		}
+		alloc[common.HexToAddress(addr)] = genesisAccount
+	}
+	return alloc
+}
+
+// saveFile marshals the object to the given file.
+func saveFile(baseDir, filename string, data interface{}) error {
+	b, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+	}
+	if err = ioutil.WriteFile(path.Join(baseDir, filename), b, 0644); err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed writing output: %v", err))
+	}
+	return nil
+}
+
+// dispatchOutput writes the output data to either stderr or stdout, or to the
+// specified files.
+func dispatchOutput(ctx *cli.Context, baseDir string, result *ExecutionResult, alloc core.GenesisAlloc) error {
+	stdOutObject := make(map[string]interface{})
+	stdErrObject := make(map[string]interface{})
+	dispatch := func(fName, name string, obj interface{}) error {
+		switch fName {
+		case "stdout":
+			stdOutObject[name] = obj
+		case "stderr":
+			stdErrObject[name] = obj
+		default: // save to file
+			return saveFile(baseDir, fName, obj)
+		}
+		return nil
+	}
+	if err := dispatch(ctx.String(OutputAllocFlag.Name), "alloc", alloc); err != nil {
+		return err
+	}
+	if err := dispatch(ctx.String(OutputResultFlag.Name), "result", result); err != nil {
+		return err
+	}
+	if err := writeJSON(os.Stdout, stdOutObject); err != nil {
+		return err
+	}
+	return writeJSON(os.Stderr, stdErrObject)
+}
+
+// writeJSON marshals the non-empty output object to the given stream.
+func writeJSON(out *os.File, obj map[string]interface{}) error {
+	if len(obj) == 0 {
+		return nil
+	}
+	b, err := json.MarshalIndent(obj, "", " ")
+	if err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+	}
+	out.Write(append(b, '\n'))
+	return nil
+}
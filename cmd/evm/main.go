@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"os"
 
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -110,8 +111,46 @@ var (
 		Name:  "nostack",
 		Usage: "disable stack output",
 	}
+	GasProfileFlag = cli.StringFlag{
+		Name:  "gasprofile",
+		Usage: "writes a gas profile per opcode, call frame and instruction to the given path",
+	}
+	FlameGraphFlag = cli.StringFlag{
+		Name:  "flamegraph",
+		Usage: "writes the gas profile as folded stacks for flame graph tools to the given path",
+	}
+	SourceMapFlag = cli.StringFlag{
+		Name:  "srcmap",
+		Usage: "file containing the solc source map of the code, used by the gas profile",
+	}
+	SourcesFlag = cli.StringFlag{
+		Name:  "sources",
+		Usage: "comma separated source files of the source map, in solc source index order",
+	}
 )
 
+var transitionCommand = cli.Command{
+	Name:    "transition",
+	Aliases: []string{"t8n"},
+	Usage:   "executes a full state transition",
+	Action:  t8ntool.Main,
+	Flags: []cli.Flag{
+		t8ntool.TraceFlag,
+		t8ntool.TraceDisableMemoryFlag,
+		t8ntool.TraceDisableStackFlag,
+		t8ntool.OutputBasedir,
+		t8ntool.OutputAllocFlag,
+		t8ntool.OutputResultFlag,
+		t8ntool.InputAllocFlag,
+		t8ntool.InputEnvFlag,
+		t8ntool.InputTxsFlag,
+		t8ntool.ForknameFlag,
+		t8ntool.ChainIDFlag,
+		t8ntool.RewardFlag,
+		t8ntool.VerbosityFlag,
+	},
+}
+
 func init() {
 	app.Flags = []cli.Flag{
 		CreateFlag,
@@ -133,18 +172,30 @@ func init() {
 		ReceiverFlag,
 		DisableMemoryFlag,
 		DisableStackFlag,
+		GasProfileFlag,
+		FlameGraphFlag,
+		SourceMapFlag,
+		SourcesFlag,
+		utils.EVMInterpreterFlag,
+		utils.EWASMInterpreterFlag,
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
 		disasmCommand,
 		runCommand,
 		stateTestCommand,
+		fillTestCommand,
+		transitionCommand,
 	}
 }
 
 func main() {
 	if err := app.Run(os.Args); err != nil {
+		code := 1
+		if ec, ok := err.(*t8ntool.NumberedError); ok {
+			code = ec.Code()
+		}
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(code)
 	}
 }
@@ -20,14 +20,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"os"
 	goruntime "runtime"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/compiler"
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/profiler"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -96,6 +99,14 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = vm.NewStructLogger(logconfig)
 	}
+	var prof *profiler.Profiler
+	if ctx.GlobalString(GasProfileFlag.Name) != "" || ctx.GlobalString(FlameGraphFlag.Name) != "" {
+		if tracer != nil {
+			utils.Fatalf("Gas profiling cannot be combined with --%s or --%s", DebugFlag.Name, MachineFlag.Name)
+		}
+		prof = profiler.New()
+		tracer = prof
+	}
 	if ctx.GlobalString(GenesisFlag.Name) != "" {
 		gen := readGenesis(ctx.GlobalString(GenesisFlag.Name))
 		genesisConfig = gen
@@ -171,8 +182,10 @@ func runCmd(ctx *cli.Context) error {
 		Coinbase:    genesisConfig.Coinbase,
 		BlockNumber: new(big.Int).SetUint64(genesisConfig.Number),
 		EVMConfig: vm.Config{
-			Tracer: tracer,
-			Debug:  ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
+			Tracer:           tracer,
+			Debug:            ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || prof != nil,
+			EVMInterpreter:   ctx.GlobalString(utils.EVMInterpreterFlag.Name),
+			EWASMInterpreter: ctx.GlobalString(utils.EWASMInterpreterFlag.Name),
 		},
 	}
 
@@ -192,19 +205,41 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
+	// Attach the source map to the executed code if profiling a compiled contract
+	input := common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))
+	if ctx.GlobalBool(CreateFlag.Name) {
+		input = append(code, input...)
+	}
+	if prof != nil && ctx.GlobalString(SourceMapFlag.Name) != "" {
+		sourceCode := code
+		if ctx.GlobalBool(CreateFlag.Name) {
+			sourceCode = input
+		} else if len(code) == 0 {
+			sourceCode = statedb.GetCode(receiver)
+		}
+		prof.AddSourceMap(sourceCode, readSourceMap(ctx, sourceCode))
+	}
 	tstart := time.Now()
 	var leftOverGas uint64
 	if ctx.GlobalBool(CreateFlag.Name) {
-		input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
 		ret, _, leftOverGas, err = runtime.Create(input, &runtimeConfig)
 	} else {
 		if len(code) > 0 {
 			statedb.SetCode(receiver, code)
 		}
-		ret, leftOverGas, err = runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
+		ret, leftOverGas, err = runtime.Call(receiver, input, &runtimeConfig)
 	}
 	execTime := time.Since(tstart)
 
+	if prof != nil {
+		if path := ctx.GlobalString(GasProfileFlag.Name); path != "" {
+			writeProfile(path, prof.WriteReport)
+		}
+		if path := ctx.GlobalString(FlameGraphFlag.Name); path != "" {
+			writeProfile(path, prof.WriteFolded)
+		}
+	}
+
 	if ctx.GlobalBool(DumpFlag.Name) {
 		statedb.Commit(true)
 		statedb.IntermediateRoot(true)
@@ -245,7 +280,7 @@ Gas used:           %d
 
 `, execTime, mem.HeapObjects, mem.Alloc, mem.TotalAlloc, mem.NumGC, initialGas-leftOverGas)
 	}
-	if tracer == nil {
+	if tracer == nil || prof != nil {
 		fmt.Printf("0x%x\n", ret)
 		if err != nil {
 			fmt.Printf(" error: %v\n", err)
@@ -254,3 +289,46 @@ Gas used:           %d
 
 	return nil
 }
+
+// readSourceMap loads the solc source map and the sources referenced by it for
+// the given bytecode.
+func readSourceMap(ctx *cli.Context, code []byte) *profiler.SourceMap {
+	srcmap, err := ioutil.ReadFile(ctx.GlobalString(SourceMapFlag.Name))
+	if err != nil {
+		utils.Fatalf("Could not load source map: %v", err)
+	}
+	var sources []*profiler.Source
+	if names := ctx.GlobalString(SourcesFlag.Name); names != "" {
+		for _, name := range strings.Split(names, ",") {
+			content, err := ioutil.ReadFile(name)
+			if err != nil {
+				utils.Fatalf("Could not load source file: %v", err)
+			}
+			sources = append(sources, profiler.NewSource(name, content))
+		}
+	}
+	sm, err := profiler.ParseSourceMap(code, string(srcmap), sources)
+	if err != nil {
+		utils.Fatalf("Invalid source map: %v", err)
+	}
+	return sm
+}
+
+// writeProfile writes a gas profile to the given path, or to stderr if it is "-".
+func writeProfile(path string, write func(io.Writer) error) {
+	if path == "-" {
+		if err := write(os.Stderr); err != nil {
+			utils.Fatalf("Could not write gas profile: %v", err)
+		}
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		utils.Fatalf("Could not create gas profile: %v", err)
+	}
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		utils.Fatalf("Could not write gas profile: %v", err)
+	}
+}
@@ -19,17 +19,25 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	mnemonicPassphraseFlag = cli.BoolFlag{
+		Name:  "mnemonic.passphrase",
+		Usage: "Prompt for an optional BIP-39 passphrase extending the mnemonic",
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage Ethereum presale wallets",
@@ -188,6 +196,83 @@ this import mechanism is not needed when you transfer an account between
 nodes.
 `,
 			},
+			{
+				Name:     "hd",
+				Usage:    "Manage hierarchical deterministic wallets",
+				Category: "ACCOUNT COMMANDS",
+				Description: `
+HD wallets derive any number of accounts from a single BIP-39 mnemonic, which
+is all that needs to be backed up. The seed of the mnemonic is stored encrypted
+in the keystore, along with the derivation paths of the accounts in use.`,
+				Subcommands: []cli.Command{
+					{
+						Name:   "new",
+						Usage:  "Create a new HD wallet",
+						Action: utils.MigrateFlags(accountHDNew),
+						Flags: []cli.Flag{
+							utils.DataDirFlag,
+							utils.KeyStoreDirFlag,
+							utils.PasswordFileFlag,
+							utils.LightKDFFlag,
+							mnemonicPassphraseFlag,
+						},
+						Description: `
+    geth account hd new
+
+Generates a new 24 word mnemonic, stores its seed in the keystore and prints
+the mnemonic along with the first account of the wallet.
+
+The mnemonic is not stored anywhere: write it down, it is the only way to
+recover the accounts of the wallet if the keystore is lost.
+
+The seed is saved in encrypted format, you are prompted for a passphrase. With
+--mnemonic.passphrase you are prompted for an additional BIP-39 passphrase,
+which is needed together with the mnemonic to recover the wallet.
+`,
+					},
+					{
+						Name:      "import",
+						Usage:     "Import a BIP-39 mnemonic into a new HD wallet",
+						Action:    utils.MigrateFlags(accountHDImport),
+						ArgsUsage: "[<mnemonicFile>]",
+						Flags: []cli.Flag{
+							utils.DataDirFlag,
+							utils.KeyStoreDirFlag,
+							utils.PasswordFileFlag,
+							utils.LightKDFFlag,
+							mnemonicPassphraseFlag,
+						},
+						Description: `
+    geth account hd import [<mnemonicFile>]
+
+Imports the mnemonic contained in <mnemonicFile>, or prompted for if no file is
+given, and prints the first account of the wallet.
+
+The seed is saved in encrypted format, you are prompted for a passphrase. Use
+--mnemonic.passphrase if the mnemonic is protected by a BIP-39 passphrase.
+`,
+					},
+					{
+						Name:      "derive",
+						Usage:     "Derive and add an account to an HD wallet",
+						Action:    utils.MigrateFlags(accountHDDerive),
+						ArgsUsage: "<wallet> <path>",
+						Flags: []cli.Flag{
+							utils.DataDirFlag,
+							utils.KeyStoreDirFlag,
+							utils.PasswordFileFlag,
+						},
+						Description: `
+    geth account hd derive <wallet> <path>
+
+Derives the account at <path> from the HD wallet stored in the seed file or URL
+<wallet>, as printed by 'geth account list', and adds it to the accounts of the
+wallet. Relative paths are appended to m/44'/60'/0'/0, so the first account is
+at path 0, the second at path 1, etc.
+`,
+					},
+				},
+			},
 		},
 	}
 )
@@ -316,6 +401,16 @@ func accountCreate(ctx *cli.Context) error {
 	return nil
 }
 
+// fetchKeystore retrieves the local keystore of the node, failing if signing is
+// delegated to an external signer.
+func fetchKeystore(stack *node.Node) *keystore.KeyStore {
+	keystores := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(keystores) == 0 {
+		utils.Fatalf("Local keystore not available when using an external signer")
+	}
+	return keystores[0].(*keystore.KeyStore)
+}
+
 // accountUpdate transitions an account from a previous format to the current
 // one, also providing the possibility to change the pass-phrase.
 func accountUpdate(ctx *cli.Context) error {
@@ -323,7 +418,7 @@ func accountUpdate(ctx *cli.Context) error {
 		utils.Fatalf("No accounts specified to update")
 	}
 	stack, _ := makeConfigNode(ctx)
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 
 	for _, addr := range ctx.Args() {
 		account, oldPassword := unlockAccount(ctx, ks, addr, 0, nil)
@@ -348,7 +443,7 @@ func importWallet(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportPreSaleKey(keyJSON, passphrase)
 	if err != nil {
 		utils.Fatalf("%v", err)
@@ -369,7 +464,7 @@ func accountImport(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	ks := fetchKeystore(stack)
 	acct, err := ks.ImportECDSA(key, passphrase)
 	if err != nil {
 		utils.Fatalf("Could not create the account: %v", err)
@@ -377,3 +472,114 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// fetchHDKeystore retrieves the HD wallet backend of the node, failing if signing
+// is delegated to an external signer.
+func fetchHDKeystore(stack *node.Node) *hdwallet.KeyStore {
+	keystores := stack.AccountManager().Backends(hdwallet.KeyStoreType)
+	if len(keystores) == 0 {
+		utils.Fatalf("HD wallets not available when using an external signer")
+	}
+	return keystores[0].(*hdwallet.KeyStore)
+}
+
+// getMnemonicPassphrase requests the optional BIP-39 passphrase of a mnemonic if
+// asked to by the CLI flags.
+func getMnemonicPassphrase(ctx *cli.Context, confirmation bool) string {
+	if !ctx.Bool(mnemonicPassphraseFlag.Name) {
+		return ""
+	}
+	fmt.Println("Please give the BIP-39 passphrase of the mnemonic.")
+	return getPassPhrase("", confirmation, 0, nil)
+}
+
+// accountHDNew generates a new mnemonic and stores it as an HD wallet.
+func accountHDNew(ctx *cli.Context) error {
+	mnemonic, err := hdwallet.NewMnemonic(256)
+	if err != nil {
+		utils.Fatalf("Failed to generate mnemonic: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchHDKeystore(stack)
+
+	mnemonicPassphrase := getMnemonicPassphrase(ctx, true)
+	password := getPassPhrase("Your new wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	wallet, account, err := ks.NewWallet(mnemonic, mnemonicPassphrase, password)
+	if err != nil {
+		utils.Fatalf("Failed to create wallet: %v", err)
+	}
+	fmt.Printf("Mnemonic: %s\n\n", mnemonic)
+	fmt.Println("Write the mnemonic down and keep it safe. It is not stored anywhere, and is the")
+	fmt.Println("only way to recover the accounts of the wallet if the keystore is lost.")
+	fmt.Println()
+	fmt.Printf("Wallet: %s\n", wallet.URL())
+	fmt.Printf("Address: {%x}\n", account.Address)
+	return nil
+}
+
+// accountHDImport stores an existing mnemonic as an HD wallet.
+func accountHDImport(ctx *cli.Context) error {
+	var mnemonic string
+	if file := ctx.Args().First(); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = string(blob)
+	} else {
+		input, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = input
+	}
+	if err := hdwallet.ValidateMnemonic(mnemonic); err != nil {
+		utils.Fatalf("Failed to import the mnemonic: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := fetchHDKeystore(stack)
+
+	mnemonicPassphrase := getMnemonicPassphrase(ctx, false)
+	password := getPassPhrase("Your new wallet is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	wallet, account, err := ks.NewWallet(strings.TrimSpace(mnemonic), mnemonicPassphrase, password)
+	if err != nil {
+		utils.Fatalf("Failed to import wallet: %v", err)
+	}
+	fmt.Printf("Wallet: %s\n", wallet.URL())
+	fmt.Printf("Address: {%x}\n", account.Address)
+	return nil
+}
+
+// accountHDDerive pins a new account to an existing HD wallet.
+func accountHDDerive(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("Wallet and derivation path must be given as arguments")
+	}
+	path, err := accounts.ParseDerivationPath(ctx.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	wallet, err := fetchHDKeystore(stack).Find(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not find the wallet: %v", err)
+	}
+	password := getPassPhrase("Unlocking wallet "+wallet.URL().String(), false, 0, utils.MakePasswordList(ctx))
+	if err := wallet.Open(password); err != nil {
+		utils.Fatalf("Failed to unlock the wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	if status, _ := wallet.Status(); status != "Unlocked" {
+		utils.Fatalf("Failed to unlock the wallet: %v", keystore.ErrDecrypt)
+	}
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		utils.Fatalf("Failed to derive the account: %v", err)
+	}
+	fmt.Printf("Path: %s\n", path)
+	fmt.Printf("Address: {%x}\n", account.Address)
+	return nil
+}
@@ -71,6 +71,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.USBBasePathsFlag,
+			utils.USBLedgerLiveFlag,
+			utils.PKCS11ModuleFlag,
+			utils.PKCS11SlotFlag,
+			utils.PKCS11LabelsFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
@@ -82,6 +87,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.ULCTrustedNodesFlag,
+			utils.ULCMinTrustedFractionFlag,
 		},
 	},
 	{
@@ -143,6 +150,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.ExternalSignerFlag,
 		},
 	},
 	{
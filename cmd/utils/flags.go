@@ -127,6 +127,35 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	USBBasePathsFlag = cli.StringFlag{
+		Name:  "usb.basepaths",
+		Usage: "Comma separated derivation paths from which USB hardware wallet accounts are discovered (default = standard and legacy Ledger paths)",
+		Value: "",
+	}
+	USBLedgerLiveFlag = cli.BoolFlag{
+		Name:  "usb.ledgerlive",
+		Usage: "Discover accounts from the USB base paths along the Ledger Live layout (account index increasing)",
+	}
+	PKCS11ModuleFlag = cli.StringFlag{
+		Name:  "pkcs11.module",
+		Usage: "Path of the PKCS#11 library of a hardware security module to sign with",
+		Value: "",
+	}
+	PKCS11SlotFlag = cli.UintFlag{
+		Name:  "pkcs11.slot",
+		Usage: "Slot of the PKCS#11 token holding the signing keys",
+		Value: 0,
+	}
+	PKCS11LabelsFlag = cli.StringFlag{
+		Name:  "pkcs11.labels",
+		Usage: "Comma separated labels of the PKCS#11 signing keys (default = all secp256k1 keys of the token)",
+		Value: "",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (url or path to ipc file)",
+		Value: "",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -190,6 +219,16 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	// Ultra light client settings
+	ULCTrustedNodesFlag = cli.StringFlag{
+		Name:  "ulc.trusted",
+		Usage: "Comma separated enode URLs of trusted LES servers (enables ultra light client mode)",
+	}
+	ULCMinTrustedFractionFlag = cli.IntFlag{
+		Name:  "ulc.fraction",
+		Usage: "Minimum percentage of trusted LES servers that have to announce a new head (1-100)",
+		Value: eth.DefaultULCMinTrustedFraction,
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -626,12 +665,12 @@ var (
 
 	EWASMInterpreterFlag = cli.StringFlag{
 		Name:  "vm.ewasm",
-		Usage: "External ewasm configuration (default = built-in interpreter)",
+		Usage: "External ewasm configuration as an EVMC module path[,option=value...] (default = built-in interpreter)",
 		Value: "",
 	}
 	EVMInterpreterFlag = cli.StringFlag{
 		Name:  "vm.evm",
-		Usage: "External EVM configuration (default = built-in interpreter)",
+		Usage: "External EVM configuration as an EVMC module path[,option=value...] (default = built-in interpreter)",
 		Value: "",
 	}
 )
@@ -850,6 +889,9 @@ func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error
 		return accounts.Account{Address: common.HexToAddress(account)}, nil
 	}
 	// Otherwise try to interpret the account as a keystore index
+	if ks == nil {
+		return accounts.Account{}, fmt.Errorf("no keystore to index account %q, use an address", account)
+	}
 	index, err := strconv.Atoi(account)
 	if err != nil || index < 0 {
 		return accounts.Account{}, fmt.Errorf("invalid account address or index %q", account)
@@ -867,6 +909,23 @@ func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error
 	return accs[index], nil
 }
 
+// MakeUSBBasePaths parses the derivation paths from which USB hardware wallets
+// should discover accounts. If none were specified, nil is returned.
+func MakeUSBBasePaths(ctx *cli.Context) []accounts.DerivationPath {
+	if !ctx.GlobalIsSet(USBBasePathsFlag.Name) {
+		return nil
+	}
+	var paths []accounts.DerivationPath
+	for _, path := range splitAndTrim(ctx.GlobalString(USBBasePathsFlag.Name)) {
+		base, err := accounts.ParseDerivationPath(path)
+		if err != nil {
+			Fatalf("Invalid derivation path %q: %v", path, err)
+		}
+		paths = append(paths, base)
+	}
+	return paths
+}
+
 // setEtherbase retrieves the etherbase either from the directly specified
 // command line flags or from the keystore if CLI indexed.
 func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
@@ -992,6 +1051,18 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(PKCS11ModuleFlag.Name) {
+		cfg.PKCS11Module = ctx.GlobalString(PKCS11ModuleFlag.Name)
+	}
+	if ctx.GlobalIsSet(PKCS11SlotFlag.Name) {
+		cfg.PKCS11Slot = ctx.GlobalUint(PKCS11SlotFlag.Name)
+	}
+	if ctx.GlobalIsSet(PKCS11LabelsFlag.Name) {
+		cfg.PKCS11Labels = splitAndTrim(ctx.GlobalString(PKCS11LabelsFlag.Name))
+	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setDataDir(ctx *cli.Context, cfg *node.Config) {
@@ -1103,6 +1174,41 @@ func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
 	}
 }
 
+// setULC configures the ultra light client mode from the command line flags.
+func setULC(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(ULCTrustedNodesFlag.Name) {
+		if cfg.ULC == nil {
+			cfg.ULC = &eth.ULCConfig{MinTrustedFraction: eth.DefaultULCMinTrustedFraction}
+		}
+		cfg.ULC.TrustedServers = nil
+		for _, url := range strings.Split(ctx.GlobalString(ULCTrustedNodesFlag.Name), ",") {
+			if url = strings.TrimSpace(url); url == "" {
+				continue
+			}
+			if _, err := enode.ParseV4(url); err != nil {
+				Fatalf("Invalid trusted server %s: %v", url, err)
+			}
+			cfg.ULC.TrustedServers = append(cfg.ULC.TrustedServers, url)
+		}
+	}
+	if ctx.GlobalIsSet(ULCMinTrustedFractionFlag.Name) {
+		if cfg.ULC == nil {
+			Fatalf("--%s requires --%s", ULCMinTrustedFractionFlag.Name, ULCTrustedNodesFlag.Name)
+		}
+		cfg.ULC.MinTrustedFraction = ctx.GlobalInt(ULCMinTrustedFractionFlag.Name)
+	}
+	if cfg.ULC == nil {
+		return
+	}
+	if fraction := cfg.ULC.MinTrustedFraction; fraction <= 0 || fraction > 100 {
+		Fatalf("Invalid minimum trusted fraction %d, must be between 1 and 100", fraction)
+	}
+	if cfg.SyncMode != downloader.LightSync {
+		log.Info("Ultra light client mode requires light sync, switching sync mode")
+		cfg.SyncMode = downloader.LightSync
+	}
+}
+
 // checkExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1162,8 +1268,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
 	checkExclusive(ctx, DeveloperFlag, TestnetFlag, RinkebyFlag)
 	checkExclusive(ctx, LightServFlag, SyncModeFlag, "light")
+	checkExclusive(ctx, LightServFlag, ULCTrustedNodesFlag)
+	checkExclusive(ctx, DeveloperFlag, ExternalSignerFlag)
 
-	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	var ks *keystore.KeyStore
+	if keystores := stack.AccountManager().Backends(keystore.KeyStoreType); len(keystores) > 0 {
+		ks = keystores[0].(*keystore.KeyStore)
+	}
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
@@ -1173,6 +1284,8 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	setULC(ctx, cfg)
+
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
@@ -1238,10 +1351,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	if ctx.GlobalIsSet(EWASMInterpreterFlag.Name) {
 		cfg.EWASMInterpreter = ctx.GlobalString(EWASMInterpreterFlag.Name)
+		if _, err := vm.LoadEVMC(cfg.EWASMInterpreter, true); err != nil {
+			Fatalf("Failed to load ewasm interpreter: %v", err)
+		}
 	}
 
 	if ctx.GlobalIsSet(EVMInterpreterFlag.Name) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
+		if _, err := vm.LoadEVMC(cfg.EVMInterpreter, false); err != nil {
+			Fatalf("Failed to load EVM interpreter: %v", err)
+		}
 	}
 
 	// Override any default configs for hard coded networks.
@@ -42,6 +42,14 @@ type Clock interface {
 	Now() AbsTime
 	Sleep(time.Duration)
 	After(time.Duration) <-chan time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer represents a cancellable event returned by AfterFunc.
+type Timer interface {
+	// Stop cancels the timer. It returns false if the timer has already
+	// expired or been stopped.
+	Stop() bool
 }
 
 // System implements Clock using the system clock.
@@ -61,3 +69,8 @@ func (System) Sleep(d time.Duration) {
 func (System) After(d time.Duration) <-chan time.Time {
 	return time.After(d)
 }
+
+// AfterFunc implements Clock.
+func (System) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -32,33 +32,37 @@ import (
 // the timeout using a channel or semaphore.
 type Simulated struct {
 	now       AbsTime
-	scheduled []event
+	scheduled []*simTimer
 	mu        sync.RWMutex
 	cond      *sync.Cond
 }
 
-type event struct {
+// simTimer implements Timer on the virtual clock.
+type simTimer struct {
 	do func()
 	at AbsTime
+	s  *Simulated
 }
 
 // Run moves the clock by the given duration, executing all timers before that duration.
+// Timer callbacks are invoked without holding the clock lock, so they may freely
+// interact with the clock themselves.
 func (s *Simulated) Run(d time.Duration) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.init()
-
 	end := s.now + AbsTime(d)
-	for len(s.scheduled) > 0 {
+
+	for len(s.scheduled) > 0 && s.scheduled[0].at <= end {
 		ev := s.scheduled[0]
-		if ev.at > end {
-			break
-		}
+		s.scheduled = s.scheduled[1:]
 		s.now = ev.at
+
+		s.mu.Unlock()
 		ev.do()
-		s.scheduled = s.scheduled[1:]
+		s.mu.Lock()
 	}
 	s.now = end
+	s.mu.Unlock()
 }
 
 func (s *Simulated) ActiveTimers() int {
@@ -95,12 +99,31 @@ func (s *Simulated) Sleep(d time.Duration) {
 func (s *Simulated) After(d time.Duration) <-chan time.Time {
 	after := make(chan time.Time, 1)
 	s.insert(d, func() {
-		after <- (time.Time{}).Add(time.Duration(s.now))
+		after <- (time.Time{}).Add(time.Duration(s.Now()))
 	})
 	return after
 }
 
-func (s *Simulated) insert(d time.Duration, do func()) {
+// AfterFunc implements Clock.
+func (s *Simulated) AfterFunc(d time.Duration, do func()) Timer {
+	return s.insert(d, do)
+}
+
+// Stop implements Timer.
+func (t *simTimer) Stop() bool {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	for i, ev := range t.s.scheduled {
+		if ev == t {
+			t.s.scheduled = append(t.s.scheduled[:i], t.s.scheduled[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Simulated) insert(d time.Duration, do func()) *simTimer {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.init()
@@ -116,10 +139,12 @@ func (s *Simulated) insert(d time.Duration, do func()) {
 			l = m + 1
 		}
 	}
-	s.scheduled = append(s.scheduled, event{})
+	ev := &simTimer{do: do, at: at, s: s}
+	s.scheduled = append(s.scheduled, nil)
 	copy(s.scheduled[l+1:], s.scheduled[l:ll])
-	s.scheduled[l] = event{do: do, at: at}
+	s.scheduled[l] = ev
 	s.cond.Broadcast()
+	return ev
 }
 
 func (s *Simulated) init() {
@@ -17,6 +17,9 @@
 package clique
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -117,3 +120,123 @@ func (api *API) Discard(address common.Address) {
 
 	delete(api.clique.proposals, address)
 }
+
+// defaultStatusBlocks is the number of recent blocks the signer status is
+// calculated over if not requested otherwise.
+const defaultStatusBlocks = 64
+
+// maxStatusBlocks is the maximum number of recent blocks the signer status may
+// be calculated over, limiting the work a single RPC request can cause.
+const maxStatusBlocks = 4096
+
+// errNoStatusBlocks is returned if the signer status is requested over an empty
+// range of blocks, or on a chain with no sealed blocks yet.
+var errNoStatusBlocks = errors.New("no sealed blocks in range")
+
+// SignerStatus is the sealing activity of a single signer within a range of
+// blocks.
+type SignerStatus struct {
+	Signed       uint64  `json:"signed"`       // Number of blocks sealed
+	InTurn       uint64  `json:"inTurn"`       // Number of blocks sealed in-turn
+	OutOfTurn    uint64  `json:"outOfTurn"`    // Number of blocks sealed out-of-turn
+	InTurnRatio  float64 `json:"inTurnRatio"`  // Ratio of the sealed blocks that were in-turn
+	MissedInTurn uint64  `json:"missedInTurn"` // Number of in-turn slots sealed by someone else
+	Difficulty   uint64  `json:"difficulty"`   // Total difficulty contributed by the sealed blocks
+	LastSigned   uint64  `json:"lastSigned"`   // Last block sealed, zero if none in the range
+	Lag          uint64  `json:"lag"`          // Number of blocks since the last sealed one
+}
+
+// Status is the sealing activity of the signers within a range of blocks.
+type Status struct {
+	From        uint64                           `json:"from"`        // First block of the range
+	To          uint64                           `json:"to"`          // Last block of the range
+	InTurnRatio float64                          `json:"inTurnRatio"` // Ratio of in-turn blocks in the range
+	Signers     map[common.Address]*SignerStatus `json:"signers"`     // Activity of the current and past signers
+}
+
+// Status retrieves the sealing activity of the signers over the last n blocks,
+// reporting which signers are missing their in-turn slots and how far behind
+// they are. At most maxStatusBlocks blocks may be requested.
+func (api *API) Status(n *uint64) (*Status, error) {
+	numBlocks := uint64(defaultStatusBlocks)
+	if n != nil {
+		numBlocks = *n
+	}
+	if numBlocks > maxStatusBlocks {
+		return nil, fmt.Errorf("too many blocks requested: %d > %d", numBlocks, maxStatusBlocks)
+	}
+	head := api.chain.CurrentHeader()
+	if headNum := head.Number.Uint64(); numBlocks > headNum {
+		numBlocks = headNum
+	}
+	if numBlocks == 0 {
+		return nil, errNoStatusBlocks
+	}
+	// Gather the headers of the range, genesis excluded as it's not sealed
+	headers := make([]*types.Header, numBlocks)
+	for i, header := int(numBlocks)-1, head; i >= 0; i-- {
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		headers[i] = header
+		header = api.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	// Roll a snapshot from the parent of the range to the head, tracking the
+	// in-turn signer of each block along the way
+	snap, err := api.clique.snapshot(api.chain, headers[0].Number.Uint64()-1, headers[0].ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		From:    headers[0].Number.Uint64(),
+		To:      head.Number.Uint64(),
+		Signers: make(map[common.Address]*SignerStatus),
+	}
+	get := func(signer common.Address) *SignerStatus {
+		if _, ok := status.Signers[signer]; !ok {
+			status.Signers[signer] = new(SignerStatus)
+		}
+		return status.Signers[signer]
+	}
+	var inturn uint64
+	for _, header := range headers {
+		signer, err := api.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		number := header.Number.Uint64()
+
+		stats := get(signer)
+		stats.Signed++
+		stats.Difficulty += header.Difficulty.Uint64()
+		stats.LastSigned = number
+
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			stats.InTurn++
+			inturn++
+		} else {
+			// The block was sealed out-of-turn, blame the in-turn signer
+			stats.OutOfTurn++
+
+			signers := snap.signers()
+			get(signers[number%uint64(len(signers))]).MissedInTurn++
+		}
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			return nil, err
+		}
+	}
+	// Report on all the current signers, even if they sealed nothing
+	for signer := range snap.Signers {
+		get(signer)
+	}
+	for _, stats := range status.Signers {
+		if stats.Signed > 0 {
+			stats.InTurnRatio = float64(stats.InTurn) / float64(stats.Signed)
+			stats.Lag = status.To - stats.LastSigned
+		} else {
+			stats.Lag = numBlocks
+		}
+	}
+	status.InTurnRatio = float64(inturn) / float64(numBlocks)
+	return status, nil
+}
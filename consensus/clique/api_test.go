@@ -0,0 +1,188 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the signer status is correctly aggregated over the requested range
+// of blocks, blaming missed in-turn slots on the right signers.
+func TestStatus(t *testing.T) {
+	// Create three signers, sorted in their in-turn order
+	accounts := newTesterAccountPool()
+	names := []string{"A", "B", "C"}
+	sort.Slice(names, func(i, j int) bool {
+		return bytes.Compare(accounts.address(names[i]).Bytes(), accounts.address(names[j]).Bytes()) < 0
+	})
+	signers := make([]common.Address, len(names))
+	for i, name := range names {
+		signers[i] = accounts.address(name)
+	}
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+	}
+	for i, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], signer[:])
+	}
+	db := ethdb.NewMemDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+
+	// Seal a chain where the second signer goes silent after the first block
+	sealers := []int{1, 2, 0, 2, 0, 2, 0, 2}
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(sealers), nil)
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffNoTurn
+		if uint64(i+1)%uint64(len(signers)) == uint64(sealers[i]) {
+			header.Difficulty = diffInTurn
+		}
+		accounts.sign(header, names[sealers[i]])
+		blocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	api := &API{chain: chain, clique: engine}
+
+	tests := []struct {
+		blocks *uint64
+		from   uint64
+		ratio  float64
+		status []SignerStatus
+	}{
+		// The default range covers the entire chain
+		{
+			blocks: nil,
+			from:   1,
+			ratio:  0.5,
+			status: []SignerStatus{
+				{Signed: 3, InTurn: 1, OutOfTurn: 2, InTurnRatio: 1.0 / 3, MissedInTurn: 1, Difficulty: 4, LastSigned: 7, Lag: 1},
+				{Signed: 1, InTurn: 1, InTurnRatio: 1, MissedInTurn: 2, Difficulty: 2, LastSigned: 1, Lag: 7},
+				{Signed: 4, InTurn: 2, OutOfTurn: 2, InTurnRatio: 0.5, MissedInTurn: 1, Difficulty: 6, LastSigned: 8},
+			},
+		},
+		// A limited range reports silent signers as lagging the whole range
+		{
+			blocks: newUint64(3),
+			from:   6,
+			ratio:  1.0 / 3,
+			status: []SignerStatus{
+				{Signed: 1, OutOfTurn: 1, MissedInTurn: 1, Difficulty: 1, LastSigned: 7, Lag: 1},
+				{MissedInTurn: 1, Lag: 3},
+				{Signed: 2, InTurn: 1, OutOfTurn: 1, InTurnRatio: 0.5, Difficulty: 3, LastSigned: 8},
+			},
+		},
+	}
+	for i, tt := range tests {
+		status, err := api.Status(tt.blocks)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve status: %v", i, err)
+			continue
+		}
+		if status.From != tt.from || status.To != uint64(len(sealers)) {
+			t.Errorf("test %d: range mismatch: have [%d, %d], want [%d, %d]", i, status.From, status.To, tt.from, len(sealers))
+		}
+		if status.InTurnRatio != tt.ratio {
+			t.Errorf("test %d: in-turn ratio mismatch: have %v, want %v", i, status.InTurnRatio, tt.ratio)
+		}
+		if len(status.Signers) != len(signers) {
+			t.Errorf("test %d: signer count mismatch: have %d, want %d", i, len(status.Signers), len(signers))
+		}
+		for j, signer := range signers {
+			if have := status.Signers[signer]; have == nil || !reflect.DeepEqual(*have, tt.status[j]) {
+				t.Errorf("test %d: signer %d status mismatch: have %+v, want %+v", i, j, have, tt.status[j])
+			}
+		}
+	}
+	// Empty and oversized ranges must be rejected
+	if _, err := api.Status(newUint64(0)); err != errNoStatusBlocks {
+		t.Errorf("empty range: error mismatch: have %v, want %v", err, errNoStatusBlocks)
+	}
+	if _, err := api.Status(newUint64(maxStatusBlocks + 1)); err == nil {
+		t.Errorf("oversized range accepted")
+	}
+	if status, err := api.Status(newUint64(maxStatusBlocks)); err != nil || status.From != 1 {
+		t.Errorf("maximum range: have %+v, %v, want range from 1", status, err)
+	}
+	// The second signer missed too many slots, it should be reported silent once
+	// the head is snapshotted (e.g. to seal or verify the next block)
+	head := chain.CurrentHeader()
+	if _, err := engine.snapshot(chain, head.Number.Uint64(), head.Hash(), nil); err != nil {
+		t.Fatalf("failed to snapshot head: %v", err)
+	}
+	if !engine.monitor.silent[signers[1]] {
+		t.Errorf("silent signer not reported")
+	}
+	for _, signer := range []common.Address{signers[0], signers[2]} {
+		if engine.monitor.silent[signer] {
+			t.Errorf("active signer %x reported silent", signer)
+		}
+	}
+}
+
+// Tests that the signer status of a chain with only the genesis block is an
+// error instead of an inverted range.
+func TestStatusGenesis(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signer := accounts.address("A")
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.ExtraData[extraVanity:], signer[:])
+
+	db := ethdb.NewMemDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	api := &API{chain: chain, clique: engine}
+	if _, err := api.Status(nil); err != errNoStatusBlocks {
+		t.Errorf("error mismatch: have %v, want %v", err, errNoStatusBlocks)
+	}
+}
+
+func newUint64(n uint64) *uint64 { return &n }
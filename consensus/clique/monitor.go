@@ -0,0 +1,121 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// silenceRounds is the number of full signer rotations a signer may go without
+// sealing a block before it's reported as silent.
+const silenceRounds = 2
+
+var (
+	inturnBlockMeter  = metrics.NewRegisteredMeter("clique/blocks/inturn", nil)
+	noturnBlockMeter  = metrics.NewRegisteredMeter("clique/blocks/noturn", nil)
+	silentSignerGauge = metrics.NewRegisteredGauge("clique/signers/silent", nil)
+)
+
+// signerMonitor tracks the sealing activity of the authorized signers as the
+// chain progresses, reporting the ones that stopped sealing blocks.
+type signerMonitor struct {
+	head       uint64                    // Highest block number observed so far
+	lastSigned map[common.Address]uint64 // Last block sealed (or first seen) by each signer
+	silent     map[common.Address]bool   // Signers currently reported as silent
+	lock       sync.Mutex
+}
+
+// newSignerMonitor creates a signer monitor with no observed activity.
+func newSignerMonitor() *signerMonitor {
+	return &signerMonitor{
+		lastSigned: make(map[common.Address]uint64),
+		silent:     make(map[common.Address]bool),
+	}
+}
+
+// observe updates the signer activity with a batch of headers applied on top
+// of a snapshot, the result of which is the given snap.
+func (m *signerMonitor) observe(snap *Snapshot, headers []*types.Header, sigcache *lru.ARCCache) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, header := range headers {
+		number := header.Number.Uint64()
+		signer, err := ecrecover(header, sigcache)
+		if err != nil {
+			continue
+		}
+		if number > m.lastSigned[signer] {
+			m.lastSigned[signer] = number
+		}
+		// Only count the blocks extending the chain, not recalculated ones
+		if number > m.head {
+			if header.Difficulty.Cmp(diffInTurn) == 0 {
+				inturnBlockMeter.Mark(1)
+			} else {
+				noturnBlockMeter.Mark(1)
+			}
+		}
+	}
+	// Historical snapshots carry no information on the current signer health
+	if snap.Number < m.head {
+		return
+	}
+	m.head = snap.Number
+
+	limit := uint64(silenceRounds * len(snap.Signers))
+	for signer := range snap.Signers {
+		last, ok := m.lastSigned[signer]
+		if !ok {
+			// Give newly observed signers a full grace period
+			m.lastSigned[signer], last = snap.Number, snap.Number
+		}
+		lag := snap.Number - last
+		metrics.GetOrRegisterGauge(signerLagGauge(signer), nil).Update(int64(lag))
+
+		switch {
+		case lag > limit && !m.silent[signer]:
+			log.Warn("Clique signer went silent", "signer", signer, "last", last, "lag", lag)
+			m.silent[signer] = true
+		case lag <= limit && m.silent[signer]:
+			log.Info("Clique signer resumed sealing", "signer", signer, "number", last)
+			delete(m.silent, signer)
+		}
+	}
+	// Drop the signers deauthorized in the meantime, along with their lag gauges
+	for signer := range m.lastSigned {
+		if _, ok := snap.Signers[signer]; !ok {
+			metrics.DefaultRegistry.Unregister(signerLagGauge(signer))
+			delete(m.lastSigned, signer)
+			delete(m.silent, signer)
+		}
+	}
+	silentSignerGauge.Update(int64(len(m.silent)))
+}
+
+// signerLagGauge returns the name of the gauge reporting the number of blocks
+// since the given signer last sealed one.
+func signerLagGauge(signer common.Address) string {
+	return fmt.Sprintf("clique/signers/%x/lag", signer)
+}
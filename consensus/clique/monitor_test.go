@@ -0,0 +1,66 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Tests that signers voted out are forgotten by the monitor, dropping their lag
+// gauges and silence reports.
+func TestMonitorDeauthorized(t *testing.T) {
+	var (
+		config   = &params.CliqueConfig{Period: 1, Epoch: 30000}
+		cache, _ = lru.NewARC(inmemorySignatures)
+		active   = common.HexToAddress("0x01")
+		dropped  = common.HexToAddress("0x02")
+	)
+	monitor := newSignerMonitor()
+
+	// Observe both signers, the second one long silent
+	monitor.observe(newSnapshot(config, cache, 1, common.Hash{}, []common.Address{active, dropped}), nil, cache)
+	monitor.lastSigned[active] = 10
+	monitor.observe(newSnapshot(config, cache, 10, common.Hash{}, []common.Address{active, dropped}), nil, cache)
+	if !monitor.silent[dropped] {
+		t.Fatalf("silent signer not reported")
+	}
+	for _, signer := range []common.Address{active, dropped} {
+		if metrics.DefaultRegistry.Get(signerLagGauge(signer)) == nil {
+			t.Fatalf("lag gauge of %x not registered", signer)
+		}
+	}
+	// Vote out the second signer and ensure it's forgotten
+	monitor.observe(newSnapshot(config, cache, 11, common.Hash{}, []common.Address{active}), nil, cache)
+
+	if _, ok := monitor.lastSigned[dropped]; ok {
+		t.Errorf("deauthorized signer still tracked")
+	}
+	if monitor.silent[dropped] {
+		t.Errorf("deauthorized signer still reported silent")
+	}
+	if metrics.DefaultRegistry.Get(signerLagGauge(dropped)) != nil {
+		t.Errorf("lag gauge of deauthorized signer still registered")
+	}
+	if metrics.DefaultRegistry.Get(signerLagGauge(active)) == nil {
+		t.Errorf("lag gauge of active signer unregistered")
+	}
+}
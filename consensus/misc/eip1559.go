@@ -0,0 +1,102 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// errMissingBaseFee is returned if a header after the EIP-1559 fork does
+	// not contain a base fee.
+	errMissingBaseFee = errors.New("header is missing baseFee")
+
+	// errUnexpectedBaseFee is returned if a header before the EIP-1559 fork
+	// contains a base fee.
+	errUnexpectedBaseFee = errors.New("header has baseFee before fork")
+)
+
+// VerifyEip1559Header verifies the EIP-1559 base fee field of a header. Before
+// the London fork the field must be absent, afterwards it must match the value
+// derived from the parent header.
+func VerifyEip1559Header(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsLondon(header.Number) {
+		if header.BaseFee != nil {
+			return errUnexpectedBaseFee
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return errMissingBaseFee
+	}
+	// Verify the baseFee is correct based on the parent header.
+	expectedBaseFee := CalcBaseFee(config, parent)
+	if header.BaseFee.Cmp(expectedBaseFee) != 0 {
+		return fmt.Errorf("invalid baseFee: have %s, want %s, parentBaseFee %s, parentGasUsed %d",
+			header.BaseFee, expectedBaseFee, parent.BaseFee, parent.GasUsed)
+	}
+	return nil
+}
+
+// CalcBaseFee calculates the base fee of the header following the given parent.
+//
+// The gas target of a block is its gas limit divided by the elasticity
+// multiplier; the base fee rises when the parent used more gas than its target
+// and falls when it used less, by at most 1/BaseFeeChangeDenominator per block.
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	// If the current block is the first EIP-1559 block, return the InitialBaseFee.
+	if !config.IsLondon(parent.Number) {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	var (
+		parentGasTarget          = parent.GasLimit / params.ElasticityMultiplier
+		parentGasTargetBig       = new(big.Int).SetUint64(parentGasTarget)
+		baseFeeChangeDenominator = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+	)
+	// If the parent gasUsed is the same as the target, the baseFee remains unchanged.
+	if parent.GasUsed == parentGasTarget {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+	if parent.GasUsed > parentGasTarget {
+		// If the parent block used more gas than its target, the baseFee should increase.
+		gasUsedDelta := new(big.Int).SetUint64(parent.GasUsed - parentGasTarget)
+		x := new(big.Int).Mul(parent.BaseFee, gasUsedDelta)
+		y := x.Div(x, parentGasTargetBig)
+		baseFeeDelta := math.BigMax(
+			x.Div(y, baseFeeChangeDenominator),
+			common.Big1,
+		)
+		return x.Add(parent.BaseFee, baseFeeDelta)
+	}
+	// Otherwise if the parent block used less gas than its target, the baseFee should decrease.
+	gasUsedDelta := new(big.Int).SetUint64(parentGasTarget - parent.GasUsed)
+	x := new(big.Int).Mul(parent.BaseFee, gasUsedDelta)
+	y := x.Div(x, parentGasTargetBig)
+	baseFeeDelta := x.Div(y, baseFeeChangeDenominator)
+
+	return math.BigMax(
+		x.Sub(parent.BaseFee, baseFeeDelta),
+		common.Big0,
+	)
+}
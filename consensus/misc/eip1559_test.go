@@ -0,0 +1,103 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// londonConfig returns a chain config with the London fork at block 5.
+func londonConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.BerlinBlock = big.NewInt(0)
+	config.LondonBlock = big.NewInt(5)
+	return &config
+}
+
+// Tests that the base fee field is only accepted after the fork and that it
+// must match the value derived from the parent.
+func TestBlockBaseFeeVerification(t *testing.T) {
+	config := londonConfig()
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+
+	tests := []struct {
+		parentNumber  int64
+		parentBaseFee *big.Int
+		baseFee       *big.Int
+		ok            bool
+	}{
+		{2, nil, nil, true},                         // pre-fork, no base fee
+		{2, nil, initial, false},                    // pre-fork, unexpected base fee
+		{4, nil, nil, false},                        // fork block, missing base fee
+		{4, nil, initial, true},                     // fork block, initial base fee
+		{4, nil, big.NewInt(1), false},              // fork block, wrong base fee
+		{5, initial, initial, true},                 // post-fork, parent on target
+		{5, initial, big.NewInt(1000000001), false}, // post-fork, wrong base fee
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(test.parentNumber),
+			GasLimit: 10000000,
+			GasUsed:  5000000,
+			BaseFee:  test.parentBaseFee,
+		}
+		header := &types.Header{
+			Number:   big.NewInt(test.parentNumber + 1),
+			GasLimit: 10000000,
+			BaseFee:  test.baseFee,
+		}
+		err := VerifyEip1559Header(config, parent, header)
+		if test.ok && err != nil {
+			t.Errorf("test %d: expected valid header, got %v", i, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("test %d: expected invalid header", i)
+		}
+	}
+}
+
+// Tests that the base fee moves towards the gas target of the parent block.
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 20000000, 10000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 20000000, 9000000, 987500000},              // usage below target
+		{params.InitialBaseFee, 20000000, 11000000, 1012500000},            // usage above target
+		{params.InitialBaseFee, 20000000, 0, 875000000},                    // empty block
+		{params.InitialBaseFee, 20000000, 20000000, 1125000000},            // full block
+		{1, 20000000, 10000001, 2},                                         // minimum increase of one
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(10),
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(londonConfig(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d, want %d", i, have, want)
+		}
+	}
+}
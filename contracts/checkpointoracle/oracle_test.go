@@ -0,0 +1,256 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	sectionSize     = big.NewInt(4)
+	processConfirms = big.NewInt(2)
+	emptyHash       = [32]byte{}
+)
+
+// Account is a test account with a private key and an address.
+type Account struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+type Accounts []Account
+
+func (a Accounts) Len() int           { return len(a) }
+func (a Accounts) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a Accounts) Less(i, j int) bool { return bytes.Compare(a[i].addr.Bytes(), a[j].addr.Bytes()) < 0 }
+
+// newTestAccounts creates n random accounts sorted by their addresses, which
+// is the order the contract expects the signatures in.
+func newTestAccounts(t *testing.T, n int) Accounts {
+	var accounts Accounts
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		accounts = append(accounts, Account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)})
+	}
+	sort.Sort(accounts)
+	return accounts
+}
+
+// signCheckpoint creates an EIP191 "data with validator" signature over the
+// given checkpoint with the v value in "ethereum style".
+func signCheckpoint(addr common.Address, key *ecdsa.PrivateKey, index uint64, hash common.Hash) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	data := append([]byte{0x19, 0x00}, append(addr.Bytes(), append(buf, hash.Bytes()...)...)...)
+	sig, _ := crypto.Sign(crypto.Keccak256(data), key)
+	sig[64] += 27
+	return sig
+}
+
+// recentBlock returns the number and hash of the latest block of the backend,
+// used as replay protection by the contract.
+func recentBlock(backend *backends.SimulatedBackend) (*big.Int, [32]byte) {
+	head := backend.Blockchain().CurrentHeader()
+	return head.Number, head.Hash()
+}
+
+// mineUntil commits empty blocks until the head reaches the given number.
+func mineUntil(backend *backends.SimulatedBackend, number uint64) {
+	for backend.Blockchain().CurrentHeader().Number.Uint64() < number {
+		backend.Commit()
+	}
+}
+
+func newTestOracle(t *testing.T, accounts Accounts, threshold int64) (*backends.SimulatedBackend, *CheckpointOracle, *bind.TransactOpts) {
+	alloc := make(core.GenesisAlloc)
+	var admins []common.Address
+	for _, account := range accounts {
+		alloc[account.addr] = core.GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+		admins = append(admins, account.addr)
+	}
+	backend := backends.NewSimulatedBackend(alloc, 10000000)
+
+	opts := bind.NewKeyedTransactor(accounts[0].key)
+	addr, _, _, err := contract.DeployCheckpointOracle(opts, backend, admins, sectionSize, processConfirms, big.NewInt(threshold))
+	if err != nil {
+		t.Fatalf("Failed to deploy registrar contract: %v", err)
+	}
+	backend.Commit()
+
+	oracle, err := NewCheckpointOracle(addr, backend)
+	if err != nil {
+		t.Fatalf("Failed to bind registrar contract: %v", err)
+	}
+	return backend, oracle, opts
+}
+
+func TestCheckpointDeploy(t *testing.T) {
+	accounts := newTestAccounts(t, 3)
+	_, oracle, _ := newTestOracle(t, accounts, 2)
+
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		t.Fatalf("Failed to retrieve admins: %v", err)
+	}
+	var want []common.Address
+	for _, account := range accounts {
+		want = append(want, account.addr)
+	}
+	if !reflect.DeepEqual(admins, want) {
+		t.Fatalf("Admin list mismatch: have %x, want %x", admins, want)
+	}
+	index, hash, height, err := oracle.Contract().GetLatestCheckpoint(nil)
+	if err != nil {
+		t.Fatalf("Failed to retrieve checkpoint: %v", err)
+	}
+	if index != 0 || hash != emptyHash || height.Sign() != 0 {
+		t.Fatalf("Unexpected initial checkpoint: index %d, hash %x, height %v", index, hash, height)
+	}
+}
+
+func TestCheckpointRegister(t *testing.T) {
+	accounts := newTestAccounts(t, 3)
+	backend, oracle, opts := newTestOracle(t, accounts, 2)
+
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: 0,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	hash := checkpoint.Hash()
+	sign := func(index uint64, hash common.Hash, signers ...int) [][]byte {
+		var sigs [][]byte
+		for _, i := range signers {
+			sigs = append(sigs, signCheckpoint(oracle.ContractAddr(), accounts[i].key, index, hash))
+		}
+		return sigs
+	}
+	assert := func(index uint64, hash [32]byte, height uint64) {
+		t.Helper()
+		i, h, n, err := oracle.Contract().GetLatestCheckpoint(nil)
+		if err != nil {
+			t.Fatalf("Failed to retrieve checkpoint: %v", err)
+		}
+		if i != index || h != hash || n.Uint64() != height {
+			t.Fatalf("Checkpoint mismatch: have (%d, %x, %d), want (%d, %x, %d)", i, h, n, index, hash, height)
+		}
+	}
+	// Registering a future checkpoint should be silently ignored
+	number, recent := recentBlock(backend)
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0, 1)); err != nil {
+		t.Fatalf("Failed to send future checkpoint: %v", err)
+	}
+	backend.Commit()
+	assert(0, emptyHash, 0)
+
+	mineUntil(backend, sectionSize.Uint64()+processConfirms.Uint64())
+
+	// Non-admins, invalid replay protection, unsorted and insufficient
+	// signatures should all be rejected
+	outsider := newTestAccounts(t, 1)[0]
+	number, recent = recentBlock(backend)
+	if _, err := oracle.RegisterCheckpoint(bind.NewKeyedTransactor(outsider.key), 0, hash.Bytes(), number, recent, sign(0, hash, 0, 1)); err == nil {
+		t.Fatalf("Checkpoint registered by non-admin")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, emptyHash, sign(0, hash, 0, 1)); err == nil {
+		t.Fatalf("Checkpoint registered with invalid replay protection")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 1, 0)); err == nil {
+		t.Fatalf("Checkpoint registered with unsorted signatures")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0, 0)); err == nil {
+		t.Fatalf("Checkpoint registered with duplicate signatures")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0)); err == nil {
+		t.Fatalf("Checkpoint registered with insufficient signatures")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(1, hash, 0, 1)); err == nil {
+		t.Fatalf("Checkpoint registered with signatures for another section")
+	}
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, [][]byte{signCheckpoint(oracle.ContractAddr(), outsider.key, 0, hash)}); err == nil {
+		t.Fatalf("Checkpoint registered with signature of non-admin")
+	}
+	// Register a valid checkpoint and check the emitted votes
+	tx, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0, 2))
+	if err != nil {
+		t.Fatalf("Failed to register checkpoint: %v", err)
+	}
+	backend.Commit()
+	height := backend.Blockchain().CurrentHeader().Number.Uint64()
+	assert(0, hash, height)
+
+	receipt := backend.Blockchain().GetReceiptsByHash(backend.Blockchain().CurrentBlock().Hash())
+	if len(receipt) != 1 || receipt[0].TxHash != tx.Hash() {
+		t.Fatalf("Registration transaction not found")
+	}
+	votes := oracle.LookupCheckpointEvents([][]*types.Log{receipt[0].Logs}, 0, hash)
+	if len(votes) != 2 {
+		t.Fatalf("Vote count mismatch: have %d, want 2", len(votes))
+	}
+	for i, vote := range votes {
+		want := sign(0, hash, 0, 2)[i]
+		if !bytes.Equal(vote.R[:], want[:32]) || !bytes.Equal(vote.S[:], want[32:64]) || vote.V != want[64] {
+			t.Fatalf("Vote %d mismatch", i)
+		}
+	}
+	// Registering a stale or old checkpoint should be silently ignored
+	number, recent = recentBlock(backend)
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0, 1)); err != nil {
+		t.Fatalf("Failed to send stale checkpoint: %v", err)
+	}
+	backend.Commit()
+	assert(0, hash, height)
+
+	// Registering the next section should succeed once it's confirmed
+	mineUntil(backend, 2*sectionSize.Uint64()+processConfirms.Uint64())
+	checkpoint.SectionIndex = 1
+	next := checkpoint.Hash()
+
+	number, recent = recentBlock(backend)
+	if _, err := oracle.RegisterCheckpoint(opts, 1, next.Bytes(), number, recent, sign(1, next, 1, 2)); err != nil {
+		t.Fatalf("Failed to register checkpoint: %v", err)
+	}
+	backend.Commit()
+	height = backend.Blockchain().CurrentHeader().Number.Uint64()
+	assert(1, next, height)
+
+	number, recent = recentBlock(backend)
+	if _, err := oracle.RegisterCheckpoint(opts, 0, hash.Bytes(), number, recent, sign(0, hash, 0, 1)); err != nil {
+		t.Fatalf("Failed to send old checkpoint: %v", err)
+	}
+	backend.Commit()
+	assert(1, next, height)
+}
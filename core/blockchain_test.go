@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -1483,3 +1484,68 @@ func BenchmarkBlockChain_1x1000Executions(b *testing.B) {
 
 	benchmarkLargeNumberOfValueToNonexisting(b, numTxs, numBlocks, recipientFn, dataFn)
 }
+
+// Tests that after the London fork the base fee of a block is burnt and only
+// the priority fee is credited to the coinbase.
+func TestEIP1559Transition(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		miner  = common.Address{0xff}
+		funds  = big.NewInt(params.Ether)
+		db     = ethdb.NewMemDatabase()
+		config = *params.AllEthashProtocolChanges
+		engine = ethash.NewFaker()
+	)
+	config.LondonBlock = common.Big0
+
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: funds}}}
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(miner)
+
+		tx, err := types.SignNewTx(key, types.LatestSigner(gspec.Config), &types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     0,
+			To:        &common.Address{0xaa},
+			Gas:       30000,
+			GasFeeCap: new(big.Int).Mul(b.BaseFee(), common.Big2),
+			GasTipCap: big.NewInt(2),
+		})
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	diskdb := ethdb.NewMemDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	block := chain.GetBlockByNumber(1)
+	if want := misc.CalcBaseFee(gspec.Config, genesis.Header()); block.BaseFee() == nil || block.BaseFee().Cmp(want) != 0 {
+		t.Fatalf("base fee mismatch: have %v, want %v", block.BaseFee(), want)
+	}
+	state, _ := chain.State()
+
+	// The miner only receives the block reward and the priority fee
+	gasUsed := new(big.Int).SetUint64(block.GasUsed())
+	expected := new(big.Int).Add(ethash.ConstantinopleBlockReward, new(big.Int).Mul(gasUsed, big.NewInt(2)))
+	if have := state.GetBalance(miner); have.Cmp(expected) != 0 {
+		t.Errorf("miner balance mismatch: have %v, want %v", have, expected)
+	}
+	// The sender pays the base fee in addition to the priority fee
+	price := new(big.Int).Add(block.BaseFee(), big.NewInt(2))
+	expected = new(big.Int).Sub(funds, new(big.Int).Mul(gasUsed, price))
+	if have := state.GetBalance(addr); have.Cmp(expected) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, expected)
+	}
+}
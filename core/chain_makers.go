@@ -92,16 +92,33 @@ func (b *BlockGen) AddTx(tx *types.Transaction) {
 // added. If contract code relies on the BLOCKHASH instruction,
 // the block in chain will be returned.
 func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
+	if err := b.TryAddTxWithChain(bc, tx); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddTxWithChain adds a transaction to the generated block, just like
+// AddTxWithChain does, but instead of panicking it returns the error if the
+// transaction cannot be executed. In that case the block is left unchanged.
+func (b *BlockGen) TryAddTxWithChain(bc *BlockChain, tx *types.Transaction) error {
 	if b.gasPool == nil {
 		b.SetCoinbase(common.Address{})
 	}
+	var (
+		snapshot = b.statedb.Snapshot()
+		gasPool  = *b.gasPool
+		gasUsed  = b.header.GasUsed
+	)
 	b.statedb.Prepare(tx.Hash(), common.Hash{}, len(b.txs))
 	receipt, _, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
 	if err != nil {
-		panic(err)
+		b.statedb.RevertToSnapshot(snapshot)
+		*b.gasPool, b.header.GasUsed = gasPool, gasUsed
+		return err
 	}
 	b.txs = append(b.txs, tx)
 	b.receipts = append(b.receipts, receipt)
+	return nil
 }
 
 // Number returns the block number of the block being generated.
@@ -109,6 +126,15 @@ func (b *BlockGen) Number() *big.Int {
 	return new(big.Int).Set(b.header.Number)
 }
 
+// BaseFee returns the EIP-1559 base fee of the block being generated, or nil
+// if the London fork is not yet active.
+func (b *BlockGen) BaseFee() *big.Int {
+	if b.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(b.header.BaseFee)
+}
+
 // AddUncheckedReceipt forcefully adds a receipts to the block without a
 // backing transaction.
 //
@@ -170,6 +196,12 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 // values. Inserting them into BlockChain requires use of FakePow or
 // a similar non-validating proof of work implementation.
 func GenerateChain(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, db ethdb.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	return GenerateChainWithState(config, parent, engine, state.NewDatabase(db), n, gen)
+}
+
+// GenerateChainWithState is like GenerateChain, but reads and writes the state
+// through the given state database instead of a fresh one over a key-value store.
+func GenerateChainWithState(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, database state.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
 	if config == nil {
 		config = params.TestChainConfig
 	}
@@ -212,7 +244,7 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		return nil, nil
 	}
 	for i := 0; i < n; i++ {
-		statedb, err := state.New(parent.Root(), state.NewDatabase(db))
+		statedb, err := state.New(parent.Root(), database)
 		if err != nil {
 			panic(err)
 		}
@@ -232,7 +264,7 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 		time = new(big.Int).Add(parent.Time(), big.NewInt(10)) // block time is fixed at 10 seconds
 	}
 
-	return &types.Header{
+	header := &types.Header{
 		Root:       state.IntermediateRoot(chain.Config().IsEIP158(parent.Number())),
 		ParentHash: parent.Hash(),
 		Coinbase:   parent.Coinbase(),
@@ -246,6 +278,10 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 		Number:   new(big.Int).Add(parent.Number(), common.Big1),
 		Time:     time,
 	}
+	if chain.Config().IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(chain.Config(), parent.Header())
+	}
+	return header
 }
 
 // makeHeaderChain creates a deterministic chain of headers rooted at parent.
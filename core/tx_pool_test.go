@@ -259,6 +259,93 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that access list transactions are only accepted into the pool once the
+// Berlin fork activated.
+func TestTypedTransactionsBerlinGating(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	prefork := *params.TestChainConfig
+	prefork.BerlinBlock = nil
+
+	for i, config := range []*params.ChainConfig{&prefork, params.TestChainConfig} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.AddBalance(from, big.NewInt(1000000000))
+		blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+		pool := NewTxPool(testTxPoolConfig, config, blockchain)
+
+		tx, err := types.SignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.AccessListTx{
+			ChainID:  params.TestChainConfig.ChainID,
+			Gas:      100000,
+			GasPrice: big.NewInt(1),
+			To:       &common.Address{},
+			Value:    big.NewInt(100),
+			AccessList: types.AccessList{
+				{Address: common.Address{0x01}, StorageKeys: []common.Hash{{0x01}}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("config %d: failed to sign transaction: %v", i, err)
+		}
+		err = pool.AddRemote(tx)
+		if i == 0 && err != ErrTxTypeNotSupported {
+			t.Errorf("config %d: pre-Berlin error mismatch: have %v, want %v", i, err, ErrTxTypeNotSupported)
+		}
+		if i == 1 && err != nil {
+			t.Errorf("config %d: post-Berlin transaction rejected: %v", i, err)
+		}
+		pool.Stop()
+	}
+}
+
+// Tests that dynamic fee transactions are only accepted after the London fork
+// and that their tip may not exceed the fee cap.
+func TestDynamicFeeTransactionsLondonGating(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	london := *params.TestChainConfig
+	london.LondonBlock = big.NewInt(1)
+
+	tests := []struct {
+		config    *params.ChainConfig
+		tip, cap  int64
+		wantError error
+	}{
+		{params.TestChainConfig, 1, params.InitialBaseFee, ErrTxTypeNotSupported},
+		{&london, 1, params.InitialBaseFee, nil},
+		{&london, params.InitialBaseFee + 1, params.InitialBaseFee, ErrTipAboveFeeCap},
+	}
+	for i, test := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.AddBalance(from, new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000)))
+		blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+		pool := NewTxPool(testTxPoolConfig, test.config, blockchain)
+
+		tx, err := types.SignNewTx(key, types.NewLondonSigner(params.TestChainConfig.ChainID), &types.DynamicFeeTx{
+			ChainID:   params.TestChainConfig.ChainID,
+			Gas:       100000,
+			GasTipCap: big.NewInt(test.tip),
+			GasFeeCap: big.NewInt(test.cap),
+			To:        &common.Address{},
+			Value:     big.NewInt(100),
+		})
+		if err != nil {
+			t.Fatalf("test %d: failed to sign transaction: %v", i, err)
+		}
+		if err := pool.AddRemote(tx); err != test.wantError {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.wantError)
+		}
+		pool.Stop()
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,116 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func newTestReceipt(typ uint8) *Receipt {
+	receipt := &Receipt{
+		Type:              typ,
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs: []*Log{
+			{Address: common.BytesToAddress([]byte{0x11}), Topics: []common.Hash{{0x01}}, Data: []byte{0x01, 0x00, 0xff}},
+		},
+		TxHash:          common.BytesToHash([]byte{0x11, 0x11}),
+		ContractAddress: common.BytesToAddress([]byte{0x01, 0x11, 0x11}),
+		GasUsed:         111111,
+	}
+	receipt.Bloom = CreateBloom(Receipts{receipt})
+	return receipt
+}
+
+// Tests that legacy and typed receipts survive the network, consensus and
+// storage encodings.
+func TestReceiptEncoding(t *testing.T) {
+	for _, typ := range []uint8{LegacyTxType, AccessListTxType} {
+		want := newTestReceipt(typ)
+
+		// Network (RLP stream) encoding
+		enc, err := rlp.EncodeToBytes(want)
+		if err != nil {
+			t.Fatalf("type %d: failed to encode receipt: %v", typ, err)
+		}
+		var have Receipt
+		if err := rlp.DecodeBytes(enc, &have); err != nil {
+			t.Fatalf("type %d: failed to decode receipt: %v", typ, err)
+		}
+		if have.Type != typ || have.CumulativeGasUsed != want.CumulativeGasUsed || have.Bloom != want.Bloom || len(have.Logs) != 1 {
+			t.Errorf("type %d: network receipt mismatch: have %+v, want %+v", typ, have, want)
+		}
+		// Consensus (binary) encoding
+		bin, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("type %d: failed to marshal receipt: %v", typ, err)
+		}
+		if typ != LegacyTxType && bin[0] != typ {
+			t.Errorf("type %d: binary encoding missing type prefix: %x", typ, bin)
+		}
+		var unbin Receipt
+		if err := unbin.UnmarshalBinary(bin); err != nil {
+			t.Fatalf("type %d: failed to unmarshal receipt: %v", typ, err)
+		}
+		if unbin.Type != typ || unbin.Status != want.Status {
+			t.Errorf("type %d: binary receipt mismatch: have %+v, want %+v", typ, unbin, want)
+		}
+		// Storage encoding
+		stored, err := rlp.EncodeToBytes((*ReceiptForStorage)(want))
+		if err != nil {
+			t.Fatalf("type %d: failed to encode stored receipt: %v", typ, err)
+		}
+		var restored ReceiptForStorage
+		if err := rlp.DecodeBytes(stored, &restored); err != nil {
+			t.Fatalf("type %d: failed to decode stored receipt: %v", typ, err)
+		}
+		if !reflect.DeepEqual((*Receipt)(&restored), want) {
+			t.Errorf("type %d: stored receipt mismatch: have %+v, want %+v", typ, restored, want)
+		}
+	}
+}
+
+// Tests that the derived roots of transactions and receipts are computed over
+// the canonical EIP-2718 encodings of their items.
+func TestDeriveShaTyped(t *testing.T) {
+	txs := Transactions{signedEip2718Tx, rightvrsTx}
+	receipts := Receipts{newTestReceipt(AccessListTxType), newTestReceipt(LegacyTxType)}
+
+	for i, list := range []DerivableList{txs, receipts} {
+		tr := new(trie.Trie)
+		for j := 0; j < list.Len(); j++ {
+			key, _ := rlp.EncodeToBytes(uint(j))
+			tr.Update(key, list.GetRlp(j))
+		}
+		if have, want := DeriveSha(list), tr.Hash(); have != want {
+			t.Errorf("list %d: root mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	// The trie values of typed items must be the type prefixed payloads
+	if enc := txs.GetRlp(0); !bytes.Equal(enc, common.FromHex("01f8630103018261a894b94f5374fce5edbc8e2a8697c15331677e6ebf0b0a825544c001a0c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b2660a032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d37521")) {
+		t.Errorf("typed transaction trie value mismatch: %x", enc)
+	}
+	if enc := receipts.GetRlp(0); enc[0] != AccessListTxType {
+		t.Errorf("typed receipt trie value missing type prefix: %x", enc)
+	}
+}
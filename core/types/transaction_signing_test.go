@@ -136,3 +136,134 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestEIP2930Signer(t *testing.T) {
+	var (
+		key, keyAddr = defaultTestKey()
+		signer1      = NewEIP2930Signer(big.NewInt(1))
+		signer2      = NewEIP2930Signer(big.NewInt(2))
+		tx0          = NewTx(&AccessListTx{Nonce: 1})
+		tx1          = NewTx(&AccessListTx{ChainID: big.NewInt(1), Nonce: 1})
+		tx2, _       = SignNewTx(key, signer2, &AccessListTx{ChainID: big.NewInt(2), Nonce: 1})
+	)
+	tests := []struct {
+		tx             *Transaction
+		signer         Signer
+		wantSignerHash common.Hash
+		wantSenderErr  error
+		wantSignErr    error
+		wantHash       common.Hash // after signing
+	}{
+		{
+			tx:             tx0,
+			signer:         signer1,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+			wantSenderErr:  ErrInvalidChainId,
+			wantHash:       common.HexToHash("5dc8224ab0e0d52be5715d33206597cf13698a399626a72d1e127db54836403e"),
+		},
+		{
+			tx:             tx1,
+			signer:         signer1,
+			wantSenderErr:  ErrInvalidSig,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+			wantHash:       common.HexToHash("5dc8224ab0e0d52be5715d33206597cf13698a399626a72d1e127db54836403e"),
+		},
+		{
+			// This checks what happens when trying to sign an unsigned tx for the wrong chain.
+			tx:             tx1,
+			signer:         signer2,
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignerHash: common.HexToHash("367967247499343401261d718ed5aa4c9486583e4d89251afce47f4a33c33362"),
+			wantSignErr:    ErrInvalidChainId,
+		},
+		{
+			// This checks what happens when trying to re-sign a signed tx for the wrong chain.
+			tx:             tx2,
+			signer:         signer1,
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignerHash: common.HexToHash("846ad7672f2a3a40c1f959cd4a8ad21786d620077084d84c8d7c077714caa139"),
+			wantSignErr:    ErrInvalidChainId,
+		},
+	}
+
+	for i, test := range tests {
+		sigHash := test.signer.Hash(test.tx)
+		if sigHash != test.wantSignerHash {
+			t.Errorf("test %d: wrong sig hash: got %x, want %x", i, sigHash, test.wantSignerHash)
+		}
+		sender, err := Sender(test.signer, test.tx)
+		if err != test.wantSenderErr {
+			t.Errorf("test %d: wrong Sender error %q", i, err)
+		}
+		if err == nil && sender != keyAddr {
+			t.Errorf("test %d: wrong sender address %x", i, sender)
+		}
+		signedTx, err := SignTx(test.tx, test.signer, key)
+		if err != test.wantSignErr {
+			t.Fatalf("test %d: wrong SignTx error %q", i, err)
+		}
+		if signedTx != nil {
+			if signedTx.Hash() != test.wantHash {
+				t.Errorf("test %d: wrong tx hash after signing: got %x, want %x", i, signedTx.Hash(), test.wantHash)
+			}
+		}
+	}
+}
+
+// Tests that the signers predating EIP-2930 reject typed transactions.
+func TestLegacySignersRejectTypedTx(t *testing.T) {
+	key, _ := defaultTestKey()
+
+	tx, err := SignNewTx(key, NewEIP2930Signer(big.NewInt(1)), &AccessListTx{ChainID: big.NewInt(1), Nonce: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, signer := range []Signer{NewEIP155Signer(big.NewInt(1)), HomesteadSigner{}, FrontierSigner{}} {
+		if _, err := Sender(signer, tx); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: sender error mismatch: have %v, want %v", signer, err, ErrTxTypeNotSupported)
+		}
+		if _, err := SignTx(tx, signer, key); err != ErrTxTypeNotSupported {
+			t.Errorf("%T: signing error mismatch: have %v, want %v", signer, err, ErrTxTypeNotSupported)
+		}
+	}
+}
+
+// Tests that dynamic fee transactions are only accepted by the London signer,
+// which still handles the older transaction types.
+func TestLondonSigner(t *testing.T) {
+	key, addr := defaultTestKey()
+
+	london := NewLondonSigner(big.NewInt(1))
+	tx, err := SignNewTx(key, london, &DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(london, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP2930Signer(big.NewInt(1)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("EIP-2930 signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if _, err := Sender(NewLondonSigner(big.NewInt(2)), tx); err != ErrInvalidChainId {
+		t.Errorf("wrong chain error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	// Older transaction types must still be recoverable
+	for _, data := range []TxData{
+		&LegacyTx{Nonce: 2, GasPrice: big.NewInt(1), Gas: 21000},
+		&AccessListTx{ChainID: big.NewInt(1), Nonce: 3, GasPrice: big.NewInt(1), Gas: 21000},
+	} {
+		tx, err := SignNewTx(key, london, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from, err := Sender(london, tx); err != nil || from != addr {
+			t.Errorf("type %d: sender mismatch: have %x (%v), want %x", tx.Type(), from, err, addr)
+		}
+	}
+}
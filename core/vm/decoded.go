@@ -0,0 +1,334 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// decodedCacheSize is the number of pre-decoded contracts kept around.
+const decodedCacheSize = 1024
+
+// decodedCache caches pre-decoded contracts, keyed by code hash and instruction
+// set, across interpreter instances.
+var decodedCache, _ = lru.New(decodedCacheSize)
+
+type decodedKey struct {
+	hash common.Hash
+	set  *[256]operation
+}
+
+// instruction is a single pre-decoded instruction of a contract.
+//
+// Instructions are grouped into segments: runs of instructions with constant gas
+// costs, optionally terminated by one instruction with a dynamic cost. Segments
+// end at every jump, halt or gas-observing instruction and start at every jump
+// destination, so that the static gas of a whole segment can be charged upfront
+// without affecting any observable behaviour.
+type instruction struct {
+	op     OpCode
+	pc     uint64   // Position of the instruction in the bytecode
+	arg    *big.Int // Immediate value of PUSHn, read only
+	static bool     // Whether the gas cost of the instruction is constant
+	gas    uint64   // Constant gas cost of the instruction
+
+	// Fields of PUSHn instructions followed by JUMP or JUMPI
+	fused  bool // Whether the push and jump may execute as one instruction
+	target int  // Instruction index of the jump destination, -1 if invalid
+
+	// Fields of the first instruction of a segment
+	segmentGas uint64 // Total constant gas of the segment
+	segmentEnd int    // Instruction index following the segment
+}
+
+// decodedCode is the bytecode of a contract decoded for a given instruction set.
+type decodedCode struct {
+	instrs    []instruction
+	jumpdests []int32 // Instruction index of each valid JUMPDEST position, -1 otherwise
+}
+
+// hasConstantGas reports whether an opcode has a fixed gas cost in all the
+// instruction sets.
+func hasConstantGas(op OpCode) bool {
+	switch {
+	case op >= PUSH1 && op <= SWAP16:
+		return true
+	case op >= LT && op <= SAR:
+		return true
+	}
+	switch op {
+	case STOP, ADD, MUL, SUB, DIV, SDIV, MOD, SMOD, ADDMOD, MULMOD, SIGNEXTEND,
+		ADDRESS, ORIGIN, CALLER, CALLVALUE, CALLDATALOAD, CALLDATASIZE, CODESIZE,
+		GASPRICE, RETURNDATASIZE, BLOCKHASH, COINBASE, TIMESTAMP, NUMBER,
+		DIFFICULTY, GASLIMIT, CHAINID, SELFBALANCE, POP, JUMP, JUMPI, PC, MSIZE,
+		GAS, JUMPDEST:
+		return true
+	}
+	return false
+}
+
+// decodeCode pre-decodes the bytecode of a contract for an instruction set.
+func decodeCode(code []byte, jt *[256]operation) *decodedCode {
+	d := &decodedCode{
+		instrs:    make([]instruction, 0, len(code)+1),
+		jumpdests: make([]int32, len(code)),
+	}
+	for i := range d.jumpdests {
+		d.jumpdests[i] = -1
+	}
+	// Split the code into instructions, decoding the push immediates the same
+	// way the PUSHn operations do
+	for pc := 0; pc < len(code); pc++ {
+		op := OpCode(code[pc])
+		ins := instruction{op: op, pc: uint64(pc), target: -1}
+
+		if op.IsPush() {
+			size := int(op - PUSH1 + 1)
+			start, end := pc+1, pc+1+size
+			if start > len(code) {
+				start = len(code)
+			}
+			if end > len(code) {
+				end = len(code)
+			}
+			ins.arg = new(big.Int).SetBytes(common.RightPadBytes(code[start:end], size))
+			pc += size
+		}
+		if op == JUMPDEST {
+			d.jumpdests[ins.pc] = int32(len(d.instrs))
+		}
+		d.setGas(&ins, jt)
+		d.instrs = append(d.instrs, ins)
+	}
+	// Running off the end of the code is an implicit STOP
+	stop := instruction{op: STOP, pc: uint64(len(code)), target: -1}
+	d.setGas(&stop, jt)
+	d.instrs = append(d.instrs, stop)
+
+	// Group the instructions into segments and fuse the static jumps
+	for i := 0; i < len(d.instrs); {
+		start := i
+		for gas := uint64(0); i < len(d.instrs); {
+			ins := &d.instrs[i]
+			if i > start && ins.op == JUMPDEST {
+				break
+			}
+			i++
+			if !ins.static {
+				break
+			}
+			gas += ins.gas
+			d.instrs[start].segmentGas = gas
+
+			if operation := &jt[ins.op]; operation.jumps || operation.halts || ins.op == GAS {
+				break
+			}
+			if ins.arg != nil && (d.instrs[i].op == JUMP || d.instrs[i].op == JUMPI) {
+				ins.fused, ins.target = true, d.jumpTarget(ins.arg)
+			}
+		}
+		d.instrs[start].segmentEnd = i
+	}
+	return d
+}
+
+// setGas marks the instructions with a constant gas cost in the instruction set.
+func (d *decodedCode) setGas(ins *instruction, jt *[256]operation) {
+	operation := &jt[ins.op]
+	if !operation.valid || !hasConstantGas(ins.op) || operation.memorySize != nil {
+		return
+	}
+	// Constant gas functions don't depend on any of their arguments
+	gas, err := operation.gasCost(params.GasTable{}, nil, nil, nil, nil, 0)
+	if err != nil {
+		return
+	}
+	ins.static, ins.gas = true, gas
+}
+
+// jumpTarget returns the instruction index of a jump destination, or -1 if the
+// destination is not a valid JUMPDEST.
+func (d *decodedCode) jumpTarget(dest *big.Int) int {
+	if !dest.IsUint64() || dest.Uint64() >= uint64(len(d.jumpdests)) {
+		return -1
+	}
+	return int(d.jumpdests[dest.Uint64()])
+}
+
+// decode returns the pre-decoded code of the contract, from the cache if the
+// code was already analysed for the interpreter's instruction set.
+func (in *EVMInterpreter) decode(contract *Contract) *decodedCode {
+	if contract.CodeHash == (common.Hash{}) {
+		return decodeCode(contract.Code, in.instructionSet)
+	}
+	key := decodedKey{contract.CodeHash, in.instructionSet}
+	if code, ok := decodedCache.Get(key); ok {
+		return code.(*decodedCode)
+	}
+	code := decodeCode(contract.Code, in.instructionSet)
+	decodedCache.Add(key, code)
+	return code
+}
+
+// invalidJump returns the error of jumping to an invalid destination.
+func invalidJump(contract *Contract, dest *big.Int) error {
+	return fmt.Errorf("invalid jump destination (%v) %v", contract.GetOp(dest.Uint64()), dest)
+}
+
+// runDecoded executes pre-decoded contract code. It behaves exactly like the
+// main loop of Run, but charges the constant gas of whole segments at once,
+// pushes pre-decoded immediates and jumps to pre-resolved destinations.
+//
+// If the contract cannot pay for a whole segment, its instructions are charged
+// one by one, failing at the very same instruction Run would.
+func (in *EVMInterpreter) runDecoded(code *decodedCode, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	var (
+		instrs   = code.instrs
+		i        int    // index of the current instruction
+		end      int    // index following the current segment
+		prepaid  bool   // whether the constant gas of the segment is paid
+		pc       uint64 // program counter of the current instruction
+		stackMax = int(params.StackLimit)
+	)
+	for {
+		if i == end {
+			if atomic.LoadInt32(&in.evm.abort) != 0 {
+				return nil, nil
+			}
+			end = instrs[i].segmentEnd
+			prepaid = contract.UseGas(instrs[i].segmentGas)
+		}
+		ins := &instrs[i]
+		op := ins.op
+
+		// Execute static jumps in one go if no stack checks can fail, otherwise
+		// run the push and the jump separately to fail in the right place
+		if ins.fused && prepaid {
+			if size := stack.len(); instrs[i+1].op == JUMP && size < stackMax {
+				if ins.target < 0 {
+					return nil, invalidJump(contract, ins.arg)
+				}
+				i, end = ins.target, ins.target
+				continue
+			} else if instrs[i+1].op == JUMPI && size >= 1 && size < stackMax {
+				cond := stack.pop()
+				if cond.Sign() != 0 {
+					if ins.target < 0 {
+						return nil, invalidJump(contract, ins.arg)
+					}
+					i, end = ins.target, ins.target
+				} else {
+					i += 2
+				}
+				in.intPool.put(cond)
+				continue
+			}
+		}
+		operation := &in.cfg.JumpTable[op]
+		if !operation.valid {
+			return nil, fmt.Errorf("invalid opcode 0x%x", int(op))
+		}
+		if err := operation.validateStack(stack); err != nil {
+			return nil, err
+		}
+		if operation.writes || op == CALL {
+			if err := in.enforceRestrictions(op, *operation, stack); err != nil {
+				return nil, err
+			}
+		}
+		if !ins.static {
+			var memorySize uint64
+			if operation.memorySize != nil {
+				memSize, overflow := bigUint64(operation.memorySize(stack))
+				if overflow {
+					return nil, errGasUintOverflow
+				}
+				if memorySize, overflow = math.SafeMul(toWordSize(memSize), 32); overflow {
+					return nil, errGasUintOverflow
+				}
+			}
+			cost, err := operation.gasCost(in.gasTable, in.evm, contract, stack, mem, memorySize)
+			if err != nil || !contract.UseGas(cost) {
+				return nil, ErrOutOfGas
+			}
+			if memorySize > 0 {
+				mem.Resize(memorySize)
+			}
+		} else if !prepaid && !contract.UseGas(ins.gas) {
+			return nil, ErrOutOfGas
+		}
+		// Execute the instructions depending on the program counter inline
+		switch {
+		case ins.arg != nil:
+			stack.push(in.intPool.get().Set(ins.arg))
+			i++
+			continue
+
+		case op == JUMPDEST:
+			i++
+			continue
+
+		case op == JUMP:
+			pos := stack.pop()
+			target := code.jumpTarget(pos)
+			if target < 0 {
+				return nil, invalidJump(contract, pos)
+			}
+			in.intPool.put(pos)
+			i, end = target, target
+			continue
+
+		case op == JUMPI:
+			pos, cond := stack.pop(), stack.pop()
+			if cond.Sign() != 0 {
+				target := code.jumpTarget(pos)
+				if target < 0 {
+					return nil, invalidJump(contract, pos)
+				}
+				i, end = target, target
+			} else {
+				i++
+			}
+			in.intPool.put(pos, cond)
+			continue
+		}
+		pc = ins.pc
+		res, err := operation.execute(&pc, in, contract, mem, stack)
+		if verifyPool {
+			verifyIntegerPool(in.intPool)
+		}
+		if operation.returns {
+			in.returnData = res
+		}
+		switch {
+		case err != nil:
+			return nil, err
+		case operation.reverts:
+			return res, errExecutionReverted
+		case operation.halts:
+			return res, nil
+		}
+		i++
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the opcodes assumed to have constant gas costs are exactly the ones
+// using a constant gas function in all the instruction sets.
+func TestConstantGasOps(t *testing.T) {
+	var (
+		constGas = reflect.ValueOf(constGasFunc(0)).Pointer()
+		push     = reflect.ValueOf(gasPush).Pointer()
+		dup      = reflect.ValueOf(gasDup).Pointer()
+		swap     = reflect.ValueOf(gasSwap).Pointer()
+	)
+	sets := map[string]*[256]operation{
+		"frontier":       &frontierInstructionSet,
+		"homestead":      &homesteadInstructionSet,
+		"byzantium":      &byzantiumInstructionSet,
+		"constantinople": &constantinopleInstructionSet,
+		"istanbul":       &istanbulInstructionSet,
+	}
+	for name, jt := range sets {
+		for i, operation := range jt {
+			if !operation.valid {
+				continue
+			}
+			switch ptr := reflect.ValueOf(operation.gasCost).Pointer(); {
+			case ptr == constGas || ptr == push || ptr == dup || ptr == swap:
+				if !hasConstantGas(OpCode(i)) {
+					t.Errorf("%s: %v has constant gas but is not marked so", name, OpCode(i))
+				}
+			default:
+				if hasConstantGas(OpCode(i)) {
+					t.Errorf("%s: %v has dynamic gas but is marked constant", name, OpCode(i))
+				}
+			}
+		}
+	}
+}
+
+// Tests the splitting of code into instructions and segments.
+func TestDecodeCode(t *testing.T) {
+	code := []byte{
+		byte(PUSH1), 0x04, byte(JUMP), // 0: static jump
+		byte(JUMPDEST),          // 3: unreachable
+		byte(JUMPDEST),          // 4
+		byte(PUSH2), 0x5b, 0x5b, // 5: jumpdests in push data
+		byte(MSTORE),                   // 8: dynamic gas
+		byte(PUSH1), 0x03, byte(JUMPI), // 9: conditional static jump
+		byte(PUSH32), 0x01, // 12: truncated push
+	}
+	d := decodeCode(code, &istanbulInstructionSet)
+
+	ops := []OpCode{PUSH1, JUMP, JUMPDEST, JUMPDEST, PUSH2, MSTORE, PUSH1, JUMPI, PUSH32, STOP}
+	if len(d.instrs) != len(ops) {
+		t.Fatalf("instruction count mismatch: have %d, want %d", len(d.instrs), len(ops))
+	}
+	for i, op := range ops {
+		if d.instrs[i].op != op {
+			t.Errorf("instruction %d: opcode mismatch: have %v, want %v", i, d.instrs[i].op, op)
+		}
+	}
+	if d.instrs[4].arg.Uint64() != 0x5b5b {
+		t.Errorf("push immediate mismatch: have %x, want %x", d.instrs[4].arg, 0x5b5b)
+	}
+	if want := new(big.Int).Lsh(big.NewInt(1), 248); d.instrs[8].arg.Cmp(want) != 0 {
+		t.Errorf("truncated push immediate mismatch: have %x, want %x", d.instrs[8].arg, want)
+	}
+	if !d.instrs[0].fused || d.instrs[0].target != 3 {
+		t.Errorf("static jump mismatch: fused %v, target %d", d.instrs[0].fused, d.instrs[0].target)
+	}
+	if !d.instrs[6].fused || d.instrs[6].target != 2 {
+		t.Errorf("static conditional jump mismatch: fused %v, target %d", d.instrs[6].fused, d.instrs[6].target)
+	}
+	for i := 5; i < len(code); i++ {
+		if d.jumpdests[i] >= 0 {
+			t.Errorf("position %d: unexpected jumpdest", i)
+		}
+	}
+	segments := []struct {
+		start, end int
+		gas        uint64
+	}{
+		{0, 2, GasFastestStep + GasMidStep},
+		{2, 3, params.JumpdestGas},
+		{3, 6, params.JumpdestGas + GasFastestStep},
+		{6, 8, GasFastestStep + GasSlowStep},
+		{8, 10, GasFastestStep},
+	}
+	for _, seg := range segments {
+		if end := d.instrs[seg.start].segmentEnd; end != seg.end {
+			t.Errorf("segment %d: end mismatch: have %d, want %d", seg.start, end, seg.end)
+		}
+		if gas := d.instrs[seg.start].segmentGas; gas != seg.gas {
+			t.Errorf("segment %d: gas mismatch: have %d, want %d", seg.start, gas, seg.gas)
+		}
+	}
+}
+
+// runCode executes the code with the given gas allowance, optionally from its
+// pre-decoded form.
+func runCode(code []byte, gas uint64, decoded bool) ([]byte, uint64, error) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		env     = NewEVM(Context{BlockNumber: new(big.Int)}, nil, params.TestChainConfig, Config{EnableCodeDecoding: decoded})
+	)
+	contract := NewContract(AccountRef(address), AccountRef(address), new(big.Int), gas)
+	contract.SetCallCode(&address, crypto.Keccak256Hash(code), code)
+
+	ret, err := env.interpreter.Run(contract, nil, false)
+	return ret, contract.Gas, err
+}
+
+// Tests that executing pre-decoded code yields the same results as the reference
+// interpreter, running out of gas at every possible point.
+func TestDecodedExecution(t *testing.T) {
+	tests := map[string][]byte{
+		"loop": {
+			byte(PUSH1), 0x0a, // counter
+			byte(JUMPDEST),
+			byte(PUSH1), 0x01, byte(SWAP1), byte(SUB),
+			byte(DUP1), byte(DUP1), byte(PUSH1), 0x00, byte(MSTORE),
+			byte(PUSH1), 0x02, byte(JUMPI),
+			byte(PUSH1), 0x20, byte(PUSH1), 0x00, byte(RETURN),
+		},
+		"dynamic-jump": {
+			byte(PUSH1), 0x02, byte(PUSH1), 0x04, byte(ADD), byte(JUMP),
+			byte(JUMPDEST), byte(PC), byte(GAS),
+			byte(PUSH1), 0x00, byte(MSTORE), byte(PUSH1), 0x20, byte(PUSH1), 0x00, byte(RETURN),
+		},
+		"not-taken": {
+			byte(PUSH1), 0x00, byte(PUSH1), 0xff, byte(JUMPI),
+			byte(PUSH1), 0x01, byte(PUSH1), 0xff, byte(JUMPI),
+		},
+		"invalid-jump":     {byte(PUSH1), 0x03, byte(JUMP), byte(PUSH1), byte(JUMPDEST)},
+		"invalid-big-jump": {byte(PUSH32), 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, byte(JUMP)},
+		"underflow":        {byte(PUSH1), 0x01, byte(ADD)},
+		"jump-underflow":   {byte(PUSH1), 0x03, byte(JUMPI), byte(JUMPDEST)},
+		"overflow":         {byte(JUMPDEST), byte(PUSH1), 0x00, byte(PUSH1), 0x00, byte(JUMP)},
+		"invalid-opcode":   {byte(PUSH1), 0x01, 0xfe},
+		"truncated-push":   {byte(PUSH32), 0x01},
+		"revert": {
+			byte(PUSH1), 0x2a, byte(PUSH1), 0x00, byte(MSTORE),
+			byte(PUSH1), 0x20, byte(PUSH1), 0x00, byte(REVERT),
+		},
+		"run-off": {byte(PUSH1), 0x01, byte(PUSH1), 0x02},
+	}
+	for name, code := range tests {
+		// Find the gas needed to run the code, then try every allowance below
+		_, left, _ := runCode(code, 1000000, false)
+		for gas := uint64(0); gas <= 1000000-left+1; gas++ {
+			wantRet, wantGas, wantErr := runCode(code, gas, false)
+			haveRet, haveGas, haveErr := runCode(code, gas, true)
+
+			if fmt.Sprint(haveErr) != fmt.Sprint(wantErr) {
+				t.Errorf("%s, gas %d: error mismatch: have %v, want %v", name, gas, haveErr, wantErr)
+			}
+			if !reflect.DeepEqual(haveRet, wantRet) {
+				t.Errorf("%s, gas %d: return mismatch: have %x, want %x", name, gas, haveRet, wantRet)
+			}
+			// Unspent gas is only observable on success or revert
+			if (wantErr == nil || wantErr == errExecutionReverted) && haveGas != wantGas {
+				t.Errorf("%s, gas %d: gas mismatch: have %d, want %d", name, gas, haveGas, wantGas)
+			}
+		}
+	}
+}
+
+// loopCode counts down from 0xffffff, running out of gas before completing.
+var loopCode = []byte{
+	byte(PUSH3), 0xff, 0xff, 0xff,
+	byte(JUMPDEST),
+	byte(PUSH1), 0x01, byte(SWAP1), byte(SUB),
+	byte(DUP1), byte(PUSH1), 0x04, byte(JUMPI),
+}
+
+func benchmarkCode(b *testing.B, code []byte, decoded bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runCode(code, 10000000, decoded)
+	}
+}
+
+func BenchmarkInterpreterLoop(b *testing.B) { benchmarkCode(b, loopCode, false) }
+func BenchmarkDecodedLoop(b *testing.B)     { benchmarkCode(b, loopCode, true) }
+
+func BenchmarkDecodeCode(b *testing.B) {
+	code := make([]byte, 24576)
+	for i := range code {
+		code[i] = byte(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeCode(code, &istanbulInstructionSet)
+	}
+}
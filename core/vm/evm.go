@@ -43,11 +43,7 @@ type (
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := evm.precompile(*contract.CodeAddr); p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
@@ -88,6 +84,7 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // EIP-1559 base fee of the block, nil before the fork
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -140,23 +137,15 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 	}
 
 	if chainConfig.IsEWASM(ctx.BlockNumber) {
-		// to be implemented by EVM-C and Wagon PRs.
-		// if vmConfig.EWASMInterpreter != "" {
-		//  extIntOpts := strings.Split(vmConfig.EWASMInterpreter, ":")
-		//  path := extIntOpts[0]
-		//  options := []string{}
-		//  if len(extIntOpts) > 1 {
-		//    options = extIntOpts[1..]
-		//  }
-		//  evm.interpreters = append(evm.interpreters, NewEVMVCInterpreter(evm, vmConfig, options))
-		// } else {
-		// 	evm.interpreters = append(evm.interpreters, NewEWASMInterpreter(evm, vmConfig))
-		// }
-		panic("No supported ewasm interpreter yet.")
-	}
-
-	// vmConfig.EVMInterpreter will be used by EVM-C, it won't be checked here
-	// as we always want to have the built-in EVM as the failover option.
+		if vmConfig.EWASMInterpreter == "" {
+			panic("No supported ewasm interpreter yet.")
+		}
+		evm.interpreters = append(evm.interpreters, NewEVMC(vmConfig.EWASMInterpreter, true, evm))
+	}
+	if vmConfig.EVMInterpreter != "" {
+		evm.interpreters = append(evm.interpreters, NewEVMC(vmConfig.EVMInterpreter, false, evm))
+	}
+	// The built-in EVM is always kept as the failover option.
 	evm.interpreters = append(evm.interpreters, NewEVMInterpreter(evm, vmConfig))
 	evm.interpreter = evm.interpreters[0]
 
@@ -174,6 +163,26 @@ func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
 }
 
+// precompile returns the pre-compiled contract at addr active in the current
+// block, if any. The BLS12-381 contracts are an extension on top of the set of
+// the active release, they don't activate earlier releases.
+func (evm *EVM) precompile(addr common.Address) PrecompiledContract {
+	precompiles := PrecompiledContractsHomestead
+	if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
+		precompiles = PrecompiledContractsByzantium
+	}
+	if evm.ChainConfig().IsIstanbul(evm.BlockNumber) {
+		precompiles = PrecompiledContractsIstanbul
+	}
+	if p := precompiles[addr]; p != nil {
+		return p
+	}
+	if evm.ChainConfig().IsBLS12381(evm.BlockNumber) {
+		return PrecompiledContractsBLS12381[addr]
+	}
+	return nil
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -197,11 +206,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			// Calling a non existing account, don't do anything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
@@ -466,3 +471,7 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
+
+// NoBaseFee returns whether the EIP-1559 fee checks are disabled for messages
+// not paying any fees.
+func (evm *EVM) NoBaseFee() bool { return evm.vmConfig.NoBaseFee }
@@ -0,0 +1,342 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm/evmc"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// wasmPrefix is the magic prefix of ewasm contract code.
+var wasmPrefix = []byte("\x00asm")
+
+// EVMC is an Interpreter executing contracts in an external VM loaded through
+// the EVMC ABI, with the host callbacks implemented against the StateDB.
+type EVMC struct {
+	instance   *evmc.Instance
+	env        *EVM
+	capability evmc.Capability // The kind of code this interpreter is used for
+	readOnly   bool            // Whether the execution is in static mode
+}
+
+var (
+	evmcLock    sync.Mutex
+	evmcModules = make(map[string]*evmc.Instance) // Loaded VMs by configuration
+)
+
+// LoadEVMC loads and configures the EVMC VM described by config, in the form
+// path[,name=value,...], checking that it is able to run EVM or ewasm code.
+// Loaded VMs are shared by all the EVMs using the same configuration.
+func LoadEVMC(config string, ewasm bool) (*evmc.Instance, error) {
+	evmcLock.Lock()
+	defer evmcLock.Unlock()
+
+	instance, ok := evmcModules[config]
+	if !ok {
+		var err error
+		if instance, err = evmc.LoadAndConfigure(config); err != nil {
+			return nil, err
+		}
+		evmcModules[config] = instance
+	}
+	capability, kind := evmc.CapabilityEVM1, "EVM"
+	if ewasm {
+		capability, kind = evmc.CapabilityEWASM, "ewasm"
+	}
+	if !instance.HasCapability(capability) {
+		return nil, fmt.Errorf("EVMC module %s %s does not support %s", instance.Name(), instance.Version(), kind)
+	}
+	return instance, nil
+}
+
+// NewEVMC returns an interpreter running EVM or ewasm code in the EVMC VM
+// described by config. It panics if the VM cannot be loaded, which should be
+// checked beforehand with LoadEVMC.
+func NewEVMC(config string, ewasm bool, env *EVM) *EVMC {
+	instance, err := LoadEVMC(config, ewasm)
+	if err != nil {
+		panic(fmt.Sprintf("EVMC VM loading failed: %v", err))
+	}
+	capability := evmc.CapabilityEVM1
+	if ewasm {
+		capability = evmc.CapabilityEWASM
+	}
+	return &EVMC{instance: instance, env: env, capability: capability}
+}
+
+// Run executes the contract in the external VM.
+func (evm *EVMC) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
+	evm.env.depth++
+	defer func() { evm.env.depth-- }()
+
+	// Make sure the readOnly is only set if we aren't in readOnly yet.
+	// This makes also sure that the readOnly flag isn't removed for child calls.
+	if readOnly && !evm.readOnly {
+		evm.readOnly = true
+		defer func() { evm.readOnly = false }()
+	}
+	// Don't bother with the execution if there's no code.
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
+	// Init code is executed against an account without code, whereas called
+	// code always belongs to the account it runs in.
+	kind := evmc.Call
+	if evm.env.StateDB.GetCodeSize(contract.Address()) == 0 {
+		kind = evmc.Create
+	}
+	output, gasLeft, err := evm.instance.Execute(
+		&evmcHost{env: evm.env, contract: contract},
+		evmcRevision(evm.env.chainRules),
+		kind,
+		evm.readOnly,
+		evm.env.depth-1,
+		int64(contract.Gas),
+		contract.Address(),
+		contract.Caller(),
+		input,
+		common.BigToHash(contract.Value()),
+		contract.Code,
+		common.Hash{},
+	)
+	contract.Gas = uint64(gasLeft)
+
+	if err == evmc.Revert {
+		err = errExecutionReverted
+	} else if evmcErr, ok := err.(evmc.Error); ok && evmcErr.IsInternalError() {
+		panic(fmt.Sprintf("EVMC VM internal error: %v", evmcErr))
+	}
+	return output, err
+}
+
+// CanRun tells if the contract, passed as an argument, can be run by the
+// external VM: ewasm VMs run wasm modules only, EVM ones anything else.
+func (evm *EVMC) CanRun(code []byte) bool {
+	isWasm := bytes.HasPrefix(code, wasmPrefix)
+	if evm.capability == evmc.CapabilityEWASM {
+		return isWasm
+	}
+	return !isWasm
+}
+
+// evmcRevision returns the EVMC revision of the protocol rules.
+func evmcRevision(rules params.Rules) evmc.Revision {
+	switch {
+	case rules.IsIstanbul:
+		return evmc.Istanbul
+	case rules.IsPetersburg:
+		return evmc.Petersburg
+	case rules.IsConstantinople:
+		return evmc.Constantinople
+	case rules.IsByzantium:
+		return evmc.Byzantium
+	case rules.IsEIP158:
+		return evmc.SpuriousDragon
+	case rules.IsEIP150:
+		return evmc.TangerineWhistle
+	case rules.IsHomestead:
+		return evmc.Homestead
+	default:
+		return evmc.Frontier
+	}
+}
+
+// evmcHost implements the EVMC host interface for a contract execution.
+type evmcHost struct {
+	env      *EVM
+	contract *Contract
+}
+
+func (host *evmcHost) AccountExists(addr common.Address) bool {
+	if host.env.chainRules.IsEIP158 {
+		return !host.env.StateDB.Empty(addr)
+	}
+	return host.env.StateDB.Exist(addr)
+}
+
+func (host *evmcHost) GetStorage(addr common.Address, key common.Hash) common.Hash {
+	return host.env.StateDB.GetState(addr, key)
+}
+
+// SetStorage writes a storage slot, applying the refunds of the active gas
+// metering rules and reporting the kind of write for the VM to charge gas.
+func (host *evmcHost) SetStorage(addr common.Address, key common.Hash, value common.Hash) evmc.StorageStatus {
+	var (
+		db      = host.env.StateDB
+		rules   = host.env.chainRules
+		current = db.GetState(addr, key)
+	)
+	if current == value {
+		return evmc.StorageUnchanged
+	}
+	db.SetState(addr, key, value)
+
+	// The legacy gas metering only takes into consideration the current state
+	if !rules.IsIstanbul && (rules.IsPetersburg || !rules.IsConstantinople) {
+		switch {
+		case current == (common.Hash{}):
+			return evmc.StorageAdded
+		case value == (common.Hash{}):
+			db.AddRefund(params.SstoreRefundGas)
+			return evmc.StorageDeleted
+		default:
+			return evmc.StorageModified
+		}
+	}
+	// The net gas metering of EIP-1283 and EIP-2200 also considers the value
+	// at the start of the transaction
+	clearRefund, resetClearRefund, resetRefund := params.NetSstoreClearRefund, params.NetSstoreResetClearRefund, params.NetSstoreResetRefund
+	if rules.IsIstanbul {
+		clearRefund, resetClearRefund, resetRefund = params.SstoreClearRefundEIP2200, params.SstoreInitRefundEIP2200, params.SstoreCleanRefundEIP2200
+	}
+	original := db.GetCommittedState(addr, key)
+	if original == current {
+		if original == (common.Hash{}) {
+			return evmc.StorageAdded
+		}
+		if value == (common.Hash{}) {
+			db.AddRefund(clearRefund)
+			return evmc.StorageDeleted
+		}
+		return evmc.StorageModified
+	}
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) {
+			db.SubRefund(clearRefund)
+		} else if value == (common.Hash{}) {
+			db.AddRefund(clearRefund)
+		}
+	}
+	if original == value {
+		if original == (common.Hash{}) {
+			db.AddRefund(resetClearRefund)
+		} else {
+			db.AddRefund(resetRefund)
+		}
+	}
+	return evmc.StorageModifiedAgain
+}
+
+func (host *evmcHost) GetBalance(addr common.Address) common.Hash {
+	return common.BigToHash(host.env.StateDB.GetBalance(addr))
+}
+
+func (host *evmcHost) GetCodeSize(addr common.Address) int {
+	return host.env.StateDB.GetCodeSize(addr)
+}
+
+func (host *evmcHost) GetCodeHash(addr common.Address) common.Hash {
+	if host.env.StateDB.Empty(addr) {
+		return common.Hash{}
+	}
+	return host.env.StateDB.GetCodeHash(addr)
+}
+
+func (host *evmcHost) GetCode(addr common.Address) []byte {
+	return host.env.StateDB.GetCode(addr)
+}
+
+func (host *evmcHost) Selfdestruct(addr common.Address, beneficiary common.Address) {
+	db := host.env.StateDB
+	if !db.HasSuicided(addr) {
+		db.AddRefund(params.SuicideRefundGas)
+	}
+	db.AddBalance(beneficiary, db.GetBalance(addr))
+	db.Suicide(addr)
+}
+
+func (host *evmcHost) GetTxContext() evmc.TxContext {
+	return evmc.TxContext{
+		GasPrice:   common.BigToHash(host.env.GasPrice),
+		Origin:     host.env.Origin,
+		Coinbase:   host.env.Coinbase,
+		Number:     host.env.BlockNumber.Int64(),
+		Timestamp:  host.env.Time.Int64(),
+		GasLimit:   int64(host.env.GasLimit),
+		Difficulty: common.BigToHash(host.env.Difficulty),
+	}
+}
+
+// GetBlockHash returns the hash of one of the 256 most recent blocks, or zero
+// for any other block, as the BLOCKHASH opcode.
+func (host *evmcHost) GetBlockHash(number int64) common.Hash {
+	current := host.env.BlockNumber.Int64()
+	if number >= 0 && number < current && number >= current-256 {
+		return host.env.GetHash(uint64(number))
+	}
+	return common.Hash{}
+}
+
+func (host *evmcHost) EmitLog(addr common.Address, topics []common.Hash, data []byte) {
+	host.env.StateDB.AddLog(&types.Log{
+		Address:     addr,
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: host.env.BlockNumber.Uint64(),
+	})
+}
+
+// Call executes a message call or contract creation requested by the VM.
+func (host *evmcHost) Call(kind evmc.CallKind, destination common.Address, sender common.Address, value *big.Int, input []byte, gas int64,
+	depth int, static bool, salt *big.Int) (output []byte, gasLeft int64, createAddr common.Address, err error) {
+
+	var left uint64
+	switch kind {
+	case evmc.Call:
+		if static {
+			output, left, err = host.env.StaticCall(host.contract, destination, input, uint64(gas))
+		} else {
+			output, left, err = host.env.Call(host.contract, destination, input, uint64(gas), value)
+		}
+	case evmc.DelegateCall:
+		output, left, err = host.env.DelegateCall(host.contract, destination, input, uint64(gas))
+	case evmc.CallCode:
+		output, left, err = host.env.CallCode(host.contract, destination, input, uint64(gas), value)
+	case evmc.Create, evmc.Create2:
+		var ret []byte
+		if kind == evmc.Create {
+			ret, createAddr, left, err = host.env.Create(host.contract, input, uint64(gas), value)
+		} else {
+			ret, createAddr, left, err = host.env.Create2(host.contract, input, uint64(gas), value, salt)
+		}
+		// Running out of gas storing the code is not a failure before Homestead
+		if !host.env.chainRules.IsHomestead && err == ErrCodeStoreOutOfGas {
+			err = nil
+		}
+		// Only the output of a reverted creation is returned
+		if err == errExecutionReverted {
+			output = ret
+		}
+	default:
+		panic(fmt.Sprintf("EVMC: unknown call kind %d", kind))
+	}
+	switch err {
+	case nil:
+	case errExecutionReverted:
+		err = evmc.Revert
+	default:
+		err = evmc.Failure
+	}
+	return output, int64(left), createAddr, err
+}
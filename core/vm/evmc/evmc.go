@@ -0,0 +1,249 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build cgo,!windows
+
+package evmc
+
+/*
+#cgo CFLAGS: -I${SRCDIR} -Wall
+#cgo linux LDFLAGS: -ldl
+
+#include <stdlib.h>
+#include <string.h>
+
+#include "glue.h"
+
+static int get_abi_version(struct evmc_instance* instance)
+{
+	return instance->abi_version;
+}
+
+static void destroy(struct evmc_instance* instance)
+{
+	instance->destroy(instance);
+}
+
+static uint32_t get_capabilities(struct evmc_instance* instance)
+{
+	if (!instance->get_capabilities)
+		return EVMC_CAPABILITY_EVM1;
+	return instance->get_capabilities(instance);
+}
+
+static enum evmc_set_option_result set_option(struct evmc_instance* instance, char* name, char* value)
+{
+	enum evmc_set_option_result result = EVMC_SET_OPTION_INVALID_NAME;
+	if (instance->set_option)
+		result = instance->set_option(instance, name, value);
+	free(name);
+	free(value);
+	return result;
+}
+
+static struct evmc_result execute(struct evmc_instance* instance, uintptr_t context_index,
+	enum evmc_revision rev, enum evmc_call_kind kind, uint32_t flags, int32_t depth, int64_t gas,
+	const evmc_address* destination, const evmc_address* sender,
+	const uint8_t* input_data, size_t input_size, const evmc_uint256be* value,
+	const uint8_t* code, size_t code_size, const evmc_bytes32* create2_salt)
+{
+	struct evmc_message msg = {
+		kind,
+		flags,
+		depth,
+		gas,
+		*destination,
+		*sender,
+		input_data,
+		input_size,
+		*value,
+		*create2_salt,
+	};
+	struct extended_context ctx = {{&evmc_go_host}, context_index};
+	return instance->execute(instance, &ctx.context, rev, &msg, code, code_size);
+}
+
+static void release_result(struct evmc_result* result)
+{
+	if (result->release)
+		result->release(result);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Instance is a loaded EVMC VM.
+type Instance struct {
+	handle *C.struct_evmc_instance
+}
+
+// Load loads a VM from the shared library at path.
+func Load(path string) (*Instance, error) {
+	var (
+		cpath   = C.CString(path)
+		csymbol = C.CString(createSymbol(path))
+		cerr    *C.char
+	)
+	defer C.free(unsafe.Pointer(cpath))
+	defer C.free(unsafe.Pointer(csymbol))
+
+	handle := C.evmc_go_load(cpath, csymbol, &cerr)
+	if handle == nil {
+		defer C.free(unsafe.Pointer(cerr))
+		return nil, fmt.Errorf("failed to load EVMC module %s: %s", path, C.GoString(cerr))
+	}
+	if version := C.get_abi_version(handle); version != C.EVMC_ABI_VERSION {
+		C.destroy(handle)
+		return nil, fmt.Errorf("incompatible EVMC module %s: ABI version %d, expected %d", path, version, C.EVMC_ABI_VERSION)
+	}
+	return &Instance{handle: handle}, nil
+}
+
+// LoadAndConfigure loads a VM from a configuration of the form
+// path[,name=value,...], setting all the listed options on the VM.
+func LoadAndConfigure(config string) (*Instance, error) {
+	path, options, err := parseConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range options {
+		if err := instance.SetOption(opt.name, opt.value); err != nil {
+			instance.Destroy()
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+// Destroy releases the VM. The instance must not be used afterwards.
+func (instance *Instance) Destroy() {
+	C.destroy(instance.handle)
+}
+
+// Name returns the name of the VM.
+func (instance *Instance) Name() string {
+	return C.GoString(instance.handle.name)
+}
+
+// Version returns the version of the VM.
+func (instance *Instance) Version() string {
+	return C.GoString(instance.handle.version)
+}
+
+// HasCapability reports whether the VM is able to execute the given kind of code.
+func (instance *Instance) HasCapability(capability Capability) bool {
+	return Capability(C.get_capabilities(instance.handle))&capability != 0
+}
+
+// SetOption sets a VM specific configuration option.
+func (instance *Instance) SetOption(name string, value string) error {
+	switch C.set_option(instance.handle, C.CString(name), C.CString(value)) {
+	case C.EVMC_SET_OPTION_SUCCESS:
+		return nil
+	case C.EVMC_SET_OPTION_INVALID_VALUE:
+		return fmt.Errorf("invalid value %q for EVMC option %s", value, name)
+	default:
+		return fmt.Errorf("unknown EVMC option %s", name)
+	}
+}
+
+// Execute runs code in the VM, accessing the state and environment through ctx.
+// On failure, the returned error is of type Error.
+func (instance *Instance) Execute(ctx HostContext, rev Revision, kind CallKind, static bool, depth int, gas int64,
+	destination common.Address, sender common.Address, input []byte, value common.Hash, code []byte, create2Salt common.Hash) (output []byte, gasLeft int64, err error) {
+
+	if len(code) == 0 {
+		return nil, 0, errors.New("no code to execute")
+	}
+	flags := C.uint32_t(0)
+	if static {
+		flags |= C.EVMC_STATIC
+	}
+	index := addHostContext(ctx)
+	defer removeHostContext(index)
+
+	result := C.execute(instance.handle, C.uintptr_t(index), C.enum_evmc_revision(rev),
+		C.enum_evmc_call_kind(kind), flags, C.int32_t(depth), C.int64_t(gas),
+		evmcAddress(destination), evmcAddress(sender), bytesPtr(input), C.size_t(len(input)),
+		evmcBytes32(value), bytesPtr(code), C.size_t(len(code)), evmcBytes32(create2Salt))
+
+	output = C.GoBytes(unsafe.Pointer(result.output_data), C.int(result.output_size))
+	gasLeft = int64(result.gas_left)
+	if result.status_code != C.EVMC_SUCCESS {
+		err = Error(result.status_code)
+	}
+	C.release_result(&result)
+
+	return output, gasLeft, err
+}
+
+// The VM references host contexts by index, as Go pointers cannot be passed
+// through C memory.
+var (
+	hostContextLock  sync.Mutex
+	hostContexts     = make(map[uintptr]HostContext)
+	hostContextIndex uintptr
+)
+
+func addHostContext(ctx HostContext) uintptr {
+	hostContextLock.Lock()
+	defer hostContextLock.Unlock()
+
+	hostContextIndex++
+	hostContexts[hostContextIndex] = ctx
+	return hostContextIndex
+}
+
+func removeHostContext(index uintptr) {
+	hostContextLock.Lock()
+	defer hostContextLock.Unlock()
+
+	delete(hostContexts, index)
+}
+
+func getHostContext(index uintptr) HostContext {
+	hostContextLock.Lock()
+	defer hostContextLock.Unlock()
+
+	return hostContexts[index]
+}
+
+func evmcAddress(addr common.Address) *C.evmc_address {
+	return (*C.evmc_address)(unsafe.Pointer(&addr))
+}
+
+func evmcBytes32(hash common.Hash) *C.evmc_bytes32 {
+	return (*C.evmc_bytes32)(unsafe.Pointer(&hash))
+}
+
+func bytesPtr(data []byte) *C.uint8_t {
+	if len(data) == 0 {
+		return nil
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&data[0]))
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !cgo windows
+
+package evmc
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errNotSupported = errors.New("EVMC modules are not supported in this build")
+
+// Instance is a loaded EVMC VM.
+type Instance struct{}
+
+// Load loads a VM from the shared library at path.
+func Load(path string) (*Instance, error) {
+	return nil, errNotSupported
+}
+
+// LoadAndConfigure loads a VM from a configuration of the form
+// path[,name=value,...], setting all the listed options on the VM.
+func LoadAndConfigure(config string) (*Instance, error) {
+	if _, _, err := parseConfig(config); err != nil {
+		return nil, err
+	}
+	return nil, errNotSupported
+}
+
+// Destroy releases the VM. The instance must not be used afterwards.
+func (instance *Instance) Destroy() {}
+
+// Name returns the name of the VM.
+func (instance *Instance) Name() string { return "" }
+
+// Version returns the version of the VM.
+func (instance *Instance) Version() string { return "" }
+
+// HasCapability reports whether the VM is able to execute the given kind of code.
+func (instance *Instance) HasCapability(capability Capability) bool { return false }
+
+// SetOption sets a VM specific configuration option.
+func (instance *Instance) SetOption(name string, value string) error {
+	return errNotSupported
+}
+
+// Execute runs code in the VM, accessing the state and environment through ctx.
+// On failure, the returned error is of type Error.
+func (instance *Instance) Execute(ctx HostContext, rev Revision, kind CallKind, static bool, depth int, gas int64,
+	destination common.Address, sender common.Address, input []byte, value common.Hash, code []byte, create2Salt common.Hash) (output []byte, gasLeft int64, err error) {
+	return nil, 0, errNotSupported
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build cgo,!windows
+
+package evmc
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// buildExampleVM compiles the example VM into a shared library, skipping the
+// test if no C compiler is available.
+func buildExampleVM(t *testing.T) (string, func()) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not found, skipping EVMC module test")
+	}
+	dir, err := ioutil.TempDir("", "evmc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "libexample-vm.so")
+	out, err := exec.Command("gcc", "-shared", "-fPIC", "-I.", "-o", path, "testdata/example_vm.c").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to build example VM: %v\n%s", err, out)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	path, cleanup := buildExampleVM(t)
+	defer cleanup()
+
+	instance, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load VM: %v", err)
+	}
+	defer instance.Destroy()
+
+	if name := instance.Name(); name != "example_vm" {
+		t.Errorf("name mismatch: have %s, want %s", name, "example_vm")
+	}
+	if version := instance.Version(); version != "1.0.0" {
+		t.Errorf("version mismatch: have %s, want %s", version, "1.0.0")
+	}
+	if !instance.HasCapability(CapabilityEVM1) {
+		t.Error("EVM capability missing")
+	}
+	if instance.HasCapability(CapabilityEWASM) {
+		t.Error("unexpected ewasm capability")
+	}
+	if err := instance.SetOption("verbose", "1"); err != nil {
+		t.Errorf("failed to set option: %v", err)
+	}
+	if err := instance.SetOption("verbose", "2"); err == nil {
+		t.Error("invalid option value accepted")
+	}
+	if err := instance.SetOption("unknown", "1"); err == nil {
+		t.Error("unknown option accepted")
+	}
+}
+
+func TestLoadAndConfigure(t *testing.T) {
+	path, cleanup := buildExampleVM(t)
+	defer cleanup()
+
+	instance, err := LoadAndConfigure(path + ",verbose=0")
+	if err != nil {
+		t.Fatalf("failed to load VM: %v", err)
+	}
+	instance.Destroy()
+
+	if _, err := LoadAndConfigure(path + ",verbose=2"); err == nil {
+		t.Error("invalid option value accepted")
+	}
+	if _, err := LoadAndConfigure(filepath.Join(filepath.Dir(path), "libmissing.so")); err == nil {
+		t.Error("missing module loaded")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build cgo,!windows
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "glue.h"
+#include "_cgo_export.h"
+
+const struct evmc_host_interface evmc_go_host = {
+	(evmc_account_exists_fn)accountExists,
+	(evmc_get_storage_fn)getStorage,
+	(evmc_set_storage_fn)setStorage,
+	(evmc_get_balance_fn)getBalance,
+	(evmc_get_code_size_fn)getCodeSize,
+	(evmc_get_code_hash_fn)getCodeHash,
+	(evmc_copy_code_fn)copyCode,
+	(evmc_selfdestruct_fn)selfdestruct,
+	(evmc_call_fn)call,
+	(evmc_get_tx_context_fn)getTxContext,
+	(evmc_get_block_hash_fn)getBlockHash,
+	(evmc_emit_log_fn)emitLog,
+};
+
+void evmc_go_free_result_output(const struct evmc_result* result)
+{
+	free((void*)result->output_data);
+}
+
+typedef struct evmc_instance* (*evmc_create_fn)(void);
+
+struct evmc_instance* evmc_go_load(const char* filename, const char* symbol, char** error)
+{
+	void* handle = dlopen(filename, RTLD_LAZY);
+	if (!handle)
+	{
+		*error = strdup(dlerror());
+		return NULL;
+	}
+	evmc_create_fn create = (evmc_create_fn)dlsym(handle, symbol);
+	if (!create)
+		create = (evmc_create_fn)dlsym(handle, "evmc_create");
+	if (!create)
+	{
+		*error = strdup("EVMC create function not found");
+		dlclose(handle);
+		return NULL;
+	}
+	struct evmc_instance* instance = create();
+	if (!instance)
+	{
+		*error = strdup("EVMC create function returned no instance");
+		dlclose(handle);
+		return NULL;
+	}
+	return instance;
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+#ifndef EVMC_GLUE_H
+#define EVMC_GLUE_H
+
+#include "evmc.h"
+
+// extended_context is the host context handed to the VM, referencing the Go
+// HostContext by its index in the context registry.
+struct extended_context
+{
+	struct evmc_context context;
+	uintptr_t index;
+};
+
+// evmc_go_host is the host interface forwarding all callbacks to Go.
+extern const struct evmc_host_interface evmc_go_host;
+
+// evmc_go_free_result_output releases results with output allocated by malloc.
+void evmc_go_free_result_output(const struct evmc_result* result);
+
+// evmc_go_load loads a VM from a shared library, calling the create function
+// with the given symbol name, or the generic evmc_create if missing. On failure
+// NULL is returned and error points to a malloc allocated message.
+struct evmc_instance* evmc_go_load(const char* filename, const char* symbol, char** error);
+
+#endif
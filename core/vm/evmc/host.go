@@ -0,0 +1,175 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build cgo,!windows
+
+package evmc
+
+/*
+#include <stdlib.h>
+#include <string.h>
+
+#include "glue.h"
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// This file implements the host interface exposed to the VMs, forwarding each
+// callback to the HostContext of the running execution.
+
+func hostContextOf(pCtx unsafe.Pointer) HostContext {
+	return getHostContext(uintptr((*C.struct_extended_context)(pCtx).index))
+}
+
+func goAddress(addr *C.evmc_address) common.Address {
+	return common.Address(*(*[common.AddressLength]byte)(unsafe.Pointer(addr)))
+}
+
+func goHash(hash *C.evmc_bytes32) common.Hash {
+	return common.Hash(*(*[common.HashLength]byte)(unsafe.Pointer(hash)))
+}
+
+func cBytes32(hash common.Hash) C.evmc_bytes32 {
+	return *(*C.evmc_bytes32)(unsafe.Pointer(&hash))
+}
+
+func cAddress(addr common.Address) C.evmc_address {
+	return *(*C.evmc_address)(unsafe.Pointer(&addr))
+}
+
+//export accountExists
+func accountExists(pCtx unsafe.Pointer, pAddr *C.evmc_address) C.bool {
+	return C.bool(hostContextOf(pCtx).AccountExists(goAddress(pAddr)))
+}
+
+//export getStorage
+func getStorage(pCtx unsafe.Pointer, pAddr *C.evmc_address, pKey *C.evmc_bytes32) C.evmc_bytes32 {
+	return cBytes32(hostContextOf(pCtx).GetStorage(goAddress(pAddr), goHash(pKey)))
+}
+
+//export setStorage
+func setStorage(pCtx unsafe.Pointer, pAddr *C.evmc_address, pKey *C.evmc_bytes32, pVal *C.evmc_bytes32) C.enum_evmc_storage_status {
+	return C.enum_evmc_storage_status(hostContextOf(pCtx).SetStorage(goAddress(pAddr), goHash(pKey), goHash(pVal)))
+}
+
+//export getBalance
+func getBalance(pCtx unsafe.Pointer, pAddr *C.evmc_address) C.evmc_uint256be {
+	return cBytes32(hostContextOf(pCtx).GetBalance(goAddress(pAddr)))
+}
+
+//export getCodeSize
+func getCodeSize(pCtx unsafe.Pointer, pAddr *C.evmc_address) C.size_t {
+	return C.size_t(hostContextOf(pCtx).GetCodeSize(goAddress(pAddr)))
+}
+
+//export getCodeHash
+func getCodeHash(pCtx unsafe.Pointer, pAddr *C.evmc_address) C.evmc_bytes32 {
+	return cBytes32(hostContextOf(pCtx).GetCodeHash(goAddress(pAddr)))
+}
+
+//export copyCode
+func copyCode(pCtx unsafe.Pointer, pAddr *C.evmc_address, offset C.size_t, p *C.uint8_t, size C.size_t) C.size_t {
+	code := hostContextOf(pCtx).GetCode(goAddress(pAddr))
+	if int(offset) >= len(code) {
+		return 0
+	}
+	code = code[offset:]
+	if int(size) < len(code) {
+		code = code[:size]
+	}
+	if len(code) > 0 {
+		C.memcpy(unsafe.Pointer(p), unsafe.Pointer(&code[0]), C.size_t(len(code)))
+	}
+	return C.size_t(len(code))
+}
+
+//export selfdestruct
+func selfdestruct(pCtx unsafe.Pointer, pAddr *C.evmc_address, pBeneficiary *C.evmc_address) {
+	hostContextOf(pCtx).Selfdestruct(goAddress(pAddr), goAddress(pBeneficiary))
+}
+
+//export getTxContext
+func getTxContext(pCtx unsafe.Pointer) C.struct_evmc_tx_context {
+	txContext := hostContextOf(pCtx).GetTxContext()
+
+	return C.struct_evmc_tx_context{
+		tx_gas_price:     cBytes32(txContext.GasPrice),
+		tx_origin:        cAddress(txContext.Origin),
+		block_coinbase:   cAddress(txContext.Coinbase),
+		block_number:     C.int64_t(txContext.Number),
+		block_timestamp:  C.int64_t(txContext.Timestamp),
+		block_gas_limit:  C.int64_t(txContext.GasLimit),
+		block_difficulty: cBytes32(txContext.Difficulty),
+	}
+}
+
+//export getBlockHash
+func getBlockHash(pCtx unsafe.Pointer, number int64) C.evmc_bytes32 {
+	return cBytes32(hostContextOf(pCtx).GetBlockHash(number))
+}
+
+//export emitLog
+func emitLog(pCtx unsafe.Pointer, pAddr *C.evmc_address, pData unsafe.Pointer, dataSize C.size_t, pTopics unsafe.Pointer, topicsCount C.size_t) {
+	var (
+		data   = C.GoBytes(pData, C.int(dataSize))
+		raw    = C.GoBytes(pTopics, C.int(topicsCount*32))
+		topics = make([]common.Hash, topicsCount)
+	)
+	for i := range topics {
+		copy(topics[i][:], raw[i*32:])
+	}
+	hostContextOf(pCtx).EmitLog(goAddress(pAddr), topics, data)
+}
+
+//export call
+func call(pCtx unsafe.Pointer, msg *C.struct_evmc_message) C.struct_evmc_result {
+	var (
+		kind   = CallKind(msg.kind)
+		value  = goHash(&msg.value)
+		salt   = goHash(&msg.create2_salt)
+		static = msg.flags&C.EVMC_STATIC != 0
+	)
+	output, gasLeft, createAddr, err := hostContextOf(pCtx).Call(kind, goAddress(&msg.destination), goAddress(&msg.sender),
+		value.Big(), C.GoBytes(unsafe.Pointer(msg.input_data), C.int(msg.input_size)), int64(msg.gas), int(msg.depth),
+		static, salt.Big())
+
+	status := C.enum_evmc_status_code(C.EVMC_SUCCESS)
+	if err != nil {
+		if evmcErr, ok := err.(Error); ok {
+			status = C.enum_evmc_status_code(evmcErr)
+		} else {
+			status = C.EVMC_INTERNAL_ERROR
+		}
+	}
+	result := C.struct_evmc_result{
+		status_code:    status,
+		gas_left:       C.int64_t(gasLeft),
+		create_address: cAddress(createAddr),
+	}
+	if len(output) > 0 {
+		// The output is owned and released by the VM
+		result.output_data = (*C.uint8_t)(C.malloc(C.size_t(len(output))))
+		result.output_size = C.size_t(len(output))
+		C.memcpy(unsafe.Pointer(result.output_data), unsafe.Pointer(&output[0]), result.output_size)
+		result.release = C.evmc_release_result_fn(C.evmc_go_free_result_output)
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// An example EVMC VM exercising the host interface, used by the tests. It
+// ignores the code and instead:
+//
+//   - increments the first byte of storage slot 0 of the executing account,
+//   - logs the input under the balance of the sender as topic,
+//   - calls the account given in the first 20 bytes of the input at depth 0,
+//     returning its output, or returns the transaction origin otherwise,
+//   - charges 1000 gas, plus the gas used by the call.
+
+#include <stdlib.h>
+#include <string.h>
+
+#include "evmc.h"
+
+static void destroy(struct evmc_instance* vm)
+{
+	(void)vm;
+}
+
+static void free_result_output(const struct evmc_result* result)
+{
+	free((void*)result->output_data);
+}
+
+static struct evmc_result execute(struct evmc_instance* vm, struct evmc_context* context,
+	enum evmc_revision rev, const struct evmc_message* msg, const uint8_t* code, size_t code_size)
+{
+	(void)vm;
+	(void)rev;
+	(void)code;
+	(void)code_size;
+
+	struct evmc_result result;
+	memset(&result, 0, sizeof(result));
+
+	if (msg->flags & EVMC_STATIC)
+	{
+		result.status_code = EVMC_STATIC_MODE_VIOLATION;
+		return result;
+	}
+	evmc_bytes32 key;
+	memset(&key, 0, sizeof(key));
+	evmc_bytes32 value = context->host->get_storage(context, &msg->destination, &key);
+	value.bytes[0]++;
+	context->host->set_storage(context, &msg->destination, &key, &value);
+
+	evmc_uint256be balance = context->host->get_balance(context, &msg->sender);
+	context->host->emit_log(context, &msg->destination, msg->input_data, msg->input_size, &balance, 1);
+
+	int64_t gas_left = msg->gas - 1000;
+	uint8_t* output = NULL;
+	size_t output_size = 0;
+
+	if (msg->depth == 0 && msg->input_size >= sizeof(evmc_address))
+	{
+		struct evmc_message call;
+		memset(&call, 0, sizeof(call));
+		call.kind = EVMC_CALL;
+		call.depth = msg->depth + 1;
+		call.gas = gas_left / 2;
+		call.sender = msg->destination;
+		memcpy(call.destination.bytes, msg->input_data, sizeof(evmc_address));
+
+		struct evmc_result call_result = context->host->call(context, &call);
+		gas_left -= call.gas - call_result.gas_left;
+		if (call_result.status_code != EVMC_SUCCESS)
+		{
+			result.status_code = EVMC_FAILURE;
+		}
+		output_size = call_result.output_size;
+		output = malloc(output_size);
+		memcpy(output, call_result.output_data, output_size);
+		if (call_result.release)
+			call_result.release(&call_result);
+	}
+	else
+	{
+		struct evmc_tx_context tx_context = context->host->get_tx_context(context);
+		output_size = sizeof(tx_context.tx_origin);
+		output = malloc(output_size);
+		memcpy(output, tx_context.tx_origin.bytes, output_size);
+	}
+	result.gas_left = gas_left;
+	result.output_data = output;
+	result.output_size = output_size;
+	result.release = free_result_output;
+	return result;
+}
+
+static evmc_capabilities_flagset get_capabilities(struct evmc_instance* vm)
+{
+	(void)vm;
+	return EVMC_CAPABILITY_EVM1;
+}
+
+static enum evmc_set_option_result set_option(struct evmc_instance* vm, char const* name, char const* value)
+{
+	(void)vm;
+	if (strcmp(name, "verbose") != 0)
+		return EVMC_SET_OPTION_INVALID_NAME;
+	if (strcmp(value, "0") != 0 && strcmp(value, "1") != 0)
+		return EVMC_SET_OPTION_INVALID_VALUE;
+	return EVMC_SET_OPTION_SUCCESS;
+}
+
+struct evmc_instance* evmc_create_example_vm(void)
+{
+	static struct evmc_instance instance = {
+		EVMC_ABI_VERSION,
+		"example_vm",
+		"1.0.0",
+		destroy,
+		execute,
+		get_capabilities,
+		NULL,
+		set_option,
+	};
+	return &instance;
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package evmc implements loading of external Ethereum virtual machines through
+// the EVMC ABI.
+package evmc
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Error is a non-success status code returned by an EVMC VM.
+type Error int32
+
+// Status codes of the EVMC ABI.
+const (
+	Failure                    Error = 1
+	Revert                     Error = 2
+	OutOfGas                   Error = 3
+	InvalidInstruction         Error = 4
+	UndefinedInstruction       Error = 5
+	StackOverflow              Error = 6
+	StackUnderflow             Error = 7
+	BadJumpDestination         Error = 8
+	InvalidMemoryAccess        Error = 9
+	CallDepthExceeded          Error = 10
+	StaticModeViolation        Error = 11
+	PrecompileFailure          Error = 12
+	ContractValidationFailure  Error = 13
+	ArgumentOutOfRange         Error = 14
+	WasmUnreachableInstruction Error = 15
+	WasmTrap                   Error = 16
+	InternalError              Error = -1
+	Rejected                   Error = -2
+	OutOfMemory                Error = -3
+)
+
+var errorNames = map[Error]string{
+	Failure:                    "failure",
+	Revert:                     "revert",
+	OutOfGas:                   "out of gas",
+	InvalidInstruction:         "invalid instruction",
+	UndefinedInstruction:       "undefined instruction",
+	StackOverflow:              "stack overflow",
+	StackUnderflow:             "stack underflow",
+	BadJumpDestination:         "bad jump destination",
+	InvalidMemoryAccess:        "invalid memory access",
+	CallDepthExceeded:          "call depth exceeded",
+	StaticModeViolation:        "static mode violation",
+	PrecompileFailure:          "precompile failure",
+	ContractValidationFailure:  "contract validation failure",
+	ArgumentOutOfRange:         "argument out of range",
+	WasmUnreachableInstruction: "wasm unreachable instruction",
+	WasmTrap:                   "wasm trap",
+	InternalError:              "internal error",
+	Rejected:                   "rejected",
+	OutOfMemory:                "out of memory",
+}
+
+func (err Error) Error() string {
+	if name, ok := errorNames[err]; ok {
+		return name
+	}
+	return fmt.Sprintf("status code %d", int32(err))
+}
+
+// IsInternalError reports whether the error is a failure of the VM itself,
+// rather than of the executed code.
+func (err Error) IsInternalError() bool {
+	return err < 0
+}
+
+// Revision is an Ethereum protocol revision understood by EVMC VMs.
+type Revision int32
+
+// Protocol revisions of the EVMC ABI.
+const (
+	Frontier         Revision = 0
+	Homestead        Revision = 1
+	TangerineWhistle Revision = 2
+	SpuriousDragon   Revision = 3
+	Byzantium        Revision = 4
+	Constantinople   Revision = 5
+	Petersburg       Revision = 6
+	Istanbul         Revision = 7
+)
+
+// CallKind is the kind of a message call.
+type CallKind int32
+
+// Message call kinds of the EVMC ABI.
+const (
+	Call         CallKind = 0
+	DelegateCall CallKind = 1
+	CallCode     CallKind = 2
+	Create       CallKind = 3
+	Create2      CallKind = 4
+)
+
+// StorageStatus is the effect of a storage write, used by VMs to charge gas.
+type StorageStatus int32
+
+// Storage statuses of the EVMC ABI.
+const (
+	StorageUnchanged     StorageStatus = 0
+	StorageModified      StorageStatus = 1
+	StorageModifiedAgain StorageStatus = 2
+	StorageAdded         StorageStatus = 3
+	StorageDeleted       StorageStatus = 4
+)
+
+// Capability is a kind of code an EVMC VM is able to execute.
+type Capability uint32
+
+// Capabilities of the EVMC ABI.
+const (
+	CapabilityEVM1  Capability = 1 << 0
+	CapabilityEWASM Capability = 1 << 1
+)
+
+// TxContext is the transaction and block information available to a VM.
+type TxContext struct {
+	GasPrice   common.Hash
+	Origin     common.Address
+	Coinbase   common.Address
+	Number     int64
+	Timestamp  int64
+	GasLimit   int64
+	Difficulty common.Hash
+}
+
+// HostContext is the interface through which a VM accesses the Ethereum state
+// and environment during execution.
+type HostContext interface {
+	AccountExists(addr common.Address) bool
+	GetStorage(addr common.Address, key common.Hash) common.Hash
+	SetStorage(addr common.Address, key common.Hash, value common.Hash) StorageStatus
+	GetBalance(addr common.Address) common.Hash
+	GetCodeSize(addr common.Address) int
+	GetCodeHash(addr common.Address) common.Hash
+	GetCode(addr common.Address) []byte
+	Selfdestruct(addr common.Address, beneficiary common.Address)
+	GetTxContext() TxContext
+	GetBlockHash(number int64) common.Hash
+	EmitLog(addr common.Address, topics []common.Hash, data []byte)
+	Call(kind CallKind, destination common.Address, sender common.Address, value *big.Int, input []byte, gas int64, depth int, static bool, salt *big.Int) (output []byte, gasLeft int64, createAddr common.Address, err error)
+}
+
+// option is a VM specific configuration option.
+type option struct {
+	name, value string
+}
+
+// parseConfig splits a VM configuration of the form path[,name=value,...] into
+// the path of the shared library and the options to set.
+func parseConfig(config string) (string, []option, error) {
+	parts := strings.Split(config, ",")
+	if parts[0] == "" {
+		return "", nil, fmt.Errorf("missing EVMC module path in %q", config)
+	}
+	var options []option
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return "", nil, fmt.Errorf("invalid EVMC option %q", part)
+		}
+		options = append(options, option{kv[0], kv[1]})
+	}
+	return parts[0], options, nil
+}
+
+// createSymbol returns the name of the create function of a VM library, derived
+// from its file name: libexample-vm.so.1 becomes evmc_create_example_vm.
+func createSymbol(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimPrefix(name, "lib")
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return "evmc_create_" + strings.Replace(name, "-", "_", -1)
+}
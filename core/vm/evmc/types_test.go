@@ -0,0 +1,68 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package evmc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		config  string
+		path    string
+		options []option
+		fail    bool
+	}{
+		{config: "/path/libvm.so", path: "/path/libvm.so"},
+		{config: "libvm.so,O=2,trace=", path: "libvm.so", options: []option{{"O", "2"}, {"trace", ""}}},
+		{config: "libvm.so,a=b=c", path: "libvm.so", options: []option{{"a", "b=c"}}},
+		{config: "", fail: true},
+		{config: ",O=2", fail: true},
+		{config: "libvm.so,O", fail: true},
+		{config: "libvm.so,=2", fail: true},
+	}
+	for _, test := range tests {
+		path, options, err := parseConfig(test.config)
+		if test.fail {
+			if err == nil {
+				t.Errorf("config %q: expected error", test.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("config %q: unexpected error: %v", test.config, err)
+			continue
+		}
+		if path != test.path || !reflect.DeepEqual(options, test.options) {
+			t.Errorf("config %q: have %q %v, want %q %v", test.config, path, options, test.path, test.options)
+		}
+	}
+}
+
+func TestCreateSymbol(t *testing.T) {
+	tests := map[string]string{
+		"/usr/lib/libevmone.so":        "evmc_create_evmone",
+		"libexample-vm.so.1.2":         "evmc_create_example_vm",
+		"./build/libaleth-interpreter": "evmc_create_aleth_interpreter",
+	}
+	for path, want := range tests {
+		if have := createSymbol(path); have != want {
+			t.Errorf("path %q: have %s, want %s", path, have, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build cgo,!windows
+
+package vm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests executing contracts in an external EVMC VM, using the example VM which
+// calls back into the host for storage, balances, logs and nested calls.
+func TestEVMCExecution(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not found, skipping EVMC module test")
+	}
+	dir, err := ioutil.TempDir("", "evmc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "libexample-vm.so")
+	out, err := exec.Command("gcc", "-shared", "-fPIC", "-Ievmc", "-o", path, "evmc/testdata/example_vm.c").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to build example VM: %v\n%s", err, out)
+	}
+	var (
+		origin = common.HexToAddress("0x01")
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetCode(caller, []byte{byte(STOP)})
+	statedb.SetCode(callee, []byte{byte(STOP)})
+	statedb.SetBalance(origin, big.NewInt(7))
+
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		Origin:      origin,
+		GasPrice:    big.NewInt(1),
+		BlockNumber: big.NewInt(0),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+	}
+	vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{EVMInterpreter: path + ",verbose=1"})
+
+	ret, gas, err := vmenv.Call(AccountRef(origin), caller, callee.Bytes(), 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if !bytes.Equal(ret, origin.Bytes()) {
+		t.Errorf("output mismatch: have %x, want %x", ret, origin.Bytes())
+	}
+	if used := 100000 - gas; used != 2000 {
+		t.Errorf("gas used mismatch: have %d, want %d", used, 2000)
+	}
+	for _, addr := range []common.Address{caller, callee} {
+		if value := statedb.GetState(addr, common.Hash{}); value != (common.Hash{1}) {
+			t.Errorf("account %x: storage mismatch: have %x, want %x", addr, value, common.Hash{1})
+		}
+	}
+	logs := statedb.Logs()
+	if len(logs) != 2 {
+		t.Fatalf("log count mismatch: have %d, want %d", len(logs), 2)
+	}
+	if topic := common.BigToHash(big.NewInt(7)); logs[0].Topics[0] != topic {
+		t.Errorf("log topic mismatch: have %x, want %x", logs[0].Topics[0], topic)
+	}
+	// The example VM refuses to run in static mode
+	if _, _, err := vmenv.StaticCall(AccountRef(origin), caller, nil, 100000); err == nil {
+		t.Error("static execution succeeded")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm/evmc"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that storage writes through the EVMC host apply the same refunds as the
+// built-in interpreter, using the test cases of EIP 2200.
+func TestEVMCSetStorageRefunds(t *testing.T) {
+	for i, tt := range eip2200Tests {
+		if tt.failure != nil {
+			continue
+		}
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.CreateAccount(address)
+		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{tt.original}))
+		statedb.Finalise(false) // Push the state into the "original" slot
+
+		vmctx := Context{BlockNumber: big.NewInt(0)}
+		host := &evmcHost{env: NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{})}
+
+		// The inputs are sequences of PUSH1 value, PUSH1 0, SSTORE
+		code := hexutil.MustDecode(tt.input)
+		for j := 0; j+5 <= len(code); j += 5 {
+			host.SetStorage(address, common.Hash{}, common.BytesToHash(code[j+1:j+2]))
+		}
+		if refund := statedb.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
+
+func TestEVMCSetStorageStatus(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetState(address, common.Hash{1}, common.Hash{1})
+	statedb.Finalise(false)
+
+	host := &evmcHost{env: NewEVM(Context{BlockNumber: big.NewInt(0)}, statedb, params.AllEthashProtocolChanges, Config{})}
+	tests := []struct {
+		key, value common.Hash
+		status     evmc.StorageStatus
+	}{
+		{common.Hash{0}, common.Hash{}, evmc.StorageUnchanged},
+		{common.Hash{0}, common.Hash{2}, evmc.StorageAdded},
+		{common.Hash{0}, common.Hash{3}, evmc.StorageModifiedAgain},
+		{common.Hash{1}, common.Hash{1}, evmc.StorageUnchanged},
+		{common.Hash{1}, common.Hash{2}, evmc.StorageModified},
+		{common.Hash{1}, common.Hash{}, evmc.StorageModifiedAgain},
+		{common.Hash{2}, common.Hash{}, evmc.StorageUnchanged},
+	}
+	for i, tt := range tests {
+		if status := host.SetStorage(address, tt.key, tt.value); status != tt.status {
+			t.Errorf("test %d: status mismatch: have %v, want %v", i, status, tt.status)
+		}
+	}
+	statedb.SetState(address, common.Hash{3}, common.Hash{3})
+	statedb.Finalise(false)
+	if status := host.SetStorage(address, common.Hash{3}, common.Hash{}); status != evmc.StorageDeleted {
+		t.Errorf("clean delete status mismatch: have %v, want %v", status, evmc.StorageDeleted)
+	}
+}
+
+func TestEVMCCanRun(t *testing.T) {
+	var (
+		evm   = &EVMC{capability: evmc.CapabilityEVM1}
+		ewasm = &EVMC{capability: evmc.CapabilityEWASM}
+		code  = []byte{byte(PUSH1), 0x00}
+		wasm  = []byte("\x00asm\x01\x00\x00\x00")
+	)
+	if !evm.CanRun(code) || evm.CanRun(wasm) {
+		t.Error("EVM interpreter runs wrong code")
+	}
+	if ewasm.CanRun(code) || !ewasm.CanRun(wasm) {
+		t.Error("ewasm interpreter runs wrong code")
+	}
+}
+
+func TestEVMCRevision(t *testing.T) {
+	tests := []struct {
+		config *params.ChainConfig
+		rev    evmc.Revision
+	}{
+		{&params.ChainConfig{}, evmc.Frontier},
+		{&params.ChainConfig{HomesteadBlock: big.NewInt(0)}, evmc.Homestead},
+		{&params.ChainConfig{HomesteadBlock: big.NewInt(0), EIP150Block: big.NewInt(0)}, evmc.TangerineWhistle},
+		{&params.ChainConfig{HomesteadBlock: big.NewInt(0), EIP150Block: big.NewInt(0), EIP158Block: big.NewInt(0)}, evmc.SpuriousDragon},
+		{params.AllEthashProtocolChanges, evmc.Istanbul},
+	}
+	for i, tt := range tests {
+		if rev := evmcRevision(tt.config.Rules(big.NewInt(0))); rev != tt.rev {
+			t.Errorf("test %d: revision mismatch: have %v, want %v", i, rev, tt.rev)
+		}
+	}
+}
@@ -37,6 +37,13 @@ type Config struct {
 	NoRecursion bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// NoBaseFee skips the EIP-1559 fee cap checks for messages not paying
+	// any fees, such as those executed by eth_call.
+	NoBaseFee bool
+	// EnableCodeDecoding executes contracts from a cached, pre-decoded form
+	// of their code instead of interpreting the raw bytecode. It has no effect
+	// in debug mode or with a custom jump table.
+	EnableCodeDecoding bool
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -84,6 +91,8 @@ type EVMInterpreter struct {
 	cfg      Config
 	gasTable params.GasTable
 
+	instructionSet *[256]operation // Default instruction set in use, nil if custom
+
 	intPool *intPool
 
 	hasher    keccakState // Keccak256 hasher instance shared across opcodes
@@ -98,23 +107,28 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	// We use the STOP instruction whether to see
 	// the jump table was initialised. If it was not
 	// we'll set the default jump table.
+	var instructionSet *[256]operation
 	if !cfg.JumpTable[STOP].valid {
 		switch {
+		case evm.ChainConfig().IsIstanbul(evm.BlockNumber):
+			instructionSet = &istanbulInstructionSet
 		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
-			cfg.JumpTable = constantinopleInstructionSet
+			instructionSet = &constantinopleInstructionSet
 		case evm.ChainConfig().IsByzantium(evm.BlockNumber):
-			cfg.JumpTable = byzantiumInstructionSet
+			instructionSet = &byzantiumInstructionSet
 		case evm.ChainConfig().IsHomestead(evm.BlockNumber):
-			cfg.JumpTable = homesteadInstructionSet
+			instructionSet = &homesteadInstructionSet
 		default:
-			cfg.JumpTable = frontierInstructionSet
+			instructionSet = &frontierInstructionSet
 		}
+		cfg.JumpTable = *instructionSet
 	}
 
 	return &EVMInterpreter{
-		evm:      evm,
-		cfg:      cfg,
-		gasTable: evm.ChainConfig().GasTable(evm.BlockNumber),
+		evm:            evm,
+		cfg:            cfg,
+		gasTable:       evm.ChainConfig().GasTable(evm.BlockNumber),
+		instructionSet: instructionSet,
 	}
 }
 
@@ -188,6 +202,11 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	// Reclaim the stack as an int pool when the execution stops
 	defer func() { in.intPool.put(stack.data...) }()
 
+	// Execute from the pre-decoded code if enabled and no tracing is needed
+	if in.cfg.EnableCodeDecoding && !in.cfg.Debug && in.instructionSet != nil {
+		return in.runDecoded(in.decode(contract), contract, mem, stack)
+	}
+
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
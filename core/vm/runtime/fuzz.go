@@ -18,14 +18,32 @@
 
 package runtime
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
 // Fuzz is the basic entry point for the go-fuzz tool
 //
 // This returns 1 for valid parsable/runable code, 0
 // for invalid opcode.
 func Fuzz(input []byte) int {
-	_, _, err := Execute(input, input, &Config{
+	ret, statedb, err := Execute(input, input, &Config{
 		GasLimit: 3000000,
 	})
+	// pre-decoded execution must match the reference interpreter
+	decodedRet, decodedState, decodedErr := Execute(input, input, &Config{
+		GasLimit:  3000000,
+		EVMConfig: vm.Config{EnableCodeDecoding: true},
+	})
+	if !bytes.Equal(ret, decodedRet) || fmt.Sprint(err) != fmt.Sprint(decodedErr) {
+		panic(fmt.Sprintf("decoded execution mismatch: have %x (%v), want %x (%v)", decodedRet, decodedErr, ret, err))
+	}
+	if root, decodedRoot := statedb.IntermediateRoot(true), decodedState.IntermediateRoot(true); root != decodedRoot {
+		panic(fmt.Sprintf("decoded state mismatch: have %x, want %x", decodedRoot, root))
+	}
 
 	// invalid opcode
 	if err != nil && len(err.Error()) > 6 && string(err.Error()[:7]) == "invalid" {
@@ -17,13 +17,17 @@
 package runtime
 
 import (
+	"bytes"
+	"fmt"
 	"math/big"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -203,3 +207,68 @@ func BenchmarkEVM_CREATE2_1200(bench *testing.B) {
 	// initcode size 1200K, repeatedly calls CREATE2 and then modifies the mem contents
 	benchmarkEVM_Create(bench, "5b5862124f80600080f5600152600056")
 }
+
+// Tests that executing pre-decoded code yields the same results and state
+// changes as the reference interpreter, for a range of gas allowances.
+func TestDecodedExecution(t *testing.T) {
+	var (
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+	)
+	codes := map[common.Address][]byte{
+		// Stores a countdown from 10, then calls the callee and logs its result
+		caller: {
+			byte(vm.PUSH1), 0x0a,
+			byte(vm.JUMPDEST),
+			byte(vm.DUP1), byte(vm.DUP1), byte(vm.SSTORE),
+			byte(vm.PUSH1), 0x01, byte(vm.SWAP1), byte(vm.SUB),
+			byte(vm.DUP1), byte(vm.PUSH1), 0x02, byte(vm.JUMPI),
+			byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+			byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x0b, byte(vm.GAS), byte(vm.CALL),
+			byte(vm.PUSH1), 0x20, byte(vm.MSTORE),
+			byte(vm.PUSH1), 0xaa, byte(vm.PUSH1), 0x40, byte(vm.PUSH1), 0x00, byte(vm.LOG1),
+			byte(vm.PUSH1), 0x40, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+		},
+		// Returns the remaining gas, or fails on an odd timestamp
+		callee: {
+			byte(vm.TIMESTAMP), byte(vm.PUSH1), 0x01, byte(vm.AND), byte(vm.PUSH1), 0x0e, byte(vm.JUMPI),
+			byte(vm.GAS), byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+			byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.JUMPDEST), byte(vm.RETURN),
+		},
+	}
+	run := func(gas uint64, time int64, decoded bool) ([]byte, uint64, common.Hash, []*types.Log, error) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		for addr, code := range codes {
+			statedb.SetCode(addr, code)
+		}
+		ret, left, err := Call(caller, nil, &Config{
+			State:     statedb,
+			GasLimit:  gas,
+			Time:      big.NewInt(time),
+			EVMConfig: vm.Config{EnableCodeDecoding: decoded},
+		})
+		return ret, left, statedb.IntermediateRoot(true), statedb.Logs(), err
+	}
+	for _, time := range []int64{0, 1} {
+		for gas := uint64(0); gas < 300000; gas += 1 + gas/200 {
+			wantRet, wantLeft, wantRoot, wantLogs, wantErr := run(gas, time, false)
+			haveRet, haveLeft, haveRoot, haveLogs, haveErr := run(gas, time, true)
+
+			if !bytes.Equal(haveRet, wantRet) {
+				t.Errorf("time %d, gas %d: return mismatch: have %x, want %x", time, gas, haveRet, wantRet)
+			}
+			if haveLeft != wantLeft {
+				t.Errorf("time %d, gas %d: leftover gas mismatch: have %d, want %d", time, gas, haveLeft, wantLeft)
+			}
+			if fmt.Sprint(haveErr) != fmt.Sprint(wantErr) {
+				t.Errorf("time %d, gas %d: error mismatch: have %v, want %v", time, gas, haveErr, wantErr)
+			}
+			if haveRoot != wantRoot {
+				t.Errorf("time %d, gas %d: state root mismatch: have %x, want %x", time, gas, haveRoot, wantRoot)
+			}
+			if !reflect.DeepEqual(haveLogs, wantLogs) {
+				t.Errorf("time %d, gas %d: logs mismatch: have %v, want %v", time, gas, haveLogs, wantLogs)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blake2b
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// Tests that a single final compression of "abc" with the standard parameters
+// and 12 rounds results in the BLAKE2b-512 digest from RFC 7693.
+func TestF(t *testing.T) {
+	h := iv
+	h[0] ^= 0x01010040 // digest length 64, fanout 1, depth 1
+
+	var (
+		block [128]byte
+		m     [16]uint64
+	)
+	copy(block[:], "abc")
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8:])
+	}
+	F(&h, m, [2]uint64{3, 0}, true, 12)
+
+	digest := make([]byte, 64)
+	for i, word := range h {
+		binary.LittleEndian.PutUint64(digest[i*8:], word)
+	}
+	want := "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"
+	if have := hex.EncodeToString(digest); have != want {
+		t.Fatalf("digest mismatch: have %s, want %s", have, want)
+	}
+}
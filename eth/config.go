@@ -94,6 +94,12 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Ultra light client options
+	ULC *ULCConfig `toml:",omitempty"`
+
+	// Checkpoint oracle light clients use to sync from the latest stable checkpoint
+	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
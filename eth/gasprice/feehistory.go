@@ -0,0 +1,162 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks that can be retrieved for a
+// fee history request.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errMissingReceipts   = errors.New("receipts of processed block not found")
+)
+
+// txGasAndReward is sorted in ascending order based on reward
+type txGasAndReward struct {
+	gasUsed uint64
+	reward  *big.Int
+}
+
+type sortGasAndReward []txGasAndReward
+
+func (s sortGasAndReward) Len() int           { return len(s) }
+func (s sortGasAndReward) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortGasAndReward) Less(i, j int) bool { return s[i].reward.Cmp(s[j].reward) < 0 }
+
+// FeeHistory returns data relevant for fee estimation based on the specified
+// range of blocks, ending with lastBlock. The range can be truncated if the
+// chain is shorter than the requested block count.
+//
+// The returned values are:
+//  - the number of the oldest block in the range
+//  - the requested priority fee percentiles for each block, weighted by gas used
+//  - the base fee of each block, plus the one of the block after the range
+//  - the ratio of gas used to gas limit of each block
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	if blocks < 1 {
+		return common.Big0, nil, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%v: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	// Resolve the last block of the range and truncate it to the chain length
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if head == nil {
+		return common.Big0, nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		reward       = make([][]*big.Int, blocks)
+		baseFee      = make([]*big.Int, blocks+1)
+		gasUsedRatio = make([]float64, blocks)
+		config       = gpo.backend.ChainConfig()
+	)
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			return common.Big0, nil, nil, nil, err
+		}
+		if baseFee[i] = block.BaseFee(); baseFee[i] == nil {
+			baseFee[i] = new(big.Int)
+		}
+		gasUsedRatio[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+
+		if len(rewardPercentiles) == 0 {
+			continue
+		}
+		var receipts types.Receipts
+		if len(block.Transactions()) > 0 {
+			if receipts, err = gpo.backend.GetReceipts(ctx, block.Hash()); err != nil {
+				return common.Big0, nil, nil, nil, err
+			}
+			if len(receipts) != len(block.Transactions()) {
+				return common.Big0, nil, nil, nil, errMissingReceipts
+			}
+		}
+		reward[i] = blockRewards(block, receipts, rewardPercentiles)
+	}
+	// The base fee of the block following the range is deterministic
+	if next := new(big.Int).SetUint64(last + 1); config.IsLondon(next) {
+		baseFee[blocks] = misc.CalcBaseFee(config, head)
+	} else {
+		baseFee[blocks] = new(big.Int)
+	}
+	if len(rewardPercentiles) == 0 {
+		reward = nil
+	}
+	return new(big.Int).SetUint64(oldest), reward, baseFee, gasUsedRatio, nil
+}
+
+// blockRewards calculates the requested effective priority fee percentiles of
+// a block, weighting each transaction by the gas it consumed.
+func blockRewards(block *types.Block, receipts types.Receipts, percentiles []float64) []*big.Int {
+	rewards := make([]*big.Int, len(percentiles))
+
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		// Return an all zero row if there are no transactions to gather data from
+		for i := range rewards {
+			rewards[i] = new(big.Int)
+		}
+		return rewards
+	}
+	sorter := make(sortGasAndReward, len(txs))
+	for i, tx := range txs {
+		reward, _ := tx.EffectiveGasTip(block.BaseFee())
+		sorter[i] = txGasAndReward{gasUsed: receipts[i].GasUsed, reward: reward}
+	}
+	sort.Sort(sorter)
+
+	var txIndex int
+	sumGasUsed := sorter[0].gasUsed
+
+	for i, p := range percentiles {
+		thresholdGasUsed := uint64(float64(block.GasUsed()) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(txs)-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		rewards[i] = sorter[txIndex].reward
+	}
+	return rewards
+}
@@ -26,6 +26,10 @@ var (
 	propTxnInTrafficMeter     = metrics.NewRegisteredMeter("eth/prop/txns/in/traffic", nil)
 	propTxnOutPacketsMeter    = metrics.NewRegisteredMeter("eth/prop/txns/out/packets", nil)
 	propTxnOutTrafficMeter    = metrics.NewRegisteredMeter("eth/prop/txns/out/traffic", nil)
+	propTxHashInPacketsMeter  = metrics.NewRegisteredMeter("eth/prop/txhashes/in/packets", nil)
+	propTxHashInTrafficMeter  = metrics.NewRegisteredMeter("eth/prop/txhashes/in/traffic", nil)
+	propTxHashOutPacketsMeter = metrics.NewRegisteredMeter("eth/prop/txhashes/out/packets", nil)
+	propTxHashOutTrafficMeter = metrics.NewRegisteredMeter("eth/prop/txhashes/out/traffic", nil)
 	propHashInPacketsMeter    = metrics.NewRegisteredMeter("eth/prop/hashes/in/packets", nil)
 	propHashInTrafficMeter    = metrics.NewRegisteredMeter("eth/prop/hashes/in/traffic", nil)
 	propHashOutPacketsMeter   = metrics.NewRegisteredMeter("eth/prop/hashes/out/packets", nil)
@@ -50,6 +54,10 @@ var (
 	reqReceiptInTrafficMeter  = metrics.NewRegisteredMeter("eth/req/receipts/in/traffic", nil)
 	reqReceiptOutPacketsMeter = metrics.NewRegisteredMeter("eth/req/receipts/out/packets", nil)
 	reqReceiptOutTrafficMeter = metrics.NewRegisteredMeter("eth/req/receipts/out/traffic", nil)
+	reqTxInPacketsMeter       = metrics.NewRegisteredMeter("eth/req/txns/in/packets", nil)
+	reqTxInTrafficMeter       = metrics.NewRegisteredMeter("eth/req/txns/in/traffic", nil)
+	reqTxOutPacketsMeter      = metrics.NewRegisteredMeter("eth/req/txns/out/packets", nil)
+	reqTxOutTrafficMeter      = metrics.NewRegisteredMeter("eth/req/txns/out/traffic", nil)
 	miscInPacketsMeter        = metrics.NewRegisteredMeter("eth/misc/in/packets", nil)
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("eth/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("eth/misc/out/packets", nil)
@@ -97,6 +105,11 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptInPacketsMeter, reqReceiptInTrafficMeter
 
+	case rw.version >= eth65 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxInPacketsMeter, reqTxInTrafficMeter
+	case rw.version >= eth65 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashInPacketsMeter, propTxHashInTrafficMeter
+
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashInPacketsMeter, propHashInTrafficMeter
 	case msg.Code == NewBlockMsg:
@@ -124,6 +137,11 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptOutPacketsMeter, reqReceiptOutTrafficMeter
 
+	case rw.version >= eth65 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxOutPacketsMeter, reqTxOutTrafficMeter
+	case rw.version >= eth65 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashOutPacketsMeter, propTxHashOutTrafficMeter
+
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashOutPacketsMeter, propHashOutTrafficMeter
 	case msg.Code == NewBlockMsg:
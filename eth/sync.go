@@ -52,6 +52,25 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	if len(txs) == 0 {
 		return
 	}
+	// The eth/65 protocol introduces proper transaction announcements, so instead
+	// of dripping transactions across multiple peers, just announce the entire
+	// set and let the remote side decide what it needs (likely nothing).
+	if p.version >= eth65 {
+		hashes := make([]common.Hash, len(txs))
+		for i, tx := range txs {
+			hashes[i] = tx.Hash()
+		}
+		for len(hashes) > 0 {
+			batch := txsyncPackSize / common.HashLength
+			if batch > len(hashes) {
+				batch = len(hashes)
+			}
+			p.AsyncSendPooledTransactionHashes(hashes[:batch])
+			hashes = hashes[batch:]
+		}
+		return
+	}
+	// Out of luck, peer is running legacy protocols, drip the txs over
 	select {
 	case pm.txsyncCh <- &txsync{p, txs}:
 	case <-pm.quitSync:
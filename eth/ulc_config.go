@@ -0,0 +1,27 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+// DefaultULCMinTrustedFraction is the default percentage of trusted servers
+// that have to announce a new head before an ultra light client accepts it.
+const DefaultULCMinTrustedFraction = 75
+
+// ULCConfig is the configuration of the ultra light client mode.
+type ULCConfig struct {
+	TrustedServers     []string `toml:",omitempty"` // Enode URLs of the trusted LES servers
+	MinTrustedFraction int      `toml:",omitempty"` // Minimum percentage of trusted servers announcing a head (1-100)
+}
@@ -25,6 +25,7 @@ var Modules = map[string]string{
 	"ethash":     Ethash_JS,
 	"debug":      Debug_JS,
 	"eth":        Eth_JS,
+	"les":        LES_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -101,6 +102,12 @@ web3._extend({
 			call: 'clique_discard',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'status',
+			call: 'clique_status',
+			params: 1,
+			inputFormatter: [null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -512,6 +519,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -761,3 +774,51 @@ web3._extend({
 	]
 });
 `
+
+const LES_JS = `
+web3._extend({
+	property: 'les',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'clientInfo',
+			call: 'les_clientInfo',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addBalance',
+			call: 'les_addBalance',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setClientCapacity',
+			call: 'les_setClientCapacity',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setDefaultPrices',
+			call: 'les_setDefaultPrices',
+			params: 3
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'totalCapacity',
+			getter: 'les_totalCapacity'
+		}),
+		new web3._extend.Property({
+			name: 'paidCapacity',
+			getter: 'les_paidCapacity'
+		}),
+		new web3._extend.Property({
+			name: 'freeClientCapacity',
+			getter: 'les_freeClientCapacity'
+		}),
+		new web3._extend.Property({
+			name: 'defaultPrices',
+			getter: 'les_defaultPrices'
+		}),
+	]
+});
+`
@@ -0,0 +1,196 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	errClientPoolNotRunning       = errors.New("client pool is not running")
+	errNoCheckpoint               = errors.New("no local checkpoint provided")
+	errNotActivated               = errors.New("checkpoint registrar is not activated")
+	errCheckpointIndexUnavailable = errors.New("checkpoint index is not available")
+)
+
+// PrivateLightServerAPI provides an API to access the LES light server and
+// manage the balances and capacities of its clients.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new LES light server API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// pool returns the client pool of the server if it is already running.
+func (api *PrivateLightServerAPI) pool() (*clientPool, error) {
+	if pool := api.server.protocolManager.clientPool; pool != nil {
+		return pool, nil
+	}
+	return nil, errClientPoolNotRunning
+}
+
+// TotalCapacity returns the total capacity available for all clients.
+func (api *PrivateLightServerAPI) TotalCapacity() (uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	total, _, _ := pool.capacityInfo()
+	return total, nil
+}
+
+// PaidCapacity returns the capacity currently assigned to paid clients.
+func (api *PrivateLightServerAPI) PaidCapacity() (uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	_, paid, _ := pool.capacityInfo()
+	return paid, nil
+}
+
+// FreeClientCapacity returns the capacity assigned to a single free client.
+func (api *PrivateLightServerAPI) FreeClientCapacity() (uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return 0, err
+	}
+	_, _, free := pool.capacityInfo()
+	return free, nil
+}
+
+// ClientInfo returns the connection status, capacity and balances of a client.
+func (api *PrivateLightServerAPI) ClientInfo(id enode.ID) (map[string]interface{}, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return nil, err
+	}
+	return pool.clientStatus(id), nil
+}
+
+// AddBalance adds the given amount of tokens to the balance of a client. The
+// amount can also be negative. Tokens are used to settle the negative balance
+// of the client first. The positive balance before and after the operation is
+// returned.
+func (api *PrivateLightServerAPI) AddBalance(id enode.ID, amount int64) ([2]uint64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return [2]uint64{}, err
+	}
+	oldBalance, newBalance, err := pool.addBalance(id, amount)
+	return [2]uint64{oldBalance, newBalance}, err
+}
+
+// SetClientCapacity sets the capacity assigned to a client while it is served
+// as a paid client. Zero resets it to the default free client capacity.
+func (api *PrivateLightServerAPI) SetClientCapacity(id enode.ID, capacity uint64) error {
+	pool, err := api.pool()
+	if err != nil {
+		return err
+	}
+	return pool.setCapacity(id, capacity)
+}
+
+// SetDefaultPrices sets the price factors applied to all clients. The cost of
+// being connected is timeFactor + capacity * capacityFactor tokens per
+// nanosecond, serving a request costs requestFactor tokens per unit of the
+// real request cost.
+func (api *PrivateLightServerAPI) SetDefaultPrices(timeFactor, capacityFactor, requestFactor float64) error {
+	if timeFactor < 0 || capacityFactor < 0 || requestFactor < 0 {
+		return errors.New("negative price factor")
+	}
+	pool, err := api.pool()
+	if err != nil {
+		return err
+	}
+	pool.setDefaultPrices(priceFactors{TimeFactor: timeFactor, CapacityFactor: capacityFactor, RequestFactor: requestFactor})
+	return nil
+}
+
+// DefaultPrices returns the price factors applied to all clients.
+func (api *PrivateLightServerAPI) DefaultPrices() (map[string]float64, error) {
+	pool, err := api.pool()
+	if err != nil {
+		return nil, err
+	}
+	prices := pool.getPrices()
+	return map[string]float64{
+		"timeFactor":     prices.TimeFactor,
+		"capacityFactor": prices.CapacityFactor,
+		"requestFactor":  prices.RequestFactor,
+	}, nil
+}
+
+// PrivateLightAPI provides an API to access the LES light server or light client.
+type PrivateLightAPI struct {
+	backend *lesCommons
+}
+
+// NewPrivateLightAPI creates a new LES service API.
+func NewPrivateLightAPI(backend *lesCommons) *PrivateLightAPI {
+	return &PrivateLightAPI{backend: backend}
+}
+
+// LatestCheckpoint returns the latest local checkpoint package.
+//
+// The checkpoint package consists of 4 strings:
+//
+//	result[0], hex encoded latest section index
+//	result[1], 32 bytes hex encoded latest section head hash
+//	result[2], 32 bytes hex encoded latest section canonical hash trie root hash
+//	result[3], 32 bytes hex encoded latest section bloom trie root hash
+func (api *PrivateLightAPI) LatestCheckpoint() ([4]string, error) {
+	var res [4]string
+	cp := api.backend.latestLocalCheckpoint()
+	if cp.Empty() {
+		return res, errNoCheckpoint
+	}
+	res[0] = hexutil.EncodeUint64(cp.SectionIndex)
+	res[1], res[2], res[3] = cp.SectionHead.Hex(), cp.CHTRoot.Hex(), cp.BloomRoot.Hex()
+	return res, nil
+}
+
+// GetCheckpoint returns the specific local checkpoint package.
+//
+// The checkpoint package consists of 3 strings:
+//
+//	result[0], 32 bytes hex encoded latest section head hash
+//	result[1], 32 bytes hex encoded latest section canonical hash trie root hash
+//	result[2], 32 bytes hex encoded latest section bloom trie root hash
+func (api *PrivateLightAPI) GetCheckpoint(index uint64) ([3]string, error) {
+	var res [3]string
+	cp := api.backend.localCheckpoint(index)
+	if cp.Empty() {
+		return res, errCheckpointIndexUnavailable
+	}
+	res[0], res[1], res[2] = cp.SectionHead.Hex(), cp.CHTRoot.Hex(), cp.BloomRoot.Hex()
+	return res, nil
+}
+
+// GetCheckpointContractAddress returns the checkpoint oracle contract address in hex format.
+func (api *PrivateLightAPI) GetCheckpointContractAddress() (string, error) {
+	if api.backend.oracle == nil {
+		return "", errNotActivated
+	}
+	return api.backend.oracle.config.Address.Hex(), nil
+}
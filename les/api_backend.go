@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -106,9 +107,34 @@ func (b *LesApiBackend) GetTd(hash common.Hash) *big.Int {
 }
 
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	// Speculatively execute the message first to retrieve the state it accesses
+	// in a few batched requests instead of a network round trip per trie node
+	if err := light.PrefetchState(ctx, header, b.eth.odr, b.dryRun(ctx, msg, header)); err != nil {
+		log.Debug("Failed to prefetch call state", "err", err)
+	}
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.eth.blockchain, nil)
-	return vm.NewEVM(context, state, b.eth.chainConfig, vm.Config{}), state.Error, nil
+	return vm.NewEVM(context, state, b.eth.chainConfig, vm.Config{NoBaseFee: true}), state.Error, nil
+}
+
+// dryRun returns a function executing the message on a throwaway state, aborting
+// as soon as the context is cancelled.
+func (b *LesApiBackend) dryRun(ctx context.Context, msg core.Message, header *types.Header) func(*state.StateDB) {
+	return func(statedb *state.StateDB) {
+		statedb.SetBalance(msg.From(), math.MaxBig256)
+		evm := vm.NewEVM(core.NewEVMContext(msg, header, b.eth.blockchain, nil), statedb, b.eth.chainConfig, vm.Config{NoBaseFee: true})
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	}
 }
 
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
@@ -175,6 +201,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
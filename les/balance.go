@@ -0,0 +1,172 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// exhaustionMargin is added to the estimated time of a positive balance running out.
+const exhaustionMargin = time.Millisecond
+
+// priceFactors determine the pricing policy of the server. The cost of being
+// connected for dt time with a given capacity is
+//
+//	dt * (TimeFactor + capacity * CapacityFactor)
+//
+// while serving a request with a given real cost (measured in the same units
+// as the flow control buffer) costs
+//
+//	requestCost * RequestFactor
+//
+// Time is measured in nanoseconds.
+type priceFactors struct {
+	TimeFactor, CapacityFactor, RequestFactor float64
+}
+
+// balanceTracker keeps track of the positive and negative token balance of a
+// single connected client. Connection time and served requests are first paid
+// from the positive balance; once that is exhausted, further costs accumulate
+// in the negative balance which has to be settled before new tokens can be
+// spent again.
+//
+// Note: balanceTracker is not thread safe, it is protected by the lock of the
+// owning client pool.
+type balanceTracker struct {
+	clock      mclock.Clock
+	prices     priceFactors
+	capacity   uint64
+	pos, neg   uint64
+	remainder  float64 // fractional part of the costs not charged yet
+	lastUpdate mclock.AbsTime
+}
+
+// init initializes the tracker with the given balances and starts charging
+// connection time from now on.
+func (bt *balanceTracker) init(clock mclock.Clock, prices priceFactors, capacity, pos, neg uint64) {
+	bt.clock = clock
+	bt.prices = prices
+	bt.capacity = capacity
+	bt.pos, bt.neg = pos, neg
+	bt.lastUpdate = clock.Now()
+}
+
+// update charges the connection time elapsed since the last update.
+func (bt *balanceTracker) update() {
+	now := bt.clock.Now()
+	dt := float64(now - bt.lastUpdate)
+	bt.lastUpdate = now
+	if dt > 0 {
+		bt.charge(dt * (bt.prices.TimeFactor + float64(bt.capacity)*bt.prices.CapacityFactor))
+	}
+}
+
+// charge deducts the given cost from the positive balance, adding any remaining
+// amount to the negative balance. Fractional costs are accumulated until they
+// add up to a whole token.
+func (bt *balanceTracker) charge(cost float64) {
+	if cost <= 0 {
+		return
+	}
+	cost += bt.remainder
+	if cost >= math.MaxUint64 {
+		cost = math.MaxUint64
+	}
+	whole := math.Floor(cost)
+	bt.remainder = cost - whole
+	c := uint64(whole)
+	if c <= bt.pos {
+		bt.pos -= c
+		return
+	}
+	c -= bt.pos
+	bt.pos = 0
+	if bt.neg+c < bt.neg {
+		bt.neg = math.MaxUint64
+	} else {
+		bt.neg += c
+	}
+}
+
+// requestCost charges the cost of a served request.
+func (bt *balanceTracker) requestCost(cost uint64) {
+	bt.update()
+	bt.charge(float64(cost) * bt.prices.RequestFactor)
+}
+
+// setCapacity changes the capacity the client is charged for.
+func (bt *balanceTracker) setCapacity(capacity uint64) {
+	bt.update()
+	bt.capacity = capacity
+}
+
+// setPrices changes the price factors applied from now on.
+func (bt *balanceTracker) setPrices(prices priceFactors) {
+	bt.update()
+	bt.prices = prices
+}
+
+// addBalance adds the given amount of tokens (which may also be negative) to
+// the balance. Added tokens are used to settle the negative balance first.
+func (bt *balanceTracker) addBalance(amount int64) {
+	bt.update()
+	if amount < 0 {
+		bt.charge(float64(-amount))
+		return
+	}
+	a := uint64(amount)
+	if a <= bt.neg {
+		bt.neg -= a
+		return
+	}
+	a -= bt.neg
+	bt.neg = 0
+	if bt.pos+a < bt.pos {
+		bt.pos = math.MaxUint64
+	} else {
+		bt.pos += a
+	}
+}
+
+// getBalance returns the current positive and negative balances.
+func (bt *balanceTracker) getBalance() (uint64, uint64) {
+	bt.update()
+	return bt.pos, bt.neg
+}
+
+// timeUntilExhausted estimates the remaining connection time until the positive
+// balance runs out, assuming that no requests are served in the meantime. The
+// second return value is false if the balance never runs out.
+func (bt *balanceTracker) timeUntilExhausted() (time.Duration, bool) {
+	bt.update()
+	rate := bt.prices.TimeFactor + float64(bt.capacity)*bt.prices.CapacityFactor
+	if rate <= 0 {
+		return 0, false
+	}
+	dt := (float64(bt.pos) - bt.remainder) / rate
+	if dt > float64(math.MaxInt64-int64(exhaustionMargin)) {
+		return 0, false
+	}
+	if dt < 0 {
+		dt = 0
+	}
+	// add a small margin to avoid waking up just before the last token is spent
+	return time.Duration(dt) + exhaustionMargin, true
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTestSigners creates n signer keys sorted by their addresses.
+func newTestSigners(t *testing.T, n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		keys[i] = key
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(crypto.PubkeyToAddress(keys[i].PublicKey).Bytes(), crypto.PubkeyToAddress(keys[j].PublicKey).Bytes()) < 0
+	})
+	addrs := make([]common.Address, n)
+	for i, key := range keys {
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return keys, addrs
+}
+
+// signTestCheckpoint signs the checkpoint hash for the given oracle address the
+// same way checkpoint admins do.
+func signTestCheckpoint(oracle common.Address, key *ecdsa.PrivateKey, index uint64, hash common.Hash) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	data := append([]byte{0x19, 0x00}, append(oracle.Bytes(), append(buf, hash.Bytes()...)...)...)
+	sig, _ := crypto.Sign(crypto.Keccak256(data), key)
+	sig[64] += 27
+	return sig
+}
+
+func TestCheckpointOracleVerifySigners(t *testing.T) {
+	keys, signers := newTestSigners(t, 3)
+	outsider, _ := newTestSigners(t, 1)
+
+	address := common.HexToAddress("0x1234")
+	oracle := newCheckpointOracle(&params.CheckpointOracleConfig{Address: address, Signers: signers, Threshold: 2}, nil)
+	if oracle == nil {
+		t.Fatalf("Failed to create checkpoint oracle")
+	}
+	hash := common.HexToHash("0xdeadbeef")
+	sign := func(index uint64, keys ...*ecdsa.PrivateKey) [][]byte {
+		var sigs [][]byte
+		for _, key := range keys {
+			sigs = append(sigs, signTestCheckpoint(address, key, index, hash))
+		}
+		return sigs
+	}
+	tests := []struct {
+		sigs  [][]byte
+		valid bool
+	}{
+		{sign(1, keys[0], keys[1]), true},
+		{sign(1, keys[2], keys[0], keys[1]), true},
+		{sign(1, keys[0]), false},
+		{sign(1, keys[0], keys[0]), false},
+		{sign(1, keys[0], outsider[0]), false},
+		{sign(2, keys[0], keys[1]), false},
+		{append(sign(1, keys[0]), []byte{0x01}), false},
+	}
+	for i, tt := range tests {
+		if valid, _ := oracle.verifySigners(1, hash, tt.sigs); valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, valid, tt.valid)
+		}
+	}
+	if newCheckpointOracle(&params.CheckpointOracleConfig{Address: address, Signers: signers, Threshold: 4}, nil) != nil {
+		t.Errorf("Checkpoint oracle created with unreachable threshold")
+	}
+}
+
+func TestCheckpointOracleStableCheckpoint(t *testing.T) {
+	keys, signers := newTestSigners(t, 2)
+
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{signers[0]: {Balance: big.NewInt(1000000000000000000)}}, 10000000)
+	opts := bind.NewKeyedTransactor(keys[0])
+	address, _, _, err := contract.DeployCheckpointOracle(opts, backend, signers, big.NewInt(1), big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatalf("Failed to deploy checkpoint oracle: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		backend.Commit()
+	}
+	local := params.TrustedCheckpoint{
+		SectionIndex: 0,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	oracle := newCheckpointOracle(&params.CheckpointOracleConfig{Address: address, Signers: signers, Threshold: 2}, func(index uint64) params.TrustedCheckpoint {
+		if index == local.SectionIndex {
+			return local
+		}
+		return params.TrustedCheckpoint{}
+	})
+	if oracle.isRunning() {
+		t.Fatalf("Checkpoint oracle running without backend")
+	}
+	oracle.start(backend)
+	if !oracle.isRunning() {
+		t.Fatalf("Checkpoint oracle not running")
+	}
+	if cp, _ := oracle.stableCheckpoint(); cp != nil {
+		t.Fatalf("Stable checkpoint announced before registration")
+	}
+	// Register the local checkpoint and ensure it's announced
+	head := backend.Blockchain().CurrentHeader()
+	sigs := [][]byte{signTestCheckpoint(address, keys[0], 0, local.Hash()), signTestCheckpoint(address, keys[1], 0, local.Hash())}
+	if _, err := oracle.contract.RegisterCheckpoint(opts, 0, local.Hash().Bytes(), head.Number, head.Hash(), sigs); err != nil {
+		t.Fatalf("Failed to register checkpoint: %v", err)
+	}
+	backend.Commit()
+
+	cp, height := oracle.stableCheckpoint()
+	if cp == nil || *cp != local {
+		t.Fatalf("Stable checkpoint mismatch: have %v, want %v", cp, local)
+	}
+	if want := backend.Blockchain().CurrentHeader().Number.Uint64(); height != want {
+		t.Fatalf("Registration height mismatch: have %d, want %d", height, want)
+	}
+	// A local checkpoint not matching the registered one shouldn't be announced
+	local.CHTRoot = common.HexToHash("0x04")
+	if cp, _ := oracle.stableCheckpoint(); cp != nil {
+		t.Fatalf("Mismatching stable checkpoint announced")
+	}
+}
@@ -0,0 +1,628 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	bufLimitRatio   = 6000             // fixed bufLimit/MRR ratio of the flow control parameters assigned to clients
+	stopGracePeriod = 10 * time.Second // time a stopped client has to top up its balance before being disconnected
+)
+
+var (
+	clientRecordPrefix = []byte("clientBalance-") // clientRecordPrefix + enode ID -> client balance record
+	clientPricingKey   = []byte("clientPricing")  // default price factors of the client pool
+
+	errPoolClosed     = errors.New("client pool is closed")
+	errNoClientRecord = errors.New("unknown client")
+	errNoCapacity     = errors.New("not enough free capacity")
+)
+
+// clientPeer represents a connected client as seen by the client pool.
+type clientPeer interface {
+	ID() enode.ID
+	canUpdateCapacity() bool // true if the client can be notified about capacity changes
+	updateCapacity(uint64)   // assigns a new capacity to the client
+	freeze()                 // notifies the client that serving has been stopped
+	unfreeze()               // notifies the client that serving has been resumed
+}
+
+// clientPool manages the connected clients of a light server, assigning a
+// priority and a request serving capacity to each of them. Clients with a
+// positive token balance are paid clients: they receive the capacity assigned
+// to them by the server operator and are charged for connection time and served
+// requests according to the pool's price factors. Paid clients are always
+// preferred over free ones and among each other the ones with a higher remaining
+// balance have a higher priority. The capacity not used by paid clients is
+// shared between free clients which are handled by a freeClientPool, based on
+// their recent usage.
+//
+// When the positive balance of a paid client runs out, it is downgraded to a
+// free client if there is room for it. Otherwise it is stopped (LES/3 clients
+// receive a StopMsg) and disconnected after a grace period unless its balance
+// is topped up in the meantime. Costs incurred after the positive balance has
+// been exhausted are accumulated as a negative balance which has to be settled
+// before the client can be served as a paid client again.
+//
+// Balances are identified by the node ID of the client and are persisted in the
+// database so they survive server restarts.
+type clientPool struct {
+	db     ethdb.Database
+	lock   sync.Mutex
+	clock  mclock.Clock
+	closed bool
+	quit   chan struct{}
+
+	freeClientPool *freeClientPool
+	removePeer     func(enode.ID)
+
+	connected         map[enode.ID]*clientInfo
+	paidCount         int
+	totalCap, paidCap uint64
+	freeClientCap     uint64
+	maxPeers          int
+	defaultPrices     priceFactors
+}
+
+// clientInfo represents a connected client.
+type clientInfo struct {
+	peer           clientPeer
+	freeID         string // identifier used by the free client pool
+	paid, stopped  bool
+	hasRecord      bool   // true if a balance record exists for the client
+	capacity       uint64 // currently assigned capacity
+	paidCapacity   uint64 // capacity assigned when served as a paid client
+	balance        balanceTracker
+	checkScheduled bool
+}
+
+// clientRecord is the RLP representation of a client's balance record stored
+// in the database.
+type clientRecord struct {
+	PosBalance, NegBalance uint64
+	Capacity               uint64 // capacity assigned to the client as a paid client (0 means default)
+}
+
+// newClientPool creates a new client pool
+func newClientPool(db ethdb.Database, freeClientCap uint64, maxPeers int, clock mclock.Clock, removePeer func(enode.ID)) *clientPool {
+	pool := &clientPool{
+		db:            db,
+		clock:         clock,
+		quit:          make(chan struct{}),
+		removePeer:    removePeer,
+		connected:     make(map[enode.ID]*clientInfo),
+		totalCap:      uint64(maxPeers) * freeClientCap,
+		freeClientCap: freeClientCap,
+		maxPeers:      maxPeers,
+	}
+	pool.freeClientPool = newFreeClientPool(db, maxPeers, 10000, clock)
+	pool.loadPrices()
+	return pool
+}
+
+// stop shuts the client pool down, saving the balances of connected clients.
+func (cp *clientPool) stop() {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.closed {
+		return
+	}
+	cp.closed = true
+	close(cp.quit)
+	for id, c := range cp.connected {
+		if c.hasRecord {
+			cp.saveClient(id, c)
+		}
+	}
+	cp.freeClientPool.stop()
+}
+
+// connect should be called after a successful handshake. It returns the capacity
+// assigned to the client or false if the client has been rejected. If the
+// connection was rejected, there is no need to call disconnect.
+//
+// Note: the freeID is used by the free client pool to identify clients without
+// a positive balance.
+func (cp *clientPool) connect(peer clientPeer, freeID string) (uint64, bool) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	id := peer.ID()
+	if cp.closed {
+		return 0, false
+	}
+	if _, ok := cp.connected[id]; ok {
+		log.Debug("Client already connected", "id", id)
+		return 0, false
+	}
+	rec, hasRecord := cp.loadRecord(id)
+	c := &clientInfo{
+		peer:         peer,
+		freeID:       freeID,
+		hasRecord:    hasRecord,
+		paidCapacity: cp.paidCapacity(rec),
+	}
+	var prices priceFactors
+	if hasRecord {
+		prices = cp.defaultPrices
+	}
+	c.balance.init(cp.clock, prices, 0, rec.PosBalance, rec.NegBalance)
+
+	if rec.PosBalance > 0 && peer.canUpdateCapacity() && cp.admitPaid(c) {
+		cp.connected[id] = c
+		cp.scheduleCheck(id, c)
+		log.Debug("Paid client accepted", "id", id, "capacity", c.capacity, "balance", rec.PosBalance)
+		return c.capacity, true
+	}
+	if !cp.freeClientPool.connect(freeID, func() { go cp.removePeer(id) }) {
+		return 0, false
+	}
+	c.capacity = cp.freeClientCap
+	c.balance.setCapacity(c.capacity)
+	cp.connected[id] = c
+	return c.capacity, true
+}
+
+// disconnect should be called when a connection is terminated. If the
+// disconnection was initiated by the pool itself then calling disconnect is
+// not necessary but permitted.
+func (cp *clientPool) disconnect(id enode.ID) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.closed {
+		return
+	}
+	c := cp.connected[id]
+	if c == nil {
+		return
+	}
+	delete(cp.connected, id)
+	switch {
+	case c.paid:
+		cp.paidCap -= c.capacity
+		cp.paidCount--
+		cp.freeClientPool.setConnectedLimit(cp.freeLimit())
+	case !c.stopped:
+		cp.freeClientPool.disconnect(c.freeID)
+	}
+	if c.hasRecord {
+		cp.saveClient(id, c)
+	}
+	log.Debug("Client disconnected", "id", id)
+}
+
+// requestCost charges the given request cost to the balance of a connected client.
+func (cp *clientPool) requestCost(id enode.ID, cost uint64) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	c := cp.connected[id]
+	if c == nil || !c.hasRecord {
+		return
+	}
+	c.balance.requestCost(cost)
+	if pos, _ := c.balance.getBalance(); c.paid && pos == 0 {
+		cp.downgrade(id, c)
+	}
+}
+
+// admitPaid tries to assign the paid capacity to the given client, kicking out
+// free clients and paid clients with a lower balance if necessary.
+//
+// Note: the client is not added to the connected map by admitPaid.
+func (cp *clientPool) admitPaid(c *clientInfo) bool {
+	capacity := c.paidCapacity
+	if capacity > cp.totalCap {
+		return false
+	}
+	pos, _ := c.balance.getBalance()
+	var (
+		kick      []*clientInfo
+		kicked    = make(map[*clientInfo]struct{})
+		available = cp.totalCap - cp.paidCap
+		count     = cp.paidCount
+	)
+	for available < capacity || count >= cp.maxPeers {
+		// find the paid client with the lowest balance
+		var (
+			lowest    *clientInfo
+			lowestBal uint64
+		)
+		for _, e := range cp.connected {
+			if !e.paid {
+				continue
+			}
+			if _, ok := kicked[e]; ok {
+				continue
+			}
+			if bal, _ := e.balance.getBalance(); lowest == nil || bal < lowestBal {
+				lowest, lowestBal = e, bal
+			}
+		}
+		if lowest == nil || lowestBal >= pos {
+			return false
+		}
+		kick = append(kick, lowest)
+		kicked[lowest] = struct{}{}
+		available += lowest.capacity
+		count--
+	}
+	for _, e := range kick {
+		cp.kick(e)
+	}
+	c.paid = true
+	c.capacity = capacity
+	c.balance.setCapacity(capacity)
+	cp.paidCap += capacity
+	cp.paidCount++
+	cp.freeClientPool.setConnectedLimit(cp.freeLimit())
+	return true
+}
+
+// kick stops and disconnects a paid client which has been outbid by another one.
+func (cp *clientPool) kick(c *clientInfo) {
+	id := c.peer.ID()
+	delete(cp.connected, id)
+	cp.paidCap -= c.capacity
+	cp.paidCount--
+	c.paid = false
+	cp.saveClient(id, c)
+	log.Debug("Paid client kicked out", "id", id)
+	go func() {
+		c.peer.freeze()
+		cp.removePeer(id)
+	}()
+}
+
+// downgrade is called when the positive balance of a paid client is exhausted.
+// The client is either moved to the free client pool or stopped if there is no
+// room for it.
+func (cp *clientPool) downgrade(id enode.ID, c *clientInfo) {
+	cp.paidCap -= c.capacity
+	cp.paidCount--
+	c.paid = false
+	cp.freeClientPool.setConnectedLimit(cp.freeLimit())
+	if cp.freeClientPool.connect(c.freeID, func() { go cp.removePeer(id) }) {
+		c.capacity = cp.freeClientCap
+		c.balance.setCapacity(c.capacity)
+		c.peer.updateCapacity(c.capacity)
+		log.Debug("Paid client downgraded", "id", id)
+		return
+	}
+	cp.stopClient(id, c)
+}
+
+// stopClient stops serving a client and disconnects it after stopGracePeriod
+// unless it becomes a paid client again in the meantime.
+func (cp *clientPool) stopClient(id enode.ID, c *clientInfo) {
+	c.stopped = true
+	c.capacity = 0
+	c.balance.setCapacity(0)
+	log.Debug("Client stopped", "id", id)
+	go c.peer.freeze()
+	timeout := cp.clock.After(stopGracePeriod)
+	go func() {
+		select {
+		case <-timeout:
+		case <-cp.quit:
+			return
+		}
+		cp.lock.Lock()
+		remove := cp.connected[id] == c && c.stopped
+		cp.lock.Unlock()
+		if remove {
+			cp.removePeer(id)
+		}
+	}()
+}
+
+// scheduleCheck schedules a balance check for a paid client at the estimated
+// time of its positive balance running out.
+func (cp *clientPool) scheduleCheck(id enode.ID, c *clientInfo) {
+	if c.checkScheduled || !c.paid {
+		return
+	}
+	d, ok := c.balance.timeUntilExhausted()
+	if !ok {
+		return
+	}
+	c.checkScheduled = true
+	timeout := cp.clock.After(d)
+	go func() {
+		select {
+		case <-timeout:
+		case <-cp.quit:
+			return
+		}
+		cp.lock.Lock()
+		defer cp.lock.Unlock()
+
+		c.checkScheduled = false
+		if cp.closed || cp.connected[id] != c || !c.paid {
+			return
+		}
+		if pos, _ := c.balance.getBalance(); pos == 0 {
+			cp.downgrade(id, c)
+		} else {
+			cp.scheduleCheck(id, c)
+		}
+	}()
+}
+
+// freeLimit returns the number of free clients that can be connected
+// simultaneously with the currently connected paid clients.
+func (cp *clientPool) freeLimit() int {
+	if cp.freeClientCap == 0 {
+		return cp.maxPeers - cp.paidCount
+	}
+	limit := (cp.totalCap - cp.paidCap) / cp.freeClientCap
+	if limit > uint64(cp.maxPeers-cp.paidCount) {
+		return cp.maxPeers - cp.paidCount
+	}
+	return int(limit)
+}
+
+// paidCapacity returns the capacity assigned to a client with the given record
+// when it is served as a paid client.
+func (cp *clientPool) paidCapacity(rec clientRecord) uint64 {
+	if rec.Capacity == 0 {
+		return cp.freeClientCap
+	}
+	return rec.Capacity
+}
+
+// addBalance adds the given amount (which may also be negative) to the balance
+// of the given client and returns the positive balance before and after the
+// operation. Connected clients are upgraded or downgraded if necessary.
+func (cp *clientPool) addBalance(id enode.ID, amount int64) (uint64, uint64, error) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.closed {
+		return 0, 0, errPoolClosed
+	}
+	c := cp.connected[id]
+	if c == nil {
+		rec, _ := cp.loadRecord(id)
+		oldBalance := rec.PosBalance
+
+		var bt balanceTracker
+		bt.init(cp.clock, priceFactors{}, 0, rec.PosBalance, rec.NegBalance)
+		bt.addBalance(amount)
+		rec.PosBalance, rec.NegBalance = bt.getBalance()
+		cp.saveRecord(id, rec)
+		return oldBalance, rec.PosBalance, nil
+	}
+	if !c.hasRecord {
+		c.hasRecord = true
+		c.balance.setPrices(cp.defaultPrices)
+	}
+	oldBalance, _ := c.balance.getBalance()
+	c.balance.addBalance(amount)
+	newBalance, _ := c.balance.getBalance()
+
+	switch {
+	case c.paid && newBalance == 0:
+		cp.downgrade(id, c)
+	case c.paid:
+		cp.scheduleCheck(id, c)
+	case newBalance > 0 && c.peer.canUpdateCapacity():
+		wasFree := !c.stopped
+		if wasFree {
+			cp.freeClientPool.disconnect(c.freeID)
+		}
+		if cp.admitPaid(c) {
+			if c.stopped {
+				c.stopped = false
+				go c.peer.unfreeze()
+			}
+			c.peer.updateCapacity(c.capacity)
+			cp.scheduleCheck(id, c)
+			log.Debug("Client upgraded to paid client", "id", id, "capacity", c.capacity)
+		} else if wasFree && !cp.freeClientPool.connect(c.freeID, func() { go cp.removePeer(id) }) {
+			cp.stopClient(id, c)
+		}
+	}
+	cp.saveClient(id, c)
+	return oldBalance, newBalance, nil
+}
+
+// setCapacity sets the capacity assigned to the given client when it is served
+// as a paid client. Zero means the default (free client) capacity.
+func (cp *clientPool) setCapacity(id enode.ID, capacity uint64) error {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.closed {
+		return errPoolClosed
+	}
+	c := cp.connected[id]
+	if c == nil {
+		rec, ok := cp.loadRecord(id)
+		if !ok {
+			return errNoClientRecord
+		}
+		rec.Capacity = capacity
+		cp.saveRecord(id, rec)
+		return nil
+	}
+	newCap := capacity
+	if newCap == 0 {
+		newCap = cp.freeClientCap
+	}
+	if c.paid {
+		if newCap > c.capacity && cp.paidCap-c.capacity+newCap > cp.totalCap {
+			return errNoCapacity
+		}
+		cp.paidCap = cp.paidCap - c.capacity + newCap
+		c.capacity = newCap
+		c.balance.setCapacity(newCap)
+		cp.freeClientPool.setConnectedLimit(cp.freeLimit())
+		c.peer.updateCapacity(newCap)
+	}
+	c.paidCapacity = newCap
+	c.hasRecord = true
+	cp.saveClient(id, c)
+	return nil
+}
+
+// setDefaultPrices changes the price factors applied to all clients.
+func (cp *clientPool) setDefaultPrices(prices priceFactors) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	cp.defaultPrices = prices
+	for id, c := range cp.connected {
+		if c.hasRecord {
+			c.balance.setPrices(prices)
+			if c.paid {
+				// pending checks are rescheduled when they fire; make sure one is pending
+				cp.scheduleCheck(id, c)
+			}
+		}
+	}
+	cp.savePrices()
+}
+
+// getPrices returns the current default price factors.
+func (cp *clientPool) getPrices() priceFactors {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	return cp.defaultPrices
+}
+
+// clientStatus returns the status of the given client.
+func (cp *clientPool) clientStatus(id enode.ID) map[string]interface{} {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	res := make(map[string]interface{})
+	if c := cp.connected[id]; c != nil {
+		pos, neg := c.balance.getBalance()
+		res["isConnected"] = true
+		res["isPaid"] = c.paid
+		res["isStopped"] = c.stopped
+		res["capacity"] = c.capacity
+		res["pricing/balance"] = pos
+		res["pricing/negBalance"] = neg
+		res["pricing/capacity"] = c.paidCapacity
+		return res
+	}
+	rec, _ := cp.loadRecord(id)
+	res["isConnected"] = false
+	res["pricing/balance"] = rec.PosBalance
+	res["pricing/negBalance"] = rec.NegBalance
+	res["pricing/capacity"] = cp.paidCapacity(rec)
+	return res
+}
+
+// capacityInfo returns the total capacity of the pool, the capacity assigned
+// to paid clients and the capacity of a single free client.
+func (cp *clientPool) capacityInfo() (total, paid, free uint64) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	return cp.totalCap, cp.paidCap, cp.freeClientCap
+}
+
+// loadRecord retrieves the balance record of a client from the database.
+func (cp *clientPool) loadRecord(id enode.ID) (clientRecord, bool) {
+	var rec clientRecord
+	enc, err := cp.db.Get(append(clientRecordPrefix, id.Bytes()...))
+	if err != nil {
+		return rec, false
+	}
+	if err := rlp.DecodeBytes(enc, &rec); err != nil {
+		log.Error("Failed to decode client balance", "id", id, "err", err)
+		return clientRecord{}, false
+	}
+	return rec, true
+}
+
+// saveRecord stores the balance record of a client in the database.
+func (cp *clientPool) saveRecord(id enode.ID, rec clientRecord) {
+	enc, err := rlp.EncodeToBytes(&rec)
+	if err != nil {
+		log.Error("Failed to encode client balance", "id", id, "err", err)
+		return
+	}
+	cp.db.Put(append(clientRecordPrefix, id.Bytes()...), enc)
+}
+
+// saveClient stores the current balance of a connected client in the database.
+func (cp *clientPool) saveClient(id enode.ID, c *clientInfo) {
+	pos, neg := c.balance.getBalance()
+	rec := clientRecord{PosBalance: pos, NegBalance: neg}
+	if c.paidCapacity != cp.freeClientCap {
+		rec.Capacity = c.paidCapacity
+	}
+	cp.saveRecord(id, rec)
+}
+
+// loadPrices restores the default price factors from the database.
+func (cp *clientPool) loadPrices() {
+	enc, err := cp.db.Get(clientPricingKey)
+	if err != nil {
+		return
+	}
+	if err := rlp.DecodeBytes(enc, &cp.defaultPrices); err != nil {
+		log.Error("Failed to decode client pricing", "err", err)
+	}
+}
+
+// savePrices stores the default price factors in the database.
+func (cp *clientPool) savePrices() {
+	enc, err := rlp.EncodeToBytes(&cp.defaultPrices)
+	if err != nil {
+		log.Error("Failed to encode client pricing", "err", err)
+		return
+	}
+	cp.db.Put(clientPricingKey, enc)
+}
+
+// EncodeRLP implements rlp.Encoder. Price factors are stored in their IEEE 754
+// binary representation.
+func (p *priceFactors) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, []uint64{math.Float64bits(p.TimeFactor), math.Float64bits(p.CapacityFactor), math.Float64bits(p.RequestFactor)})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (p *priceFactors) DecodeRLP(s *rlp.Stream) error {
+	var bits [3]uint64
+	if err := s.Decode(&bits); err != nil {
+		return err
+	}
+	p.TimeFactor = math.Float64frombits(bits[0])
+	p.CapacityFactor = math.Float64frombits(bits[1])
+	p.RequestFactor = math.Float64frombits(bits[2])
+	return nil
+}
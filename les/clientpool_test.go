@@ -0,0 +1,305 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+type poolTestPeer struct {
+	id     enode.ID
+	lock   sync.Mutex
+	cap    uint64
+	frozen bool
+}
+
+func newPoolTestPeer(i int) *poolTestPeer {
+	p := &poolTestPeer{}
+	p.id[0] = byte(i >> 8)
+	p.id[1] = byte(i)
+	return p
+}
+
+func (p *poolTestPeer) ID() enode.ID            { return p.id }
+func (p *poolTestPeer) canUpdateCapacity() bool { return true }
+
+func (p *poolTestPeer) updateCapacity(cap uint64) {
+	p.lock.Lock()
+	p.cap = cap
+	p.lock.Unlock()
+}
+
+func (p *poolTestPeer) capacity() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cap
+}
+
+func (p *poolTestPeer) freeze() {
+	p.lock.Lock()
+	p.frozen = true
+	p.lock.Unlock()
+}
+
+func (p *poolTestPeer) unfreeze() {
+	p.lock.Lock()
+	p.frozen = false
+	p.lock.Unlock()
+}
+
+func (p *poolTestPeer) isFrozen() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.frozen
+}
+
+// testPrices charges one token per 2^26 nanoseconds for a client of capacity 16.
+// Values are powers of two so that the expected balances are exact.
+var testPrices = priceFactors{CapacityFactor: 1.0 / (1 << 30)}
+
+const testTokenTime = time.Duration(1 << 26)
+
+// waitForStatus waits until the status of the given client satisfies the check.
+func waitForStatus(t *testing.T, pool *clientPool, id enode.ID, check func(map[string]interface{}) bool) {
+	for i := 0; i < 100; i++ {
+		if check(pool.clientStatus(id)) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Client status check timed out, status: %v", pool.clientStatus(id))
+}
+
+func expectRemoved(t *testing.T, removed chan enode.ID, id enode.ID) {
+	select {
+	case r := <-removed:
+		if r != id {
+			t.Fatalf("Wrong client removed: got %v, want %v", r, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Client %v not removed", id)
+	}
+}
+
+func TestClientPoolPaidPriority(t *testing.T) {
+	var (
+		clock   mclock.Simulated
+		db      = ethdb.NewMemDatabase()
+		removed = make(chan enode.ID, 100)
+		pool    = newClientPool(db, 10, 5, &clock, func(id enode.ID) { removed <- id })
+		peers   = make([]*poolTestPeer, 10)
+	)
+	defer pool.stop()
+
+	for i := range peers {
+		peers[i] = newPoolTestPeer(i)
+	}
+	// fill the pool with free clients
+	for i := 0; i < 5; i++ {
+		if _, ok := pool.connect(peers[i], fmt.Sprintf("free #%d", i)); !ok {
+			t.Fatalf("Free client #%d rejected", i)
+		}
+	}
+	if _, ok := pool.connect(peers[5], "free #5"); ok {
+		t.Fatalf("Free client accepted over the connection limit")
+	}
+	// a paid client should kick out free ones
+	if _, _, err := pool.addBalance(peers[6].id, 1000); err != nil {
+		t.Fatalf("Failed to add balance: %v", err)
+	}
+	if err := pool.setCapacity(peers[6].id, 20); err != nil {
+		t.Fatalf("Failed to set capacity: %v", err)
+	}
+	cap, ok := pool.connect(peers[6], "paid #6")
+	if !ok {
+		t.Fatalf("Paid client rejected")
+	}
+	if cap != 20 {
+		t.Fatalf("Wrong paid client capacity: got %d, want 20", cap)
+	}
+	// paid capacity 20 leaves room for 3 free clients only
+	for i := 0; i < 2; i++ {
+		select {
+		case <-removed:
+		case <-time.After(time.Second):
+			t.Fatalf("Free client #%d not kicked out", i)
+		}
+	}
+	// a paid client with a lower balance should not kick out the first one
+	pool.addBalance(peers[7].id, 500)
+	pool.setCapacity(peers[7].id, 50)
+	if _, ok := pool.connect(peers[7], "paid #7"); ok {
+		t.Fatalf("Paid client with lower balance accepted")
+	}
+	// but one with a higher balance should
+	pool.addBalance(peers[8].id, 2000)
+	pool.setCapacity(peers[8].id, 50)
+	if _, ok := pool.connect(peers[8], "paid #8"); !ok {
+		t.Fatalf("Paid client with higher balance rejected")
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case <-removed:
+		case <-time.After(time.Second):
+			t.Fatalf("Client #%d not kicked out", i)
+		}
+	}
+	if !peers[6].isFrozen() {
+		t.Fatalf("Kicked out paid client not stopped")
+	}
+	if total, paid, _ := pool.capacityInfo(); total != 50 || paid != 50 {
+		t.Fatalf("Wrong capacity info: total %d, paid %d", total, paid)
+	}
+}
+
+func TestClientPoolBalanceExhaustion(t *testing.T) {
+	var (
+		clock mclock.Simulated
+		db    = ethdb.NewMemDatabase()
+		pool  = newClientPool(db, 16, 1, &clock, func(id enode.ID) {})
+		paid  = newPoolTestPeer(0)
+	)
+	defer pool.stop()
+
+	pool.setDefaultPrices(testPrices)
+	pool.addBalance(paid.id, 100)
+	if _, ok := pool.connect(paid, "paid"); !ok {
+		t.Fatalf("Paid client rejected")
+	}
+	// a free client cannot be accepted while the paid one is connected
+	if _, ok := pool.connect(newPoolTestPeer(1), "free"); ok {
+		t.Fatalf("Free client accepted")
+	}
+	clock.Run(50 * testTokenTime)
+	if pos := pool.clientStatus(paid.id)["pricing/balance"].(uint64); pos != 50 {
+		t.Fatalf("Wrong balance: got %d, want 50", pos)
+	}
+	// the balance runs out, the client is downgraded to a free client and
+	// accumulates negative balance from now on
+	clock.Run(50*testTokenTime + exhaustionMargin)
+	waitForStatus(t, pool, paid.id, func(status map[string]interface{}) bool {
+		return !status["isPaid"].(bool)
+	})
+	if cap := paid.capacity(); cap != 16 {
+		t.Fatalf("Wrong capacity after downgrade: got %d, want 16", cap)
+	}
+	clock.Run(10 * testTokenTime)
+	if neg := pool.clientStatus(paid.id)["pricing/negBalance"].(uint64); neg != 10 {
+		t.Fatalf("Wrong negative balance: got %d, want 10", neg)
+	}
+	// adding balance settles the negative balance first and upgrades the client
+	pool.addBalance(paid.id, 110)
+	status := pool.clientStatus(paid.id)
+	if !status["isPaid"].(bool) {
+		t.Fatalf("Client not upgraded after adding balance")
+	}
+	if pos, neg := status["pricing/balance"].(uint64), status["pricing/negBalance"].(uint64); pos != 100 || neg != 0 {
+		t.Fatalf("Wrong balance after upgrade: got %d/%d, want 100/0", pos, neg)
+	}
+}
+
+func TestClientPoolStopPaid(t *testing.T) {
+	var (
+		clock   mclock.Simulated
+		db      = ethdb.NewMemDatabase()
+		removed = make(chan enode.ID, 100)
+		pool    = newClientPool(db, 16, 2, &clock, func(id enode.ID) { removed <- id })
+		paid    = newPoolTestPeer(0)
+		free    = newPoolTestPeer(1)
+	)
+	defer pool.stop()
+
+	pool.setDefaultPrices(testPrices)
+	pool.addBalance(paid.id, 100)
+	if _, ok := pool.connect(paid, "shared"); !ok {
+		t.Fatalf("Paid client rejected")
+	}
+	// a free client from the same address prevents the downgrade of the paid
+	// one when its balance runs out so it is stopped instead
+	if _, ok := pool.connect(free, "shared"); !ok {
+		t.Fatalf("Free client rejected")
+	}
+	clock.Run(100*testTokenTime + exhaustionMargin)
+	waitForStatus(t, pool, paid.id, func(status map[string]interface{}) bool {
+		return status["isStopped"].(bool)
+	})
+	waitForFrozen(t, paid, true)
+
+	// topping up the balance within the grace period resumes serving
+	pool.addBalance(paid.id, 100)
+	if !pool.clientStatus(paid.id)["isPaid"].(bool) {
+		t.Fatalf("Stopped client not upgraded after adding balance")
+	}
+	waitForFrozen(t, paid, false)
+
+	// if the balance is not topped up, the client is disconnected
+	clock.Run(100*testTokenTime + exhaustionMargin)
+	waitForStatus(t, pool, paid.id, func(status map[string]interface{}) bool {
+		return status["isStopped"].(bool)
+	})
+	clock.Run(stopGracePeriod)
+	expectRemoved(t, removed, paid.id)
+}
+
+func waitForFrozen(t *testing.T, p *poolTestPeer, frozen bool) {
+	for i := 0; i < 100; i++ {
+		if p.isFrozen() == frozen {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Client frozen status is not %v", frozen)
+}
+
+func TestClientPoolPersistence(t *testing.T) {
+	var (
+		clock mclock.Simulated
+		db    = ethdb.NewMemDatabase()
+		peer  = newPoolTestPeer(0)
+		pool  = newClientPool(db, 10, 5, &clock, func(id enode.ID) {})
+	)
+	pool.setDefaultPrices(priceFactors{TimeFactor: 1, RequestFactor: 2})
+	pool.addBalance(peer.id, 1000000000)
+	pool.setCapacity(peer.id, 30)
+	if cap, ok := pool.connect(peer, "paid"); !ok || cap != 30 {
+		t.Fatalf("Paid client not accepted with the assigned capacity (cap %d, ok %v)", cap, ok)
+	}
+	pool.requestCost(peer.id, 1000)
+	clock.Run(time.Millisecond)
+	pool.stop()
+
+	pool = newClientPool(db, 10, 5, &clock, func(id enode.ID) {})
+	defer pool.stop()
+
+	if prices := pool.getPrices(); prices.TimeFactor != 1 || prices.RequestFactor != 2 {
+		t.Fatalf("Prices not restored: %+v", prices)
+	}
+	status := pool.clientStatus(peer.id)
+	if pos, want := status["pricing/balance"].(uint64), uint64(1000000000-2000-1000000); pos != want {
+		t.Fatalf("Balance not restored: got %d, want %d", pos, want)
+	}
+	if cap := status["pricing/capacity"].(uint64); cap != 30 {
+		t.Fatalf("Capacity not restored: got %d, want 30", cap)
+	}
+}
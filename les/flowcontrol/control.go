@@ -54,6 +54,36 @@ func (peer *ClientNode) Remove(cm *ClientManager) {
 	cm.removeNode(peer.cmNode)
 }
 
+// UpdateParams changes the flow control parameters assigned to the client. The
+// current buffer value is kept but capped at the new buffer limit.
+func (peer *ClientNode) UpdateParams(params ServerParams) {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBV(mclock.Now())
+	peer.params = &params
+	if peer.bufValue > params.BufLimit {
+		peer.bufValue = params.BufLimit
+	}
+}
+
+// Params returns the flow control parameters currently assigned to the client.
+func (peer *ClientNode) Params() ServerParams {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	return *peer.params
+}
+
+// BufferValue returns the current buffer value of the client.
+func (peer *ClientNode) BufferValue() uint64 {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBV(mclock.Now())
+	return peer.bufValue
+}
+
 func (peer *ClientNode) recalcBV(time mclock.AbsTime) {
 	dt := uint64(time - peer.lastTime)
 	if time < peer.lastTime {
@@ -110,6 +140,19 @@ func NewServerNode(params *ServerParams) *ServerNode {
 	}
 }
 
+// UpdateParams changes the flow control parameters announced by the server.
+// The estimated buffer value is capped at the new buffer limit.
+func (peer *ServerNode) UpdateParams(params ServerParams) {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBLE(mclock.Now())
+	peer.params = &params
+	if peer.bufEstimate > params.BufLimit {
+		peer.bufEstimate = params.BufLimit
+	}
+}
+
 func (peer *ServerNode) recalcBLE(time mclock.AbsTime) {
 	dt := uint64(time - peer.lastTime)
 	if time < peer.lastTime {
@@ -159,6 +202,19 @@ func (peer *ServerNode) QueueRequest(reqID, maxCost uint64) {
 	peer.pending[reqID] = peer.sumCost
 }
 
+// ResumeFreeze resets the estimated buffer value to the value reported by the
+// server when it resumes serving requests after a stop.
+func (peer *ServerNode) ResumeFreeze(bv uint64) {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	if bv > peer.params.BufLimit {
+		bv = peer.params.BufLimit
+	}
+	peer.bufEstimate = bv
+	peer.lastTime = mclock.Now()
+}
+
 // GotReply adjusts estimated buffer value according to the value included in
 // the latest request reply.
 func (peer *ServerNode) GotReply(reqID, bv uint64) {
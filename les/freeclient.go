@@ -110,8 +110,12 @@ func (f *freeClientPool) connect(address string, disconnectFn func()) bool {
 		recentUsage = int64(math.Exp(float64(e.logUsage-f.logOffset(now)) / fixedPointMultiplier))
 	}
 	e.linUsage = recentUsage - int64(now)
+	if f.connectedLimit <= 0 {
+		log.Debug("Client rejected", "address", address)
+		return false
+	}
 	// check whether (linUsage+connectedBias) is smaller than the highest entry in the connected pool
-	if f.connPool.Size() == f.connectedLimit {
+	if f.connPool.Size() >= f.connectedLimit {
 		i := f.connPool.PopItem().(*freeClientPoolEntry)
 		if e.linUsage+int64(connectedBias)-i.linUsage < 0 {
 			// kick it out and accept the new client
@@ -163,6 +167,28 @@ func (f *freeClientPool) disconnect(address string) {
 	log.Debug("Client disconnected", "address", address)
 }
 
+// setConnectedLimit changes the maximum number of simultaneously connected
+// clients. If the new limit is lower than the number of currently connected
+// clients then the ones with the highest recent usage are kicked out.
+func (f *freeClientPool) setConnectedLimit(limit int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.connectedLimit = limit
+	now := f.clock.Now()
+	for f.connPool.Size() > limit {
+		i := f.connPool.PopItem().(*freeClientPoolEntry)
+		f.calcLogUsage(i, now)
+		i.connected = false
+		f.disconnPool.Push(i, -i.logUsage)
+		log.Debug("Client kicked out", "address", i.address)
+		i.disconnectFn()
+	}
+}
+
 // logOffset calculates the time-dependent offset for the logarithmic
 // representation of recent usage
 func (f *freeClientPool) logOffset(now mclock.AbsTime) int64 {
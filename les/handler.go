@@ -18,6 +18,7 @@
 package les
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -40,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -100,10 +102,12 @@ type ProtocolManager struct {
 	odr         *LesOdr
 	server      *LesServer
 	serverPool  *serverPool
-	clientPool  *freeClientPool
+	clientPool  *clientPool
 	lesTopic    discv5.Topic
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
+	ulc         *ulc
+	oracle      *checkpointOracle
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
@@ -124,7 +128,7 @@ type ProtocolManager struct {
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
 // with the ethereum network.
-func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.IndexerConfig, lightSync bool, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, quitSync chan struct{}, wg *sync.WaitGroup) (*ProtocolManager, error) {
+func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.IndexerConfig, lightSync bool, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, ulc *ulc, oracle *checkpointOracle, quitSync chan struct{}, wg *sync.WaitGroup) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		lightSync:   lightSync,
@@ -138,6 +142,8 @@ func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.In
 		txpool:      txpool,
 		txrelay:     txrelay,
 		serverPool:  serverPool,
+		ulc:         ulc,
+		oracle:      oracle,
 		peers:       peers,
 		newPeerCh:   make(chan *peer),
 		quitSync:    quitSync,
@@ -174,7 +180,8 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	if pm.lightSync {
 		go pm.syncer()
 	} else {
-		pm.clientPool = newFreeClientPool(pm.chainDb, maxPeers, 10000, mclock.System{})
+		removePeer := func(id enode.ID) { pm.removePeer(peerIdToString(id)) }
+		pm.clientPool = newClientPool(pm.chainDb, pm.server.defParams.MinRecharge, maxPeers, mclock.System{}, removePeer)
 		go func() {
 			for range pm.newPeerCh {
 			}
@@ -234,7 +241,14 @@ func (pm *ProtocolManager) runPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWrit
 }
 
 func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	peer.isTrusted = pm.isULCEnabled() && pm.ulc.isTrusted(p.ID())
+	return peer
+}
+
+// isULCEnabled returns true if the client runs in ultra light client mode.
+func (pm *ProtocolManager) isULCEnabled() bool {
+	return pm.ulc != nil
 }
 
 // handle is the callback invoked to manage the life cycle of a les peer. When
@@ -261,16 +275,19 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		return err
 	}
 
+	var capacity uint64
 	if !pm.lightSync && !p.Peer.Info().Network.Trusted {
-		addr, ok := p.RemoteAddr().(*net.TCPAddr)
-		// test peer address is not a tcp address, don't use client pool if can not typecast
-		if ok {
-			id := addr.IP.String()
-			if !pm.clientPool.connect(id, func() { go pm.removePeer(p.id) }) {
-				return p2p.DiscTooManyPeers
-			}
-			defer pm.clientPool.disconnect(id)
+		// free clients are identified by IP address; test peer address is not a
+		// tcp address, use the peer id in that case
+		freeID := p.id
+		if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok {
+			freeID = addr.IP.String()
+		}
+		var ok bool
+		if capacity, ok = pm.clientPool.connect(p, freeID); !ok {
+			return p2p.DiscTooManyPeers
 		}
+		defer pm.clientPool.disconnect(p.ID())
 	}
 
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
@@ -287,6 +304,11 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		}
 		pm.removePeer(p.id)
 	}()
+	// Apply the capacity assigned by the client pool if it differs from the
+	// default one announced in the handshake
+	if capacity != 0 && capacity != pm.server.defParams.MinRecharge {
+		p.updateCapacity(capacity)
+	}
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
 	if pm.lightSync {
 		p.lock.Lock()
@@ -338,7 +360,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 	costs := p.fcCosts[msg.Code]
 	reject := func(reqCnt, maxCnt uint64) bool {
-		if p.fcClient == nil || reqCnt > maxCnt {
+		if p.fcClient == nil || reqCnt > maxCnt || p.isFrozen() {
 			return true
 		}
 		bufValue, _ := p.fcClient.AcceptRequest()
@@ -351,6 +373,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
+		if pm.clientPool != nil {
+			pm.clientPool.requestCost(p.ID(), cost)
+		}
 		return false
 	}
 
@@ -371,14 +396,22 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	// Block header query, collect the requested headers and reply
 	case AnnounceMsg:
 		p.Log().Trace("Received announce message")
-		if p.requestAnnounceType == announceTypeNone {
-			return errResp(ErrUnexpectedResponse, "")
-		}
-
 		var req announceData
 		if err := msg.Decode(&req); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
+		// the update list also carries the signature of signed announcements,
+		// flow control updates are only accepted from LES/3 servers
+		if len(req.Update) != 0 && p.version >= lpv3 {
+			p.updateFlowControl(req.Update.decode())
+		}
+		if req.Hash == (common.Hash{}) {
+			// capacity update without a block announcement
+			break
+		}
+		if p.requestAnnounceType == announceTypeNone {
+			return errResp(ErrUnexpectedResponse, "")
+		}
 
 		if p.requestAnnounceType == announceTypeSigned {
 			if err := req.checkSignature(p.ID()); err != nil {
@@ -393,6 +426,25 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			pm.fetcher.announce(p, &req)
 		}
 
+	case StopMsg:
+		if p.version < lpv3 || pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+		p.setFrozen(true)
+		p.Log().Debug("Service stopped by server")
+
+	case ResumeMsg:
+		if p.version < lpv3 || pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+		var bv uint64
+		if err := msg.Decode(&bv); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.ResumeFreeze(bv)
+		p.setFrozen(false)
+		p.Log().Debug("Service resumed by server")
+
 	case GetBlockHeadersMsg:
 		p.Log().Trace("Received block header request")
 		// Decode the complex header query
@@ -769,9 +821,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		// Gather state data until the fetch or network limits is reached
 		var (
-			lastBHash common.Hash
-			statedb   *state.StateDB
-			root      common.Hash
+			lastBHash  common.Hash
+			lastAccKey []byte
+			statedb    *state.StateDB
+			root       common.Hash
+			trie       state.Trie
 		)
 		reqCnt := len(req.Reqs)
 		if reject(uint64(reqCnt), MaxProofsFetch) {
@@ -783,7 +837,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, req := range req.Reqs {
 			// Look up the state belonging to the request
 			if statedb == nil || req.BHash != lastBHash {
-				statedb, root, lastBHash = nil, common.Hash{}, req.BHash
+				statedb, root, lastBHash, trie = nil, common.Hash{}, req.BHash, nil
 
 				if number := rawdb.ReadHeaderNumber(pm.chainDb, req.BHash); number != nil {
 					if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil {
@@ -795,16 +849,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if statedb == nil {
 				continue
 			}
-			// Pull the account or storage trie of the request
-			var trie state.Trie
-			if len(req.AccKey) > 0 {
-				account, err := pm.getAccount(statedb, root, common.BytesToHash(req.AccKey))
-				if err != nil {
-					continue
+			// Pull the account or storage trie of the request, reusing the one
+			// opened for the previous request if it was for the same account
+			if trie == nil || !bytes.Equal(req.AccKey, lastAccKey) {
+				trie, lastAccKey = nil, req.AccKey
+
+				if len(req.AccKey) > 0 {
+					account, err := pm.getAccount(statedb, root, common.BytesToHash(req.AccKey))
+					if err != nil {
+						continue
+					}
+					trie, _ = statedb.Database().OpenStorageTrie(common.BytesToHash(req.AccKey), account.Root)
+				} else {
+					trie, _ = statedb.Database().OpenTrie(root)
 				}
-				trie, _ = statedb.Database().OpenStorageTrie(common.BytesToHash(req.AccKey), account.Root)
-			} else {
-				trie, _ = statedb.Database().OpenTrie(root)
 			}
 			if trie == nil {
 				continue
@@ -62,6 +62,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ReceiptsRequest)(r)
 	case *light.TrieRequest:
 		return (*TrieRequest)(r)
+	case *light.TrieBatchRequest:
+		return (*TrieBatchRequest)(r)
 	case *light.CodeRequest:
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
@@ -166,7 +168,12 @@ func (r *ReceiptsRequest) Validate(db ethdb.Database, msg *Msg) error {
 	receipt := receipts[0]
 
 	// Retrieve our stored header and validate receipt content against it
-	header := rawdb.ReadHeader(db, r.Hash, r.Number)
+	var header *types.Header
+	if r.Untrusted {
+		header = r.Header
+	} else {
+		header = rawdb.ReadHeader(db, r.Hash, r.Number)
+	}
 	if header == nil {
 		return errHeaderUnavailable
 	}
@@ -193,7 +200,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -256,6 +263,87 @@ func (r *TrieRequest) Validate(db ethdb.Database, msg *Msg) error {
 	}
 }
 
+// ODR request type for batches of state/storage trie entries, see LesOdrRequest interface
+type TrieBatchRequest light.TrieBatchRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) GetCost(peer *peer) uint64 {
+	switch peer.version {
+	case lpv1:
+		return peer.GetRequestCost(GetProofsV1Msg, len(r.Entries))
+	case lpv2, lpv3:
+		return peer.GetRequestCost(GetProofsV2Msg, len(r.Entries))
+	default:
+		panic(nil)
+	}
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *TrieBatchRequest) CanSend(peer *peer) bool {
+	id := r.Entries[0].Id
+	return peer.HasBlock(id.BlockHash, id.BlockNumber, true)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting batch of trie proofs", "count", len(r.Entries))
+	reqs := make([]ProofReq, len(r.Entries))
+	for i, entry := range r.Entries {
+		reqs[i] = ProofReq{
+			BHash:  entry.Id.BlockHash,
+			AccKey: entry.Id.AccKey,
+			Key:    entry.Key,
+		}
+	}
+	return peer.RequestProofs(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *TrieBatchRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating batch of trie proofs", "count", len(r.Entries))
+
+	switch msg.MsgType {
+	case MsgProofsV1:
+		proofs := msg.Obj.([]light.NodeList)
+		if len(proofs) != len(r.Entries) {
+			return errInvalidEntryCount
+		}
+		nodeSet := light.NewNodeSet()
+		for i, entry := range r.Entries {
+			// Verify each proof separately and collect them if they check out
+			if _, _, err := trie.VerifyProof(entry.Id.Root, entry.Key, proofs[i].NodeSet()); err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+			proofs[i].Store(nodeSet)
+		}
+		r.Proof = nodeSet
+		return nil
+
+	case MsgProofsV2:
+		proofs := msg.Obj.(light.NodeList)
+		// Verify all the proofs against the shared node set and store if checks out
+		nodeSet := proofs.NodeSet()
+		reads := &readTraceDB{db: nodeSet}
+		for _, entry := range r.Entries {
+			if _, _, err := trie.VerifyProof(entry.Id.Root, entry.Key, reads); err != nil {
+				return fmt.Errorf("merkle proof verification failed: %v", err)
+			}
+		}
+		// check if all nodes have been read by VerifyProof
+		if len(reads.reads) != nodeSet.KeyCount() {
+			return errUselessNodes
+		}
+		r.Proof = nodeSet
+		return nil
+
+	default:
+		return errInvalidMessageType
+	}
+}
+
 type CodeReq struct {
 	BHash  common.Hash
 	AccKey []byte
@@ -353,7 +441,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -365,6 +453,11 @@ func (r *ChtRequest) CanSend(peer *peer) bool {
 	peer.lock.RLock()
 	defer peer.lock.RUnlock()
 
+	// Untrusted headers are requested from a specific peer only, which has to
+	// support returning headers without a valid proof
+	if r.Untrusted {
+		return peer.id == r.PeerId && peer.version >= lpv2 && peer.headInfo.Number >= r.BlockNum
+	}
 	return peer.headInfo.Number >= r.Config.ChtConfirms && r.ChtNum <= (peer.headInfo.Number-r.Config.ChtConfirms)/r.Config.ChtSize
 }
 
@@ -389,7 +482,7 @@ func (r *ChtRequest) Request(reqID uint64, peer *peer) error {
 		// convert HelperTrie request to old CHT request
 		reqsV1 = ChtReq{ChtNum: (req.TrieIdx + 1) * (r.Config.ChtSize / r.Config.PairChtSize), BlockNum: blockNum, FromLevel: req.FromLevel}
 		return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []ChtReq{reqsV1})
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []HelperTrieReq{req})
 	default:
 		panic(nil)
@@ -443,7 +536,14 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 		if err := rlp.DecodeBytes(headerEnc, header); err != nil {
 			return errHeaderUnavailable
 		}
-
+		if r.BlockNum != header.Number.Uint64() {
+			return errCHTNumberMismatch
+		}
+		// Untrusted requests carry no proof, the header is returned as is
+		if r.Untrusted {
+			r.Header = header
+			return nil
+		}
 		// Verify the CHT
 		var encNumber [8]byte
 		binary.BigEndian.PutUint64(encNumber[:], r.BlockNum)
@@ -464,9 +564,6 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 		if node.Hash != header.Hash() {
 			return errCHTHashMismatch
 		}
-		if r.BlockNum != header.Number.Uint64() {
-			return errCHTNumberMismatch
-		}
 		// Verifications passed, store and return
 		r.Header = header
 		r.Proof = nodeSet
@@ -41,6 +41,7 @@ type odrTestFn func(ctx context.Context, db ethdb.Database, config *params.Chain
 func TestOdrGetBlockLes1(t *testing.T) { testOdr(t, 1, 1, odrGetBlock) }
 
 func TestOdrGetBlockLes2(t *testing.T) { testOdr(t, 2, 1, odrGetBlock) }
+func TestOdrGetBlockLes3(t *testing.T) { testOdr(t, 3, 1, odrGetBlock) }
 
 func odrGetBlock(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
 	var block *types.Block
@@ -59,6 +60,7 @@ func odrGetBlock(ctx context.Context, db ethdb.Database, config *params.ChainCon
 func TestOdrGetReceiptsLes1(t *testing.T) { testOdr(t, 1, 1, odrGetReceipts) }
 
 func TestOdrGetReceiptsLes2(t *testing.T) { testOdr(t, 2, 1, odrGetReceipts) }
+func TestOdrGetReceiptsLes3(t *testing.T) { testOdr(t, 3, 1, odrGetReceipts) }
 
 func odrGetReceipts(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
 	var receipts types.Receipts
@@ -81,6 +83,7 @@ func odrGetReceipts(ctx context.Context, db ethdb.Database, config *params.Chain
 func TestOdrAccountsLes1(t *testing.T) { testOdr(t, 1, 1, odrAccounts) }
 
 func TestOdrAccountsLes2(t *testing.T) { testOdr(t, 2, 1, odrAccounts) }
+func TestOdrAccountsLes3(t *testing.T) { testOdr(t, 3, 1, odrAccounts) }
 
 func odrAccounts(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
 	dummyAddr := common.HexToAddress("1234567812345678123456781234567812345678")
@@ -111,6 +114,7 @@ func odrAccounts(ctx context.Context, db ethdb.Database, config *params.ChainCon
 func TestOdrContractCallLes1(t *testing.T) { testOdr(t, 1, 2, odrContractCall) }
 
 func TestOdrContractCallLes2(t *testing.T) { testOdr(t, 2, 2, odrContractCall) }
+func TestOdrContractCallLes3(t *testing.T) { testOdr(t, 3, 2, odrContractCall) }
 
 type callmsg struct {
 	types.Message
@@ -132,7 +136,7 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 				from := statedb.GetOrNewStateObject(testBankAddress)
 				from.SetBalance(math.MaxBig256)
 
-				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), new(big.Int), new(big.Int), data, nil, false)}
 
 				context := core.NewEVMContext(msg, header, bc, nil)
 				vmenv := vm.NewEVM(context, statedb, config, vm.Config{})
@@ -146,7 +150,7 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 			header := lc.GetHeaderByHash(bhash)
 			state := light.NewState(ctx, header, lc.Odr())
 			state.SetBalance(testBankAddress, math.MaxBig256)
-			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), new(big.Int), new(big.Int), data, nil, false)}
 			context := core.NewEVMContext(msg, header, lc, nil)
 			vmenv := vm.NewEVM(context, state, config, vm.Config{})
 			gp := new(core.GasPool).AddGas(math.MaxUint64)
@@ -202,3 +206,81 @@ func testOdr(t *testing.T, protocol int, expFail uint64, fn odrTestFn) {
 	time.Sleep(time.Millisecond * 10) // ensure that all peerSetNotify callbacks are executed
 	test(5)
 }
+
+func TestOdrPrefetchStateLes1(t *testing.T) { testOdr(t, 1, 1, odrPrefetchState) }
+func TestOdrPrefetchStateLes2(t *testing.T) { testOdr(t, 2, 1, odrPrefetchState) }
+func TestOdrPrefetchStateLes3(t *testing.T) { testOdr(t, 3, 1, odrPrefetchState) }
+
+func odrPrefetchState(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	read := func(statedb *state.StateDB) []byte {
+		var res []byte
+		for i := int64(0); i < 3; i++ {
+			res = append(res, statedb.GetState(testContractAddr, common.BigToHash(big.NewInt(i))).Bytes()...)
+		}
+		return res
+	}
+	if bc != nil {
+		header := bc.GetHeaderByHash(bhash)
+		statedb, err := state.New(header.Root, state.NewDatabase(db))
+		if err != nil {
+			return nil
+		}
+		return read(statedb)
+	}
+	header := lc.GetHeaderByHash(bhash)
+	if err := light.PrefetchState(ctx, header, lc.Odr(), func(statedb *state.StateDB) { read(statedb) }); err != nil {
+		return nil
+	}
+	// All the state should be local now, ensure no further retrievals are made
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	statedb := light.NewState(cancelled, header, lc.Odr())
+	res := read(statedb)
+	if statedb.Error() != nil {
+		return nil
+	}
+	return res
+}
+
+func TestOdrUntrustedLes2(t *testing.T) { testOdrUntrusted(t, 2) }
+func TestOdrUntrustedLes3(t *testing.T) { testOdrUntrusted(t, 3) }
+
+// testOdrUntrusted tests that headers and logs needed for checkpoint validation
+// can be retrieved from a specific peer and are not stored in the database.
+func testOdrUntrusted(t *testing.T, protocol int) {
+	server, client, tearDown := newClientServerEnv(t, 4, protocol, nil, true)
+	defer tearDown()
+
+	client.peers.lock.Lock()
+	client.rPeer.hasBlock = func(common.Hash, uint64, bool) bool { return true }
+	client.peers.lock.Unlock()
+
+	odr := client.pm.odr
+	for i := uint64(1); i <= server.pm.blockchain.CurrentHeader().Number.Uint64(); i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		if _, err := light.GetUntrustedHeaderByNumber(ctx, odr, i, "unknown"); err == nil {
+			t.Fatalf("block %d: untrusted header retrieved from unknown peer", i)
+		}
+		header, err := light.GetUntrustedHeaderByNumber(ctx, odr, i, client.rPeer.id)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve untrusted header: %v", i, err)
+		}
+		if want := rawdb.ReadCanonicalHash(server.db, i); header.Hash() != want {
+			t.Fatalf("block %d: header mismatch: have %x, want %x", i, header.Hash(), want)
+		}
+		logs, err := light.GetUntrustedBlockLogs(ctx, odr, header)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve untrusted logs: %v", i, err)
+		}
+		receipts := rawdb.ReadReceipts(server.db, header.Hash(), i)
+		if len(logs) != len(receipts) {
+			t.Fatalf("block %d: log batch count mismatch: have %d, want %d", i, len(logs), len(receipts))
+		}
+		if rawdb.ReadReceipts(client.db, header.Hash(), i) != nil {
+			t.Fatalf("block %d: untrusted receipts stored", i)
+		}
+	}
+}
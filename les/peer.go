@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -30,6 +31,8 @@ import (
 	"github.com/ethereum/go-ethereum/les/flowcontrol"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -40,7 +43,10 @@ var (
 	errInvalidHelpTrieReq = errors.New("invalid help trie request")
 )
 
-const maxResponseErrors = 50 // number of invalid responses tolerated (makes the protocol less brittle but still avoids spam)
+const (
+	maxResponseErrors = 50          // number of invalid responses tolerated (makes the protocol less brittle but still avoids spam)
+	frozenRetryWait   = time.Second // waiting time reported to the request distributor while a server has stopped serving us
+)
 
 const (
 	announceTypeNone = iota
@@ -58,7 +64,8 @@ type peer struct {
 
 	announceType, requestAnnounceType uint64
 
-	id string
+	id        string
+	isTrusted bool // trusted server of an ultra light client
 
 	headInfo *announceData
 	lock     sync.RWMutex
@@ -69,26 +76,33 @@ type peer struct {
 	poolEntry      *poolEntry
 	hasBlock       func(common.Hash, uint64, bool) bool
 	responseErrors int
+	frozen         uint32 // non-zero if request serving has been stopped (LES/3)
 
 	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
 	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
+
+	checkpoint       params.TrustedCheckpoint // Stable checkpoint announced by the server
+	checkpointNumber uint64                   // Block height at which the checkpoint was registered
 }
 
 func newPeer(version int, network uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	id := p.ID()
-
 	return &peer{
 		Peer:        p,
 		rw:          rw,
 		version:     version,
 		network:     network,
-		id:          fmt.Sprintf("%x", id[:8]),
+		id:          peerIdToString(p.ID()),
 		announceChn: make(chan announceData, 20),
 	}
 }
 
+// peerIdToString converts enode.ID to a string form used as the peer set key.
+func peerIdToString(id enode.ID) string {
+	return fmt.Sprintf("%x", id[:8])
+}
+
 func (p *peer) canQueue() bool {
 	return p.sendQueue.canQueue()
 }
@@ -140,9 +154,96 @@ func (p *peer) Td() *big.Int {
 
 // waitBefore implements distPeer interface
 func (p *peer) waitBefore(maxCost uint64) (time.Duration, float64) {
+	if p.isFrozen() {
+		return frozenRetryWait, 0
+	}
 	return p.fcServer.CanSend(maxCost)
 }
 
+// isFrozen returns true if request serving has been stopped between the peers.
+func (p *peer) isFrozen() bool {
+	return atomic.LoadUint32(&p.frozen) != 0
+}
+
+// setFrozen sets the frozen flag of the peer.
+func (p *peer) setFrozen(frozen bool) {
+	if frozen {
+		atomic.StoreUint32(&p.frozen, 1)
+	} else {
+		atomic.StoreUint32(&p.frozen, 0)
+	}
+}
+
+// canUpdateCapacity implements clientPeer. Capacity updates are announced to
+// LES/3 and later clients only.
+func (p *peer) canUpdateCapacity() bool {
+	return p.version >= lpv3
+}
+
+// updateCapacity implements clientPeer. It changes the flow control parameters
+// of the client and announces them if the capacity has changed.
+func (p *peer) updateCapacity(cap uint64) {
+	params := flowcontrol.ServerParams{MinRecharge: cap, BufLimit: cap * bufLimitRatio}
+	if p.fcClient.Params() == params {
+		return
+	}
+	p.fcClient.UpdateParams(params)
+	if !p.canUpdateCapacity() {
+		return
+	}
+	var kvList keyValueList
+	kvList = kvList.add("flowControl/MRR", cap)
+	kvList = kvList.add("flowControl/BL", cap*bufLimitRatio)
+	p.queueSend(func() { p.SendAnnounce(announceData{Update: kvList}) })
+}
+
+// freeze implements clientPeer. It stops serving requests from the client and
+// notifies LES/3 clients about it.
+func (p *peer) freeze() {
+	p.setFrozen(true)
+	if p.version >= lpv3 {
+		p2p.Send(p.rw, StopMsg, struct{}{})
+	}
+}
+
+// unfreeze implements clientPeer. It resumes serving requests from the client
+// and sends the current buffer value to LES/3 clients.
+func (p *peer) unfreeze() {
+	p.setFrozen(false)
+	if p.version >= lpv3 {
+		p2p.Send(p.rw, ResumeMsg, p.fcClient.BufferValue())
+	}
+}
+
+// updateFlowControl applies the flow control parameters announced by a server.
+func (p *peer) updateFlowControl(update keyValueMap) {
+	if p.fcServer == nil {
+		return
+	}
+	p.lock.Lock()
+	var (
+		params  = *p.fcServerParams
+		bl, mrr uint64
+	)
+	updated := false
+	if update.get("flowControl/BL", &bl) == nil {
+		params.BufLimit = bl
+		updated = true
+	}
+	if update.get("flowControl/MRR", &mrr) == nil {
+		params.MinRecharge = mrr
+		updated = true
+	}
+	if !updated {
+		p.lock.Unlock()
+		return
+	}
+	p.fcServerParams = &params
+	p.lock.Unlock()
+
+	p.fcServer.UpdateParams(params)
+}
+
 func sendRequest(w p2p.MsgWriter, msgcode, reqID, cost uint64, data interface{}) error {
 	type req struct {
 		ReqID uint64
@@ -272,7 +373,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -289,7 +390,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, data interface{}) err
 		}
 		p.Log().Debug("Fetching batch of header proofs", "count", len(reqs))
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		reqs, ok := data.([]HelperTrieReq)
 		if !ok {
 			return errInvalidHelpTrieReq
@@ -313,7 +414,7 @@ func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
@@ -410,8 +511,20 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
+
+		// Add the stable checkpoint registered in the oracle contract, if any
+		if server.oracle != nil && server.oracle.isRunning() {
+			if cp, height := server.oracle.stableCheckpoint(); cp != nil {
+				send = send.add("checkpoint/value", cp)
+				send = send.add("checkpoint/registerHeight", height)
+			}
+		}
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		// ultra light clients rely on the signed announcements of their trusted servers
+		p.requestAnnounceType = announceTypeSimple
+		if p.isTrusted {
+			p.requestAnnounceType = announceTypeSigned
+		}
 		send = send.add("announceType", p.requestAnnounceType)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
@@ -485,6 +598,11 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		p.fcServerParams = params
 		p.fcServer = flowcontrol.NewServerNode(params)
 		p.fcCosts = MRC.decode()
+
+		// The stable checkpoint is optional, older servers don't announce it
+		if recv.get("checkpoint/registerHeight", &p.checkpointNumber) == nil {
+			recv.get("checkpoint/value", &p.checkpoint)
+		}
 	}
 
 	p.headInfo = &announceData{Td: rTd, Hash: rHash, Number: rNum}
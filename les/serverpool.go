@@ -138,10 +138,13 @@ type serverPool struct {
 	knownSelect, newSelect     *weightedRandomSelect
 	knownSelected, newSelected int
 	fastDiscover               bool
+
+	trustedNodes map[enode.ID]*enode.Node // trusted servers of an ultra light client
 }
 
-// newServerPool creates a new serverPool instance
-func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup) *serverPool {
+// newServerPool creates a new serverPool instance. Trusted nodes are always kept
+// connected, independently of the selection mechanism of the pool.
+func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup, trustedNodes []*enode.Node) *serverPool {
 	pool := &serverPool{
 		db:           db,
 		quit:         quit,
@@ -156,6 +159,10 @@ func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup) *s
 		knownSelect:  newWeightedRandomSelect(),
 		newSelect:    newWeightedRandomSelect(),
 		fastDiscover: true,
+		trustedNodes: make(map[enode.ID]*enode.Node),
+	}
+	for _, node := range trustedNodes {
+		pool.trustedNodes[node.ID()] = node
 	}
 	pool.knownQueue = newPoolEntryQueue(maxKnownEntries, pool.removeEntry)
 	pool.newQueue = newPoolEntryQueue(maxNewEntries, pool.removeEntry)
@@ -168,6 +175,7 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	pool.dbKey = append([]byte("serverPool/"), []byte(topic)...)
 	pool.wg.Add(1)
 	pool.loadNodes()
+	pool.connectToTrustedNodes()
 
 	if pool.server.DiscV5 != nil {
 		pool.discSetPeriod = make(chan time.Duration, 1)
@@ -195,12 +203,27 @@ func (pool *serverPool) discoverNodes() {
 	}
 }
 
+// connectToTrustedNodes adds the trusted nodes as static and trusted peers of
+// the p2p server so they are always dialed and never dropped because of the
+// peer limit.
+func (pool *serverPool) connectToTrustedNodes() {
+	for _, node := range pool.trustedNodes {
+		pool.server.AddTrustedPeer(node)
+		pool.server.AddPeer(node)
+		log.Debug("Added trusted node", "id", node.ID())
+	}
+}
+
 // connect should be called upon any incoming connection. If the connection has been
 // dialed by the server pool recently, the appropriate pool entry is returned.
 // Otherwise, the connection should be rejected.
 // Note that whenever a connection has been accepted and a pool entry has been returned,
-// disconnect should also always be called.
+// disconnect should also always be called. Trusted nodes are not managed by the
+// pool, no entry is returned for them.
 func (pool *serverPool) connect(p *peer, node *enode.Node) *poolEntry {
+	if _, ok := pool.trustedNodes[node.ID()]; ok {
+		return nil
+	}
 	log.Debug("Connect new entry", "enode", p.id)
 	req := &connReq{p: p, node: node, result: make(chan *poolEntry, 1)}
 	select {
@@ -337,8 +360,10 @@ func (pool *serverPool) eventLoop() {
 			}
 
 		case node := <-pool.discNodes:
-			entry := pool.findOrNewNode(node)
-			pool.updateCheckDial(entry)
+			if _, ok := pool.trustedNodes[node.ID()]; !ok {
+				entry := pool.findOrNewNode(node)
+				pool.updateCheckDial(entry)
+			}
 
 		case conv := <-pool.discLookups:
 			if conv {
@@ -18,13 +18,17 @@ package les
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 )
 
+var errInvalidCheckpoint = errors.New("invalid advertised checkpoint")
+
 // syncer is responsible for periodically synchronising with the network, both
 // downloading hashes and blocks as well as handling the announcement handler.
 func (pm *ProtocolManager) syncer() {
@@ -74,6 +78,64 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
+
+	// If the peer announced a newer stable checkpoint than the local one, verify
+	// it against the votes of the checkpoint oracle and sync from there
+	if pm.needCheckpoint(peer) {
+		if err := pm.validateCheckpoint(ctx, peer); err != nil {
+			log.Debug("Failed to validate checkpoint", "peer", peer.id, "err", err)
+			pm.removePeer(peer.id)
+			return
+		}
+		pm.blockchain.(*light.LightChain).AddTrustedCheckpoint(&peer.checkpoint)
+	}
 	pm.blockchain.(*light.LightChain).SyncCht(ctx)
 	pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync)
 }
+
+// needCheckpoint reports whether the stable checkpoint announced by the peer
+// is usable and newer than both the local chain head and the local checkpoint.
+func (pm *ProtocolManager) needCheckpoint(peer *peer) bool {
+	if pm.oracle == nil || peer.checkpointNumber == 0 || peer.checkpoint.Empty() {
+		return false
+	}
+	head := pm.blockchain.CurrentHeader().Number.Uint64()
+	if head >= (peer.checkpoint.SectionIndex+1)*pm.iConfig.ChtSize-1 {
+		return false
+	}
+	sections, _, _ := pm.odr.ChtIndexer().Sections()
+	return peer.checkpoint.SectionIndex >= sections
+}
+
+// validateCheckpoint verifies the stable checkpoint announced by the peer. The
+// header of the block in which the checkpoint was registered is retrieved from
+// the announcing peer without any proof. The receipts of that block are checked
+// against the header, so the checkpoint is only accepted if the votes logged by
+// the oracle contract carry enough signatures of the trusted signers.
+func (pm *ProtocolManager) validateCheckpoint(ctx context.Context, peer *peer) error {
+	// Fetch the block header corresponding to the checkpoint registration.
+	cp := peer.checkpoint
+	header, err := light.GetUntrustedHeaderByNumber(ctx, pm.odr, peer.checkpointNumber, peer.id)
+	if err != nil {
+		return err
+	}
+	// Fetch block logs associated with the block header.
+	logs, err := light.GetUntrustedBlockLogs(ctx, pm.odr, header)
+	if err != nil {
+		return err
+	}
+	events := pm.oracle.contract.LookupCheckpointEvents(logs, cp.SectionIndex, cp.Hash())
+	if len(events) == 0 {
+		return errInvalidCheckpoint
+	}
+	var signatures [][]byte
+	for _, event := range events {
+		signatures = append(signatures, append(event.R[:], append(event.S[:], event.V)...))
+	}
+	valid, signers := pm.oracle.verifySigners(cp.SectionIndex, cp.Hash(), signatures)
+	if !valid {
+		return errInvalidCheckpoint
+	}
+	log.Info("Verified advertised checkpoint", "peer", peer.id, "index", cp.SectionIndex, "signers", len(signers))
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// ulc holds the state of the ultra light client mode. An ultra light client
+// does not verify the proof of work of the headers, it accepts a new head once
+// a sufficient fraction of its trusted servers have announced it with a valid
+// signature.
+type ulc struct {
+	trustedKeys        map[enode.ID]*enode.Node
+	minTrustedFraction int
+}
+
+// newULC creates and returns an ultra light client instance. Nil is returned if
+// the configuration does not contain any valid trusted server.
+func newULC(config *eth.ULCConfig) *ulc {
+	if config == nil {
+		return nil
+	}
+	trustedKeys := make(map[enode.ID]*enode.Node)
+	for _, url := range config.TrustedServers {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			log.Error("Failed to parse trusted server", "url", url, "err", err)
+			continue
+		}
+		trustedKeys[node.ID()] = node
+	}
+	if len(trustedKeys) == 0 {
+		return nil
+	}
+	fraction := config.MinTrustedFraction
+	if fraction <= 0 || fraction > 100 {
+		log.Warn("Invalid minimum trusted fraction, using default", "fraction", fraction, "default", eth.DefaultULCMinTrustedFraction)
+		fraction = eth.DefaultULCMinTrustedFraction
+	}
+	return &ulc{trustedKeys: trustedKeys, minTrustedFraction: fraction}
+}
+
+// isTrusted returns true if the given node is a trusted server.
+func (u *ulc) isTrusted(id enode.ID) bool {
+	if u == nil {
+		return false
+	}
+	_, ok := u.trustedKeys[id]
+	return ok
+}
+
+// trustedNodes returns the list of trusted servers.
+func (u *ulc) trustedNodes() []*enode.Node {
+	if u == nil {
+		return nil
+	}
+	nodes := make([]*enode.Node, 0, len(u.trustedKeys))
+	for _, node := range u.trustedKeys {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// quorumReached returns true if the given number of trusted servers announcing
+// a head is enough to accept it.
+func (u *ulc) quorumReached(agreed int) bool {
+	return agreed*100 >= u.minTrustedFraction*len(u.trustedKeys)
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func newTestServerKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key, enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), 30303, 30303).String()
+}
+
+func TestULCConfig(t *testing.T) {
+	_, url1 := newTestServerKey(t)
+	key2, url2 := newTestServerKey(t)
+
+	if newULC(nil) != nil {
+		t.Fatalf("ULC enabled without config")
+	}
+	if newULC(&eth.ULCConfig{TrustedServers: []string{"invalid"}}) != nil {
+		t.Fatalf("ULC enabled without valid trusted servers")
+	}
+	ulc := newULC(&eth.ULCConfig{TrustedServers: []string{url1, "invalid", url2}, MinTrustedFraction: 200})
+	if ulc == nil {
+		t.Fatalf("ULC not enabled")
+	}
+	if len(ulc.trustedKeys) != 2 {
+		t.Fatalf("Wrong number of trusted servers: got %d, want 2", len(ulc.trustedKeys))
+	}
+	if ulc.minTrustedFraction != eth.DefaultULCMinTrustedFraction {
+		t.Fatalf("Invalid fraction not replaced by default: %d", ulc.minTrustedFraction)
+	}
+	if !ulc.isTrusted(enode.PubkeyToIDV4(&key2.PublicKey)) {
+		t.Fatalf("Trusted server not recognized")
+	}
+	if ulc.isTrusted(enode.ID{}) {
+		t.Fatalf("Untrusted server recognized as trusted")
+	}
+	if ulc.quorumReached(1) || !ulc.quorumReached(2) {
+		t.Fatalf("Wrong quorum with fraction %d", ulc.minTrustedFraction)
+	}
+}
+
+func TestULCSyncWithOnePeerLes2(t *testing.T) { testULCSync(t, 2, 1) }
+func TestULCSyncWithOnePeerLes3(t *testing.T) { testULCSync(t, 3, 1) }
+
+func TestULCNoQuorumLes2(t *testing.T) { testULCSync(t, 2, 2) }
+func TestULCNoQuorumLes3(t *testing.T) { testULCSync(t, 3, 2) }
+
+// testULCSync connects an ultra light client requiring all of its trusted
+// servers to agree to one of them. The client should follow the head of that
+// server only if it is the only trusted server.
+func testULCSync(t *testing.T, protocol int, trusted int) {
+	key, url := newTestServerKey(t)
+	urls := []string{url}
+	for i := 1; i < trusted; i++ {
+		_, url := newTestServerKey(t)
+		urls = append(urls, url)
+	}
+	var (
+		db, ldb       = ethdb.NewMemDatabase(), ethdb.NewMemDatabase()
+		peers, lPeers = newPeerSet(), newPeerSet()
+	)
+	rm := newRetrieveManager(lPeers, newRequestDistributor(lPeers, make(chan struct{})), nil)
+	odr := NewLesOdr(ldb, light.TestClientIndexerConfig, rm)
+
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, peers, db, nil)
+	pm.server.privateKey = key
+	pm.blockLoop()
+	lpm := newTestProtocolManagerMust(t, true, 0, nil, odr, lPeers, ldb, &eth.ULCConfig{TrustedServers: urls, MinTrustedFraction: 100})
+
+	_, err1, lPeer, err2 := newTestPeerPairWithID("peer", enode.PubkeyToIDV4(&key.PublicKey), protocol, pm, lpm)
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case err := <-err1:
+		t.Fatalf("server handshake error: %v", err)
+	case err := <-err2:
+		t.Fatalf("client handshake error: %v", err)
+	}
+	if !lPeer.isTrusted || lPeer.requestAnnounceType != announceTypeSigned {
+		t.Fatalf("Trusted server does not send signed announcements")
+	}
+	// Extend the server chain so that the head is announced with a signature
+	server := pm.blockchain.(*core.BlockChain)
+	blocks, _ := core.GenerateChain(server.Config(), server.CurrentBlock(), ethash.NewFaker(), db, 2, nil)
+	if _, err := server.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to extend server chain: %v", err)
+	}
+	head := server.CurrentHeader()
+
+	// Without a quorum the client should stay at genesis, otherwise it should
+	// follow the server head
+	want := head.Hash()
+	if trusted > 1 {
+		want = lpm.blockchain.Genesis().Hash()
+		time.Sleep(500 * time.Millisecond)
+	}
+	client := lpm.blockchain.(*light.LightChain)
+	for i := 0; i < 100 && client.CurrentHeader().Hash() != want; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if have := client.CurrentHeader(); have.Hash() != want {
+		t.Fatalf("Wrong client head: have #%d [%x…], want [%x…]", have.Number, have.Hash().Bytes()[:4], want.Bytes()[:4])
+	}
+}
@@ -95,6 +95,30 @@ func (req *TrieRequest) StoreResult(db ethdb.Database) {
 	req.Proof.Store(db)
 }
 
+// TrieEntry identifies a single entry of a state or storage trie.
+type TrieEntry struct {
+	Id  *TrieID
+	Key []byte
+}
+
+// MaxTrieBatchSize is the maximum number of trie entries retrieved in a single
+// batched request, matching the proof limit of the LES servers.
+const MaxTrieBatchSize = 64
+
+// TrieBatchRequest is the ODR request type for retrieving the Merkle proofs of
+// many state and storage trie entries in a single round trip. All entries have
+// to belong to the state of the same block.
+type TrieBatchRequest struct {
+	OdrRequest
+	Entries []TrieEntry
+	Proof   *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *TrieBatchRequest) StoreResult(db ethdb.Database) {
+	req.Proof.Store(db)
+}
+
 // CodeRequest is the ODR request type for retrieving contract code
 type CodeRequest struct {
 	OdrRequest
@@ -124,19 +148,25 @@ func (req *BlockRequest) StoreResult(db ethdb.Database) {
 // ReceiptsRequest is the ODR request type for retrieving block bodies
 type ReceiptsRequest struct {
 	OdrRequest
-	Hash     common.Hash
-	Number   uint64
-	Receipts types.Receipts
+	Untrusted bool // Indicator whether the result retrieved is trusted or not
+	Hash      common.Hash
+	Number    uint64
+	Header    *types.Header // Header to validate untrusted receipts against
+	Receipts  types.Receipts
 }
 
 // StoreResult stores the retrieved data in local database
 func (req *ReceiptsRequest) StoreResult(db ethdb.Database) {
-	rawdb.WriteReceipts(db, req.Hash, req.Number, req.Receipts)
+	if !req.Untrusted {
+		rawdb.WriteReceipts(db, req.Hash, req.Number, req.Receipts)
+	}
 }
 
 // ChtRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
+	Untrusted        bool   // Indicator whether the result retrieved is trusted or not
+	PeerId           string // The specified peer id from which to retrieve data.
 	Config           *IndexerConfig
 	ChtNum, BlockNum uint64
 	ChtRoot          common.Hash
@@ -147,6 +177,9 @@ type ChtRequest struct {
 
 // StoreResult stores the retrieved data in local database
 func (req *ChtRequest) StoreResult(db ethdb.Database) {
+	if req.Untrusted {
+		return
+	}
 	hash, num := req.Header.Hash(), req.Header.Number.Uint64()
 
 	rawdb.WriteHeader(db, req.Header)
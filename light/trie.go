@@ -17,9 +17,11 @@
 package light
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -35,13 +37,32 @@ func NewState(ctx context.Context, head *types.Header, odr OdrBackend) *state.St
 }
 
 func NewStateDatabase(ctx context.Context, head *types.Header, odr OdrBackend) state.Database {
-	return &odrDatabase{ctx, StateTrieID(head), odr}
+	return &odrDatabase{ctx: ctx, id: StateTrieID(head), backend: odr}
 }
 
 type odrDatabase struct {
 	ctx     context.Context
 	id      *TrieID
 	backend OdrBackend
+
+	prefetch *trieRecorder // Recorder of missing trie entries in speculative mode, nil otherwise
+}
+
+// trieRecorder collects the trie entries a speculative execution pass failed to
+// access because of missing nodes.
+type trieRecorder struct {
+	seen    map[string]struct{}
+	entries []TrieEntry
+}
+
+// add records a missing trie entry unless it was already recorded before.
+func (r *trieRecorder) add(id *TrieID, key []byte) {
+	ref := string(id.AccKey) + string(key)
+	if _, ok := r.seen[ref]; ok {
+		return
+	}
+	r.seen[ref] = struct{}{}
+	r.entries = append(r.entries, TrieEntry{Id: id, Key: common.CopyBytes(key)})
 }
 
 func (db *odrDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
@@ -89,6 +110,64 @@ func (db *odrDatabase) TrieDB() *trie.Database {
 	return nil
 }
 
+// maxPrefetchRounds is the maximum number of speculative execution passes done
+// to discover the state accessed by an execution.
+const maxPrefetchRounds = 4
+
+// PrefetchState speculatively runs fn against the state of the given header and
+// retrieves all the trie entries it accessed but were missing locally in a
+// single batched request instead of one round trip per entry. Since the data
+// retrieved may lead the execution down different paths, this is repeated until
+// nothing is missing anymore or maxPrefetchRounds is reached. The state passed
+// to fn is throwaway, any modifications done to it are discarded.
+func PrefetchState(ctx context.Context, head *types.Header, odr OdrBackend, fn func(*state.StateDB)) error {
+	for round := 0; round < maxPrefetchRounds; round++ {
+		rec := &trieRecorder{seen: make(map[string]struct{})}
+		db := &odrDatabase{ctx: ctx, id: StateTrieID(head), backend: odr, prefetch: rec}
+
+		statedb, _ := state.New(head.Root, db)
+		fn(statedb)
+
+		if len(rec.entries) == 0 {
+			return nil
+		}
+		if err := retrieveTrieEntries(ctx, odr, rec.entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrieveTrieEntries retrieves the proofs of the given trie entries, split into
+// as few requests of at most MaxTrieBatchSize entries as possible, sent
+// concurrently.
+func retrieveTrieEntries(ctx context.Context, odr OdrBackend, entries []TrieEntry) error {
+	// Group the entries by account so servers can reuse the opened storage tries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Id.AccKey, entries[j].Id.AccKey) < 0
+	})
+	var batches [][]TrieEntry
+	for len(entries) > MaxTrieBatchSize {
+		batches = append(batches, entries[:MaxTrieBatchSize])
+		entries = entries[MaxTrieBatchSize:]
+	}
+	batches = append(batches, entries)
+
+	errc := make(chan error, len(batches))
+	for _, batch := range batches {
+		go func(batch []TrieEntry) {
+			errc <- odr.Retrieve(ctx, &TrieBatchRequest{Entries: batch})
+		}(batch)
+	}
+	var err error
+	for range batches {
+		if e := <-errc; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 type odrTrie struct {
 	db   *odrDatabase
 	id   *TrieID
@@ -159,6 +238,12 @@ func (t *odrTrie) do(key []byte, fn func() error) error {
 		if _, ok := err.(*trie.MissingNodeError); !ok {
 			return err
 		}
+		// Speculative tries only record the missing entry, it is retrieved
+		// together with all the others after the execution pass
+		if t.db.prefetch != nil {
+			t.db.prefetch.add(t.id, key)
+			return err
+		}
 		r := &TrieRequest{Id: t.id, Key: key}
 		if err := t.db.backend.Retrieve(t.db.ctx, r); err != nil {
 			return err
@@ -20,9 +20,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -80,3 +83,44 @@ func diffTries(t1, t2 state.Trie) error {
 	}
 	return nil
 }
+
+func TestPrefetchState(t *testing.T) {
+	var (
+		fulldb  = ethdb.NewMemDatabase()
+		lightdb = ethdb.NewMemDatabase()
+		gspec   = core.Genesis{Alloc: core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}}}
+		genesis = gspec.MustCommit(fulldb)
+	)
+	gspec.MustCommit(lightdb)
+	blockchain, _ := core.NewBlockChain(fulldb, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{}, nil)
+	gchain, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), fulldb, 4, testChainGen)
+	if _, err := blockchain.InsertChain(gchain); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	odr := &testOdr{sdb: fulldb, ldb: lightdb, indexerConfig: TestClientIndexerConfig}
+	head := blockchain.CurrentHeader()
+
+	// Read a few storage slots of the test contract
+	read := func(st *state.StateDB) []common.Hash {
+		var res []common.Hash
+		for i := int64(0); i < 3; i++ {
+			res = append(res, st.GetState(testContractAddr, common.BigToHash(big.NewInt(i))))
+		}
+		return res
+	}
+	if err := PrefetchState(ctx, head, odr, func(st *state.StateDB) { read(st) }); err != nil {
+		t.Fatalf("Failed to prefetch state: %v", err)
+	}
+	// Everything accessed should be available locally now
+	odr.disable = true
+
+	full, _ := state.New(head.Root, state.NewDatabase(fulldb))
+	light := NewState(ctx, head, odr)
+	if have, want := read(light), read(full); !reflect.DeepEqual(have, want) {
+		t.Fatalf("Storage mismatch: have %x, want %x", have, want)
+	}
+	if err := light.Error(); err != nil {
+		t.Fatalf("Prefetched state incomplete: %v", err)
+	}
+}
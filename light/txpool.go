@@ -68,6 +68,9 @@ type TxPool struct {
 	clearIdx     uint64                               // earliest block nr that can contain mined tx info
 
 	homestead bool
+	istanbul  bool
+	eip2718   bool
+	eip1559   bool
 }
 
 // TxRelayBackend provides an interface to the mechanism that forwards transacions
@@ -310,6 +313,9 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	m, r := txc.getLists()
 	pool.relay.NewHead(pool.head, m, r)
 	pool.homestead = pool.config.IsHomestead(head.Number)
+	pool.istanbul = pool.config.IsIstanbul(head.Number)
+	pool.eip2718 = pool.config.IsBerlin(head.Number)
+	pool.eip1559 = pool.config.IsLondon(head.Number)
 	pool.signer = types.MakeSigner(pool.config, head.Number)
 }
 
@@ -340,6 +346,18 @@ func (pool *TxPool) Stats() (pending int) {
 
 // validateTx checks whether a transaction is valid according to the consensus rules.
 func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error {
+	// Accept only legacy transactions until EIP-2718/2930 activates
+	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
+		return core.ErrTxTypeNotSupported
+	}
+	// Reject dynamic fee transactions until EIP-1559 activates
+	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
+		return core.ErrTxTypeNotSupported
+	}
+	// Ensure the tip doesn't exceed the fee cap
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		return core.ErrTipAboveFeeCap
+	}
 	// Validate sender
 	var (
 		from common.Address
@@ -378,7 +396,7 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	}
 
 	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+	gas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead, pool.istanbul)
 	if err != nil {
 		return err
 	}
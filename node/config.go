@@ -27,7 +27,10 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/pkcs11"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -87,6 +90,22 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// PKCS11Module is the path of the PKCS#11 library of a hardware security
+	// module holding secp256k1 signing keys. If empty, no HSM is used.
+	PKCS11Module string `toml:",omitempty"`
+
+	// PKCS11Slot is the slot of the PKCS#11 token holding the signing keys.
+	PKCS11Slot uint `toml:",omitempty"`
+
+	// PKCS11Labels are the labels of the PKCS#11 signing keys. If empty, all the
+	// secp256k1 keys of the token are used.
+	PKCS11Labels []string `toml:",omitempty"`
+
+	// ExternalSigner specifies an external URI for a clef-type signer. If set,
+	// all signing is delegated to it and the local keystore and hardware wallets
+	// are not used.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -429,9 +448,20 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	if err := os.MkdirAll(keydir, 0700); err != nil {
 		return nil, "", err
 	}
-	// Assemble the account manager and supported backends
+	// Assemble the account manager and supported backends. Local and external
+	// signers are exclusive, having the same accounts in both would be confusing
+	// and racy.
+	if len(conf.ExternalSigner) > 0 {
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		return accounts.NewManager(extapi), ephemeral, nil
+	}
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		hdwallet.NewKeyStore(keydir, scryptN, scryptP),
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
@@ -447,6 +477,14 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 			backends = append(backends, trezorhub)
 		}
 	}
+	if conf.PKCS11Module != "" {
+		// Start a hub for the keys of the configured HSM token
+		hsmhub, err := pkcs11.NewHub(conf.PKCS11Module, conf.PKCS11Slot, conf.PKCS11Labels)
+		if err != nil {
+			return nil, "", fmt.Errorf("error opening PKCS#11 token: %v", err)
+		}
+		backends = append(backends, hsmhub)
+	}
 	return accounts.NewManager(backends...), ephemeral, nil
 }
 
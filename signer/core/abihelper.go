@@ -89,7 +89,7 @@ func parseCallData(calldata []byte, abidata string) (*decodedCallData, error) {
 		return nil, err
 	}
 
-	decoded := decodedCallData{signature: method.Sig(), name: method.Name}
+	decoded := decodedCallData{signature: method.Sig(), name: method.RawName}
 
 	for n, argument := range method.Inputs {
 		if err != nil {
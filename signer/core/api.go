@@ -26,14 +26,15 @@ import (
 	"reflect"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/hdwallet"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
@@ -49,6 +50,8 @@ type ExternalAPI interface {
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
 	Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignTypedData - request to sign the given EIP-712 typed structured data
+	SignTypedData(ctx context.Context, addr common.MixedcaseAddress, typedData TypedData) (hexutil.Bytes, error)
 	// Export - request to export an account
 	Export(ctx context.Context, addr common.Address) (json.RawMessage, error)
 	// Import - request to import an account
@@ -94,6 +97,8 @@ type SignerAPI struct {
 	UI         SignerUI
 	validator  *Validator
 	rejectMode bool
+	basePaths  []accounts.DerivationPath
+	ledgerLive bool
 }
 
 // Metadata about a request
@@ -103,11 +108,13 @@ type Metadata struct {
 	Scheme    string `json:"scheme"`
 	UserAgent string `json:"User-Agent"`
 	Origin    string `json:"Origin"`
+
+	audit *AuditEntry // Audit log entry of the request, if audited
 }
 
 // MetadataFromContext extracts Metadata from a given context.Context
 func MetadataFromContext(ctx context.Context) Metadata {
-	m := Metadata{"NA", "NA", "NA", "", ""} // batman
+	m := Metadata{Remote: "NA", Local: "NA", Scheme: "NA", audit: auditEntryFromContext(ctx)} // batman
 
 	if v := ctx.Value("remote"); v != nil {
 		m.Remote = v.(string)
@@ -170,11 +177,13 @@ type (
 		NewPassword string `json:"new_password"`
 	}
 	SignDataRequest struct {
-		Address common.MixedcaseAddress `json:"address"`
-		Rawdata hexutil.Bytes           `json:"raw_data"`
-		Message string                  `json:"message"`
-		Hash    hexutil.Bytes           `json:"hash"`
-		Meta    Metadata                `json:"meta"`
+		ContentType string                  `json:"content_type"`
+		Address     common.MixedcaseAddress `json:"address"`
+		Rawdata     hexutil.Bytes           `json:"raw_data"`
+		Message     string                  `json:"message"`
+		Messages    []*NameValueType        `json:"messages,omitempty"`
+		Hash        hexutil.Bytes           `json:"hash"`
+		Meta        Metadata                `json:"meta"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -222,8 +231,11 @@ var ErrRequestDenied = errors.New("Request denied")
 // ksLocation specifies the directory where to store the password protected private
 // key that is generated when a new Account is created.
 // noUSB disables USB support that is required to support hardware devices such as
-// ledger and trezor.
-func NewSignerAPI(chainID int64, ksLocation string, noUSB bool, ui SignerUI, abidb *AbiDb, lightKDF bool, advancedMode bool) *SignerAPI {
+// ledger and trezor. basePaths overrides the derivation paths along which accounts
+// are derived on hardware devices, progressing along the Ledger Live layout if
+// ledgerLive is set. Any extra backends, such as PKCS#11 tokens, are
+// used next to the local keystore and USB wallets.
+func NewSignerAPI(chainID int64, ksLocation string, noUSB bool, basePaths []accounts.DerivationPath, ledgerLive bool, ui SignerUI, abidb *AbiDb, lightKDF bool, advancedMode bool, extra ...accounts.Backend) *SignerAPI {
 	var (
 		backends []accounts.Backend
 		n, p     = keystore.StandardScryptN, keystore.StandardScryptP
@@ -231,9 +243,10 @@ func NewSignerAPI(chainID int64, ksLocation string, noUSB bool, ui SignerUI, abi
 	if lightKDF {
 		n, p = keystore.LightScryptN, keystore.LightScryptP
 	}
-	// support password based accounts
+	// support password based accounts and HD wallets
 	if len(ksLocation) > 0 {
 		backends = append(backends, keystore.NewKeyStore(ksLocation, n, p))
+		backends = append(backends, hdwallet.NewKeyStore(ksLocation, n, p))
 	}
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
@@ -254,7 +267,9 @@ func NewSignerAPI(chainID int64, ksLocation string, noUSB bool, ui SignerUI, abi
 			log.Debug("Trezor support enabled")
 		}
 	}
-	signer := &SignerAPI{big.NewInt(chainID), accounts.NewManager(backends...), ui, NewValidator(abidb), !advancedMode}
+	backends = append(backends, extra...)
+
+	signer := &SignerAPI{big.NewInt(chainID), accounts.NewManager(backends...), ui, NewValidator(abidb), !advancedMode, basePaths, ledgerLive}
 	if !noUSB {
 		signer.startUSBListener()
 	}
@@ -321,20 +336,17 @@ func (api *SignerAPI) startUSBListener() {
 				status, _ := event.Wallet.Status()
 				log.Info("New wallet appeared", "url", event.Wallet.URL(), "status", status)
 
-				derivationPath := accounts.DefaultBaseDerivationPath
-				if event.Wallet.URL().Scheme == "ledger" {
-					derivationPath = accounts.DefaultLedgerBaseDerivationPath
-				}
-				var nextPath = derivationPath
-				// Derive first N accounts, hardcoded for now
-				for i := 0; i < numberOfAccountsToDerive; i++ {
-					acc, err := event.Wallet.Derive(nextPath, true)
-					if err != nil {
-						log.Warn("account derivation failed", "error", err)
-					} else {
-						log.Info("derived account", "address", acc.Address)
+				// Derive first N accounts along each path layout, hardcoded for now
+				for _, next := range api.derivationIterators(event.Wallet) {
+					for i := 0; i < numberOfAccountsToDerive; i++ {
+						path := next()
+						acc, err := event.Wallet.Derive(path, true)
+						if err != nil {
+							log.Warn("account derivation failed", "path", path, "error", err)
+						} else {
+							log.Info("derived account", "address", acc.Address, "path", path)
+						}
 					}
-					nextPath[len(nextPath)-1]++
 				}
 			case accounts.WalletDropped:
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
@@ -344,6 +356,12 @@ func (api *SignerAPI) startUSBListener() {
 	}()
 }
 
+// derivationIterators returns the path iterators along which accounts are derived
+// on the given hardware wallet.
+func (api *SignerAPI) derivationIterators(wallet accounts.Wallet) []func() accounts.DerivationPath {
+	return accounts.DiscoveryIterators(wallet.URL().Scheme, api.basePaths, api.ledgerLive)
+}
+
 // List returns the set of wallet this signer manages. Each wallet can contain
 // multiple accounts.
 func (api *SignerAPI) List(ctx context.Context) ([]common.Address, error) {
@@ -424,6 +442,14 @@ func logDiff(original *SignTxRequest, new *SignTxResponse) bool {
 		modified = true
 		log.Info("GasPrice changed by UI", "was", g0, "is", g1)
 	}
+	if f0, f1 := original.Transaction.MaxFeePerGas, new.Transaction.MaxFeePerGas; !reflect.DeepEqual(f0, f1) {
+		modified = true
+		log.Info("MaxFeePerGas changed by UI", "was", f0, "is", f1)
+	}
+	if f0, f1 := original.Transaction.MaxPriorityFeePerGas, new.Transaction.MaxPriorityFeePerGas; !reflect.DeepEqual(f0, f1) {
+		modified = true
+		log.Info("MaxPriorityFeePerGas changed by UI", "was", f0, "is", f1)
+	}
 	if v0, v1 := big.Int(original.Transaction.Value), big.Int(new.Transaction.Value); v0.Cmp(&v1) != 0 {
 		modified = true
 		log.Info("Value changed by UI", "was", v0, "is", v1)
@@ -455,6 +481,14 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		err    error
 		result SignTxResponse
 	)
+	// Typed transactions commit to a chain id, which must be the one we sign for
+	if args.ChainID != nil {
+		if requested := args.ChainID.ToInt(); requested.Cmp(api.chainID) != 0 {
+			return nil, fmt.Errorf("requested chain id %d does not match the configuration of the signer (%d)", requested, api.chainID)
+		}
+	} else if args.TxType() != types.LegacyTxType {
+		args.ChainID = (*hexutil.Big)(api.chainID)
+	}
 	msgs, err := api.validator.ValidateTransaction(&args, methodSelector)
 	if err != nil {
 		return nil, err
@@ -491,7 +525,7 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		return nil, err
 	}
 	// Convert fields into a real transaction
-	var unsignedTx = result.Transaction.toTransaction()
+	var unsignedTx = result.Transaction.ToTransaction()
 
 	// The one to sign is the one that was returned from the UI
 	signedTx, err := wallet.SignTxWithPassphrase(acc, result.Password, unsignedTx, api.chainID)
@@ -500,8 +534,11 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		return nil, err
 	}
 
-	rlpdata, err := rlp.EncodeToBytes(signedTx)
-	response := ethapi.SignTransactionResult{Raw: rlpdata, Tx: signedTx}
+	data, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	response := ethapi.SignTransactionResult{Raw: data, Tx: signedTx}
 
 	// Finally, send the signed tx to the UI
 	api.UI.OnApprovedTx(response)
@@ -523,7 +560,7 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	sighash, msg := SignHash(data)
 	// We make the request prior to looking up if we actually have the account, to prevent
 	// account-enumeration via the API
-	req := &SignDataRequest{Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
+	req := &SignDataRequest{ContentType: MimetypeTextPlain, Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
 	res, err := api.UI.ApproveSignData(req)
 
 	if err != nil {
@@ -538,8 +575,13 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	if err != nil {
 		return nil, err
 	}
-	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	// Hardware wallets can't sign arbitrary hashes, hand them the message itself
+	var signature []byte
+	if signer, ok := wallet.(accounts.TextSigner); ok {
+		signature, err = signer.SignText(account, data)
+	} else {
+		signature, err = wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	}
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
@@ -29,7 +29,6 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
-	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -135,7 +134,7 @@ func setup(t *testing.T) (*SignerAPI, chan string) {
 
 	db, err := NewAbiDBFromFile("../../cmd/clef/4byte.json")
 	if err != nil {
-		utils.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	var (
 		ui  = &HeadlessUI{controller}
@@ -143,6 +142,8 @@ func setup(t *testing.T) (*SignerAPI, chan string) {
 			1,
 			tmpDirName(t),
 			true,
+			nil,
+			false,
 			ui,
 			db,
 			true, true)
@@ -384,6 +385,45 @@ func TestSignTx(t *testing.T) {
 
 }
 
+func TestSignDynamicFeeTx(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := mkTestTx(common.NewMixedcaseAddress(list[0]))
+	tx.GasPrice = hexutil.Big{}
+	tx.MaxFeePerGas = (*hexutil.Big)(big.NewInt(3000000000))
+	tx.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(1000000000))
+
+	control <- "Y"
+	control <- "a_long_password"
+	res, err := api.SignTransaction(context.Background(), tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedTx := new(types.Transaction)
+	if err := parsedTx.UnmarshalBinary(res.Raw); err != nil {
+		t.Fatalf("Failed to decode signed tx: %v", err)
+	}
+	if parsedTx.Type() != types.DynamicFeeTxType {
+		t.Errorf("Expected tx type %d, got %d", types.DynamicFeeTxType, parsedTx.Type())
+	}
+	if parsedTx.ChainId().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected chain id 1, got %v", parsedTx.ChainId())
+	}
+	if parsedTx.GasFeeCap().Cmp(tx.MaxFeePerGas.ToInt()) != 0 || parsedTx.GasTipCap().Cmp(tx.MaxPriorityFeePerGas.ToInt()) != 0 {
+		t.Errorf("Expected fee caps to be unchanged, got %v/%v", parsedTx.GasFeeCap(), parsedTx.GasTipCap())
+	}
+	// Requests for a different chain should be rejected before reaching the UI
+	tx.ChainID = (*hexutil.Big)(big.NewInt(4))
+	if _, err := api.SignTransaction(context.Background(), tx, nil); err == nil {
+		t.Errorf("Expected error for mismatching chain id")
+	}
+}
+
 /*
 func TestAsyncronousResponses(t *testing.T){
 
@@ -17,66 +17,247 @@
 package core
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// Approval layers which can be audited by wrapping them with NewAuditUI.
+const (
+	AuditStageRules = "rules" // The rule engine, which may defer to the UI
+	AuditStageUI    = "ui"    // The (human) user interface
+)
+
+// Verdicts recorded for the approval layers in an audit entry.
+const (
+	auditApproved = "approved"
+	auditRejected = "rejected"
+	auditDeferred = "deferred"
+	auditFailed   = "error"
+)
+
+// AuditEntry is a single record of the audit log, describing one call made on
+// the external API. Entries are stored as JSON lines, each one committing to
+// its predecessor through a hash chain, which makes edits and deletions of
+// entries detectable.
+type AuditEntry struct {
+	Seq           uint64           `json:"seq"`                     // Position of the entry in the log
+	Time          time.Time        `json:"time"`                    // Time the request was received
+	Method        string           `json:"method"`                  // External API method called
+	Metadata      Metadata         `json:"metadata"`                // Information about the caller
+	Account       *common.Address  `json:"account,omitempty"`       // Account the request concerns
+	Request       json.RawMessage  `json:"request,omitempty"`       // Parameters of the request
+	Callinfo      []ValidationInfo `json:"callinfo,omitempty"`      // Decoded calldata and warnings
+	Rules         string           `json:"rules,omitempty"`         // Verdict of the rule engine
+	UI            string           `json:"ui,omitempty"`            // Decision made in the UI
+	Response      json.RawMessage  `json:"response,omitempty"`      // Result of the request, if not sensitive
+	Transaction   json.RawMessage  `json:"transaction,omitempty"`   // Signed transaction, decoded
+	SignatureHash *common.Hash     `json:"signatureHash,omitempty"` // Hash of the produced signature
+	Error         string           `json:"error,omitempty"`         // Error returned to the caller
+	Prev          common.Hash      `json:"prev"`                    // Hash of the previous entry
+	Hash          *common.Hash     `json:"hash,omitempty"`          // Hash of this entry
+}
+
+// computeHash calculates the hash of the entry, which is the sha256 of its JSON
+// encoding without the hash field.
+func (e *AuditEntry) computeHash() (common.Hash, error) {
+	cpy := *e
+	cpy.Hash = nil
+	blob, err := json.Marshal(&cpy)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sha256.Sum256(blob), nil
+}
+
+// decide records the verdict of an approval layer.
+func (e *AuditEntry) decide(stage string, approved bool, err error) {
+	verdict := auditRejected
+	if err != nil {
+		verdict = auditFailed
+	} else if approved {
+		verdict = auditApproved
+	}
+	switch stage {
+	case AuditStageUI:
+		e.UI = verdict
+	case AuditStageRules:
+		// The rule engine wraps the UI, so if the UI has decided, the rules deferred
+		if e.UI != "" {
+			verdict = auditDeferred
+		}
+		e.Rules = verdict
+	}
+}
+
+// setRequest stores the JSON encoding of the request parameters.
+func (e *AuditEntry) setRequest(v interface{}) {
+	if blob, err := json.Marshal(v); err == nil {
+		e.Request = blob
+	}
+}
+
+// setResponse stores the JSON encoding of the result of the request.
+func (e *AuditEntry) setResponse(v interface{}) {
+	if blob, err := json.Marshal(v); err == nil {
+		e.Response = blob
+	}
+}
+
+// auditContextKey is the context key under which the entry of a request in
+// flight is stored, so that the approval layers can add their verdicts.
+type auditContextKey struct{}
+
+// auditEntryFromContext returns the audit entry of a request, if it is audited.
+func auditEntryFromContext(ctx context.Context) *AuditEntry {
+	entry, _ := ctx.Value(auditContextKey{}).(*AuditEntry)
+	return entry
+}
+
+// AuditLogger is an ExternalAPI which records every call made on the wrapped
+// API in a hash-chained audit log.
 type AuditLogger struct {
-	log log.Logger
 	api ExternalAPI
+
+	lock sync.Mutex
+	out  io.Writer
+	seq  uint64      // Sequence number of the next entry
+	prev common.Hash // Hash of the last entry written
+}
+
+// begin creates the audit entry of a new request, and tags the context with it.
+func (l *AuditLogger) begin(ctx context.Context, method string) (context.Context, *AuditEntry) {
+	entry := &AuditEntry{
+		Time:     time.Now().UTC(),
+		Method:   method,
+		Metadata: MetadataFromContext(ctx),
+	}
+	return context.WithValue(ctx, auditContextKey{}, entry), entry
+}
+
+// commit links the entry of a finished request into the hash chain and appends
+// it to the log.
+func (l *AuditLogger) commit(entry *AuditEntry, err error) {
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entry.Seq, entry.Prev = l.seq, l.prev
+	hash, err := entry.computeHash()
+	if err != nil {
+		log.Error("Failed to hash audit entry", "method", entry.Method, "err", err)
+		return
+	}
+	entry.Hash = &hash
+
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		log.Error("Failed to encode audit entry", "method", entry.Method, "err", err)
+		return
+	}
+	if _, err := l.out.Write(append(blob, '\n')); err != nil {
+		log.Error("Failed to write audit entry", "method", entry.Method, "err", err)
+		return
+	}
+	l.seq, l.prev = l.seq+1, hash
 }
 
 func (l *AuditLogger) List(ctx context.Context) ([]common.Address, error) {
-	l.log.Info("List", "type", "request", "metadata", MetadataFromContext(ctx).String())
+	ctx, entry := l.begin(ctx, "account_list")
 	res, e := l.api.List(ctx)
-	l.log.Info("List", "type", "response", "data", res)
-
+	if e == nil {
+		entry.setResponse(res)
+	}
+	l.commit(entry, e)
 	return res, e
 }
 
 func (l *AuditLogger) New(ctx context.Context) (accounts.Account, error) {
-	return l.api.New(ctx)
+	ctx, entry := l.begin(ctx, "account_new")
+	acc, e := l.api.New(ctx)
+	if e == nil {
+		entry.Account = &acc.Address
+	}
+	l.commit(entry, e)
+	return acc, e
 }
 
 func (l *AuditLogger) SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error) {
-	sel := "<nil>"
-	if methodSelector != nil {
-		sel = *methodSelector
-	}
-	l.log.Info("SignTransaction", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"tx", args.String(),
-		"methodSelector", sel)
+	ctx, entry := l.begin(ctx, "account_signTransaction")
+	from := args.From.Address()
+	entry.Account = &from
+	entry.setRequest(struct {
+		Transaction    SendTxArgs `json:"transaction"`
+		MethodSelector *string    `json:"methodSelector,omitempty"`
+	}{args, methodSelector})
 
 	res, e := l.api.SignTransaction(ctx, args, methodSelector)
-	if res != nil {
-		l.log.Info("SignTransaction", "type", "response", "data", common.Bytes2Hex(res.Raw), "error", e)
-	} else {
-		l.log.Info("SignTransaction", "type", "response", "data", res, "error", e)
+	if res != nil && res.Tx != nil {
+		if blob, err := json.Marshal(res.Tx); err == nil {
+			entry.Transaction = blob
+		}
+		hash := res.Tx.Hash()
+		entry.SignatureHash = &hash
 	}
+	l.commit(entry, e)
 	return res, e
 }
 
 func (l *AuditLogger) Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
-	l.log.Info("Sign", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"addr", addr.String(), "data", common.Bytes2Hex(data))
+	ctx, entry := l.begin(ctx, "account_sign")
+	account := addr.Address()
+	entry.Account = &account
+	entry.setRequest(struct {
+		Data hexutil.Bytes `json:"data"`
+	}{data})
+
 	b, e := l.api.Sign(ctx, addr, data)
-	l.log.Info("Sign", "type", "response", "data", common.Bytes2Hex(b), "error", e)
+	if e == nil {
+		hash := crypto.Keccak256Hash(b)
+		entry.SignatureHash = &hash
+	}
+	l.commit(entry, e)
+	return b, e
+}
+
+func (l *AuditLogger) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, typedData TypedData) (hexutil.Bytes, error) {
+	ctx, entry := l.begin(ctx, "account_signTypedData")
+	account := addr.Address()
+	entry.Account = &account
+	entry.setRequest(typedData)
+
+	b, e := l.api.SignTypedData(ctx, addr, typedData)
+	if e == nil {
+		hash := crypto.Keccak256Hash(b)
+		entry.SignatureHash = &hash
+	}
+	l.commit(entry, e)
 	return b, e
 }
 
 func (l *AuditLogger) Export(ctx context.Context, addr common.Address) (json.RawMessage, error) {
-	l.log.Info("Export", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"addr", addr.Hex())
-	j, e := l.api.Export(ctx, addr)
+	ctx, entry := l.begin(ctx, "account_export")
+	entry.Account = &addr
+
 	// In this case, we don't actually log the json-response, which may be extra sensitive
-	l.log.Info("Export", "type", "response", "json response size", len(j), "error", e)
+	j, e := l.api.Export(ctx, addr)
+	l.commit(entry, e)
 	return j, e
 }
 
@@ -89,13 +270,234 @@ func (l *AuditLogger) Export(ctx context.Context, addr common.Address) (json.Raw
 //	return a, e
 //}
 
+// NewAuditLogger creates an audit logger appending to the log at the given path.
+// If the log already contains entries, new ones are chained onto the last one.
+// A legacy logfmt audit log is moved aside and a new chain is started.
 func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
-	l := log.New("api", "signer")
-	handler, err := log.FileHandler(path, log.LogfmtFormat())
+	l := &AuditLogger{api: api}
+
+	legacy, err := isLegacyAuditLog(path)
 	if err != nil {
 		return nil, err
 	}
-	l.SetHandler(handler)
-	l.Info("Configured", "audit log", path)
-	return &AuditLogger{l, api}, nil
+	if legacy {
+		rotated := path + ".legacy"
+		for i := 1; ; i++ {
+			if _, err := os.Stat(rotated); os.IsNotExist(err) {
+				break
+			}
+			rotated = fmt.Sprintf("%s.legacy.%d", path, i)
+		}
+		if err := os.Rename(path, rotated); err != nil {
+			return nil, fmt.Errorf("failed to move legacy audit log aside: %v", err)
+		}
+		log.Warn("Legacy audit log moved aside, starting new chain", "file", path, "legacy", rotated)
+	}
+	// Find the end of the chain in the existing log, if any
+	if f, err := os.Open(path); err == nil {
+		var (
+			v      = NewAuditVerifier()
+			broken error
+		)
+		err := IterateAuditLog(f, func(entry *AuditEntry) error {
+			if err := v.Check(entry); err != nil && broken == nil {
+				broken = err
+			}
+			l.seq, l.prev = entry.Seq+1, *entry.Hash
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unusable audit log %s: %v", path, err)
+		}
+		if broken != nil {
+			log.Warn("Audit log integrity check failed", "file", path, "err", broken)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l.out = f
+	log.Info("Configured", "audit log", path, "entries", l.seq)
+	return l, nil
+}
+
+// auditUI is a SignerUI wrapper recording the verdicts of an approval layer in
+// the audit entries of the requests passing through it.
+type auditUI struct {
+	SignerUI
+	stage string
+}
+
+// NewAuditUI wraps an approval layer of the signer, so that its decisions are
+// included in the audit log. Stage is either AuditStageRules or AuditStageUI.
+func NewAuditUI(next SignerUI, stage string) SignerUI {
+	return &auditUI{next, stage}
+}
+
+func (ui *auditUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	res, err := ui.SignerUI.ApproveTx(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.Callinfo = request.Callinfo
+		entry.decide(ui.stage, res.Approved, err)
+	}
+	return res, err
+}
+
+func (ui *auditUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	res, err := ui.SignerUI.ApproveSignData(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.decide(ui.stage, res.Approved, err)
+	}
+	return res, err
+}
+
+func (ui *auditUI) ApproveExport(request *ExportRequest) (ExportResponse, error) {
+	res, err := ui.SignerUI.ApproveExport(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.decide(ui.stage, res.Approved, err)
+	}
+	return res, err
+}
+
+func (ui *auditUI) ApproveImport(request *ImportRequest) (ImportResponse, error) {
+	res, err := ui.SignerUI.ApproveImport(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.decide(ui.stage, res.Approved, err)
+	}
+	return res, err
+}
+
+func (ui *auditUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	res, err := ui.SignerUI.ApproveListing(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.decide(ui.stage, res.Accounts != nil, err)
+	}
+	return res, err
+}
+
+func (ui *auditUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	res, err := ui.SignerUI.ApproveNewAccount(request)
+	if entry := request.Meta.audit; entry != nil {
+		entry.decide(ui.stage, res.Approved, err)
+	}
+	return res, err
+}
+
+// isLegacyAuditLog reports whether the file at path is an audit log in the
+// logfmt format written by older versions of clef, which can't be chained onto.
+func isLegacyAuditLog(path string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		blob, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		// The first non-empty line decides, entries are JSON objects
+		if blob = bytes.TrimSpace(blob); len(blob) > 0 {
+			return blob[0] != '{', nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
+}
+
+// IterateAuditLog decodes the entries of an audit log one by one, invoking the
+// callback with each. Iteration stops at the first error.
+func IterateAuditLog(r io.Reader, fn func(entry *AuditEntry) error) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		blob, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if blob = bytes.TrimSpace(blob); len(blob) > 0 {
+			entry := new(AuditEntry)
+			if err := json.Unmarshal(blob, entry); err != nil {
+				return fmt.Errorf("line %d: invalid entry: %v", line, err)
+			}
+			if entry.Hash == nil {
+				return fmt.Errorf("line %d: entry without hash", line)
+			}
+			if err := fn(entry); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// AuditVerifier checks the hash chain of consecutive audit log entries.
+type AuditVerifier struct {
+	count uint64      // Number of valid entries checked
+	prev  common.Hash // Hash of the last entry checked
+}
+
+// NewAuditVerifier creates a verifier expecting the first entry of a log.
+func NewAuditVerifier() *AuditVerifier {
+	return new(AuditVerifier)
+}
+
+// Check verifies that the entry is intact and follows the previous one.
+func (v *AuditVerifier) Check(entry *AuditEntry) error {
+	if entry.Seq != v.count {
+		return fmt.Errorf("entry %d out of sequence, expected %d", entry.Seq, v.count)
+	}
+	if entry.Prev != v.prev {
+		return fmt.Errorf("entry %d not linked to its predecessor: prev %x, expected %x", entry.Seq, entry.Prev, v.prev)
+	}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return err
+	}
+	if hash != *entry.Hash {
+		return fmt.Errorf("entry %d modified: hash %x, expected %x", entry.Seq, hash, *entry.Hash)
+	}
+	v.count, v.prev = v.count+1, hash
+	return nil
+}
+
+// VerifyAuditLog checks the integrity of an audit log, returning the number of
+// valid entries. An error is returned for the first entry which has been modified,
+// or which does not follow its predecessor, revealing deletions.
+func VerifyAuditLog(r io.Reader) (uint64, error) {
+	v := NewAuditVerifier()
+	err := IterateAuditLog(r, v.Check)
+	return v.count, err
+}
+
+// AuditFilter selects entries of the audit log. Zero fields match everything.
+type AuditFilter struct {
+	Account *common.Address // Account the request concerns
+	From    time.Time       // Earliest time of the request (inclusive)
+	To      time.Time       // Latest time of the request (inclusive)
+}
+
+// Match returns whether the entry is selected by the filter.
+func (f *AuditFilter) Match(entry *AuditEntry) bool {
+	if f.Account != nil && (entry.Account == nil || *entry.Account != *f.Account) {
+		return false
+	}
+	if !f.From.IsZero() && entry.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Time.After(f.To) {
+		return false
+	}
+	return true
 }
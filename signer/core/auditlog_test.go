@@ -0,0 +1,215 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// readAuditLog decodes all entries of the audit log at path.
+func readAuditLog(t *testing.T, path string) []*AuditEntry {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*AuditEntry
+	err = IterateAuditLog(bytes.NewReader(blob), func(entry *AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	api, control := setup(t)
+	api.UI = NewAuditUI(api.UI, AuditStageUI)
+	createAccount(control, api, t)
+
+	dir, err := ioutil.TempDir("", "clef-audit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	logger, err := NewAuditLogger(path, api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	control <- "A"
+	list, err := logger.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := common.NewMixedcaseAddress(list[0])
+
+	control <- "No way"
+	if _, err := logger.SignTransaction(context.Background(), mkTestTx(account), nil); err != ErrRequestDenied {
+		t.Fatalf("expected ErrRequestDenied, got %v", err)
+	}
+	control <- "Y"
+	control <- "a_long_password"
+	res, err := logger.SignTransaction(context.Background(), mkTestTx(account), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reopen the log, new entries should extend the existing chain
+	if logger, err = NewAuditLogger(path, api); err != nil {
+		t.Fatal(err)
+	}
+	control <- "Y"
+	control <- "a_long_password"
+	if _, err := logger.Sign(context.Background(), account, []byte("EHLO world")); err != nil {
+		t.Fatal(err)
+	}
+	entries := readAuditLog(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	checks := []struct {
+		method string
+		ui     string
+		err    string
+	}{
+		{"account_list", auditApproved, ""},
+		{"account_signTransaction", auditRejected, ErrRequestDenied.Error()},
+		{"account_signTransaction", auditApproved, ""},
+		{"account_sign", auditApproved, ""},
+	}
+	for i, check := range checks {
+		entry := entries[i]
+		if entry.Seq != uint64(i) || entry.Method != check.method || entry.UI != check.ui || entry.Error != check.err {
+			t.Errorf("entry %d: have seq %d, method %s, ui %q, error %q; want method %s, ui %q, error %q",
+				i, entry.Seq, entry.Method, entry.UI, entry.Error, check.method, check.ui, check.err)
+		}
+		if entry.Rules != "" {
+			t.Errorf("entry %d: unexpected rule verdict %q", i, entry.Rules)
+		}
+	}
+	if hash := entries[2].SignatureHash; hash == nil || *hash != res.Tx.Hash() {
+		t.Errorf("signature hash mismatch: have %v, want %x", hash, res.Tx.Hash())
+	}
+	if len(entries[2].Transaction) == 0 {
+		t.Errorf("signed transaction missing from entry")
+	}
+	// Verify the log, and check that tampering with it is detected
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VerifyAuditLog(bytes.NewReader(blob)); err != nil || n != 4 {
+		t.Fatalf("verification failed: %d entries, %v", n, err)
+	}
+	lines := strings.SplitAfter(strings.TrimSpace(string(blob)), "\n")
+
+	edited := strings.Join(lines[:1], "") + strings.Replace(lines[1], auditRejected, auditApproved, 1) + strings.Join(lines[2:], "")
+	if n, err := VerifyAuditLog(strings.NewReader(edited)); err == nil || n != 1 {
+		t.Errorf("edit not detected: %d entries, %v", n, err)
+	}
+	deleted := strings.Join(lines[:2], "") + strings.Join(lines[3:], "")
+	if n, err := VerifyAuditLog(strings.NewReader(deleted)); err == nil || n != 2 {
+		t.Errorf("deletion not detected: %d entries, %v", n, err)
+	}
+}
+
+func TestAuditLegacyLog(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+
+	dir, err := ioutil.TempDir("", "clef-audit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	legacy := []byte("t=2019-02-01T12:00:00+0100 lvl=info msg=List type=request metadata=\"{}\"\n")
+	if err := ioutil.WriteFile(path, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewAuditLogger(path, api)
+	if err != nil {
+		t.Fatalf("legacy audit log rejected: %v", err)
+	}
+	control <- "A"
+	if _, err := logger.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The legacy log should be moved aside untouched, with a new chain started
+	if blob, err := ioutil.ReadFile(path + ".legacy"); err != nil || !bytes.Equal(blob, legacy) {
+		t.Errorf("legacy log not preserved: %q, %v", blob, err)
+	}
+	if entries := readAuditLog(t, path); len(entries) != 1 || entries[0].Seq != 0 {
+		t.Errorf("expected a new chain with 1 entry, got %d", len(entries))
+	}
+}
+
+func TestAuditVerdicts(t *testing.T) {
+	entry := new(AuditEntry)
+	entry.decide(AuditStageRules, true, nil)
+	if entry.Rules != auditApproved || entry.UI != "" {
+		t.Errorf("rule approval: have rules %q, ui %q", entry.Rules, entry.UI)
+	}
+	// When the UI decides, the rules must have deferred to it
+	entry = new(AuditEntry)
+	entry.decide(AuditStageUI, false, nil)
+	entry.decide(AuditStageRules, false, nil)
+	if entry.Rules != auditDeferred || entry.UI != auditRejected {
+		t.Errorf("ui rejection: have rules %q, ui %q", entry.Rules, entry.UI)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	var (
+		alice = common.HexToAddress("0xa11ce")
+		bob   = common.HexToAddress("0xb0b")
+		start = time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	)
+	entry := &AuditEntry{Account: &alice, Time: start}
+	tests := []struct {
+		filter AuditFilter
+		match  bool
+	}{
+		{AuditFilter{}, true},
+		{AuditFilter{Account: &alice}, true},
+		{AuditFilter{Account: &bob}, false},
+		{AuditFilter{From: start, To: start}, true},
+		{AuditFilter{From: start.Add(time.Second)}, false},
+		{AuditFilter{To: start.Add(-time.Second)}, false},
+		{AuditFilter{Account: &alice, From: start.Add(-time.Hour), To: start.Add(time.Hour)}, true},
+	}
+	for i, test := range tests {
+		if match := test.filter.Match(entry); match != test.match {
+			t.Errorf("test %d: have match %v, want %v", i, match, test.match)
+		}
+	}
+	if (&AuditFilter{Account: &alice}).Match(&AuditEntry{}) {
+		t.Errorf("entry without account matched account filter")
+	}
+}
@@ -0,0 +1,686 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// MimetypeTextPlain is the content type of data signed with the
+	// personal-message prefix, as done by account_sign.
+	MimetypeTextPlain = "text/plain"
+	// MimetypeTypedData is the content type of EIP-712 structured data, as
+	// signed by account_signTypedData.
+	MimetypeTypedData = "data/typed"
+)
+
+// typedDataReferenceTypeRegexp matches the names of (arrays of) struct types.
+var typedDataReferenceTypeRegexp = regexp.MustCompile(`^[A-Z](\w*)(\[\])?$`)
+
+// TypedData is the EIP-712 structured data to sign, consisting of the type
+// definitions, the signing domain and the message of the primary type.
+type TypedData struct {
+	Types       Types            `json:"types"`
+	PrimaryType string           `json:"primaryType"`
+	Domain      TypedDataDomain  `json:"domain"`
+	Message     TypedDataMessage `json:"message"`
+}
+
+// Type is a single named member of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// isArray returns whether the member is a dynamic array.
+func (t *Type) isArray() bool {
+	return strings.HasSuffix(t.Type, "[]")
+}
+
+// typeName returns the canonical name of the type, i.e. 'Person' for both
+// 'Person' and 'Person[]'.
+func (t *Type) typeName() string {
+	return strings.TrimSuffix(t.Type, "[]")
+}
+
+// isReferenceType returns whether the member refers to a struct type, which by
+// convention have a leading uppercase character.
+func (t *Type) isReferenceType() bool {
+	if len(t.Type) == 0 {
+		return false
+	}
+	return unicode.IsUpper([]rune(t.Type)[0])
+}
+
+// Types maps struct type names to their members.
+type Types map[string][]Type
+
+// TypedDataMessage is the JSON decoded value of a struct.
+type TypedDataMessage = map[string]interface{}
+
+// TypedDataDomain is the EIP-712 domain separator struct. Only the fields set
+// are included in the domain hash.
+type TypedDataDomain struct {
+	Name              string                `json:"name"`
+	Version           string                `json:"version"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract string                `json:"verifyingContract"`
+	Salt              string                `json:"salt"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the chain id both as a
+// JSON number, as sent by most dapps, and as a decimal or hex string.
+func (domain *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type typedDataDomain TypedDataDomain
+	var dec struct {
+		typedDataDomain
+		ChainId json.RawMessage `json:"chainId"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*domain = TypedDataDomain(dec.typedDataDomain)
+	if len(dec.ChainId) == 0 || string(dec.ChainId) == "null" {
+		return nil
+	}
+	text := dec.ChainId
+	if text[0] == '"' {
+		var str string
+		if err := json.Unmarshal(dec.ChainId, &str); err != nil {
+			return err
+		}
+		text = []byte(str)
+	}
+	domain.ChainId = new(math.HexOrDecimal256)
+	if err := domain.ChainId.UnmarshalText(text); err != nil {
+		return fmt.Errorf("invalid chainId: %v", err)
+	}
+	return nil
+}
+
+// SignTypedData signs EIP-712 conformant typed data. The typed data is validated
+// and decoded for the user to review, after which the hash
+//   keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+// is signed by the requested account.
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, typedData TypedData) (hexutil.Bytes, error) {
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	typedDataHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := typedData.Format()
+	if err != nil {
+		return nil, err
+	}
+	rawData := append([]byte("\x19\x01"), append(domainSeparator, typedDataHash...)...)
+	sighash := crypto.Keccak256(rawData)
+
+	// We make the request prior to looking up if we actually have the account, to prevent
+	// account-enumeration via the API
+	req := &SignDataRequest{
+		ContentType: MimetypeTypedData,
+		Address:     addr,
+		Rawdata:     rawData,
+		Messages:    messages,
+		Hash:        sighash,
+		Meta:        MetadataFromContext(ctx),
+	}
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr.Address()}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Hardware wallets can't sign arbitrary hashes, hand them the typed data hashes
+	var signature []byte
+	if signer, ok := wallet.(accounts.TypedDataSigner); ok {
+		signature, err = signer.SignTypedData(account, domainSeparator, typedDataHash)
+	} else {
+		signature, err = wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	}
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
+// HashStruct generates a keccak256 hash of the encoding of the provided data.
+func (typedData *TypedData) HashStruct(primaryType string, data TypedDataMessage) (hexutil.Bytes, error) {
+	encodedData, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encodedData), nil
+}
+
+// Dependencies returns the struct types referenced by primaryType, including
+// itself, ordered by their discovery in the reference tree.
+func (typedData *TypedData) Dependencies(primaryType string, found []string) []string {
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if typedData.Types[primaryType] == nil {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range typedData.Types[primaryType] {
+		found = typedData.Dependencies(field.typeName(), found)
+	}
+	return found
+}
+
+// EncodeType generates the following encoding:
+//   name ‖ "(" ‖ member₁ ‖ "," ‖ member₂ ‖ "," ‖ … ‖ memberₙ ")"
+//
+// where each member is written as `type ‖ " " ‖ name`. The encodings of the
+// referenced struct types are appended, sorted by name.
+func (typedData *TypedData) EncodeType(primaryType string) hexutil.Bytes {
+	// Get dependencies primary first, then alphabetical
+	deps := typedData.Dependencies(primaryType, []string{})
+	if len(deps) > 0 {
+		sort.Strings(deps[1:])
+	}
+	// Format as a string with fields
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, obj := range typedData.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(obj.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(obj.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.Bytes()
+}
+
+// TypeHash creates the keccak256 hash of the type encoding.
+func (typedData *TypedData) TypeHash(primaryType string) hexutil.Bytes {
+	return crypto.Keccak256(typedData.EncodeType(primaryType))
+}
+
+// EncodeData generates the following encoding:
+//   typeHash ‖ enc(value₁) ‖ enc(value₂) ‖ … ‖ enc(valueₙ)
+//
+// where each encoded member is 32 bytes long.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) (hexutil.Bytes, error) {
+	if err := typedData.validate(); err != nil {
+		return nil, err
+	}
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("type %q is undefined", primaryType)
+	}
+	// Reject data not covered by the type, it would not be signed
+	for name := range data {
+		if !hasField(fields, name) {
+			return nil, fmt.Errorf("field %q is not defined by type %q", name, primaryType)
+		}
+	}
+	buffer := bytes.Buffer{}
+	buffer.Write(typedData.TypeHash(primaryType))
+
+	// Add field contents. Structs and arrays have special handlers.
+	for _, field := range fields {
+		encoded, err := typedData.encodeValue(field, data[field.Name])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue encodes a single struct member into its 32 byte representation.
+func (typedData *TypedData) encodeValue(field Type, value interface{}) ([]byte, error) {
+	if field.isArray() {
+		arrayValue, ok := value.([]interface{})
+		if !ok {
+			return nil, dataMismatchError(field.Type, value)
+		}
+		var buffer bytes.Buffer
+		for _, item := range arrayValue {
+			encoded, err := typedData.encodeValue(Type{Name: field.Name, Type: field.typeName()}, item)
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	if typedData.Types[field.Type] != nil {
+		mapValue, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, dataMismatchError(field.Type, value)
+		}
+		encoded, err := typedData.EncodeData(field.Type, mapValue)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	return EncodePrimitiveValue(field.Type, value)
+}
+
+// EncodePrimitiveValue encodes an atomic or dynamic (bytes, string) value into
+// its 32 byte representation.
+func EncodePrimitiveValue(encType string, encValue interface{}) ([]byte, error) {
+	switch encType {
+	case "address":
+		stringValue, ok := encValue.(string)
+		if !ok || !common.IsHexAddress(stringValue) {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		return common.LeftPadBytes(common.HexToAddress(stringValue).Bytes(), 32), nil
+
+	case "bool":
+		boolValue, ok := encValue.(bool)
+		if !ok {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		if boolValue {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return math.PaddedBigBytes(common.Big0, 32), nil
+
+	case "string":
+		strVal, ok := encValue.(string)
+		if !ok {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		return crypto.Keccak256([]byte(strVal)), nil
+
+	case "bytes":
+		bytesValue, ok := parseBytes(encValue)
+		if !ok {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		return crypto.Keccak256(bytesValue), nil
+	}
+	if strings.HasPrefix(encType, "bytes") {
+		length, err := strconv.Atoi(strings.TrimPrefix(encType, "bytes"))
+		if err != nil || length < 1 || length > 32 {
+			return nil, fmt.Errorf("invalid size on bytes: %v", strings.TrimPrefix(encType, "bytes"))
+		}
+		bytesValue, ok := parseBytes(encValue)
+		if !ok || len(bytesValue) != length {
+			return nil, dataMismatchError(encType, encValue)
+		}
+		return common.RightPadBytes(bytesValue, 32), nil
+	}
+	if strings.HasPrefix(encType, "int") || strings.HasPrefix(encType, "uint") {
+		b, err := parseInteger(encType, encValue)
+		if err != nil {
+			return nil, err
+		}
+		return math.PaddedBigBytes(math.U256(new(big.Int).Set(b)), 32), nil
+	}
+	return nil, fmt.Errorf("unrecognized type '%s'", encType)
+}
+
+// parseBytes attempts to interpret a value as a byte slice, either directly or
+// from a hex string.
+func parseBytes(encValue interface{}) ([]byte, bool) {
+	switch v := encValue.(type) {
+	case []byte:
+		return v, true
+	case hexutil.Bytes:
+		return v, true
+	case string:
+		bytes, err := hexutil.Decode(v)
+		if err != nil {
+			return nil, false
+		}
+		return bytes, true
+	default:
+		return nil, false
+	}
+}
+
+// parseInteger attempts to interpret a value as an integer of the given type,
+// accepting JSON numbers as well as decimal or hex strings.
+func parseInteger(encType string, encValue interface{}) (*big.Int, error) {
+	signed := strings.HasPrefix(encType, "int")
+	bits, ok := integerSize(encType)
+	if !ok {
+		return nil, fmt.Errorf("invalid size on integer: %v", encType)
+	}
+	var b *big.Int
+	switch v := encValue.(type) {
+	case *math.HexOrDecimal256:
+		b = (*big.Int)(v)
+	case *big.Int:
+		b = v
+	case string:
+		var value math.HexOrDecimal256
+		if err := value.UnmarshalText([]byte(v)); err != nil {
+			return nil, err
+		}
+		b = (*big.Int)(&value)
+	case float64:
+		// JSON parses non-strings as float64, fail if it's not lossless
+		if float64(int64(v)) != v {
+			return nil, fmt.Errorf("invalid float value %v for type %v", v, encType)
+		}
+		b = big.NewInt(int64(v))
+	default:
+		return nil, dataMismatchError(encType, encValue)
+	}
+	if !signed {
+		if b.Sign() < 0 {
+			return nil, fmt.Errorf("invalid negative value for unsigned type %v", encType)
+		}
+		if b.BitLen() > bits {
+			return nil, fmt.Errorf("integer larger than '%v'", encType)
+		}
+		return b, nil
+	}
+	limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+	if b.Cmp(limit) >= 0 || b.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("integer out of range for '%v'", encType)
+	}
+	return b, nil
+}
+
+// integerSize returns the bit size of an int<M> or uint<M> type, which must be a
+// multiple of 8 between 8 and 256. Unsized types default to 256 bits.
+func integerSize(encType string) (int, bool) {
+	size := strings.TrimPrefix(strings.TrimPrefix(encType, "u"), "int")
+	if size == "" {
+		return 256, true
+	}
+	bits, err := strconv.Atoi(size)
+	if err != nil || strconv.Itoa(bits) != size || bits < 8 || bits > 256 || bits%8 != 0 {
+		return 0, false
+	}
+	return bits, true
+}
+
+// dataMismatchError generates an error for a mismatch between the provided type
+// and data.
+func dataMismatchError(encType string, encValue interface{}) error {
+	return fmt.Errorf("provided data '%v' doesn't match type '%s'", encValue, encType)
+}
+
+// hasField returns whether a member with the given name is defined.
+func hasField(fields []Type, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate makes sure the types and the domain are sound.
+func (typedData *TypedData) validate() error {
+	if err := typedData.Types.validate(); err != nil {
+		return err
+	}
+	if _, ok := typedData.Types["EIP712Domain"]; !ok {
+		return errors.New("type EIP712Domain is undefined")
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok {
+		return fmt.Errorf("primary type %q is undefined", typedData.PrimaryType)
+	}
+	return typedData.Domain.validate()
+}
+
+// validate checks that the types are conformant to the specification.
+func (t Types) validate() error {
+	for typeKey, typeArr := range t {
+		if len(typeKey) == 0 {
+			return errors.New("empty type key")
+		}
+		names := make(map[string]bool)
+		for i, typeObj := range typeArr {
+			if len(typeObj.Type) == 0 {
+				return fmt.Errorf("type %q:%d: empty Type", typeKey, i)
+			}
+			if len(typeObj.Name) == 0 {
+				return fmt.Errorf("type %q:%d: empty Name", typeKey, i)
+			}
+			if names[typeObj.Name] {
+				return fmt.Errorf("type %q:%d: duplicate Name %q", typeKey, i, typeObj.Name)
+			}
+			names[typeObj.Name] = true
+
+			if typeKey == typeObj.Type {
+				return fmt.Errorf("type %q cannot reference itself", typeObj.Type)
+			}
+			if typeObj.isReferenceType() {
+				if !typedDataReferenceTypeRegexp.MatchString(typeObj.Type) {
+					return fmt.Errorf("unknown reference type %q", typeObj.Type)
+				}
+				if _, exist := t[typeObj.typeName()]; !exist {
+					return fmt.Errorf("reference type %q is undefined", typeObj.Type)
+				}
+			} else if !isPrimitiveTypeValid(typeObj.Type) {
+				return fmt.Errorf("unknown type %q", typeObj.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// isPrimitiveTypeValid checks whether the type (or array thereof) is one of the
+// atomic or dynamic types supported by EIP-712.
+func isPrimitiveTypeValid(primitiveType string) bool {
+	typ := strings.TrimSuffix(primitiveType, "[]")
+	switch typ {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	if strings.HasPrefix(typ, "bytes") {
+		size := strings.TrimPrefix(typ, "bytes")
+		length, err := strconv.Atoi(size)
+		return err == nil && strconv.Itoa(length) == size && length >= 1 && length <= 32
+	}
+	if strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") {
+		_, ok := integerSize(typ)
+		return ok
+	}
+	return false
+}
+
+// validate checks that the domain contains at least one of the domain fields.
+func (domain *TypedDataDomain) validate() error {
+	if domain.ChainId == nil && len(domain.Name) == 0 && len(domain.Version) == 0 && len(domain.VerifyingContract) == 0 && len(domain.Salt) == 0 {
+		return errors.New("domain is undefined")
+	}
+	return nil
+}
+
+// Map returns the domain as a message, containing only the fields set.
+func (domain *TypedDataDomain) Map() map[string]interface{} {
+	dataMap := map[string]interface{}{}
+
+	if domain.ChainId != nil {
+		dataMap["chainId"] = domain.ChainId
+	}
+	if len(domain.Name) > 0 {
+		dataMap["name"] = domain.Name
+	}
+	if len(domain.Version) > 0 {
+		dataMap["version"] = domain.Version
+	}
+	if len(domain.VerifyingContract) > 0 {
+		dataMap["verifyingContract"] = domain.VerifyingContract
+	}
+	if len(domain.Salt) > 0 {
+		dataMap["salt"] = domain.Salt
+	}
+	return dataMap
+}
+
+// NameValueType is a very simple struct with Name, Value and Type. It's meant for simple
+// json structures used to communicate signing-info about typed data with the UI
+type NameValueType struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Typ   string      `json:"type"`
+}
+
+// Pprint returns a pretty-printed version of nvt, indented by depth.
+func (nvt *NameValueType) Pprint(depth int) string {
+	output := bytes.Buffer{}
+	output.WriteString(strings.Repeat(" ", depth*2))
+	output.WriteString(fmt.Sprintf("%s [%s]: ", nvt.Name, nvt.Typ))
+	if nvts, ok := nvt.Value.([]*NameValueType); ok {
+		output.WriteString("\n")
+		for _, next := range nvts {
+			output.WriteString(next.Pprint(depth + 1))
+		}
+	} else if nvt.Value != nil {
+		output.WriteString(fmt.Sprintf("%q\n", nvt.Value))
+	} else {
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// Format returns a representation of the typed data which can be displayed by a
+// user interface without in-depth knowledge about EIP-712 rules.
+func (typedData *TypedData) Format() ([]*NameValueType, error) {
+	domain, err := typedData.formatData("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	message, err := typedData.formatData(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	return []*NameValueType{
+		{Name: "EIP712Domain", Value: domain, Typ: "domain"},
+		{Name: typedData.PrimaryType, Value: message, Typ: "primary type"},
+	}, nil
+}
+
+// formatData converts the members of a struct into their displayable form.
+func (typedData *TypedData) formatData(primaryType string, data map[string]interface{}) ([]*NameValueType, error) {
+	var output []*NameValueType
+	for _, field := range typedData.Types[primaryType] {
+		value, err := typedData.formatValue(field, data[field.Name])
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, &NameValueType{Name: field.Name, Value: value, Typ: field.Type})
+	}
+	return output, nil
+}
+
+// formatValue converts a single struct member into its displayable form.
+func (typedData *TypedData) formatValue(field Type, value interface{}) (interface{}, error) {
+	if field.isArray() {
+		arrayValue, ok := value.([]interface{})
+		if !ok {
+			return nil, dataMismatchError(field.Type, value)
+		}
+		items := make([]*NameValueType, 0, len(arrayValue))
+		for i, item := range arrayValue {
+			formatted, err := typedData.formatValue(Type{Type: field.typeName()}, item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, &NameValueType{Name: strconv.Itoa(i), Value: formatted, Typ: field.typeName()})
+		}
+		return items, nil
+	}
+	if typedData.Types[field.Type] != nil {
+		mapValue, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, dataMismatchError(field.Type, value)
+		}
+		return typedData.formatData(field.Type, mapValue)
+	}
+	return formatPrimitiveValue(field.Type, value)
+}
+
+// formatPrimitiveValue converts an atomic or dynamic value into a string.
+func formatPrimitiveValue(encType string, encValue interface{}) (string, error) {
+	switch encType {
+	case "address":
+		stringValue, ok := encValue.(string)
+		if !ok || !common.IsHexAddress(stringValue) {
+			return "", fmt.Errorf("could not format value %v as address", encValue)
+		}
+		return common.HexToAddress(stringValue).String(), nil
+
+	case "bool":
+		boolValue, ok := encValue.(bool)
+		if !ok {
+			return "", fmt.Errorf("could not format value %v as bool", encValue)
+		}
+		return fmt.Sprintf("%t", boolValue), nil
+
+	case "string":
+		return fmt.Sprintf("%s", encValue), nil
+	}
+	if strings.HasPrefix(encType, "bytes") {
+		bytesValue, ok := parseBytes(encValue)
+		if !ok {
+			return "", fmt.Errorf("could not format value %v as %s", encValue, encType)
+		}
+		return hexutil.Encode(bytesValue), nil
+	}
+	if strings.HasPrefix(encType, "uint") || strings.HasPrefix(encType, "int") {
+		b, err := parseInteger(encType, encValue)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d (0x%x)", b, b), nil
+	}
+	return "", fmt.Errorf("unhandled type %v", encType)
+}
@@ -0,0 +1,248 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mailTypedData is the example from the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func mailData(t *testing.T) TypedData {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typedData); err != nil {
+		t.Fatalf("failed to unmarshal typed data: %v", err)
+	}
+	return typedData
+}
+
+// Tests the intermediate and final hashes of the specification example.
+func TestTypedDataHashes(t *testing.T) {
+	typedData := mailData(t)
+
+	if have, want := string(typedData.EncodeType("Mail")), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("type encoding mismatch: have %q, want %q", have, want)
+	}
+	if have, want := typedData.TypeHash("Mail").String(), "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"; have != want {
+		t.Errorf("type hash mismatch: have %s, want %s", have, want)
+	}
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if have, want := domainSeparator.String(), "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; have != want {
+		t.Errorf("domain separator mismatch: have %s, want %s", have, want)
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if have, want := messageHash.String(), "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; have != want {
+		t.Errorf("message hash mismatch: have %s, want %s", have, want)
+	}
+	sighash := crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash)
+	if have, want := hexutil.Encode(sighash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Errorf("signing hash mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the types of array members are included in the type encoding.
+func TestTypedDataArrayDependencies(t *testing.T) {
+	typedData := mailData(t)
+	typedData.Types["Mail"] = []Type{
+		{Name: "from", Type: "Person"},
+		{Name: "to", Type: "Recipient[]"},
+	}
+	typedData.Types["Recipient"] = []Type{{Name: "person", Type: "Person"}}
+
+	if have, want := string(typedData.EncodeType("Mail")), "Mail(Person from,Recipient[] to)Person(string name,address wallet)Recipient(Person person)"; have != want {
+		t.Errorf("type encoding mismatch: have %q, want %q", have, want)
+	}
+}
+
+// Tests that malformed types and values are rejected.
+func TestTypedDataValidation(t *testing.T) {
+	tests := []struct {
+		mutate func(*TypedData)
+		err    string
+	}{
+		{func(td *TypedData) { td.PrimaryType = "Letter" }, `primary type "Letter" is undefined`},
+		{func(td *TypedData) { delete(td.Types, "EIP712Domain") }, "type EIP712Domain is undefined"},
+		{func(td *TypedData) { td.Domain = TypedDataDomain{} }, "domain is undefined"},
+		{func(td *TypedData) { td.Types["Person"][1].Type = "address20" }, `unknown type "address20"`},
+		{func(td *TypedData) { td.Types["Person"][1].Type = "uint7" }, `unknown type "uint7"`},
+		{func(td *TypedData) { td.Types["Person"][1].Type = "bytes33" }, `unknown type "bytes33"`},
+		{func(td *TypedData) { td.Types["Mail"][0].Type = "Animal" }, `reference type "Animal" is undefined`},
+		{func(td *TypedData) { td.Types["Person"][1].Type = "Person" }, `type "Person" cannot reference itself`},
+		{func(td *TypedData) { td.Types["Person"][1].Name = "name" }, `duplicate Name "name"`},
+		{func(td *TypedData) { td.Message["cc"] = "Alice" }, `field "cc" is not defined by type "Mail"`},
+		{func(td *TypedData) { td.Message["contents"] = 1.0 }, "doesn't match type 'string'"},
+		{func(td *TypedData) { td.Message["to"].(map[string]interface{})["wallet"] = "0xbeef" }, "doesn't match type 'address'"},
+	}
+	for i, tt := range tests {
+		typedData := mailData(t)
+		tt.mutate(&typedData)
+
+		_, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+// Tests the encoding of atomic values, including integer range checks.
+func TestEncodePrimitiveValue(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  string
+		err   bool
+	}{
+		{typ: "uint8", value: 255.0, want: "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+		{typ: "uint8", value: 256.0, err: true},
+		{typ: "uint256", value: -1.0, err: true},
+		{typ: "uint256", value: "0x10", want: "0x0000000000000000000000000000000000000000000000000000000000000010"},
+		{typ: "int8", value: -128.0, want: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80"},
+		{typ: "int8", value: 128.0, err: true},
+		{typ: "int8", value: -129.0, err: true},
+		{typ: "int", value: 1.5, err: true},
+		{typ: "bool", value: true, want: "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{typ: "bytes2", value: "0xbeef", want: "0xbeef000000000000000000000000000000000000000000000000000000000000"},
+		{typ: "bytes2", value: "0xbe", err: true},
+		{typ: "address", value: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC", want: "0x000000000000000000000000cccccccccccccccccccccccccccccccccccccccc"},
+	}
+	for i, tt := range tests {
+		have, err := EncodePrimitiveValue(tt.typ, tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: expected error for %v as %s, got %x", i, tt.value, tt.typ, have)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to encode %v as %s: %v", i, tt.value, tt.typ, err)
+			continue
+		}
+		if hexutil.Encode(have) != tt.want {
+			t.Errorf("test %d: encoding mismatch: have %x, want %s", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the typed data is formatted into a tree the UI can display.
+func TestTypedDataFormat(t *testing.T) {
+	typedData := mailData(t)
+
+	messages, err := typedData.Format()
+	if err != nil {
+		t.Fatalf("failed to format typed data: %v", err)
+	}
+	var output string
+	for _, nvt := range messages {
+		output += nvt.Pprint(0)
+	}
+	want := `EIP712Domain [domain]: 
+  name [string]: "Ether Mail"
+  version [string]: "1"
+  chainId [uint256]: "1 (0x1)"
+  verifyingContract [address]: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+Mail [primary type]: 
+  from [Person]: 
+    name [string]: "Cow"
+    wallet [address]: "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"
+  to [Person]: 
+    name [string]: "Bob"
+    wallet [address]: "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"
+  contents [string]: "Hello, Bob!"
+`
+	if output != want {
+		t.Errorf("formatting mismatch:\nhave:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0])
+	typedData := mailData(t)
+
+	control <- "No way"
+	if _, err := api.SignTypedData(context.Background(), a, typedData); err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+	control <- "Y"
+	control <- "a_long_password"
+	sig, err := api.SignTypedData(context.Background(), a, typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 65 || (sig[64] != 27 && sig[64] != 28) {
+		t.Fatalf("Expected 65 byte signature with legacy V (got %x)", sig)
+	}
+	// Ensure the signature recovers to the signing account
+	sig[64] -= 27
+	pubkey, err := crypto.SigToPub(hexutil.MustDecode("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := crypto.PubkeyToAddress(*pubkey); have != a.Address() {
+		t.Errorf("signer mismatch: have %x, want %x", have, a.Address())
+	}
+}
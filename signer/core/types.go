@@ -102,6 +102,13 @@ type SendTxArgs struct {
 	// We accept "data" and "input" for backwards-compatibility reasons.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// For non-legacy transactions
+	Type                 *hexutil.Uint64   `json:"type,omitempty"`
+	ChainID              *hexutil.Big      `json:"chainId,omitempty"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
 }
 
 func (args SendTxArgs) String() string {
@@ -112,15 +119,73 @@ func (args SendTxArgs) String() string {
 	return err.Error()
 }
 
-func (args *SendTxArgs) toTransaction() *types.Transaction {
+// TxType returns the EIP-2718 type of the requested transaction. Unless set
+// explicitly, it is derived from the fee and access list fields present.
+func (args *SendTxArgs) TxType() uint8 {
+	switch {
+	case args.Type != nil:
+		return uint8(*args.Type)
+	case args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil:
+		return types.DynamicFeeTxType
+	case args.AccessList != nil:
+		return types.AccessListTxType
+	default:
+		return types.LegacyTxType
+	}
+}
+
+// ToTransaction converts the arguments into an unsigned transaction of the
+// requested type.
+func (args *SendTxArgs) ToTransaction() *types.Transaction {
 	var input []byte
 	if args.Data != nil {
 		input = *args.Data
 	} else if args.Input != nil {
 		input = *args.Input
 	}
-	if args.To == nil {
-		return types.NewContractCreation(uint64(args.Nonce), (*big.Int)(&args.Value), uint64(args.Gas), (*big.Int)(&args.GasPrice), input)
+	var to *common.Address
+	if args.To != nil {
+		addr := args.To.Address()
+		to = &addr
+	}
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	var data types.TxData
+	switch args.TxType() {
+	case types.DynamicFeeTxType:
+		data = &types.DynamicFeeTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        uint64(args.Gas),
+			To:         to,
+			Value:      (*big.Int)(&args.Value),
+			Data:       input,
+			AccessList: accessList,
+		}
+	case types.AccessListTxType:
+		data = &types.AccessListTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(args.Nonce),
+			GasPrice:   (*big.Int)(&args.GasPrice),
+			Gas:        uint64(args.Gas),
+			To:         to,
+			Value:      (*big.Int)(&args.Value),
+			Data:       input,
+			AccessList: accessList,
+		}
+	default:
+		data = &types.LegacyTx{
+			Nonce:    uint64(args.Nonce),
+			GasPrice: (*big.Int)(&args.GasPrice),
+			Gas:      uint64(args.Gas),
+			To:       to,
+			Value:    (*big.Int)(&args.Value),
+			Data:     input,
+		}
 	}
-	return types.NewTransaction(uint64(args.Nonce), args.To.Address(), (*big.Int)(&args.Value), (uint64)(args.Gas), (*big.Int)(&args.GasPrice), input)
+	return types.NewTx(data)
 }
@@ -24,6 +24,7 @@ import (
 	"regexp"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // The validation package contains validation checks for transactions
@@ -114,7 +115,22 @@ func (v *Validator) validate(msgs *ValidationMessages, txargs *SendTxArgs, metho
 	if txargs.Data != nil {
 		data = *txargs.Data
 	}
-
+	// Make sure the fee fields match the requested transaction type
+	switch txargs.TxType() {
+	case types.LegacyTxType, types.AccessListTxType:
+		if txargs.MaxFeePerGas != nil || txargs.MaxPriorityFeePerGas != nil {
+			return fmt.Errorf("Tx of type %d cannot have maxFeePerGas or maxPriorityFeePerGas", txargs.TxType())
+		}
+	case types.DynamicFeeTxType:
+		if txargs.MaxFeePerGas == nil || txargs.MaxPriorityFeePerGas == nil {
+			return errors.New("Dynamic fee tx requires both maxFeePerGas and maxPriorityFeePerGas")
+		}
+		if txargs.MaxPriorityFeePerGas.ToInt().Cmp(txargs.MaxFeePerGas.ToInt()) > 0 {
+			return errors.New("Tx maxPriorityFeePerGas is higher than maxFeePerGas")
+		}
+	default:
+		return fmt.Errorf("Unsupported tx type %d", txargs.TxType())
+	}
 	if txargs.To == nil {
 		//Contract creation should contain sufficient data to deploy a contract
 		// A typical error is omitting sender due to some quirk in the javascript call
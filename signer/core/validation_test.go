@@ -56,7 +56,7 @@ func dummyTxArgs(t txtestcase) *SendTxArgs {
 		input = &a
 
 	}
-	return &SendTxArgs{
+	args := &SendTxArgs{
 		From:     *from,
 		To:       to,
 		Value:    value,
@@ -66,10 +66,24 @@ func dummyTxArgs(t txtestcase) *SendTxArgs {
 		Data:     data,
 		Input:    input,
 	}
+	if t.typ != "" {
+		typ := toHexUint(t.typ)
+		args.Type = &typ
+	}
+	if t.maxFee != "" {
+		maxFee := toHexBig(t.maxFee)
+		args.MaxFeePerGas = &maxFee
+	}
+	if t.tip != "" {
+		tip := toHexBig(t.tip)
+		args.MaxPriorityFeePerGas = &tip
+	}
+	return args
 }
 
 type txtestcase struct {
 	from, to, n, g, gp, value, d, i string
+	typ, maxFee, tip                string
 	expectErr                       bool
 	numMessages                     int
 }
@@ -108,6 +122,21 @@ func TestValidator(t *testing.T) {
 		// Small payload for create
 		{from: "000000000000000000000000000000000000dead", to: "",
 			n: "0x01", g: "0x20", gp: "0x40", value: "0x01", d: "0x01", numMessages: 1},
+		// Dynamic fee tx
+		{from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+			n: "0x01", g: "0x20", maxFee: "0x40", tip: "0x02", value: "0x01", numMessages: 0},
+		// Dynamic fee tx without a priority fee
+		{from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+			n: "0x01", g: "0x20", typ: "0x02", maxFee: "0x40", value: "0x01", expectErr: true},
+		// Dynamic fee tx with the priority fee above the fee cap
+		{from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+			n: "0x01", g: "0x20", maxFee: "0x40", tip: "0x41", value: "0x01", expectErr: true},
+		// Legacy tx with a fee cap
+		{from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+			n: "0x01", g: "0x20", typ: "0x00", maxFee: "0x40", tip: "0x02", value: "0x01", expectErr: true},
+		// Unknown tx type
+		{from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+			n: "0x01", g: "0x20", gp: "0x40", typ: "0x03", value: "0x01", expectErr: true},
 	}
 	for i, test := range testcases {
 		msgs, err := v.ValidateTransaction(dummyTxArgs(test), nil)
@@ -25,6 +25,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -98,6 +99,40 @@ func (s *AESEncryptedStorage) Get(key string) string {
 	return string(entry)
 }
 
+// Del removes the value stored by key, if any. 0-length keys results in no-op
+func (s *AESEncryptedStorage) Del(key string) {
+	if len(key) == 0 {
+		return
+	}
+	data, err := s.readEncryptedStorage()
+	if err != nil {
+		log.Warn("Failed to read encrypted storage", "err", err, "file", s.filename)
+		return
+	}
+	if _, exist := data[key]; !exist {
+		return
+	}
+	delete(data, key)
+	if err = s.writeEncryptedStorage(data); err != nil {
+		log.Warn("Failed to write storage", "err", err)
+	}
+}
+
+// Keys returns the sorted list of keys with stored values. The keys are not
+// encrypted, so this doesn't require decrypting any values.
+func (s *AESEncryptedStorage) Keys() ([]string, error) {
+	data, err := s.readEncryptedStorage()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
 // readEncryptedStorage reads the file with encrypted creds
 func (s *AESEncryptedStorage) readEncryptedStorage() (map[string]storedCredential, error) {
 	creds := make(map[string]storedCredential)
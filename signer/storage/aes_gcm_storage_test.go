@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -162,3 +163,29 @@ func TestSwappedKeys(t *testing.T) {
 		t.Errorf("double-swapped value should work fine")
 	}
 }
+
+func TestDeleteAndKeys(t *testing.T) {
+	d, err := ioutil.TempDir("", "eth-encrypted-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewAESEncryptedStorage(fmt.Sprintf("%v/vault.json", d), []byte("AES256Key-32Characters1234567890"))
+
+	s.Put("k2", "v2")
+	s.Put("k1", "v1")
+	s.Put("k3", "v3")
+	if keys, err := s.Keys(); err != nil || strings.Join(keys, ",") != "k1,k2,k3" {
+		t.Fatalf("Expected keys k1,k2,k3, got %v (err %v)", keys, err)
+	}
+	s.Del("k2")
+	s.Del("missing")
+	if keys, err := s.Keys(); err != nil || strings.Join(keys, ",") != "k1,k3" {
+		t.Errorf("Expected keys k1,k3, got %v (err %v)", keys, err)
+	}
+	if v := s.Get("k2"); v != "" {
+		t.Errorf("Expected deleted value to be empty, got '%v'", v)
+	}
+	if v := s.Get("k3"); v != "v3" {
+		t.Errorf("Expected k3->v3, got '%v'", v)
+	}
+}
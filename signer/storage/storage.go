@@ -26,6 +26,8 @@ type Storage interface {
 	Put(key, value string)
 	// Get returns the previously stored value, or the empty string if it does not exist or key is of 0-length
 	Get(key string) string
+	// Del removes the value stored by key, if any. 0-length keys results in no-op
+	Del(key string)
 }
 
 // EphemeralStorage is an in-memory storage that does
@@ -54,6 +56,14 @@ func (s *EphemeralStorage) Get(key string) string {
 	return ""
 }
 
+func (s *EphemeralStorage) Del(key string) {
+	if len(key) == 0 {
+		return
+	}
+	fmt.Printf("storage: del %v\n", key)
+	delete(s.data, key)
+}
+
 func NewEphemeralStorage() Storage {
 	s := &EphemeralStorage{
 		data: make(map[string]string),
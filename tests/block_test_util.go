@@ -48,6 +48,11 @@ func (t *BlockTest) UnmarshalJSON(in []byte) error {
 	return json.Unmarshal(in, &t.json)
 }
 
+// MarshalJSON implements json.Marshaler interface.
+func (t *BlockTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&t.json)
+}
+
 type btJSON struct {
 	Blocks     []btBlock             `json:"blocks"`
 	Genesis    btHeader              `json:"genesisBlockHeader"`
@@ -59,30 +64,30 @@ type btJSON struct {
 }
 
 type btBlock struct {
-	BlockHeader  *btHeader
-	Rlp          string
-	UncleHeaders []*btHeader
+	BlockHeader  *btHeader   `json:"blockHeader,omitempty"`
+	Rlp          string      `json:"rlp"`
+	UncleHeaders []*btHeader `json:"uncleHeaders"`
 }
 
 //go:generate gencodec -type btHeader -field-override btHeaderMarshaling -out gen_btheader.go
 
 type btHeader struct {
-	Bloom            types.Bloom
-	Coinbase         common.Address
-	MixHash          common.Hash
-	Nonce            types.BlockNonce
-	Number           *big.Int
-	Hash             common.Hash
-	ParentHash       common.Hash
-	ReceiptTrie      common.Hash
-	StateRoot        common.Hash
-	TransactionsTrie common.Hash
-	UncleHash        common.Hash
-	ExtraData        []byte
-	Difficulty       *big.Int
-	GasLimit         uint64
-	GasUsed          uint64
-	Timestamp        *big.Int
+	Bloom            types.Bloom      `json:"bloom"`
+	Coinbase         common.Address   `json:"coinbase"`
+	MixHash          common.Hash      `json:"mixHash"`
+	Nonce            types.BlockNonce `json:"nonce"`
+	Number           *big.Int         `json:"number"`
+	Hash             common.Hash      `json:"hash"`
+	ParentHash       common.Hash      `json:"parentHash"`
+	ReceiptTrie      common.Hash      `json:"receiptTrie"`
+	StateRoot        common.Hash      `json:"stateRoot"`
+	TransactionsTrie common.Hash      `json:"transactionsTrie"`
+	UncleHash        common.Hash      `json:"uncleHash"`
+	ExtraData        []byte           `json:"extraData"`
+	Difficulty       *big.Int         `json:"difficulty"`
+	GasLimit         uint64           `json:"gasLimit"`
+	GasUsed          uint64           `json:"gasUsed"`
+	Timestamp        *big.Int         `json:"timestamp"`
 }
 
 type btHeaderMarshaling struct {
@@ -0,0 +1,589 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"gopkg.in/yaml.v2"
+)
+
+// A Filler is the compact description of a consensus test, from which a state
+// or blockchain test fixture is generated.
+//
+// Fillers with a transaction section describe state tests: the transaction is
+// executed with every combination of its data, gas and value variants that is
+// covered by an expectation. Fillers with a blocks section describe blockchain
+// tests: the blocks are generated on top of the genesis described by the env
+// and pre sections, yielding one test per network.
+type Filler struct {
+	json fillerJSON
+}
+
+type fillerJSON struct {
+	Env    stEnv                             `json:"env"`
+	Pre    map[common.Address]*fillerAccount `json:"pre"`
+	Tx     *stTransaction                    `json:"transaction"`
+	Blocks []*btFillerBlock                  `json:"blocks"`
+	Expect []*fillerExpect                   `json:"expect"`
+}
+
+// fillerAccount is an account in the pre-state of a filler, or the expected
+// post-state of one. Fields left empty are zero in the pre-state and are not
+// checked in the post-state.
+type fillerAccount struct {
+	Balance *math.HexOrDecimal256 `json:"balance"`
+	Nonce   *math.HexOrDecimal64  `json:"nonce"`
+	Code    *hexutil.Bytes        `json:"code"`
+	Storage map[string]string     `json:"storage"`
+}
+
+// fillerExpect is an expectation on the outcome of a test on a set of networks.
+// The indexes select the transaction variants of state tests it applies to,
+// an omitted index matching all variants.
+type fillerExpect struct {
+	Indexes struct {
+		Data  *math.HexOrDecimal64 `json:"data"`
+		Gas   *math.HexOrDecimal64 `json:"gas"`
+		Value *math.HexOrDecimal64 `json:"value"`
+	} `json:"indexes"`
+	Network         []string                          `json:"network"`
+	ExpectException string                            `json:"expectException"`
+	Result          map[common.Address]*fillerAccount `json:"result"`
+}
+
+type btFillerBlock struct {
+	Coinbase     *common.Address        `json:"coinbase"`
+	Transactions []*btFillerTransaction `json:"transactions"`
+}
+
+// btFillerTransaction is a transaction to include into a generated block. If
+// an exception is expected, the transaction must fail with an error containing
+// it and is left out of the block. Contract creations omit the recipient.
+type btFillerTransaction struct {
+	Data            hexutil.Bytes         `json:"data"`
+	GasLimit        math.HexOrDecimal64   `json:"gasLimit"`
+	GasPrice        *math.HexOrDecimal256 `json:"gasPrice"`
+	Nonce           math.HexOrDecimal64   `json:"nonce"`
+	To              *common.Address       `json:"to"`
+	Value           *math.HexOrDecimal256 `json:"value"`
+	SecretKey       hexutil.Bytes         `json:"secretKey"`
+	ExpectException string                `json:"expectException"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (f *Filler) UnmarshalJSON(in []byte) error {
+	if err := json.Unmarshal(in, &f.json); err != nil {
+		return err
+	}
+	if (f.json.Tx == nil) == (f.json.Blocks == nil) {
+		return errors.New("filler must have exactly one of a transaction or a blocks section")
+	}
+	return nil
+}
+
+// ParseFillers parses a set of fillers keyed by test name. As YAML is a superset
+// of JSON, fillers may be written in either format.
+func ParseFillers(data []byte) (map[string]*Filler, error) {
+	var raw map[string]fillerValue
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	fillers := make(map[string]*Filler, len(raw))
+	for name, value := range raw {
+		blob, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		filler := new(Filler)
+		if err := json.Unmarshal(blob, filler); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		fillers[name] = filler
+	}
+	return fillers, nil
+}
+
+// fillerValue is a generic YAML value which keeps all scalars in their textual
+// form, so hex numbers and byte strings are passed on to the JSON decoders of
+// the filler fields untouched.
+type fillerValue struct {
+	v interface{}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface.
+func (v *fillerValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var generic interface{}
+	if err := unmarshal(&generic); err != nil {
+		return err
+	}
+	switch generic.(type) {
+	case nil:
+		v.v = nil
+		return nil
+	case map[interface{}]interface{}:
+		var m map[string]fillerValue
+		if err := unmarshal(&m); err != nil {
+			return err
+		}
+		v.v = m
+		return nil
+	case []interface{}:
+		var s []fillerValue
+		if err := unmarshal(&s); err != nil {
+			return err
+		}
+		v.v = s
+		return nil
+	default:
+		var s string
+		if err := unmarshal(&s); err != nil {
+			return err
+		}
+		v.v = s
+		return nil
+	}
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (v fillerValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.v)
+}
+
+// IsBlockTest reports whether the filler describes a blockchain test.
+func (f *Filler) IsBlockTest() bool {
+	return f.json.Blocks != nil
+}
+
+// FillStateTest generates the state test described by the filler. Every
+// transaction variant covered by an expectation is executed, verified against
+// the expectation and recorded in the post section of the test.
+func (f *Filler) FillStateTest() (*StateTest, error) {
+	if f.IsBlockTest() {
+		return nil, errors.New("not a state test filler")
+	}
+	pre, err := f.genesisAlloc()
+	if err != nil {
+		return nil, err
+	}
+	forks, err := f.networks()
+	if err != nil {
+		return nil, err
+	}
+	test := &StateTest{json: stJSON{
+		Env:  f.json.Env,
+		Pre:  pre,
+		Tx:   *f.json.Tx,
+		Post: make(map[string][]stPostState),
+	}}
+	for _, fork := range forks {
+		for d := range test.json.Tx.Data {
+			for g := range test.json.Tx.GasLimit {
+				for v := range test.json.Tx.Value {
+					if err := f.fillStatePost(test, fork, d, g, v); err != nil {
+						return nil, fmt.Errorf("%s, indexes data %d gas %d value %d: %v", fork, d, g, v, err)
+					}
+				}
+			}
+		}
+	}
+	// Make sure the filled test passes with the regular runner
+	for _, subtest := range test.Subtests() {
+		if _, err := test.Run(subtest, vm.Config{}); err != nil {
+			return nil, fmt.Errorf("%s/%d: filled test failed: %v", subtest.Fork, subtest.Index, err)
+		}
+	}
+	return test, nil
+}
+
+// fillStatePost executes a single variant of a state test transaction if it is
+// covered by any expectation, adding the result to the post section.
+func (f *Filler) fillStatePost(test *StateTest, fork string, d, g, v int) error {
+	var (
+		expects   []*fillerExpect
+		exception string
+	)
+	for _, exp := range f.json.Expect {
+		if !exp.matches(fork, d, g, v) {
+			continue
+		}
+		if exp.ExpectException != "" {
+			if exception != "" && exception != exp.ExpectException {
+				return fmt.Errorf("conflicting exceptions %q and %q", exception, exp.ExpectException)
+			}
+			exception = exp.ExpectException
+		}
+		expects = append(expects, exp)
+	}
+	if len(expects) == 0 {
+		return nil
+	}
+	post := stPostState{ExpectException: exception}
+	post.Indexes.Data, post.Indexes.Gas, post.Indexes.Value = d, g, v
+
+	index := len(test.json.Post[fork])
+	test.json.Post[fork] = append(test.json.Post[fork], post)
+
+	statedb, root, err := test.RunNoVerify(StateSubtest{fork, index}, vm.Config{})
+	if statedb == nil {
+		return err
+	}
+	if err := checkException(err, exception); err != nil {
+		return err
+	}
+	for _, exp := range expects {
+		if err := exp.verify(statedb); err != nil {
+			return err
+		}
+	}
+	test.json.Post[fork][index].Root = common.UnprefixedHash(root)
+	test.json.Post[fork][index].Logs = common.UnprefixedHash(rlpHash(statedb.Logs()))
+	return nil
+}
+
+// FillBlockTests generates the blockchain tests described by the filler, one
+// for each network an expectation is given for. The tests are named after the
+// filler, suffixed with the network.
+func (f *Filler) FillBlockTests(name string) (map[string]*BlockTest, error) {
+	if !f.IsBlockTest() {
+		return nil, errors.New("not a blockchain test filler")
+	}
+	if f.json.Env.Number != 0 {
+		return nil, fmt.Errorf("genesis number must be zero, have %d", f.json.Env.Number)
+	}
+	for _, exp := range f.json.Expect {
+		if exp.ExpectException != "" {
+			return nil, errors.New("blockchain test exceptions must be set on the transactions")
+		}
+	}
+	pre, err := f.genesisAlloc()
+	if err != nil {
+		return nil, err
+	}
+	forks, err := f.networks()
+	if err != nil {
+		return nil, err
+	}
+	tests := make(map[string]*BlockTest, len(forks))
+	for _, fork := range forks {
+		test, err := f.fillBlockTest(fork, pre)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fork, err)
+		}
+		tests[name+"_"+fork] = test
+	}
+	return tests, nil
+}
+
+// fillBlockTest generates the blocks of a blockchain test filler on a single
+// network, importing them one by one so transactions can access the hashes of
+// their ancestors.
+func (f *Filler) fillBlockTest(fork string, pre core.GenesisAlloc) (*BlockTest, error) {
+	config := Forks[fork]
+	genesis := &core.Genesis{
+		Config:     config,
+		Coinbase:   f.json.Env.Coinbase,
+		Difficulty: f.json.Env.Difficulty,
+		GasLimit:   f.json.Env.GasLimit,
+		Timestamp:  f.json.Env.Timestamp,
+		Alloc:      pre,
+	}
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = ethash.NewFaker()
+		gblock = genesis.MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer chain.Stop()
+
+	test := &BlockTest{json: btJSON{
+		Genesis:    *newBtHeader(gblock.Header()),
+		Pre:        pre,
+		Network:    fork,
+		SealEngine: "NoProof",
+	}}
+	for i, fb := range f.json.Blocks {
+		var genErr error
+		blocks, _ := core.GenerateChain(config, chain.CurrentBlock(), engine, db, 1, func(_ int, b *core.BlockGen) {
+			if fb.Coinbase != nil {
+				b.SetCoinbase(*fb.Coinbase)
+			}
+			signer := types.MakeSigner(config, b.Number())
+			for j, ftx := range fb.Transactions {
+				tx, err := ftx.sign(signer)
+				if err == nil {
+					err = checkException(b.TryAddTxWithChain(chain, tx), ftx.ExpectException)
+				}
+				if err != nil {
+					genErr = fmt.Errorf("transaction %d: %v", j, err)
+					return
+				}
+			}
+		})
+		if genErr != nil {
+			return nil, fmt.Errorf("block %d: %v", i, genErr)
+		}
+		if _, err := chain.InsertChain(blocks); err != nil {
+			return nil, fmt.Errorf("block %d: import failed: %v", i, err)
+		}
+		blob, err := rlp.EncodeToBytes(blocks[0])
+		if err != nil {
+			return nil, err
+		}
+		test.json.Blocks = append(test.json.Blocks, btBlock{
+			BlockHeader:  newBtHeader(blocks[0].Header()),
+			Rlp:          hexutil.Encode(blob),
+			UncleHeaders: []*btHeader{},
+		})
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		return nil, err
+	}
+	for _, exp := range f.json.Expect {
+		if exp.hasNetwork(fork) {
+			if err := exp.verify(statedb); err != nil {
+				return nil, err
+			}
+		}
+	}
+	test.json.Post = dumpGenesisAlloc(statedb)
+	test.json.BestBlock = common.UnprefixedHash(chain.CurrentBlock().Hash())
+
+	// Make sure the filled test passes with the regular runner
+	if err := test.Run(); err != nil {
+		return nil, fmt.Errorf("filled test failed: %v", err)
+	}
+	return test, nil
+}
+
+// genesisAlloc converts the pre-state of the filler into a genesis allocation.
+func (f *Filler) genesisAlloc() (core.GenesisAlloc, error) {
+	alloc := make(core.GenesisAlloc, len(f.json.Pre))
+	for addr, acc := range f.json.Pre {
+		account := core.GenesisAccount{Balance: new(big.Int)}
+		if acc.Balance != nil {
+			account.Balance = (*big.Int)(acc.Balance)
+		}
+		if acc.Nonce != nil {
+			account.Nonce = uint64(*acc.Nonce)
+		}
+		if acc.Code != nil {
+			account.Code = *acc.Code
+		}
+		storage, err := parseStorage(acc.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("pre-state of %x: %v", addr, err)
+		}
+		if len(storage) > 0 {
+			account.Storage = storage
+		}
+		alloc[addr] = account
+	}
+	return alloc, nil
+}
+
+// networks returns the sorted set of networks the filler has expectations for.
+func (f *Filler) networks() ([]string, error) {
+	set := make(map[string]bool)
+	for _, exp := range f.json.Expect {
+		for _, fork := range exp.Network {
+			if _, ok := Forks[fork]; !ok {
+				return nil, UnsupportedForkError{fork}
+			}
+			set[fork] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil, errors.New("no networks to fill the test for")
+	}
+	forks := make([]string, 0, len(set))
+	for fork := range set {
+		forks = append(forks, fork)
+	}
+	sort.Strings(forks)
+	return forks, nil
+}
+
+// hasNetwork reports whether the expectation applies to the given network.
+func (e *fillerExpect) hasNetwork(fork string) bool {
+	for _, network := range e.Network {
+		if network == fork {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the expectation applies to the given network and
+// state test transaction variant.
+func (e *fillerExpect) matches(fork string, d, g, v int) bool {
+	match := func(index *math.HexOrDecimal64, i int) bool {
+		return index == nil || uint64(*index) == uint64(i)
+	}
+	return e.hasNetwork(fork) && match(e.Indexes.Data, d) && match(e.Indexes.Gas, g) && match(e.Indexes.Value, v)
+}
+
+// verify checks the expected accounts against the given state.
+func (e *fillerExpect) verify(statedb *state.StateDB) error {
+	for addr, acc := range e.Result {
+		if acc.Balance != nil {
+			if have, want := statedb.GetBalance(addr), (*big.Int)(acc.Balance); have.Cmp(want) != 0 {
+				return fmt.Errorf("account %x: balance mismatch: have %v, want %v", addr, have, want)
+			}
+		}
+		if acc.Nonce != nil {
+			if have, want := statedb.GetNonce(addr), uint64(*acc.Nonce); have != want {
+				return fmt.Errorf("account %x: nonce mismatch: have %d, want %d", addr, have, want)
+			}
+		}
+		if acc.Code != nil {
+			if have, want := statedb.GetCode(addr), []byte(*acc.Code); !bytes.Equal(have, want) {
+				return fmt.Errorf("account %x: code mismatch: have %x, want %x", addr, have, want)
+			}
+		}
+		storage, err := parseStorage(acc.Storage)
+		if err != nil {
+			return fmt.Errorf("account %x: %v", addr, err)
+		}
+		for key, want := range storage {
+			if have := statedb.GetState(addr, key); have != want {
+				return fmt.Errorf("account %x: storage %x mismatch: have %x, want %x", addr, key, have, want)
+			}
+		}
+	}
+	return nil
+}
+
+// sign assembles the transaction and signs it with its secret key.
+func (tx *btFillerTransaction) sign(signer types.Signer) (*types.Transaction, error) {
+	key, err := crypto.ToECDSA(tx.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %v", err)
+	}
+	value, gasPrice := new(big.Int), new(big.Int)
+	if tx.Value != nil {
+		value = (*big.Int)(tx.Value)
+	}
+	if tx.GasPrice != nil {
+		gasPrice = (*big.Int)(tx.GasPrice)
+	}
+	var unsigned *types.Transaction
+	if tx.To == nil {
+		unsigned = types.NewContractCreation(uint64(tx.Nonce), value, uint64(tx.GasLimit), gasPrice, tx.Data)
+	} else {
+		unsigned = types.NewTransaction(uint64(tx.Nonce), *tx.To, value, uint64(tx.GasLimit), gasPrice, tx.Data)
+	}
+	return types.SignTx(unsigned, signer, key)
+}
+
+// checkException verifies that a transaction failed with the expected error, or
+// succeeded if none was expected.
+func checkException(err error, exception string) error {
+	switch {
+	case exception == "" && err != nil:
+		return fmt.Errorf("unexpected transaction error: %v", err)
+	case exception != "" && err == nil:
+		return fmt.Errorf("expected transaction error %q, got none", exception)
+	case exception != "" && !strings.Contains(err.Error(), exception):
+		return fmt.Errorf("transaction error mismatch: have %q, want %q", err, exception)
+	}
+	return nil
+}
+
+// parseStorage converts storage slots given as hex or decimal numbers.
+func parseStorage(storage map[string]string) (map[common.Hash]common.Hash, error) {
+	slots := make(map[common.Hash]common.Hash, len(storage))
+	for k, v := range storage {
+		key, ok := math.ParseBig256(k)
+		if !ok {
+			return nil, fmt.Errorf("invalid storage key %q", k)
+		}
+		val, ok := math.ParseBig256(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid storage value %q", v)
+		}
+		slots[common.BigToHash(key)] = common.BigToHash(val)
+	}
+	return slots, nil
+}
+
+// newBtHeader converts a block header into its test representation.
+func newBtHeader(h *types.Header) *btHeader {
+	return &btHeader{
+		Bloom:            h.Bloom,
+		Coinbase:         h.Coinbase,
+		MixHash:          h.MixDigest,
+		Nonce:            h.Nonce,
+		Number:           h.Number,
+		Hash:             h.Hash(),
+		ParentHash:       h.ParentHash,
+		ReceiptTrie:      h.ReceiptHash,
+		StateRoot:        h.Root,
+		TransactionsTrie: h.TxHash,
+		UncleHash:        h.UncleHash,
+		ExtraData:        h.Extra,
+		Difficulty:       h.Difficulty,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Timestamp:        h.Time,
+	}
+}
+
+// dumpGenesisAlloc converts the content of a state database into a genesis
+// allocation.
+func dumpGenesisAlloc(statedb *state.StateDB) core.GenesisAlloc {
+	alloc := make(core.GenesisAlloc)
+	for addr, acc := range statedb.RawDump().Accounts {
+		balance, _ := new(big.Int).SetString(acc.Balance, 10)
+		account := core.GenesisAccount{
+			Code:    common.FromHex(acc.Code),
+			Balance: balance,
+			Nonce:   acc.Nonce,
+		}
+		if len(acc.Storage) > 0 {
+			account.Storage = make(map[common.Hash]common.Hash)
+			for k, v := range acc.Storage {
+				// Storage values are kept rlp encoded in the trie
+				_, content, _, _ := rlp.Split(common.FromHex(v))
+				account.Storage[common.HexToHash(k)] = common.BytesToHash(content)
+			}
+		}
+		alloc[common.HexToAddress(addr)] = account
+	}
+	return alloc
+}
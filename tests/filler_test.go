@@ -0,0 +1,191 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+const stateFiller = `
+sstoreTest:
+  env:
+    currentCoinbase: 0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba
+    currentDifficulty: 0x20000
+    currentGasLimit: 10000000
+    currentNumber: 1
+    currentTimestamp: 1000
+  pre:
+    0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b:
+      balance: 1000000000000000000
+    0x095e7baea6a6c7c4c2dfeb977efac326af552d87:
+      code: 0x600160005500 # SSTORE(0, 1)
+  transaction:
+    data: [0x]
+    gasLimit: [100000, 21000]
+    gasPrice: 10
+    nonce: 0
+    secretKey: 0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8
+    to: 0x095e7baea6a6c7c4c2dfeb977efac326af552d87
+    value: [0]
+  expect:
+    - indexes: {gas: 0}
+      network: [Byzantium, Istanbul]
+      result:
+        0x095e7baea6a6c7c4c2dfeb977efac326af552d87:
+          storage: {0x00: 0x01}
+        0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b:
+          nonce: 1
+    - indexes: {gas: 1}
+      network: [Istanbul]
+      result:
+        0x095e7baea6a6c7c4c2dfeb977efac326af552d87:
+          storage: {0x00: 0}
+`
+
+const blockFiller = `
+{
+  "transferTest": {
+    "env": {
+      "currentCoinbase": "0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+      "currentDifficulty": "0x20000",
+      "currentGasLimit": "10000000",
+      "currentNumber": "0",
+      "currentTimestamp": "0"
+    },
+    "pre": {
+      "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {"balance": "1000000000000000000"}
+    },
+    "blocks": [
+      {
+        "transactions": [
+          {"nonce": 0, "gasLimit": 21000, "gasPrice": 1, "value": 10, "to": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87",
+           "secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8"},
+          {"nonce": 0, "gasLimit": 21000, "gasPrice": 1, "value": 10, "to": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87",
+           "secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8", "expectException": "nonce too low"}
+        ]
+      },
+      {
+        "coinbase": "0x0000000000000000000000000000000000000001",
+        "transactions": [
+          {"nonce": 1, "gasLimit": 21000, "gasPrice": 1, "value": 10, "to": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87",
+           "secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8"}
+        ]
+      }
+    ],
+    "expect": [
+      {
+        "network": ["Byzantium", "Istanbul"],
+        "result": {
+          "0x095e7baea6a6c7c4c2dfeb977efac326af552d87": {"balance": 20},
+          "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {"nonce": 2}
+        }
+      }
+    ]
+  }
+}
+`
+
+// Tests that state test fillers in YAML format are filled for the covered
+// transaction variants, and the fixtures survive a JSON round trip.
+func TestFillStateTest(t *testing.T) {
+	fillers, err := ParseFillers([]byte(stateFiller))
+	if err != nil {
+		t.Fatalf("failed to parse fillers: %v", err)
+	}
+	filler := fillers["sstoreTest"]
+	if filler == nil || filler.IsBlockTest() {
+		t.Fatalf("state test filler not found: %v", fillers)
+	}
+	test, err := filler.FillStateTest()
+	if err != nil {
+		t.Fatalf("failed to fill test: %v", err)
+	}
+	if n := len(test.json.Post["Byzantium"]); n != 1 {
+		t.Errorf("Byzantium post state count mismatch: have %d, want 1", n)
+	}
+	if n := len(test.json.Post["Istanbul"]); n != 2 {
+		t.Errorf("Istanbul post state count mismatch: have %d, want 2", n)
+	}
+	blob, err := json.Marshal(test)
+	if err != nil {
+		t.Fatalf("failed to encode test: %v", err)
+	}
+	var filled StateTest
+	if err := json.Unmarshal(blob, &filled); err != nil {
+		t.Fatalf("failed to decode test: %v", err)
+	}
+	for _, subtest := range filled.Subtests() {
+		if _, err := filled.Run(subtest, vm.Config{}); err != nil {
+			t.Errorf("%s/%d: %v", subtest.Fork, subtest.Index, err)
+		}
+	}
+}
+
+// Tests that blockchain test fillers in JSON format are filled for every
+// network, skipping the transactions expected to fail.
+func TestFillBlockTests(t *testing.T) {
+	fillers, err := ParseFillers([]byte(blockFiller))
+	if err != nil {
+		t.Fatalf("failed to parse fillers: %v", err)
+	}
+	tests, err := fillers["transferTest"].FillBlockTests("transferTest")
+	if err != nil {
+		t.Fatalf("failed to fill tests: %v", err)
+	}
+	if len(tests) != 2 {
+		t.Fatalf("test count mismatch: have %d, want 2", len(tests))
+	}
+	for name, test := range tests {
+		if n := len(test.json.Blocks); n != 2 {
+			t.Errorf("%s: block count mismatch: have %d, want 2", name, n)
+		}
+		blob, err := json.Marshal(test)
+		if err != nil {
+			t.Fatalf("%s: failed to encode test: %v", name, err)
+		}
+		var filled BlockTest
+		if err := json.Unmarshal(blob, &filled); err != nil {
+			t.Fatalf("%s: failed to decode test: %v", name, err)
+		}
+		if err := filled.Run(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// Tests that failing expectations abort the filling.
+func TestFillExpectationMismatch(t *testing.T) {
+	filler := strings.Replace(stateFiller, "storage: {0x00: 0x01}", "storage: {0x00: 0x02}", 1)
+	fillers, err := ParseFillers([]byte(filler))
+	if err != nil {
+		t.Fatalf("failed to parse fillers: %v", err)
+	}
+	if _, err := fillers["sstoreTest"].FillStateTest(); err == nil || !strings.Contains(err.Error(), "storage") {
+		t.Errorf("expected storage mismatch, got %v", err)
+	}
+	filler = strings.Replace(blockFiller, `"expectException": "nonce too low"`, `"expectException": "intrinsic gas too low"`, 1)
+	if fillers, err = ParseFillers([]byte(filler)); err != nil {
+		t.Fatalf("failed to parse fillers: %v", err)
+	}
+	if _, err := fillers["transferTest"].FillBlockTests("transferTest"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("expected exception mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// istanbulTest is a single contract execution checked against the rules of a
+// given fork.
+type istanbulTest struct {
+	name   string
+	fork   string
+	number int64
+	code   string
+	output string // Expected return data, empty if none
+	used   uint64 // Expected gas consumption, 0 to skip the check
+	fail   bool
+}
+
+var istanbulTests = []istanbulTest{
+	// EIP-1344: CHAINID returns the chain id of the fork config
+	{name: "chainid", fork: "Istanbul", code: "0x4660005260206000f3", output: "0x0000000000000000000000000000000000000000000000000000000000000001"},
+	{name: "chainid-pre-fork", fork: "ConstantinopleFix", code: "0x4660005260206000f3", fail: true},
+	{name: "chainid-transition-before", fork: "ConstantinopleFixToIstanbulAt5", number: 4, code: "0x4660005260206000f3", fail: true},
+	{name: "chainid-transition-after", fork: "ConstantinopleFixToIstanbulAt5", number: 5, code: "0x4660005260206000f3", output: "0x0000000000000000000000000000000000000000000000000000000000000001"},
+
+	// EIP-1884: SELFBALANCE returns the balance of the executing contract
+	{name: "selfbalance", fork: "Istanbul", code: "0x4760005260206000f3", output: "0x000000000000000000000000000000000000000000000000000000000000002a", used: 20},
+	{name: "selfbalance-pre-fork", fork: "ConstantinopleFix", code: "0x4760005260206000f3", fail: true},
+
+	// EIP-1884: SLOAD, BALANCE and EXTCODEHASH repricing
+	{name: "sload", fork: "Istanbul", code: "0x60005400", used: 803},
+	{name: "sload-pre-fork", fork: "ConstantinopleFix", code: "0x60005400", used: 203},
+	{name: "balance", fork: "Istanbul", code: "0x303100", used: 702},
+	{name: "balance-pre-fork", fork: "ConstantinopleFix", code: "0x303100", used: 402},
+	{name: "extcodehash", fork: "Istanbul", code: "0x303f00", used: 702},
+	{name: "extcodehash-pre-fork", fork: "ConstantinopleFix", code: "0x303f00", used: 402},
+
+	// EIP-2200: net gas metering for SSTORE with the reentrancy sentry
+	{name: "sstore-noop", fork: "Istanbul", code: "0x60006000556000600055", used: 1612},
+	{name: "sstore-create-reset", fork: "Istanbul", code: "0x60016000556000600055", used: 20812},
+	{name: "sstore-pre-fork", fork: "ConstantinopleFix", code: "0x60006000556000600055", used: 10012},
+}
+
+// Tests the Istanbul opcode and gas rule changes against the fork configs used
+// by the consensus test suites.
+func TestIstanbulRules(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+
+	for _, tt := range istanbulTests {
+		config, ok := Forks[tt.fork]
+		if !ok {
+			t.Fatalf("%s: unsupported fork %s", tt.name, tt.fork)
+		}
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.SetCode(address, hexutil.MustDecode(tt.code))
+		statedb.SetBalance(address, big.NewInt(42))
+
+		cfg := &runtime.Config{
+			ChainConfig: config,
+			BlockNumber: big.NewInt(tt.number),
+			GasLimit:    100000,
+			State:       statedb,
+		}
+		ret, gas, err := runtime.Call(address, nil, cfg)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s: expected failure, got output %x", tt.name, ret)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: execution failed: %v", tt.name, err)
+			continue
+		}
+		if want := hexutil.MustDecode(nonEmpty(tt.output)); !bytes.Equal(ret, want) {
+			t.Errorf("%s: output mismatch: have %x, want %x", tt.name, ret, want)
+		}
+		if used := cfg.GasLimit - gas; tt.used != 0 && used != tt.used {
+			t.Errorf("%s: gas used mismatch: have %d, want %d", tt.name, used, tt.used)
+		}
+	}
+}
+
+// Tests that EIP-2028 reduces the intrinsic gas of non-zero calldata bytes.
+func TestIstanbulIntrinsicGas(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02}
+
+	tests := []struct {
+		fork string
+		gas  uint64
+	}{
+		{"ConstantinopleFix", 21000 + 4 + 2*68},
+		{"Istanbul", 21000 + 4 + 2*16},
+	}
+	for _, tt := range tests {
+		istanbul := Forks[tt.fork].IsIstanbul(new(big.Int))
+
+		gas, err := core.IntrinsicGas(data, nil, false, true, istanbul)
+		if err != nil {
+			t.Errorf("%s: failed to calculate intrinsic gas: %v", tt.fork, err)
+			continue
+		}
+		if gas != tt.gas {
+			t.Errorf("%s: intrinsic gas mismatch: have %d, want %d", tt.fork, gas, tt.gas)
+		}
+	}
+}
+
+// nonEmpty converts an empty hex string into its canonical 0x form.
+func nonEmpty(hex string) string {
+	if hex == "" {
+		return "0x"
+	}
+	return hex
+}
@@ -83,6 +83,12 @@ var testVMConfig = func() vm.Config {
 func withTrace(t *testing.T, gasLimit uint64, test func(vm.Config) error) {
 	err := test(testVMConfig)
 	if err == nil {
+		// The pre-decoded execution must match the reference interpreter
+		decoded := testVMConfig
+		decoded.EnableCodeDecoding = true
+		if err := test(decoded); err != nil {
+			t.Errorf("pre-decoded execution failed: %v", err)
+		}
 		return
 	}
 	t.Error(err)